@@ -4,7 +4,9 @@ import (
 	_ "embed" // Required for go:embed
 
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"log"
 	"os"
 	"path/filepath"
@@ -15,7 +17,10 @@ import (
 
 	"github.com/spf13/viper"
 
+	"github.com/highercomve/tasktracker/internal/cli"
 	"github.com/highercomve/tasktracker/internal/i18n"
+	"github.com/highercomve/tasktracker/internal/instance"
+	"github.com/highercomve/tasktracker/internal/service"
 	"github.com/highercomve/tasktracker/internal/store"
 	"github.com/highercomve/tasktracker/internal/ui"
 	"github.com/highercomve/tasktracker/internal/updater"
@@ -64,9 +69,22 @@ func setupViper() error {
 	}
 
 	viper.SetDefault("data_folder", "./data")
+	viper.SetDefault("storage_backend", store.BackendJSON)
 	viper.SetDefault("hourly_rate", 0.0)
 	viper.SetDefault("max_hours", 0.0)
 	viper.SetDefault("extra_rate", 0.0)
+	viper.SetDefault("currency", "")
+	viper.SetDefault("weekly_target", 0.0)
+	viper.SetDefault("away_policy", service.AwayAsk)
+	viper.SetDefault("pomodoro_work", 25)
+	viper.SetDefault("pomodoro_short_break", 5)
+	viper.SetDefault("pomodoro_long_break", 15)
+	viper.SetDefault("pomodoro_long_break_every", 4)
+	viper.SetDefault("reminders.no_timer_minutes", 30)
+	viper.SetDefault("reminders.long_running_hours", 4.0)
+	viper.SetDefault("reminders.work_start", "09:00")
+	viper.SetDefault("reminders.work_end", "17:00")
+	viper.SetDefault("reminders.day_summary_at", "18:00")
 
 	if err := viper.ReadInConfig(); err != nil {
 		if _, ok := err.(viper.ConfigFileNotFoundError); ok || os.IsNotExist(err) {
@@ -81,6 +99,21 @@ func setupViper() error {
 	return nil
 }
 
+// runCLI runs a headless subcommand directly against the data folder.
+func runCLI(args []string) error {
+	storage, err := store.Open(viper.GetString("storage_backend"), viper.GetString("data_folder"))
+	if err != nil {
+		return err
+	}
+	defer storage.Close()
+
+	timer := service.NewTimer(storage, service.SystemClock)
+	if err := timer.Restore(); err != nil {
+		return err
+	}
+	return cli.New(timer, storage, os.Stdout).Run(args)
+}
+
 func main() {
 	os.Setenv("FYNE_SCALE", "auto")
 
@@ -89,6 +122,47 @@ func main() {
 		log.Printf("Warning: error setting up config: %v\n", viperErr)
 	}
 
+	// Headless subcommands (start, pause, stop, ...) never open the GUI.
+	if len(os.Args) > 1 && cli.IsCommand(os.Args[1]) {
+		if viperErr != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", viperErr)
+			os.Exit(1)
+		}
+		// A running GUI executes the command itself, so its timer and
+		// the files never disagree.
+		args := cli.AbsPaths(os.Args[1:])
+		err := instance.Forward(filepath.Dir(userConfigFilePath), args, os.Stdout)
+		if errors.Is(err, instance.ErrNotRunning) {
+			err = runCLI(args)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Only one GUI per user: a second launch raises the first window.
+	var server *instance.Server
+	if viperErr == nil {
+		configDir := filepath.Dir(userConfigFilePath)
+		var err error
+		server, err = instance.Listen(configDir)
+		if errors.Is(err, instance.ErrAlreadyRunning) {
+			if err := instance.Forward(configDir, []string{instance.ShowWindow}, io.Discard); err != nil {
+				log.Printf("TaskTracker is already running but did not respond: %v", err)
+				os.Exit(1)
+			}
+			log.Println("TaskTracker is already running; showing its window")
+			return
+		}
+		if err != nil {
+			log.Printf("Warning: single-instance check failed: %v", err)
+		} else {
+			defer server.Close()
+		}
+	}
+
 	// Pre-load translations to check for fallback needs
 	// We need to do this before app.New if we want to force FYNE_LANG
 	supportedLocales := make(map[string]bool)
@@ -178,26 +252,56 @@ func main() {
 		return
 	}
 
-	storage := store.NewStorage(viper.GetString("data_folder"))
-	dashboard := ui.NewDashboard(storage)
-	reports := ui.NewReports(storage)
-	projects := ui.NewProjects(storage)
-	configUI := ui.NewConfig(w, storage, userConfigFilePath)
+	storage, err := store.Open(viper.GetString("storage_backend"), viper.GetString("data_folder"))
+	if err != nil {
+		dialog.ShowError(err, w)
+		w.ShowAndRun()
+		return
+	}
+	defer storage.Close()
+
+	timer := service.NewTimer(storage, service.SystemClock)
+	undo := service.NewUndoStack(50)
+	dashboard := ui.NewDashboard(storage, timer, undo)
+	reports := ui.NewReports(storage, undo)
+	projects := ui.NewProjects(storage, undo)
+	invoices := ui.NewInvoices(storage)
+	configUI := ui.NewConfig(w, storage, userConfigFilePath, undo)
 
 	tabs := container.NewAppTabs(
 		container.NewTabItem(lang.L("tracker_tab"), dashboard.MakeUI()),
 		container.NewTabItem(lang.L("reports_tab"), reports.MakeUI()),
 		container.NewTabItem(lang.L("projects_tab"), projects.MakeUI()),
+		container.NewTabItem(lang.L("invoices_tab"), invoices.MakeUI()),
 		container.NewTabItem(lang.L("config_tab"), configUI.MakeUI()),
 	)
 
 	w.SetContent(tabs)
 
+	if server != nil {
+		go server.Serve(func(args []string, out io.Writer) error {
+			if len(args) == 1 && args[0] == instance.ShowWindow {
+				fyne.Do(func() {
+					w.Show()
+					w.RequestFocus()
+				})
+				return nil
+			}
+			return cli.New(timer, storage, out).Run(args)
+		})
+	}
+
 	dashboard.SetupShortcuts(w)
+	ui.SetupUndo(w, undo)
 
 	ui.SetupTray(a, w, iconResource, dashboard)
 
 	ui.CheckVersion(w, storage)
 
+	ui.WatchSyncConflicts(w, storage)
+
+	reminders := ui.StartReminders(storage, timer)
+	defer reminders.Stop()
+
 	w.ShowAndRun()
 }
@@ -0,0 +1,404 @@
+// Package cli implements the headless command-line interface of TaskTracker.
+//
+// The subcommands drive the same service.Timer, store.Storage and AppState the
+// GUI uses, so timers can be driven from scripts, git hooks or window-manager
+// keybindings without opening the main window.
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/highercomve/tasktracker/internal/importer"
+	"github.com/highercomve/tasktracker/internal/models"
+	"github.com/highercomve/tasktracker/internal/service"
+	"github.com/highercomve/tasktracker/internal/store"
+	"github.com/highercomve/tasktracker/internal/utils"
+)
+
+type command struct {
+	usage string
+	help  string
+	run   func(c *CLI, args []string) error
+}
+
+var commands map[string]command
+
+// commandOrder keeps the help output stable.
+var commandOrder = []string{"start", "pause", "resume", "stop", "status", "import"}
+
+func init() {
+	// Populated in init because the handlers refer back to the table for
+	// their usage strings.
+	commands = map[string]command{
+		"start": {
+			usage: "start <description> [--project NAME] [--tag a,b]",
+			help:  "Start a new task, stopping the active one first",
+			run:   (*CLI).start,
+		},
+		"pause": {
+			usage: "pause",
+			help:  "Pause the running task",
+			run:   (*CLI).pause,
+		},
+		"resume": {
+			usage: "resume",
+			help:  "Resume the paused task",
+			run:   (*CLI).resume,
+		},
+		"stop": {
+			usage: "stop",
+			help:  "Stop the active task and save it to history",
+			run:   (*CLI).stop,
+		},
+		"status": {
+			usage: "status [--json]",
+			help:  "Show the active task and its elapsed time",
+			run:   (*CLI).status,
+		},
+		"import": {
+			usage: "import <file> [--format NAME] [--date-format dd/mm|mm/dd] [--apply]",
+			help:  "Preview importing entries from another tracker; --apply saves them",
+			run:   (*CLI).importEntries,
+		},
+	}
+}
+
+// IsCommand reports whether name is a CLI subcommand (including help).
+func IsCommand(name string) bool {
+	if name == "help" || name == "-h" || name == "--help" {
+		return true
+	}
+	_, ok := commands[name]
+	return ok
+}
+
+// AbsPaths returns args with the file arguments made absolute, so a command
+// forwarded to a running instance reads the files the user meant.
+func AbsPaths(args []string) []string {
+	if len(args) < 2 || args[0] != "import" {
+		return args
+	}
+	out := slices.Clone(args)
+	for i := 1; i < len(out); i++ {
+		switch arg := out[i]; {
+		case arg == "--format" || arg == "-format" || arg == "--date-format" || arg == "-date-format":
+			i++ // skip the value
+		case strings.HasPrefix(arg, "-"):
+		default:
+			if abs, err := filepath.Abs(arg); err == nil {
+				out[i] = abs
+			}
+		}
+	}
+	return out
+}
+
+// CLI runs subcommands against the shared timer and storage.
+type CLI struct {
+	timer   *service.Timer
+	storage store.Store
+	out     io.Writer
+}
+
+// New creates a CLI that writes its output to out. The timer should already
+// be restored from storage.
+func New(timer *service.Timer, s store.Store, out io.Writer) *CLI {
+	return &CLI{timer: timer, storage: s, out: out}
+}
+
+// Run executes the subcommand named by args[0] with the remaining arguments.
+func (c *CLI) Run(args []string) error {
+	if len(args) == 0 || args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+		c.usage()
+		return nil
+	}
+	cmd, ok := commands[args[0]]
+	if !ok {
+		c.usage()
+		return fmt.Errorf("unknown command %q", args[0])
+	}
+	return cmd.run(c, args[1:])
+}
+
+func (c *CLI) usage() {
+	fmt.Fprintln(c.out, "Usage: tasktracker [command] [arguments]")
+	fmt.Fprintln(c.out, "")
+	fmt.Fprintln(c.out, "Without a command the graphical interface is started.")
+	fmt.Fprintln(c.out, "")
+	fmt.Fprintln(c.out, "Commands:")
+	for _, name := range commandOrder {
+		cmd := commands[name]
+		fmt.Fprintf(c.out, "  %-50s %s\n", cmd.usage, cmd.help)
+	}
+}
+
+// parseInterspersed parses flags that may appear before, after or between
+// positional arguments, so `start "fix bug" --project X` works as expected.
+func parseInterspersed(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+func newFlagSet(name string, out io.Writer) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(out)
+	fs.Usage = func() {
+		fmt.Fprintf(out, "Usage: tasktracker %s\n", commands[name].usage)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+func (c *CLI) resolveProjectID(name string) (string, error) {
+	if name == "" {
+		return "", nil
+	}
+	projects, err := c.storage.LoadProjects()
+	if err != nil {
+		return "", err
+	}
+	if p := service.FindProjectByName(projects, name); p != nil {
+		return p.ID, nil
+	}
+	if p := service.FindProjectByID(projects, name); p != nil {
+		return p.ID, nil
+	}
+	return "", fmt.Errorf("project %q not found", name)
+}
+
+func (c *CLI) start(args []string) error {
+	fs := newFlagSet("start", c.out)
+	projectName := fs.String("project", "", "project name or ID")
+	tagList := fs.String("tag", "", "comma-separated tags, first is primary")
+	positional, err := parseInterspersed(fs, args)
+	if err != nil {
+		return err
+	}
+	desc := strings.TrimSpace(strings.Join(positional, " "))
+	if desc == "" {
+		fs.Usage()
+		return errors.New("a task description is required")
+	}
+
+	projectID, err := c.resolveProjectID(*projectName)
+	if err != nil {
+		return err
+	}
+
+	var tags []string
+	for _, tag := range strings.Split(*tagList, ",") {
+		if trimmed := strings.TrimSpace(tag); trimmed != "" {
+			tags = append(tags, trimmed)
+		}
+	}
+
+	entry, err := c.timer.Start(desc, projectID, tags)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(c.out, "Started: %s\n", entry.Description)
+	return nil
+}
+
+func (c *CLI) pause(args []string) error {
+	if err := c.timer.Pause(); err != nil {
+		return err
+	}
+	entry, _ := c.timer.Active()
+	fmt.Fprintf(c.out, "Paused: %s (%s)\n", entry.Description, utils.FormatDuration(c.timer.Elapsed()))
+	return nil
+}
+
+func (c *CLI) resume(args []string) error {
+	if err := c.timer.Resume(); err != nil {
+		return err
+	}
+	entry, _ := c.timer.Active()
+	fmt.Fprintf(c.out, "Resumed: %s\n", entry.Description)
+	return nil
+}
+
+func (c *CLI) stop(args []string) error {
+	entry, err := c.timer.Stop()
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(c.out, "Stopped: %s (%s)\n", entry.Description,
+		utils.FormatDuration(time.Duration(entry.Duration)*time.Second))
+	return nil
+}
+
+// statusOutput is the machine-readable form of the status command.
+type statusOutput struct {
+	Active      bool      `json:"active"`
+	ID          string    `json:"id,omitempty"`
+	Description string    `json:"description,omitempty"`
+	Project     string    `json:"project,omitempty"`
+	Tags        []string  `json:"tags,omitempty"`
+	State       string    `json:"state,omitempty"`
+	StartTime   time.Time `json:"start_time,omitempty"`
+	ElapsedSec  int64     `json:"elapsed_sec"`
+}
+
+func (c *CLI) status(args []string) error {
+	fs := newFlagSet("status", c.out)
+	asJSON := fs.Bool("json", false, "print the status as JSON")
+	if _, err := parseInterspersed(fs, args); err != nil {
+		return err
+	}
+
+	out := statusOutput{}
+	if entry, ok := c.timer.Active(); ok {
+		state := "paused"
+		if entry.State == models.TaskStateRunning {
+			state = "running"
+		}
+		out = statusOutput{
+			Active:      true,
+			ID:          entry.ID,
+			Description: entry.Description,
+			Tags:        entry.Tags,
+			State:       state,
+			StartTime:   entry.StartTime,
+			ElapsedSec:  int64(c.timer.Elapsed().Seconds()),
+		}
+		if entry.ProjectID != "" {
+			projects, _ := c.storage.LoadProjects()
+			if p := service.FindProjectByID(projects, entry.ProjectID); p != nil {
+				out.Project = p.Name
+			}
+		}
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(c.out)
+		enc.SetIndent("", "  ")
+		return enc.Encode(out)
+	}
+
+	if !out.Active {
+		fmt.Fprintln(c.out, "No active task")
+		return nil
+	}
+	label := "Running"
+	if out.State == "paused" {
+		label = "Paused"
+	}
+	fmt.Fprintf(c.out, "%s: %s\n", label, out.Description)
+	if out.Project != "" {
+		fmt.Fprintf(c.out, "Project: %s\n", out.Project)
+	}
+	if len(out.Tags) > 0 {
+		fmt.Fprintf(c.out, "Tags: %s\n", strings.Join(out.Tags, ", "))
+	}
+	fmt.Fprintf(c.out, "Started: %s\n", out.StartTime.Format("2006-01-02 15:04:05"))
+	fmt.Fprintf(c.out, "Elapsed: %s\n", utils.FormatDuration(time.Duration(out.ElapsedSec)*time.Second))
+	return nil
+}
+
+func (c *CLI) importEntries(args []string) error {
+	fs := newFlagSet("import", c.out)
+	formatName := fs.String("format", "", "source format, detected when empty: "+formatNames())
+	dateOrder := fs.String("date-format", "", "order of day and month in the dates, "+importer.DateOrderDMY+" or "+importer.DateOrderMDY+"; detected when empty")
+	apply := fs.Bool("apply", false, "save the entries instead of only showing what would be imported")
+	positional, err := parseInterspersed(fs, args)
+	if err != nil {
+		return err
+	}
+	*dateOrder = strings.ToLower(*dateOrder)
+	if *dateOrder != "" && *dateOrder != importer.DateOrderDMY && *dateOrder != importer.DateOrderMDY {
+		fs.Usage()
+		return fmt.Errorf("unknown date format %q, expected %s or %s", *dateOrder, importer.DateOrderDMY, importer.DateOrderMDY)
+	}
+	if len(positional) != 1 {
+		fs.Usage()
+		return errors.New("exactly one file is required")
+	}
+	path := positional[0]
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if *formatName == "" {
+		*formatName = importer.Detect(path, data)
+	}
+	format, ok := importer.Lookup(*formatName)
+	if !ok {
+		return fmt.Errorf("unknown format %q, expected one of %s", *formatName, formatNames())
+	}
+	projects, err := c.storage.LoadProjects()
+	if err != nil {
+		return err
+	}
+	res, err := format.Parse(bytes.NewReader(data), projects, importer.Options{DateOrder: *dateOrder})
+	if errors.Is(err, importer.ErrAmbiguousDates) {
+		return fmt.Errorf("%w; run again with --date-format %s or %s", err, importer.DateOrderDMY, importer.DateOrderMDY)
+	}
+	if err != nil {
+		return err
+	}
+	plan, err := service.PlanImport(c.storage, res.Entries, res.Projects)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(c.out, "Format: %s\n", format.Description)
+	fmt.Fprintf(c.out, "Entries to import: %d\n", len(plan.Entries))
+	fmt.Fprintf(c.out, "Duplicates skipped: %d\n", plan.Duplicates)
+	if len(plan.Projects) > 0 {
+		names := make([]string, len(plan.Projects))
+		for i, p := range plan.Projects {
+			names[i] = p.Name
+		}
+		fmt.Fprintf(c.out, "Projects to create: %s\n", strings.Join(names, ", "))
+	}
+	if len(res.Problems) > 0 {
+		fmt.Fprintf(c.out, "Skipped records: %d\n", len(res.Problems))
+		for _, p := range res.Problems {
+			fmt.Fprintf(c.out, "  %s\n", p)
+		}
+	}
+
+	if !*apply {
+		if len(plan.Entries) > 0 {
+			fmt.Fprintln(c.out, "Dry run, nothing was saved. Run again with --apply to import.")
+		}
+		return nil
+	}
+	if err := service.Import(c.storage, plan); err != nil {
+		return err
+	}
+	fmt.Fprintf(c.out, "Imported %d entries\n", len(plan.Entries))
+	return nil
+}
+
+func formatNames() string {
+	names := make([]string, len(importer.Formats))
+	for i, f := range importer.Formats {
+		names[i] = f.Name
+	}
+	return strings.Join(names, ", ")
+}
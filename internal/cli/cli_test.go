@@ -0,0 +1,216 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/highercomve/tasktracker/internal/models"
+	"github.com/highercomve/tasktracker/internal/service"
+	"github.com/highercomve/tasktracker/internal/store"
+)
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time { return c.now }
+
+func (c *fakeClock) Advance(d time.Duration) { c.now = c.now.Add(d) }
+
+func newTestCLI(t *testing.T) (*CLI, *bytes.Buffer, *store.Storage, *fakeClock) {
+	t.Helper()
+	s := store.NewStorage(t.TempDir())
+	if err := s.SaveProjects([]models.Project{{ID: "p1", Name: "Website"}}); err != nil {
+		t.Fatal(err)
+	}
+	clock := &fakeClock{now: time.Date(2026, 3, 10, 9, 0, 0, 0, time.Local)}
+	var out bytes.Buffer
+	return New(service.NewTimer(s, clock), s, &out), &out, s, clock
+}
+
+// run runs args and returns the output, failing the test on error.
+func run(t *testing.T, c *CLI, out *bytes.Buffer, args ...string) string {
+	t.Helper()
+	out.Reset()
+	if err := c.Run(args); err != nil {
+		t.Fatalf("%v: %v", args, err)
+	}
+	return out.String()
+}
+
+func TestTimerCommands(t *testing.T) {
+	c, out, s, clock := newTestCLI(t)
+
+	if got := run(t, c, out, "status"); got != "No active task\n" {
+		t.Errorf("status: %q", got)
+	}
+	if got := run(t, c, out, "start", "Fix", "--project", "Website", "bug", "--tag", "dev, urgent"); got != "Started: Fix bug\n" {
+		t.Errorf("start: %q", got)
+	}
+	clock.Advance(30 * time.Minute)
+	if got := run(t, c, out, "pause"); got != "Paused: Fix bug (00:30:00)\n" {
+		t.Errorf("pause: %q", got)
+	}
+	clock.Advance(time.Hour)
+
+	expectStatus := "Paused: Fix bug\nProject: Website\nTags: dev, urgent\nStarted: 2026-03-10 09:00:00\nElapsed: 00:30:00\n"
+	if got := run(t, c, out, "status"); got != expectStatus {
+		t.Errorf("status: %q", got)
+	}
+	if got := run(t, c, out, "resume"); got != "Resumed: Fix bug\n" {
+		t.Errorf("resume: %q", got)
+	}
+	clock.Advance(15 * time.Minute)
+
+	var status statusOutput
+	if err := json.Unmarshal([]byte(run(t, c, out, "status", "--json")), &status); err != nil {
+		t.Fatal(err)
+	}
+	if !status.Active || status.State != "running" || status.Project != "Website" || status.ElapsedSec != 45*60 {
+		t.Errorf("status --json: %+v", status)
+	}
+
+	if got := run(t, c, out, "stop"); got != "Stopped: Fix bug (00:45:00)\n" {
+		t.Errorf("stop: %q", got)
+	}
+	entries, err := s.LoadEntries(clock.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].ProjectID != "p1" || !slices.Equal(entries[0].Tags, []string{"dev", "urgent"}) || entries[0].State != models.TaskStateStopped {
+		t.Errorf("entries: %+v", entries)
+	}
+}
+
+func TestStartStopsActiveTask(t *testing.T) {
+	c, out, s, clock := newTestCLI(t)
+	run(t, c, out, "start", "First")
+	clock.Advance(time.Hour)
+	run(t, c, out, "start", "Second")
+
+	entries, _ := s.LoadEntries(clock.Now())
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 entries, got %d", len(entries))
+	}
+	for _, e := range entries {
+		if e.Description == "First" && (e.State != models.TaskStateStopped || e.Duration != 3600) {
+			t.Errorf("first task not stopped: %+v", e)
+		}
+	}
+	if active, _ := c.timer.Active(); active.Description != "Second" {
+		t.Errorf("active: %+v", active)
+	}
+}
+
+func TestCommandErrors(t *testing.T) {
+	tests := []struct {
+		name        string
+		args        []string
+		expectErr   string
+		expectUsage string
+	}{
+		{
+			name:        "Unknown command",
+			args:        []string{"launch"},
+			expectErr:   `unknown command "launch"`,
+			expectUsage: "Usage: tasktracker [command] [arguments]",
+		},
+		{
+			name:        "Bad flag",
+			args:        []string{"start", "Task", "--colour", "red"},
+			expectErr:   "flag provided but not defined: -colour",
+			expectUsage: "Usage: tasktracker start",
+		},
+		{
+			name:        "Missing description",
+			args:        []string{"start", "--project", "Website"},
+			expectErr:   "a task description is required",
+			expectUsage: "Usage: tasktracker start",
+		},
+		{
+			name:      "Unknown project",
+			args:      []string{"start", "Task", "--project", "Nope"},
+			expectErr: `project "Nope" not found`,
+		},
+		{
+			name:      "Pause without a task",
+			args:      []string{"pause"},
+			expectErr: service.ErrNoActiveTask.Error(),
+		},
+		{
+			name:      "Resume without a task",
+			args:      []string{"resume"},
+			expectErr: service.ErrNoActiveTask.Error(),
+		},
+		{
+			name:      "Stop without a task",
+			args:      []string{"stop"},
+			expectErr: service.ErrNoActiveTask.Error(),
+		},
+		{
+			name:        "Unknown date format",
+			args:        []string{"import", "export.csv", "--date-format", "yy/dd"},
+			expectErr:   `unknown date format "yy/dd", expected dd/mm or mm/dd`,
+			expectUsage: "Usage: tasktracker import",
+		},
+		{
+			name:        "Import without a file",
+			args:        []string{"import", "--apply"},
+			expectErr:   "exactly one file is required",
+			expectUsage: "Usage: tasktracker import",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, out, _, _ := newTestCLI(t)
+			err := c.Run(tt.args)
+			if err == nil || err.Error() != tt.expectErr {
+				t.Errorf("expected error %q, got %v", tt.expectErr, err)
+			}
+			if !strings.Contains(out.String(), tt.expectUsage) {
+				t.Errorf("expected usage %q, got %q", tt.expectUsage, out.String())
+			}
+		})
+	}
+}
+
+func TestHelp(t *testing.T) {
+	c, out, _, _ := newTestCLI(t)
+	got := run(t, c, out, "help")
+	for _, name := range commandOrder {
+		if !strings.Contains(got, "  "+commands[name].usage) {
+			t.Errorf("help lacks %s: %q", name, got)
+		}
+	}
+	if !IsCommand("--help") || !IsCommand("status") || IsCommand("launch") {
+		t.Error("IsCommand")
+	}
+}
+
+func TestAbsPaths(t *testing.T) {
+	wd, err := filepath.Abs(".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		args   []string
+		expect []string
+	}{
+		{[]string{"start", "notes.csv"}, []string{"start", "notes.csv"}},
+		{[]string{"import", "export.csv", "--apply"}, []string{"import", filepath.Join(wd, "export.csv"), "--apply"}},
+		{[]string{"import", "--format", "toggl", "export.csv"}, []string{"import", "--format", "toggl", filepath.Join(wd, "export.csv")}},
+		{[]string{"import", "/tmp/export.csv"}, []string{"import", "/tmp/export.csv"}},
+		{[]string{"import", "--date-format", "dd/mm", "export.csv"}, []string{"import", "--date-format", "dd/mm", filepath.Join(wd, "export.csv")}},
+	}
+	for _, tt := range tests {
+		if got := AbsPaths(tt.args); !slices.Equal(got, tt.expect) {
+			t.Errorf("AbsPaths(%v): expected %v, got %v", tt.args, tt.expect, got)
+		}
+	}
+}
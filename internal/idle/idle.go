@@ -0,0 +1,109 @@
+// Package idle tells how long the user has been away from the computer.
+//
+// The desktop session knows best: on Linux it is asked over D-Bus (GNOME,
+// KDE and other freedesktop screensavers) or through the X11 screensaver
+// extension. Where none of them answers, the time since the last activity
+// seen by the application is used instead.
+package idle
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrUnavailable is returned by sources that cannot tell the idle time in
+// this session.
+var ErrUnavailable = errors.New("idle time unavailable")
+
+// Source reports the time since the last user input.
+type Source interface {
+	IdleTime() (time.Duration, error)
+}
+
+// Activity is the in-app heuristic: the time since Touch was last called,
+// on input to the application's own windows.
+type Activity struct {
+	mu   sync.RWMutex
+	last time.Time
+	now  func() time.Time
+}
+
+// NewActivity returns an Activity touched now.
+func NewActivity() *Activity {
+	return &Activity{last: time.Now(), now: time.Now}
+}
+
+// Touch records activity now.
+func (a *Activity) Touch() {
+	a.Set(a.now())
+}
+
+// Set records activity at t.
+func (a *Activity) Set(t time.Time) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.last = t
+}
+
+// Last returns when activity was last recorded.
+func (a *Activity) Last() time.Time {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.last
+}
+
+// IdleTime returns the time since the last activity.
+func (a *Activity) IdleTime() (time.Duration, error) {
+	return a.now().Sub(a.Last()), nil
+}
+
+// Fake is a Source for tests that returns what it is set to.
+type Fake struct {
+	mu   sync.Mutex
+	idle time.Duration
+	err  error
+}
+
+// Set makes the source report idle, or fail with err when not nil.
+func (f *Fake) Set(idle time.Duration, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.idle, f.err = idle, err
+}
+
+// IdleTime returns the values last passed to Set.
+func (f *Fake) IdleTime() (time.Duration, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.idle, f.err
+}
+
+// WithFallback returns a Source that asks primary and, when it fails,
+// fallback.
+func WithFallback(primary, fallback Source) Source {
+	return fallbackSource{primary, fallback}
+}
+
+type fallbackSource struct {
+	primary, fallback Source
+}
+
+func (s fallbackSource) IdleTime() (time.Duration, error) {
+	if d, err := s.primary.IdleTime(); err == nil {
+		return d, nil
+	}
+	return s.fallback.IdleTime()
+}
+
+// Detect returns the first system source that answers in this session,
+// falling back to fallback whenever it fails, or fallback alone when none
+// answers.
+func Detect(fallback Source) Source {
+	for _, s := range systemSources() {
+		if _, err := s.IdleTime(); err == nil {
+			return WithFallback(s, fallback)
+		}
+	}
+	return fallback
+}
@@ -0,0 +1,70 @@
+//go:build linux
+
+package idle
+
+import (
+	"time"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// systemSources returns the sources of Linux desktop sessions, the D-Bus
+// interfaces first as they also work on Wayland, where the X11 extension
+// only sees the input of X clients.
+func systemSources() []Source {
+	return []Source{
+		dbusSource{
+			dest:   "org.gnome.Mutter.IdleMonitor",
+			path:   "/org/gnome/Mutter/IdleMonitor/Core",
+			method: "org.gnome.Mutter.IdleMonitor.GetIdletime",
+		},
+		dbusSource{
+			dest:   "org.freedesktop.ScreenSaver",
+			path:   "/org/freedesktop/ScreenSaver",
+			method: "org.freedesktop.ScreenSaver.GetSessionIdleTime",
+		},
+		x11Source{},
+	}
+}
+
+// dbusTimeout bounds a call, so a stuck session bus does not stall the
+// checks.
+const dbusTimeout = 2 * time.Second
+
+// dbusSource calls a method of the session bus returning the idle time in
+// milliseconds.
+type dbusSource struct {
+	dest   string
+	path   dbus.ObjectPath
+	method string
+}
+
+func (s dbusSource) IdleTime() (time.Duration, error) {
+	conn, err := dbus.SessionBus()
+	if err != nil {
+		return 0, err
+	}
+	call := conn.Object(s.dest, s.path).Go(s.method, 0, make(chan *dbus.Call, 1))
+	select {
+	case <-call.Done:
+	case <-time.After(dbusTimeout):
+		return 0, ErrUnavailable
+	}
+	if call.Err != nil {
+		return 0, call.Err
+	}
+	if len(call.Body) != 1 {
+		return 0, ErrUnavailable
+	}
+	// GNOME answers a uint64, the freedesktop interface a uint32.
+	var ms uint64
+	switch v := call.Body[0].(type) {
+	case uint64:
+		ms = v
+	case uint32:
+		ms = uint64(v)
+	default:
+		return 0, ErrUnavailable
+	}
+	return time.Duration(ms) * time.Millisecond, nil
+}
@@ -0,0 +1,8 @@
+//go:build !linux
+
+package idle
+
+// systemSources returns no source: only Linux sessions are asked for now.
+func systemSources() []Source {
+	return nil
+}
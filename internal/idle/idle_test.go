@@ -0,0 +1,40 @@
+package idle
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestActivity(t *testing.T) {
+	now := time.Date(2026, 3, 10, 9, 0, 0, 0, time.UTC)
+	a := &Activity{now: func() time.Time { return now }}
+	a.Touch()
+	now = now.Add(90 * time.Second)
+	if d, err := a.IdleTime(); err != nil || d != 90*time.Second {
+		t.Errorf("expected 90s, got %v (%v)", d, err)
+	}
+	a.Touch()
+	if d, _ := a.IdleTime(); d != 0 {
+		t.Errorf("expected 0 after Touch, got %v", d)
+	}
+}
+
+func TestWithFallback(t *testing.T) {
+	primary, fallback := &Fake{}, &Fake{}
+	primary.Set(time.Minute, nil)
+	fallback.Set(time.Hour, nil)
+	s := WithFallback(primary, fallback)
+
+	if d, _ := s.IdleTime(); d != time.Minute {
+		t.Errorf("expected the primary's minute, got %v", d)
+	}
+	primary.Set(0, ErrUnavailable)
+	if d, _ := s.IdleTime(); d != time.Hour {
+		t.Errorf("expected the fallback's hour, got %v", d)
+	}
+	fallback.Set(0, errors.New("broken"))
+	if _, err := s.IdleTime(); err == nil {
+		t.Error("expected an error when both fail")
+	}
+}
@@ -0,0 +1,206 @@
+//go:build linux
+
+package idle
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// x11Timeout bounds the exchange with the X server.
+const x11Timeout = 2 * time.Second
+
+// x11SocketDir holds the sockets of the local X displays.
+var x11SocketDir = "/tmp/.X11-unix"
+
+// x11Source asks the X server of $DISPLAY through the MIT-SCREEN-SAVER
+// extension. It speaks the few requests it needs of the X11 protocol
+// itself, so no X library is linked.
+type x11Source struct{}
+
+func (x11Source) IdleTime() (time.Duration, error) {
+	display, err := parseDisplay(os.Getenv("DISPLAY"))
+	if err != nil {
+		return 0, err
+	}
+	conn, err := net.DialTimeout("unix", filepath.Join(x11SocketDir, "X"+display), x11Timeout)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(x11Timeout))
+
+	root, err := x11Setup(conn, display)
+	if err != nil {
+		return 0, err
+	}
+	opcode, err := x11QueryExtension(conn, "MIT-SCREEN-SAVER")
+	if err != nil {
+		return 0, err
+	}
+	// ScreenSaverQueryInfo (minor opcode 1) of the root window
+	req := make([]byte, 8)
+	req[0], req[1] = opcode, 1
+	binary.LittleEndian.PutUint16(req[2:], 2)
+	binary.LittleEndian.PutUint32(req[4:], root)
+	reply, err := x11Request(conn, req)
+	if err != nil {
+		return 0, err
+	}
+	ms := binary.LittleEndian.Uint32(reply[16:])
+	return time.Duration(ms) * time.Millisecond, nil
+}
+
+// parseDisplay returns the display number of a local display name such as
+// ":0" or ":1.0".
+func parseDisplay(name string) (string, error) {
+	host, rest, ok := strings.Cut(name, ":")
+	if !ok || (host != "" && host != "unix") {
+		return "", ErrUnavailable
+	}
+	number, _, _ := strings.Cut(rest, ".")
+	if _, err := strconv.Atoi(number); err != nil {
+		return "", ErrUnavailable
+	}
+	return number, nil
+}
+
+// x11Setup opens the connection, with the MIT-MAGIC-COOKIE-1 of the
+// display when there is one, and returns the root window of the first
+// screen.
+func x11Setup(conn net.Conn, display string) (uint32, error) {
+	authName, authData := xauthCookie(display)
+	req := make([]byte, 12, 12+pad4(len(authName))+pad4(len(authData)))
+	req[0] = 'l' // little-endian
+	binary.LittleEndian.PutUint16(req[2:], 11)
+	binary.LittleEndian.PutUint16(req[6:], uint16(len(authName)))
+	binary.LittleEndian.PutUint16(req[8:], uint16(len(authData)))
+	req = append(req, padded(authName)...)
+	req = append(req, padded(authData)...)
+	if _, err := conn.Write(req); err != nil {
+		return 0, err
+	}
+
+	header := make([]byte, 8)
+	if _, err := io.ReadFull(conn, header); err != nil {
+		return 0, err
+	}
+	data := make([]byte, int(binary.LittleEndian.Uint16(header[6:]))*4)
+	if _, err := io.ReadFull(conn, data); err != nil {
+		return 0, err
+	}
+	if header[0] != 1 {
+		return 0, errors.New("X server refused the connection")
+	}
+	// Offsets below are from the start of the reply, header included.
+	reply := append(header, data...)
+	if len(reply) < 40 {
+		return 0, ErrUnavailable
+	}
+	vendor := int(binary.LittleEndian.Uint16(reply[24:]))
+	formats := int(reply[29])
+	screen := 40 + pad4(vendor) + 8*formats
+	if len(reply) < screen+4 {
+		return 0, ErrUnavailable
+	}
+	return binary.LittleEndian.Uint32(reply[screen:]), nil
+}
+
+// x11QueryExtension returns the major opcode of an extension.
+func x11QueryExtension(conn net.Conn, name string) (byte, error) {
+	req := make([]byte, 8, 8+pad4(len(name)))
+	req[0] = 98 // QueryExtension
+	binary.LittleEndian.PutUint16(req[2:], uint16(2+pad4(len(name))/4))
+	binary.LittleEndian.PutUint16(req[4:], uint16(len(name)))
+	req = append(req, padded([]byte(name))...)
+	reply, err := x11Request(conn, req)
+	if err != nil {
+		return 0, err
+	}
+	if reply[8] == 0 {
+		return 0, ErrUnavailable
+	}
+	return reply[9], nil
+}
+
+// x11Request sends a request and reads its reply, failing on an X error.
+func x11Request(conn net.Conn, req []byte) ([]byte, error) {
+	if _, err := conn.Write(req); err != nil {
+		return nil, err
+	}
+	reply := make([]byte, 32)
+	if _, err := io.ReadFull(conn, reply); err != nil {
+		return nil, err
+	}
+	if reply[0] != 1 {
+		return nil, fmt.Errorf("X error %d", reply[1])
+	}
+	if extra := binary.LittleEndian.Uint32(reply[4:]); extra > 0 {
+		more := make([]byte, int(extra)*4)
+		if _, err := io.ReadFull(conn, more); err != nil {
+			return nil, err
+		}
+		reply = append(reply, more...)
+	}
+	return reply, nil
+}
+
+// xauthCookie returns the authorization of the display in $XAUTHORITY or
+// ~/.Xauthority, or nothing when there is none.
+func xauthCookie(display string) (name, data []byte) {
+	path := os.Getenv("XAUTHORITY")
+	if path == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, nil
+		}
+		path = filepath.Join(home, ".Xauthority")
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil
+	}
+	defer f.Close()
+	r := bufio.NewReader(f)
+	readField := func() ([]byte, error) {
+		var n uint16
+		if err := binary.Read(r, binary.BigEndian, &n); err != nil {
+			return nil, err
+		}
+		b := make([]byte, n)
+		_, err := io.ReadFull(r, b)
+		return b, err
+	}
+	for {
+		var family uint16
+		if err := binary.Read(r, binary.BigEndian, &family); err != nil {
+			return nil, nil
+		}
+		var fields [4][]byte // address, display number, name, data
+		for i := range fields {
+			if fields[i], err = readField(); err != nil {
+				return nil, nil
+			}
+		}
+		if string(fields[1]) == display && string(fields[2]) == "MIT-MAGIC-COOKIE-1" {
+			return fields[2], fields[3]
+		}
+	}
+}
+
+func pad4(n int) int {
+	return (n + 3) &^ 3
+}
+
+func padded(b []byte) []byte {
+	return append(b, make([]byte, pad4(len(b))-len(b))...)
+}
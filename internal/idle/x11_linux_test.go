@@ -0,0 +1,109 @@
+package idle
+
+import (
+	"encoding/binary"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestParseDisplay(t *testing.T) {
+	for name, want := range map[string]string{":0": "0", ":1.0": "1", "unix:2": "2", "remote:0": "", "": "", ":x": ""} {
+		got, err := parseDisplay(name)
+		if got != want || (want == "") != (err != nil) {
+			t.Errorf("%q: expected %q, got %q (%v)", name, want, got, err)
+		}
+	}
+}
+
+// fakeXServer answers the connection setup, QueryExtension and
+// ScreenSaverQueryInfo on display :7 of dir.
+func fakeXServer(t *testing.T, dir string, idleMs uint32) {
+	t.Helper()
+	l, err := net.Listen("unix", filepath.Join(dir, "X7"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		le := binary.LittleEndian
+
+		// Setup: the request names and sends the cookie.
+		setup := make([]byte, 12)
+		io.ReadFull(conn, setup)
+		auth := make([]byte, pad4(int(le.Uint16(setup[6:])))+pad4(int(le.Uint16(setup[8:]))))
+		io.ReadFull(conn, auth)
+		vendor := "Fake"
+		data := make([]byte, 32+pad4(len(vendor))+8+4)
+		le.PutUint16(data[16:], uint16(len(vendor)))
+		data[21] = 1 // one pixmap format
+		copy(data[32:], vendor)
+		le.PutUint32(data[32+pad4(len(vendor))+8:], 0x2a) // root window
+		header := make([]byte, 8)
+		header[0] = 1
+		le.PutUint16(header[6:], uint16(len(data)/4))
+		conn.Write(append(header, data...))
+
+		// QueryExtension
+		req := make([]byte, 4)
+		io.ReadFull(conn, req)
+		io.ReadFull(conn, make([]byte, int(le.Uint16(req[2:]))*4-4))
+		reply := make([]byte, 32)
+		reply[0], reply[8], reply[9] = 1, 1, 140
+		conn.Write(reply)
+
+		// ScreenSaverQueryInfo of the root window
+		req = make([]byte, 8)
+		io.ReadFull(conn, req)
+		reply = make([]byte, 32)
+		if req[0] == 140 && req[1] == 1 && le.Uint32(req[4:]) == 0x2a {
+			reply[0] = 1
+			le.PutUint32(reply[16:], idleMs)
+		}
+		conn.Write(reply)
+	}()
+}
+
+func TestX11Source(t *testing.T) {
+	dir := t.TempDir()
+	old := x11SocketDir
+	x11SocketDir = dir
+	t.Cleanup(func() { x11SocketDir = old })
+
+	// An Xauthority with a cookie for another display and one for :7
+	var xauth []byte
+	for _, entry := range [][4]string{{"host", "0", "MIT-MAGIC-COOKIE-1", "other"}, {"host", "7", "MIT-MAGIC-COOKIE-1", "secret"}} {
+		xauth = binary.BigEndian.AppendUint16(xauth, 256)
+		for _, field := range entry {
+			xauth = binary.BigEndian.AppendUint16(xauth, uint16(len(field)))
+			xauth = append(xauth, field...)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(dir, "xauth"), xauth, 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("XAUTHORITY", filepath.Join(dir, "xauth"))
+	if name, data := xauthCookie("7"); string(name) != "MIT-MAGIC-COOKIE-1" || string(data) != "secret" {
+		t.Errorf("cookie: %q %q", name, data)
+	}
+
+	fakeXServer(t, dir, 4500)
+	t.Setenv("DISPLAY", ":7")
+	d, err := x11Source{}.IdleTime()
+	if err != nil || d != 4500*time.Millisecond {
+		t.Errorf("expected 4.5s, got %v (%v)", d, err)
+	}
+
+	t.Setenv("DISPLAY", ":8")
+	if _, err := (x11Source{}).IdleTime(); err == nil {
+		t.Error("expected an error without a server")
+	}
+}
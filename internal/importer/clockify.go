@@ -0,0 +1,54 @@
+package importer
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/highercomve/tasktracker/internal/models"
+)
+
+// clockifyDateLayouts and clockifyTimeLayouts are the layouts of the date
+// and time columns of Clockify reports, which follow the workspace settings.
+var (
+	clockifyDateLayouts = []string{"01/02/2006", "2006-01-02", "02.01.2006", "02/01/2006", "02-01-2006"}
+	clockifyTimeLayouts = []string{"03:04:05 PM", "3:04:05 PM", "03:04 PM", "3:04 PM", "15:04:05", "15:04"}
+)
+
+// parseClockifyCSV reads a Clockify detailed report exported as CSV.
+func parseClockifyCSV(r io.Reader, projects []models.Project, opts Options) (Result, error) {
+	t, err := readCSVTable(r)
+	if err != nil {
+		return Result{}, err
+	}
+	if err := t.require("description", "start date", "start time", "end date", "end time"); err != nil {
+		return Result{}, err
+	}
+	layout, err := dateLayout(t.values("start date", "end date"), clockifyDateLayouts, opts.DateOrder)
+	if err != nil {
+		return Result{}, err
+	}
+	ps := &projectSet{existing: projects}
+	var res Result
+	for i, record := range t.records {
+		description := t.field(record, "description")
+		if task := t.field(record, "task"); task != "" && description == "" {
+			description = task
+		}
+		start, err1 := parseDateTime(t.field(record, "start date"), t.field(record, "start time"), layout, clockifyTimeLayouts)
+		end, err2 := parseDateTime(t.field(record, "end date"), t.field(record, "end time"), layout, clockifyTimeLayouts)
+		if err := errors.Join(err1, err2); err != nil {
+			res.Problems = append(res.Problems, fmt.Sprintf("line %d: %v", i+2, err))
+			continue
+		}
+		e, err := newEntry(description, start, end, splitTags(t.field(record, "tags")))
+		if err != nil {
+			res.Problems = append(res.Problems, fmt.Sprintf("line %d: %v", i+2, err))
+			continue
+		}
+		e.ProjectID = ps.id(t.field(record, "project"), t.field(record, "client"), "")
+		res.Entries = append(res.Entries, e)
+	}
+	res.Projects = ps.created
+	return res, nil
+}
@@ -0,0 +1,508 @@
+package importer
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/highercomve/tasktracker/internal/models"
+)
+
+// CalendarEvent is a VEVENT read from an iCalendar file.
+type CalendarEvent struct {
+	UID         string
+	Summary     string
+	Description string
+	Categories  []string
+	Start       time.Time
+	End         time.Time
+	AllDay      bool
+
+	rule         *recurrence
+	exceptions   []time.Time
+	recurrenceID time.Time // set on the events that replace an occurrence
+	cancelled    bool
+}
+
+// recurrence is the subset of RRULE the calendar reader expands.
+type recurrence struct {
+	freq     string
+	interval int
+	count    int
+	until    time.Time
+	byDay    []time.Weekday
+}
+
+// maxOccurrences bounds the expansion of rules without an end.
+const maxOccurrences = 5000
+
+// ReadCalendar reads the events of an iCalendar (.ics) file. Recurring
+// events are expanded by Occurrences.
+func ReadCalendar(r io.Reader) ([]CalendarEvent, error) {
+	lines, err := unfoldLines(r)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		events   []CalendarEvent
+		ev       *CalendarEvent
+		duration time.Duration
+		depth    int // nesting inside the event, for VALARM and friends
+	)
+	for _, line := range lines {
+		name, params, value, ok := parseContentLine(line)
+		if !ok {
+			continue
+		}
+		switch {
+		case name == "BEGIN" && strings.EqualFold(value, "VEVENT"):
+			ev, duration, depth = &CalendarEvent{}, 0, 0
+			continue
+		case name == "END" && strings.EqualFold(value, "VEVENT") && ev != nil:
+			if !ev.Start.IsZero() {
+				if ev.End.IsZero() {
+					ev.End = ev.Start.Add(duration)
+					if ev.AllDay && duration == 0 {
+						ev.End = ev.Start.AddDate(0, 0, 1)
+					}
+				}
+				events = append(events, *ev)
+			}
+			ev = nil
+			continue
+		}
+		if ev == nil {
+			continue
+		}
+		if name == "BEGIN" {
+			depth++
+		} else if name == "END" {
+			depth--
+		}
+		if depth > 0 {
+			continue
+		}
+
+		switch name {
+		case "UID":
+			ev.UID = value
+		case "SUMMARY":
+			ev.Summary = unescapeText(value)
+		case "DESCRIPTION":
+			ev.Description = unescapeText(value)
+		case "CATEGORIES":
+			for _, c := range splitText(value) {
+				if c = strings.TrimSpace(c); c != "" {
+					ev.Categories = append(ev.Categories, c)
+				}
+			}
+		case "STATUS":
+			ev.cancelled = strings.EqualFold(value, "CANCELLED")
+		case "DTSTART":
+			t, allDay, err := parseICSTime(value, params)
+			if err != nil {
+				return nil, err
+			}
+			ev.Start, ev.AllDay = t, allDay
+		case "DTEND":
+			t, _, err := parseICSTime(value, params)
+			if err != nil {
+				return nil, err
+			}
+			ev.End = t
+		case "DURATION":
+			if duration, err = parseICSDuration(value); err != nil {
+				return nil, err
+			}
+		case "RRULE":
+			rule, err := parseRRule(value)
+			if err != nil {
+				return nil, err
+			}
+			ev.rule = rule
+		case "EXDATE":
+			for _, v := range strings.Split(value, ",") {
+				t, _, err := parseICSTime(v, params)
+				if err != nil {
+					return nil, err
+				}
+				ev.exceptions = append(ev.exceptions, t)
+			}
+		case "RECURRENCE-ID":
+			t, _, err := parseICSTime(value, params)
+			if err != nil {
+				return nil, err
+			}
+			ev.recurrenceID = t
+		}
+	}
+
+	// Occurrences moved or cancelled by another VEVENT are not repeated by
+	// the rule of the series.
+	for _, o := range events {
+		if o.recurrenceID.IsZero() {
+			continue
+		}
+		for i := range events {
+			if events[i].UID == o.UID && events[i].rule != nil {
+				events[i].exceptions = append(events[i].exceptions, o.recurrenceID)
+			}
+		}
+	}
+	return slices.DeleteFunc(events, func(ev CalendarEvent) bool { return ev.cancelled }), nil
+}
+
+// Occurrences returns the events, with recurring ones expanded, that
+// overlap [start, end). All-day events are left out.
+func Occurrences(events []CalendarEvent, start, end time.Time) []CalendarEvent {
+	var out []CalendarEvent
+	for _, ev := range events {
+		if ev.AllDay {
+			continue
+		}
+		length := ev.End.Sub(ev.Start)
+		ev.forEach(end, func(t time.Time) {
+			if t.Before(end) && t.Add(length).After(start) {
+				o := ev
+				o.Start, o.End = t, t.Add(length)
+				o.rule, o.exceptions = nil, nil
+				out = append(out, o)
+			}
+		})
+	}
+	slices.SortFunc(out, func(a, b CalendarEvent) int { return a.Start.Compare(b.Start) })
+	return out
+}
+
+// forEach calls fn with the start of every occurrence of ev that begins
+// before limit.
+func (ev CalendarEvent) forEach(limit time.Time, fn func(time.Time)) {
+	excluded := func(t time.Time) bool {
+		return slices.ContainsFunc(ev.exceptions, t.Equal)
+	}
+	rule := ev.rule
+	if rule == nil {
+		fn(ev.Start)
+		return
+	}
+	n := 0
+	emit := func(t time.Time) bool {
+		if t.Before(ev.Start) {
+			return true
+		}
+		if !t.Before(limit) || (!rule.until.IsZero() && t.After(rule.until)) || (rule.count > 0 && n >= rule.count) || n >= maxOccurrences {
+			return false
+		}
+		n++
+		if !excluded(t) {
+			fn(t)
+		}
+		return true
+	}
+	for period := 0; ; period++ {
+		k := period * rule.interval
+		switch rule.freq {
+		case "DAILY":
+			if !emit(ev.Start.AddDate(0, 0, k)) {
+				return
+			}
+		case "WEEKLY":
+			week := ev.Start.AddDate(0, 0, 7*k-int(ev.Start.Weekday()))
+			days := rule.byDay
+			if len(days) == 0 {
+				days = []time.Weekday{ev.Start.Weekday()}
+			}
+			for _, d := range days {
+				if !emit(week.AddDate(0, 0, int(d))) {
+					return
+				}
+			}
+		case "MONTHLY":
+			t := ev.Start.AddDate(0, k, 0)
+			if t.Day() != ev.Start.Day() {
+				// Skip months without that day, as RFC 5545 does.
+				if !t.Before(limit) {
+					return
+				}
+				continue
+			}
+			if !emit(t) {
+				return
+			}
+		case "YEARLY":
+			if !emit(ev.Start.AddDate(k, 0, 0)) {
+				return
+			}
+		default:
+			fn(ev.Start)
+			return
+		}
+	}
+}
+
+// SuggestEntries turns the events that overlap [start, end) into entries
+// the user can add with one click. Events already tracked, with the same
+// start and description as an entry, are left out. An event is filed under
+// the project named in its categories or, failing that, in its summary;
+// its other categories become tags.
+func SuggestEntries(events []CalendarEvent, entries []models.TimeEntry, projects []models.Project, start, end time.Time) []models.TimeEntry {
+	var suggestions []models.TimeEntry
+	for _, ev := range Occurrences(events, start, end) {
+		if ev.Summary == "" || !ev.End.After(ev.Start) {
+			continue
+		}
+		e := models.TimeEntry{
+			ID:          uuid.New().String(),
+			Description: ev.Summary,
+			StartTime:   ev.Start.Local(),
+			EndTime:     ev.End.Local(),
+			Duration:    int64(ev.End.Sub(ev.Start) / time.Second),
+			State:       models.TaskStateStopped,
+		}
+		for _, c := range ev.Categories {
+			if p := findProject(projects, c); p != nil && e.ProjectID == "" {
+				e.ProjectID = p.ID
+				continue
+			}
+			e.Tags = append(e.Tags, c)
+		}
+		if e.ProjectID == "" {
+			e.ProjectID = projectInSummary(projects, ev.Summary)
+		}
+		if slices.ContainsFunc(entries, func(x models.TimeEntry) bool {
+			return x.StartTime.Equal(e.StartTime) && x.Description == e.Description
+		}) {
+			continue
+		}
+		suggestions = append(suggestions, e)
+	}
+	return suggestions
+}
+
+// findProject returns the project called name, ignoring case.
+func findProject(projects []models.Project, name string) *models.Project {
+	for i := range projects {
+		if strings.EqualFold(projects[i].Name, strings.TrimSpace(name)) {
+			return &projects[i]
+		}
+	}
+	return nil
+}
+
+// projectInSummary returns the ID of the project with the longest name that
+// appears in summary, ignoring case.
+func projectInSummary(projects []models.Project, summary string) string {
+	summary = strings.ToLower(summary)
+	id, longest := "", 0
+	for _, p := range projects {
+		if n := len(p.Name); n > longest && strings.Contains(summary, strings.ToLower(p.Name)) {
+			id, longest = p.ID, n
+		}
+	}
+	return id
+}
+
+// unfoldLines returns the content lines of an iCalendar file, joining the
+// lines folded with a leading space or tab.
+func unfoldLines(r io.Reader) ([]string, error) {
+	var lines []string
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), 1024*1024)
+	for sc.Scan() {
+		line := strings.TrimRight(sc.Text(), "\r")
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	if len(lines) == 0 || !strings.EqualFold(strings.TrimPrefix(lines[0], "\ufeff"), "BEGIN:VCALENDAR") {
+		return nil, fmt.Errorf("not an iCalendar file")
+	}
+	return lines, nil
+}
+
+// parseContentLine splits "NAME;PARAM=x:value" into its parts.
+func parseContentLine(line string) (name string, params map[string]string, value string, ok bool) {
+	// The value starts at the first colon outside a quoted parameter.
+	quoted, colon := false, -1
+	for i, c := range line {
+		if c == '"' {
+			quoted = !quoted
+		} else if c == ':' && !quoted {
+			colon = i
+			break
+		}
+	}
+	if colon < 0 {
+		return "", nil, "", false
+	}
+	parts := strings.Split(line[:colon], ";")
+	params = map[string]string{}
+	for _, p := range parts[1:] {
+		if k, v, found := strings.Cut(p, "="); found {
+			params[strings.ToUpper(k)] = strings.Trim(v, `"`)
+		}
+	}
+	return strings.ToUpper(parts[0]), params, line[colon+1:], true
+}
+
+// parseICSTime reads a DATE or DATE-TIME value. Times in UTC end in Z,
+// TZID gives the zone of the others and floating times are local.
+func parseICSTime(value string, params map[string]string) (time.Time, bool, error) {
+	value = strings.TrimSpace(value)
+	if params["VALUE"] == "DATE" || len(value) == 8 {
+		t, err := time.ParseInLocation("20060102", value, time.Local)
+		if err != nil {
+			return time.Time{}, false, fmt.Errorf("invalid date %q", value)
+		}
+		return t, true, nil
+	}
+	loc := time.Local
+	if tzid := params["TZID"]; tzid != "" {
+		if l, err := time.LoadLocation(tzid); err == nil {
+			loc = l
+		}
+	}
+	if strings.HasSuffix(value, "Z") {
+		t, err := time.Parse("20060102T150405Z", value)
+		if err != nil {
+			return time.Time{}, false, fmt.Errorf("invalid time %q", value)
+		}
+		return t, false, nil
+	}
+	t, err := time.ParseInLocation("20060102T150405", value, loc)
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("invalid time %q", value)
+	}
+	return t, false, nil
+}
+
+// parseICSDuration reads a duration such as PT1H30M or P1D.
+func parseICSDuration(value string) (time.Duration, error) {
+	s := strings.TrimPrefix(strings.TrimPrefix(value, "+"), "P")
+	if s == value || s == "" {
+		return 0, fmt.Errorf("invalid duration %q", value)
+	}
+	var d time.Duration
+	inTime := false
+	num := ""
+	for _, c := range s {
+		switch {
+		case c >= '0' && c <= '9':
+			num += string(c)
+		case c == 'T':
+			inTime = true
+		default:
+			n, err := strconv.Atoi(num)
+			if err != nil {
+				return 0, fmt.Errorf("invalid duration %q", value)
+			}
+			num = ""
+			unit := map[rune]time.Duration{'W': 7 * 24 * time.Hour, 'D': 24 * time.Hour}
+			if inTime {
+				unit = map[rune]time.Duration{'H': time.Hour, 'M': time.Minute, 'S': time.Second}
+			}
+			u, ok := unit[c]
+			if !ok {
+				return 0, fmt.Errorf("invalid duration %q", value)
+			}
+			d += time.Duration(n) * u
+		}
+	}
+	return d, nil
+}
+
+var icsWeekdays = map[string]time.Weekday{
+	"SU": time.Sunday, "MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday,
+	"TH": time.Thursday, "FR": time.Friday, "SA": time.Saturday,
+}
+
+// parseRRule reads the FREQ, INTERVAL, COUNT, UNTIL and BYDAY parts of a
+// recurrence rule.
+func parseRRule(value string) (*recurrence, error) {
+	rule := &recurrence{interval: 1}
+	for _, part := range strings.Split(value, ";") {
+		k, v, _ := strings.Cut(part, "=")
+		switch strings.ToUpper(k) {
+		case "FREQ":
+			rule.freq = strings.ToUpper(v)
+		case "INTERVAL":
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("invalid RRULE %q", value)
+			}
+			rule.interval = n
+		case "COUNT":
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				return nil, fmt.Errorf("invalid RRULE %q", value)
+			}
+			rule.count = n
+		case "UNTIL":
+			t, allDay, err := parseICSTime(v, nil)
+			if err != nil {
+				return nil, err
+			}
+			if allDay {
+				t = t.AddDate(0, 0, 1).Add(-time.Second)
+			}
+			rule.until = t
+		case "BYDAY":
+			for _, d := range strings.Split(v, ",") {
+				// Ordinals such as 1MO only make sense for monthly rules,
+				// which repeat on the day of the month of DTSTART here.
+				d = strings.TrimLeft(d, "+-0123456789")
+				if wd, ok := icsWeekdays[strings.ToUpper(d)]; ok {
+					rule.byDay = append(rule.byDay, wd)
+				}
+			}
+			slices.Sort(rule.byDay)
+		}
+	}
+	return rule, nil
+}
+
+// unescapeText undoes the escaping of TEXT values.
+func unescapeText(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) {
+			i++
+			switch s[i] {
+			case 'n', 'N':
+				b.WriteByte('\n')
+			default:
+				b.WriteByte(s[i])
+			}
+			continue
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+// splitText splits a list of TEXT values at the commas that are not
+// escaped, unescaping each value.
+func splitText(s string) []string {
+	var values []string
+	start := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case ',':
+			values = append(values, unescapeText(s[start:i]))
+			start = i + 1
+		}
+	}
+	return append(values, unescapeText(s[start:]))
+}
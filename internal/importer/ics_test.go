@@ -0,0 +1,131 @@
+package importer
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/highercomve/tasktracker/internal/models"
+	"github.com/highercomve/tasktracker/internal/service"
+)
+
+const calendar = "BEGIN:VCALENDAR\r\n" +
+	"VERSION:2.0\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:standup\r\n" +
+	"DTSTART;TZID=UTC:20240506T090000\r\n" +
+	"DURATION:PT15M\r\n" +
+	"RRULE:FREQ=WEEKLY;BYDAY=MO,WE,FR;COUNT=6\r\n" +
+	"EXDATE;TZID=UTC:20240508T090000\r\n" +
+	"SUMMARY:Standup\r\n" +
+	"CATEGORIES:Meetings\r\n" +
+	"BEGIN:VALARM\r\n" +
+	"DESCRIPTION:Reminder\r\n" +
+	"END:VALARM\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:standup\r\n" +
+	"RECURRENCE-ID;TZID=UTC:20240510T090000\r\n" +
+	"DTSTART;TZID=UTC:20240510T093000\r\n" +
+	"DTEND;TZID=UTC:20240510T094500\r\n" +
+	"SUMMARY:Standup (moved)\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:review\r\n" +
+	"DTSTART:20240506T140000Z\r\n" +
+	"DTEND:20240506T150000Z\r\n" +
+	"SUMMARY:Website design review\\, round 2\r\n" +
+	"CATEGORIES:client,Billing\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:cancelled\r\n" +
+	"STATUS:CANCELLED\r\n" +
+	"DTSTART:20240506T160000Z\r\n" +
+	"DTEND:20240506T170000Z\r\n" +
+	"SUMMARY:Cancelled\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:holiday\r\n" +
+	"DTSTART;VALUE=DATE:20240506\r\n" +
+	"SUMMARY:Holiday\r\n" +
+	"END:VEVENT\r\n" +
+	"END:VCALENDAR\r\n"
+
+func TestReadCalendarOccurrences(t *testing.T) {
+	events, err := ReadCalendar(strings.NewReader(calendar))
+	if err != nil {
+		t.Fatalf("ReadCalendar: %v", err)
+	}
+	if len(events) != 4 {
+		t.Fatalf("expected 4 events, got %d", len(events))
+	}
+
+	start := time.Date(2024, 5, 6, 0, 0, 0, 0, time.UTC)
+	got := Occurrences(events, start, start.AddDate(0, 0, 14))
+	var summaries []string
+	for _, o := range got {
+		summaries = append(summaries, o.Start.UTC().Format("01-02 15:04")+" "+o.Summary)
+	}
+	want := []string{
+		"05-06 09:00 Standup",
+		"05-06 14:00 Website design review, round 2",
+		"05-10 09:30 Standup (moved)",
+		"05-13 09:00 Standup",
+		"05-15 09:00 Standup",
+		"05-17 09:00 Standup", // sixth: COUNT includes the excluded dates
+	}
+	if strings.Join(summaries, "|") != strings.Join(want, "|") {
+		t.Errorf("expected\n%v\ngot\n%v", want, summaries)
+	}
+	if got[0].End.Sub(got[0].Start) != 15*time.Minute {
+		t.Errorf("standup length: %v", got[0].End.Sub(got[0].Start))
+	}
+}
+
+func TestSuggestEntries(t *testing.T) {
+	events, err := ReadCalendar(strings.NewReader(calendar))
+	if err != nil {
+		t.Fatalf("ReadCalendar: %v", err)
+	}
+	projects := []models.Project{{ID: "m", Name: "meetings"}, {ID: "w", Name: "Website"}}
+	day := time.Date(2024, 5, 6, 0, 0, 0, 0, time.UTC)
+	tracked := []models.TimeEntry{{Description: "Standup", StartTime: time.Date(2024, 5, 6, 9, 0, 0, 0, time.UTC)}}
+
+	got := SuggestEntries(events, tracked, projects, day, day.AddDate(0, 0, 1))
+	if len(got) != 1 {
+		t.Fatalf("expected the review only, got %+v", got)
+	}
+	e := got[0]
+	if e.Description != "Website design review, round 2" || e.ProjectID != "w" || e.Duration != 3600 ||
+		strings.Join(e.Tags, "|") != "client|Billing" || e.State != models.TaskStateStopped {
+		t.Errorf("suggestion: %+v", e)
+	}
+
+	got = SuggestEntries(events, nil, projects, day, day.AddDate(0, 0, 1))
+	if len(got) != 2 || got[0].ProjectID != "m" || len(got[0].Tags) != 0 {
+		t.Errorf("standup should be filed under its category: %+v", got)
+	}
+}
+
+func TestICSRoundTrip(t *testing.T) {
+	start := time.Date(2024, 5, 6, 9, 0, 0, 0, time.Local)
+	entries := []models.TimeEntry{{ID: "a", Description: "Line one\nline; two, \\ three", Tags: []string{"a,b"},
+		StartTime: start, EndTime: start.Add(90 * time.Minute), Duration: 5400, State: models.TaskStateStopped}}
+	var buf bytes.Buffer
+	if err := service.ExportICS(&buf, entries, nil, start); err != nil {
+		t.Fatalf("ExportICS: %v", err)
+	}
+	events, err := ReadCalendar(&buf)
+	if err != nil {
+		t.Fatalf("ReadCalendar: %v", err)
+	}
+	if len(events) != 1 {
+		t.Fatalf("expected 1 event, got %d", len(events))
+	}
+	ev := events[0]
+	if ev.Summary != entries[0].Description || !ev.Start.Equal(start) || !ev.End.Equal(entries[0].EndTime) ||
+		len(ev.Categories) != 1 || ev.Categories[0] != "a,b" {
+		t.Errorf("round trip: %+v", ev)
+	}
+}
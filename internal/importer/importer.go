@@ -0,0 +1,307 @@
+// Package importer reads the exports of other time trackers into TaskTracker
+// entries and projects.
+//
+// Importers only parse: the entries they return are checked against the
+// stored ones and written with service.PlanImport and service.Import, so a
+// dry run can be shown before anything is saved.
+package importer
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/highercomve/tasktracker/internal/models"
+	"github.com/highercomve/tasktracker/internal/service"
+)
+
+// Format names.
+const (
+	FormatCSV         = "csv"
+	FormatTogglCSV    = "toggl-csv"
+	FormatTogglJSON   = "toggl-json"
+	FormatClockifyCSV = "clockify-csv"
+	FormatWatson      = "watson"
+)
+
+// Date orders, for the dates of CSV reports that read either way, such as
+// 03/04/2024.
+const (
+	DateOrderDMY = "dd/mm"
+	DateOrderMDY = "mm/dd"
+)
+
+// ErrAmbiguousDates is returned when the dates of an export read both as
+// day/month and as month/day, so Options.DateOrder has to tell.
+var ErrAmbiguousDates = errors.New("the dates read both as day/month and as month/day")
+
+// Options tune how an export is read.
+type Options struct {
+	// DateOrder is DateOrderDMY or DateOrderMDY; the order is found from
+	// the dates themselves when empty.
+	DateOrder string
+}
+
+// Result is what an importer read from an export.
+type Result struct {
+	Entries []models.TimeEntry
+	// Projects are the projects the entries refer to that do not exist
+	// yet, with the client and color found in the export.
+	Projects []models.Project
+	// Problems describe the records that were skipped.
+	Problems []string
+}
+
+// Format is a supported export format.
+type Format struct {
+	Name        string
+	Description string
+	// Parse reads an export. Projects are matched by name against
+	// projects; missing ones are returned to be created.
+	Parse func(r io.Reader, projects []models.Project, opts Options) (Result, error)
+}
+
+// Formats lists the supported formats.
+var Formats = []Format{
+	{FormatCSV, "TaskTracker CSV, or any CSV with recognizable column names", parseCSV},
+	{FormatTogglCSV, "Toggl Track detailed report (CSV)", parseTogglCSV},
+	{FormatTogglJSON, "Toggl Track time entries or detailed report (JSON)", parseTogglJSON},
+	{FormatClockifyCSV, "Clockify detailed report (CSV)", parseClockifyCSV},
+	{FormatWatson, "Watson frames file", parseWatson},
+}
+
+// Lookup returns the format named name.
+func Lookup(name string) (Format, bool) {
+	for _, f := range Formats {
+		if f.Name == name {
+			return f, true
+		}
+	}
+	return Format{}, false
+}
+
+// Detect guesses the format of an export from its file name and content.
+func Detect(filename string, data []byte) string {
+	trimmed := bytes.TrimLeft(data, " \t\r\n\ufeff")
+	if strings.EqualFold(filepath.Ext(filename), ".json") || bytes.HasPrefix(trimmed, []byte("[")) || bytes.HasPrefix(trimmed, []byte("{")) {
+		// Watson frames are arrays of arrays.
+		if bytes.HasPrefix(bytes.TrimLeft(trimmed[min(1, len(trimmed)):], " \t\r\n"), []byte("[")) || filepath.Base(filename) == "frames" {
+			return FormatWatson
+		}
+		return FormatTogglJSON
+	}
+	line, _, _ := bytes.Cut(trimmed, []byte("\n"))
+	header := strings.ToLower(string(line))
+	switch {
+	case strings.Contains(header, "duration (decimal)"):
+		return FormatClockifyCSV
+	case strings.Contains(header, "start date") && strings.Contains(header, "end time") && strings.Contains(header, "email"):
+		return FormatTogglCSV
+	}
+	return FormatCSV
+}
+
+// projectSet resolves project names to IDs, creating the missing projects.
+type projectSet struct {
+	existing []models.Project
+	created  []models.Project
+}
+
+// id returns the ID of the project called name, creating it with client
+// and color if it does not exist. An empty name means no project.
+func (ps *projectSet) id(name, client, color string) string {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return ""
+	}
+	if p := service.FindProjectByName(ps.existing, name); p != nil {
+		return p.ID
+	}
+	if p := service.FindProjectByName(ps.created, name); p != nil {
+		return p.ID
+	}
+	p := service.CreateProject(name, "", normalizeColor(color))
+	p.Client = strings.TrimSpace(client)
+	ps.created = append(ps.created, p)
+	return p.ID
+}
+
+// normalizeColor returns color as "#rrggbb", or empty if it is not one.
+func normalizeColor(color string) string {
+	color = strings.TrimPrefix(strings.TrimSpace(color), "#")
+	if len(color) != 6 {
+		return ""
+	}
+	for _, c := range strings.ToLower(color) {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return ""
+		}
+	}
+	return "#" + strings.ToLower(color)
+}
+
+// newEntry builds a stopped entry from start to end.
+func newEntry(description string, start, end time.Time, tags []string) (models.TimeEntry, error) {
+	if start.IsZero() || end.IsZero() {
+		return models.TimeEntry{}, fmt.Errorf("%q is still running or has no start", description)
+	}
+	if end.Before(start) {
+		return models.TimeEntry{}, fmt.Errorf("%q ends before it starts", description)
+	}
+	return models.TimeEntry{
+		ID:          uuid.New().String(),
+		Description: description,
+		Tags:        tags,
+		StartTime:   start,
+		EndTime:     end,
+		Duration:    int64(end.Sub(start) / time.Second),
+		State:       models.TaskStateStopped,
+	}, nil
+}
+
+// splitTags splits a list of tags separated by commas.
+func splitTags(s string) []string {
+	var tags []string
+	for _, tag := range strings.Split(s, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// csvTable is a CSV file with its columns looked up by header name.
+type csvTable struct {
+	columns map[string]int
+	records [][]string
+}
+
+func readCSVTable(r io.Reader) (*csvTable, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	header, records, err := service.ReadCSV(bytes.NewReader(data), service.CSVOptions{Delimiter: service.SniffCSVDelimiter(data)})
+	if err != nil {
+		return nil, err
+	}
+	t := &csvTable{columns: map[string]int{}, records: records}
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if _, dup := t.columns[name]; !dup {
+			t.columns[name] = i
+		}
+	}
+	return t, nil
+}
+
+// require fails unless the file has all the given columns.
+func (t *csvTable) require(names ...string) error {
+	for _, name := range names {
+		if _, ok := t.columns[name]; !ok {
+			return fmt.Errorf("missing column %q", name)
+		}
+	}
+	return nil
+}
+
+// field returns the value of the named column in record.
+func (t *csvTable) field(record []string, name string) string {
+	i, ok := t.columns[name]
+	if !ok || i >= len(record) {
+		return ""
+	}
+	return strings.TrimSpace(record[i])
+}
+
+// parseLocal reads value with the first matching layout, in local time.
+func parseLocal(value string, layouts []string) (time.Time, error) {
+	for _, layout := range layouts {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unrecognized date or time %q", value)
+}
+
+// values returns the values of the named columns that are not empty, row
+// by row.
+func (t *csvTable) values(names ...string) []string {
+	var values []string
+	for _, record := range t.records {
+		for _, name := range names {
+			if v := t.field(record, name); v != "" {
+				values = append(values, v)
+			}
+		}
+	}
+	return values
+}
+
+// dateLayout picks the layout the dates of a whole export are written in:
+// the one of layouts that reads most of them, so that 03/04 and 25/04 in one
+// file are read in the same order. Layouts not in order, if given, are left
+// out. It fails with ErrAmbiguousDates when day/month and month/day read as
+// many dates, as when no day is above 12.
+func dateLayout(dates, layouts []string, order string) (string, error) {
+	best, most, tie := layouts[0], 0, false
+	for _, layout := range layouts {
+		if order != "" && layoutOrder(layout) != "" && layoutOrder(layout) != order {
+			continue
+		}
+		n := 0
+		for _, d := range dates {
+			if _, err := time.Parse(layout, d); err == nil {
+				n++
+			}
+		}
+		switch {
+		case n > most:
+			best, most, tie = layout, n, false
+		case n == most && n > 0:
+			tie = true
+		}
+	}
+	if tie {
+		return "", ErrAmbiguousDates
+	}
+	return best, nil
+}
+
+// layoutOrder returns the order of day and month in a date layout, or empty
+// for layouts starting with the year, which are never ambiguous.
+func layoutOrder(layout string) string {
+	switch day, month := strings.Index(layout, "02"), strings.Index(layout, "01"); {
+	case strings.HasPrefix(layout, "2006"):
+		return ""
+	case day < month:
+		return DateOrderDMY
+	default:
+		return DateOrderMDY
+	}
+}
+
+// parseCSV reads a CSV file with the columns detected from its header, as
+// the CSV importer of the Config tab does by default.
+func parseCSV(r io.Reader, projects []models.Project, _ Options) (Result, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return Result{}, err
+	}
+	opts := service.CSVOptions{Delimiter: service.SniffCSVDelimiter(data)}
+	header, records, err := service.ReadCSV(bytes.NewReader(data), opts)
+	if err != nil {
+		return Result{}, err
+	}
+	entries, created, rowErrors := service.ParseCSVEntries(records, service.DetectCSVMapping(header), projects, opts)
+	res := Result{Entries: entries, Projects: created}
+	for _, e := range rowErrors {
+		res.Problems = append(res.Problems, e.Error())
+	}
+	return res, nil
+}
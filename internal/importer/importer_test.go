@@ -0,0 +1,159 @@
+package importer
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/highercomve/tasktracker/internal/models"
+)
+
+const togglCSV = `User,Email,Client,Project,Task,Description,Billable,Start date,Start time,End date,End time,Duration,Tags,Amount ()
+Ana,ana@example.com,ACME,Website,,Design review,Yes,2024-05-06,09:00:00,2024-05-06,10:30:00,01:30:00,"ux, review",
+Ana,ana@example.com,,,,Email,No,2024-05-06,23:30:00,2024-05-07,00:15:00,00:45:00,,
+Ana,ana@example.com,ACME,Website,,Broken,No,2024-05-06,nope,2024-05-06,10:00:00,,,
+`
+
+const clockifyCSV = `Project,Client,Description,Task,User,Group,Email,Tags,Billable,Start Date,Start Time,End Date,End Time,Duration (h),Duration (decimal),Billable Rate (USD),Billable Amount (USD)
+Website,ACME,Standup,,Ana,,ana@example.com,meeting,Yes,05/06/2024,09:00:00 AM,05/06/2024,09:15:00 AM,00:15:00,0.25,0,0
+Internal,,Planning,,Ana,,ana@example.com,,No,05/06/2024,01:00:00 PM,05/06/2024,02:00:00 PM,01:00:00,1.00,0,0
+`
+
+const togglJSON = `[
+  {"description": "Design review", "start": "2024-05-06T09:00:00Z", "stop": "2024-05-06T10:30:00Z", "duration": 5400,
+   "tags": ["ux"], "project_name": "Website", "project_color": "#06AED5", "client_name": "ACME"},
+  {"description": "Running", "start": "2024-05-06T11:00:00Z", "stop": null, "duration": -1714993200}
+]`
+
+const togglReport = `{"total_count": 1, "data": [
+  {"description": "Report row", "start": "2024-05-06T09:00:00+02:00", "end": "2024-05-06T09:45:00+02:00", "dur": 2700000,
+   "project": "Website", "project_hex_color": "#c56bff", "client": "ACME", "tags": []}
+]}`
+
+const watsonFrames = `[
+  [1714986000, 1714989600, "website", "0b1d4c", ["design", "ux"], 1714989600],
+  [1714990000, 1714991800, "chores", "9f0e21", [], 1714991800],
+  [1714992000, 1714991000, "backwards", "1a2b3c", [], 1714992000]
+]`
+
+func parse(t *testing.T, name, data string, projects []models.Project, opts Options) Result {
+	t.Helper()
+	f, ok := Lookup(name)
+	if !ok {
+		t.Fatalf("format %q not found", name)
+	}
+	res, err := f.Parse(strings.NewReader(data), projects, opts)
+	if err != nil {
+		t.Fatalf("%s: %v", name, err)
+	}
+	return res
+}
+
+func TestTogglCSV(t *testing.T) {
+	existing := []models.Project{{ID: "web", Name: "Website"}}
+	res := parse(t, FormatTogglCSV, togglCSV, existing, Options{})
+
+	if len(res.Entries) != 2 || len(res.Problems) != 1 || len(res.Projects) != 0 {
+		t.Fatalf("entries %d, problems %v, projects %v", len(res.Entries), res.Problems, res.Projects)
+	}
+	e := res.Entries[0]
+	want := time.Date(2024, 5, 6, 9, 0, 0, 0, time.Local)
+	if e.Description != "Design review" || e.ProjectID != "web" || !e.StartTime.Equal(want) || e.Duration != 5400 ||
+		strings.Join(e.Tags, "|") != "ux|review" || e.State != models.TaskStateStopped || e.ID == "" {
+		t.Errorf("first entry: %+v", e)
+	}
+	if e := res.Entries[1]; e.Duration != 45*60 || e.ProjectID != "" || e.EndTime.Day() != 7 {
+		t.Errorf("entry across midnight: %+v", e)
+	}
+}
+
+func TestClockifyCSV(t *testing.T) {
+	// 05/06/2024 reads both ways, so the order has to be given
+	if _, err := parseClockifyCSV(strings.NewReader(clockifyCSV), nil, Options{}); !errors.Is(err, ErrAmbiguousDates) {
+		t.Fatalf("expected ErrAmbiguousDates, got %v", err)
+	}
+	res := parse(t, FormatClockifyCSV, clockifyCSV, nil, Options{DateOrder: DateOrderMDY})
+
+	if len(res.Entries) != 2 || len(res.Problems) != 0 || len(res.Projects) != 2 {
+		t.Fatalf("entries %d, problems %v, projects %v", len(res.Entries), res.Problems, res.Projects)
+	}
+	if p := res.Projects[0]; p.Name != "Website" || p.Client != "ACME" || res.Entries[0].ProjectID != p.ID {
+		t.Errorf("project: %+v", p)
+	}
+	if e := res.Entries[1]; e.StartTime.Hour() != 13 || e.Duration != 3600 {
+		t.Errorf("afternoon entry: %+v", e)
+	}
+}
+
+func TestDateOrderPerFile(t *testing.T) {
+	const dayFirst = `Description,Start date,Start time,End date,End time
+Ambiguous,03/04/2024,09:00:00,03/04/2024,10:00:00
+Unambiguous,25/04/2024,09:00:00,25/04/2024,10:00:00
+`
+	res := parse(t, FormatTogglCSV, dayFirst, nil, Options{})
+	if len(res.Entries) != 2 || len(res.Problems) != 0 {
+		t.Fatalf("entries %d, problems %v", len(res.Entries), res.Problems)
+	}
+	for i, want := range []time.Time{time.Date(2024, 4, 3, 9, 0, 0, 0, time.Local), time.Date(2024, 4, 25, 9, 0, 0, 0, time.Local)} {
+		if e := res.Entries[i]; !e.StartTime.Equal(want) {
+			t.Errorf("%s: expected %v, got %v", e.Description, want, e.StartTime)
+		}
+	}
+
+	// The order given wins over the one found
+	res = parse(t, FormatTogglCSV, dayFirst, nil, Options{DateOrder: DateOrderMDY})
+	if len(res.Entries) != 1 || len(res.Problems) != 1 || res.Entries[0].StartTime.Month() != time.March {
+		t.Errorf("month first: entries %+v, problems %v", res.Entries, res.Problems)
+	}
+}
+
+func TestTogglJSON(t *testing.T) {
+	res := parse(t, FormatTogglJSON, togglJSON, nil, Options{})
+	if len(res.Entries) != 1 || len(res.Problems) != 1 || len(res.Projects) != 1 {
+		t.Fatalf("entries %d, problems %v, projects %v", len(res.Entries), res.Problems, res.Projects)
+	}
+	if p := res.Projects[0]; p.Client != "ACME" || p.ColorHex != "#06aed5" {
+		t.Errorf("project: %+v", p)
+	}
+	if e := res.Entries[0]; e.Duration != 5400 || !e.StartTime.Equal(time.Date(2024, 5, 6, 9, 0, 0, 0, time.UTC)) {
+		t.Errorf("entry: %+v", e)
+	}
+
+	res = parse(t, FormatTogglJSON, togglReport, nil, Options{})
+	if len(res.Entries) != 1 || res.Entries[0].Duration != 2700 || res.Projects[0].ColorHex != "#c56bff" {
+		t.Errorf("report: %+v", res)
+	}
+}
+
+func TestWatson(t *testing.T) {
+	res := parse(t, FormatWatson, watsonFrames, []models.Project{{ID: "c", Name: "chores"}}, Options{})
+	if len(res.Entries) != 2 || len(res.Problems) != 1 || len(res.Projects) != 1 {
+		t.Fatalf("entries %d, problems %v, projects %v", len(res.Entries), res.Problems, res.Projects)
+	}
+	e := res.Entries[0]
+	if e.Description != "website" || e.ProjectID != res.Projects[0].ID || e.Duration != 3600 || strings.Join(e.Tags, "|") != "design|ux" {
+		t.Errorf("first frame: %+v", e)
+	}
+	if res.Entries[1].ProjectID != "c" {
+		t.Errorf("existing project not reused: %+v", res.Entries[1])
+	}
+}
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		filename, data, want string
+	}{
+		{"toggl.csv", togglCSV, FormatTogglCSV},
+		{"clockify.csv", clockifyCSV, FormatClockifyCSV},
+		{"entries.json", togglJSON, FormatTogglJSON},
+		{"report.json", togglReport, FormatTogglJSON},
+		{"frames", watsonFrames, FormatWatson},
+		{"export.csv", "date,start,end,description\n", FormatCSV},
+	}
+	for _, tt := range tests {
+		if got := Detect(tt.filename, []byte(tt.data)); got != tt.want {
+			t.Errorf("Detect(%s) = %s, want %s", tt.filename, got, tt.want)
+		}
+	}
+}
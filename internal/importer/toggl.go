@@ -0,0 +1,149 @@
+package importer
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/highercomve/tasktracker/internal/models"
+)
+
+// togglDateLayouts and togglTimeLayouts are the layouts of the date and
+// time columns of Toggl CSV reports, which follow the user's settings.
+var (
+	togglDateLayouts = []string{"2006-01-02", "01/02/2006", "02/01/2006", "02.01.2006", "02-01-2006"}
+	togglTimeLayouts = []string{"15:04:05", "15:04", "03:04:05 PM", "3:04:05 PM", "03:04 PM", "3:04 PM"}
+)
+
+// parseTogglCSV reads a Toggl Track detailed report exported as CSV.
+func parseTogglCSV(r io.Reader, projects []models.Project, opts Options) (Result, error) {
+	t, err := readCSVTable(r)
+	if err != nil {
+		return Result{}, err
+	}
+	if err := t.require("description", "start date", "start time", "end date", "end time"); err != nil {
+		return Result{}, err
+	}
+	layout, err := dateLayout(t.values("start date", "end date"), togglDateLayouts, opts.DateOrder)
+	if err != nil {
+		return Result{}, err
+	}
+	ps := &projectSet{existing: projects}
+	var res Result
+	for i, record := range t.records {
+		description := t.field(record, "description")
+		start, err1 := parseDateTime(t.field(record, "start date"), t.field(record, "start time"), layout, togglTimeLayouts)
+		end, err2 := parseDateTime(t.field(record, "end date"), t.field(record, "end time"), layout, togglTimeLayouts)
+		if err := errors.Join(err1, err2); err != nil {
+			res.Problems = append(res.Problems, fmt.Sprintf("line %d: %v", i+2, err))
+			continue
+		}
+		e, err := newEntry(description, start, end, splitTags(t.field(record, "tags")))
+		if err != nil {
+			res.Problems = append(res.Problems, fmt.Sprintf("line %d: %v", i+2, err))
+			continue
+		}
+		e.ProjectID = ps.id(t.field(record, "project"), t.field(record, "client"), "")
+		res.Entries = append(res.Entries, e)
+	}
+	res.Projects = ps.created
+	return res, nil
+}
+
+// parseDateTime reads a date and a time given in separate columns, the
+// date in the layout dateLayout picked for the whole file.
+func parseDateTime(date, clock, layout string, timeLayouts []string) (time.Time, error) {
+	layouts := make([]string, len(timeLayouts))
+	for i, t := range timeLayouts {
+		layouts[i] = layout + " " + t
+	}
+	return parseLocal(date+" "+clock, layouts)
+}
+
+// togglEntry is a time entry of the Toggl Track API (v9) or of a detailed
+// report in JSON; each uses its own names for some fields.
+type togglEntry struct {
+	Description string   `json:"description"`
+	Start       string   `json:"start"`
+	Stop        string   `json:"stop"`
+	End         string   `json:"end"`
+	Tags        []string `json:"tags"`
+
+	// Time entries API.
+	ProjectName  string `json:"project_name"`
+	ProjectColor string `json:"project_color"`
+	ClientName   string `json:"client_name"`
+
+	// Detailed report.
+	Project         string `json:"project"`
+	ProjectHexColor string `json:"project_hex_color"`
+	Client          string `json:"client"`
+}
+
+// parseTogglJSON reads Toggl Track time entries, either as returned by the
+// API (an array) or as a detailed report (an object with a data array).
+func parseTogglJSON(r io.Reader, projects []models.Project, _ Options) (Result, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return Result{}, err
+	}
+	var entries []togglEntry
+	if trimmed := bytes.TrimSpace(data); bytes.HasPrefix(trimmed, []byte("{")) {
+		var report struct {
+			Data []togglEntry `json:"data"`
+		}
+		err = json.Unmarshal(trimmed, &report)
+		entries = report.Data
+	} else {
+		err = json.Unmarshal(trimmed, &entries)
+	}
+	if err != nil {
+		return Result{}, fmt.Errorf("reading Toggl JSON: %w", err)
+	}
+
+	ps := &projectSet{existing: projects}
+	var res Result
+	for i, te := range entries {
+		start, err1 := parseTogglTime(te.Start)
+		end, err2 := parseTogglTime(firstOf(te.Stop, te.End))
+		if err := errors.Join(err1, err2); err != nil {
+			res.Problems = append(res.Problems, fmt.Sprintf("entry %d: %v", i+1, err))
+			continue
+		}
+		e, err := newEntry(te.Description, start, end, te.Tags)
+		if err != nil {
+			res.Problems = append(res.Problems, fmt.Sprintf("entry %d: %v", i+1, err))
+			continue
+		}
+		e.ProjectID = ps.id(firstOf(te.ProjectName, te.Project), firstOf(te.ClientName, te.Client), firstOf(te.ProjectColor, te.ProjectHexColor))
+		res.Entries = append(res.Entries, e)
+	}
+	res.Projects = ps.created
+	return res, nil
+}
+
+// parseTogglTime reads an RFC 3339 time; empty means the entry is running.
+func parseTogglTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("unrecognized time %q", value)
+	}
+	return t.Local(), nil
+}
+
+// firstOf returns the first of values that is not empty.
+func firstOf(values ...string) string {
+	for _, v := range values {
+		if v = strings.TrimSpace(v); v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,56 @@
+package importer
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/highercomve/tasktracker/internal/models"
+)
+
+// parseWatson reads a Watson frames file, a JSON array of
+// [start, stop, project, id, tags, updated_at] arrays with Unix times.
+// Watson has no descriptions, so the project name is used as one.
+func parseWatson(r io.Reader, projects []models.Project, _ Options) (Result, error) {
+	var frames [][]json.RawMessage
+	if err := json.NewDecoder(r).Decode(&frames); err != nil {
+		return Result{}, fmt.Errorf("reading Watson frames: %w", err)
+	}
+	ps := &projectSet{existing: projects}
+	var res Result
+	for i, frame := range frames {
+		var (
+			start, stop int64
+			project     string
+			tags        []string
+		)
+		if len(frame) < 3 {
+			res.Problems = append(res.Problems, fmt.Sprintf("frame %d: too few fields", i+1))
+			continue
+		}
+		err := json.Unmarshal(frame[0], &start)
+		if err == nil {
+			err = json.Unmarshal(frame[1], &stop)
+		}
+		if err == nil {
+			err = json.Unmarshal(frame[2], &project)
+		}
+		if err == nil && len(frame) > 4 {
+			err = json.Unmarshal(frame[4], &tags)
+		}
+		if err != nil {
+			res.Problems = append(res.Problems, fmt.Sprintf("frame %d: %v", i+1, err))
+			continue
+		}
+		e, err := newEntry(project, time.Unix(start, 0), time.Unix(stop, 0), tags)
+		if err != nil {
+			res.Problems = append(res.Problems, fmt.Sprintf("frame %d: %v", i+1, err))
+			continue
+		}
+		e.ProjectID = ps.id(project, "", "")
+		res.Entries = append(res.Entries, e)
+	}
+	res.Projects = ps.created
+	return res, nil
+}
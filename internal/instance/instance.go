@@ -0,0 +1,207 @@
+// Package instance keeps a single TaskTracker GUI running per user.
+//
+// The first GUI takes an exclusive lock in the config folder and listens on a
+// loopback port. Later launches, including the headless subcommands, find
+// that port through an address file and forward their arguments to the
+// running instance instead of touching the data folder behind its back.
+package instance
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/highercomve/tasktracker/internal/lockfile"
+)
+
+// ShowWindow is the request sent by a second GUI launch. It is not a CLI
+// subcommand; the handler of the running GUI raises its window.
+const ShowWindow = "show-window"
+
+const (
+	lockFileName = "instance.lock"
+	addrFileName = "instance.addr"
+	dialTimeout  = 2 * time.Second
+	// Commands touch storage only, so a generous limit still catches a
+	// hung instance.
+	requestTimeout = 30 * time.Second
+)
+
+var (
+	// ErrAlreadyRunning is returned by Listen when another instance holds
+	// the lock.
+	ErrAlreadyRunning = errors.New("another instance is already running")
+	// ErrNotRunning is returned by Forward when no instance is listening.
+	ErrNotRunning = errors.New("no running instance")
+)
+
+// Handler runs a forwarded request. Output written to out is sent back to
+// the caller, and a non-nil error is reported to it.
+type Handler func(args []string, out io.Writer) error
+
+type request struct {
+	Token string   `json:"token"`
+	Args  []string `json:"args"`
+}
+
+type response struct {
+	Output string `json:"output"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Server is the listening side held by the primary instance.
+type Server struct {
+	dir      string
+	lock     *lockfile.Lock
+	listener net.Listener
+	token    string
+	wg       sync.WaitGroup
+}
+
+// Listen makes this process the primary instance for dir, normally the user
+// config folder. It returns ErrAlreadyRunning if another process is.
+func Listen(dir string) (*Server, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	lock, err := lockfile.TryExclusive(filepath.Join(dir, lockFileName))
+	if errors.Is(err, lockfile.ErrLocked) {
+		return nil, ErrAlreadyRunning
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		lock.Unlock()
+		return nil, err
+	}
+
+	// The token keeps other local users from driving our timer through
+	// the port; only readers of the 0600 address file know it.
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		ln.Close()
+		lock.Unlock()
+		return nil, err
+	}
+	token := hex.EncodeToString(buf)
+
+	addr := ln.Addr().String() + "\n" + token + "\n"
+	if err := os.WriteFile(filepath.Join(dir, addrFileName), []byte(addr), 0600); err != nil {
+		ln.Close()
+		lock.Unlock()
+		return nil, err
+	}
+
+	return &Server{dir: dir, lock: lock, listener: ln, token: token}, nil
+}
+
+// Serve accepts forwarded requests until Close is called. Requests are
+// handled one at a time, in arrival order.
+func (s *Server) Serve(h Handler) {
+	var mu sync.Mutex
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			log.Printf("Instance listener error: %v", err)
+			continue
+		}
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			defer conn.Close()
+			conn.SetDeadline(time.Now().Add(requestTimeout))
+
+			var req request
+			if err := json.NewDecoder(bufio.NewReader(conn)).Decode(&req); err != nil {
+				return
+			}
+			if req.Token != s.token {
+				json.NewEncoder(conn).Encode(response{Error: "invalid token"})
+				return
+			}
+
+			mu.Lock()
+			var out strings.Builder
+			err := h(req.Args, &out)
+			mu.Unlock()
+
+			resp := response{Output: out.String()}
+			if err != nil {
+				resp.Error = err.Error()
+			}
+			json.NewEncoder(conn).Encode(resp)
+		}()
+	}
+}
+
+// Close stops listening, waits for in-flight requests and releases the
+// instance lock.
+func (s *Server) Close() error {
+	err := s.listener.Close()
+	s.wg.Wait()
+	os.Remove(filepath.Join(s.dir, addrFileName))
+	s.lock.Unlock()
+	return err
+}
+
+// Forward sends args to the instance running for dir and copies its output
+// to out. It returns ErrNotRunning when there is no instance to talk to;
+// errors reported by the instance are returned as is.
+func Forward(dir string, args []string, out io.Writer) error {
+	// An instance holds the lock for as long as it runs. If we can take
+	// it, any address file is stale.
+	lock, err := lockfile.TryExclusive(filepath.Join(dir, lockFileName))
+	if err == nil {
+		lock.Unlock()
+		return ErrNotRunning
+	}
+	if !errors.Is(err, lockfile.ErrLocked) {
+		return ErrNotRunning
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, addrFileName))
+	if err != nil {
+		return ErrNotRunning
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 2 {
+		return ErrNotRunning
+	}
+
+	conn, err := net.DialTimeout("tcp", lines[0], dialTimeout)
+	if err != nil {
+		return ErrNotRunning
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(requestTimeout))
+
+	if err := json.NewEncoder(conn).Encode(request{Token: lines[1], Args: args}); err != nil {
+		return fmt.Errorf("failed to reach running instance: %w", err)
+	}
+	var resp response
+	if err := json.NewDecoder(conn).Decode(&resp); err != nil {
+		return fmt.Errorf("no reply from running instance: %w", err)
+	}
+	io.WriteString(out, resp.Output)
+	if resp.Error != "" {
+		return errors.New(resp.Error)
+	}
+	return nil
+}
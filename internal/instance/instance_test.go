@@ -0,0 +1,58 @@
+package instance
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestForwardToRunningInstance(t *testing.T) {
+	dir := t.TempDir()
+
+	if err := Forward(dir, []string{"status"}, io.Discard); !errors.Is(err, ErrNotRunning) {
+		t.Fatalf("Forward without instance: expected ErrNotRunning, got %v", err)
+	}
+
+	server, err := Listen(dir)
+	if err != nil {
+		t.Fatalf("Listen: %v", err)
+	}
+	go server.Serve(func(args []string, out io.Writer) error {
+		if args[0] == "fail" {
+			io.WriteString(out, "partial\n")
+			return errors.New("boom")
+		}
+		io.WriteString(out, "ran "+strings.Join(args, " ")+"\n")
+		return nil
+	})
+
+	if _, err := Listen(dir); !errors.Is(err, ErrAlreadyRunning) {
+		t.Errorf("second Listen: expected ErrAlreadyRunning, got %v", err)
+	}
+
+	var out strings.Builder
+	if err := Forward(dir, []string{"start", "fix bug"}, &out); err != nil {
+		t.Fatalf("Forward: %v", err)
+	}
+	if out.String() != "ran start fix bug\n" {
+		t.Errorf("output: got %q", out.String())
+	}
+
+	out.Reset()
+	err = Forward(dir, []string{"fail"}, &out)
+	if err == nil || err.Error() != "boom" || out.String() != "partial\n" {
+		t.Errorf("failing command: got %q, %v", out.String(), err)
+	}
+
+	server.Close()
+	if err := Forward(dir, []string{"status"}, io.Discard); !errors.Is(err, ErrNotRunning) {
+		t.Errorf("Forward after Close: expected ErrNotRunning, got %v", err)
+	}
+	// The lock is free again for the next instance.
+	next, err := Listen(dir)
+	if err != nil {
+		t.Fatalf("Listen after Close: %v", err)
+	}
+	next.Close()
+}
@@ -0,0 +1,62 @@
+// Package lockfile provides advisory, cross-process file locks.
+//
+// Locks are tied to an open file descriptor, so they are released when the
+// process exits, even if it crashes. They only coordinate processes that use
+// this package (or flock/LockFileEx) on the same path.
+package lockfile
+
+import (
+	"errors"
+	"os"
+)
+
+// ErrLocked is returned by TryLock when another process holds the lock.
+var ErrLocked = errors.New("lock is held by another process")
+
+// Lock is a held lock on a file.
+type Lock struct {
+	f *os.File
+}
+
+// Exclusive blocks until it holds an exclusive lock on path, creating the
+// file if needed.
+func Exclusive(path string) (*Lock, error) {
+	return acquire(path, true, true)
+}
+
+// Shared blocks until it holds a shared lock on path, creating the file if
+// needed. Shared locks exclude exclusive ones but not each other.
+func Shared(path string) (*Lock, error) {
+	return acquire(path, false, true)
+}
+
+// TryExclusive takes an exclusive lock on path without waiting. It returns
+// ErrLocked if the lock is held elsewhere.
+func TryExclusive(path string) (*Lock, error) {
+	return acquire(path, true, false)
+}
+
+func acquire(path string, exclusive, wait bool) (*Lock, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+	if err := lock(f, exclusive, wait); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return &Lock{f: f}, nil
+}
+
+// Unlock releases the lock. It is safe to call on a nil Lock.
+func (l *Lock) Unlock() error {
+	if l == nil || l.f == nil {
+		return nil
+	}
+	err := unlock(l.f)
+	if cerr := l.f.Close(); err == nil {
+		err = cerr
+	}
+	l.f = nil
+	return err
+}
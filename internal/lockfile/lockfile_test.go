@@ -0,0 +1,46 @@
+package lockfile
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLocks(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.lock")
+
+	held, err := TryExclusive(path)
+	if err != nil {
+		t.Fatalf("TryExclusive: %v", err)
+	}
+	if _, err := TryExclusive(path); !errors.Is(err, ErrLocked) {
+		t.Errorf("second TryExclusive: expected ErrLocked, got %v", err)
+	}
+
+	acquired := make(chan *Lock)
+	go func() {
+		l, _ := Shared(path)
+		acquired <- l
+	}()
+	select {
+	case <-acquired:
+		t.Fatalf("Shared acquired while an exclusive lock was held")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	held.Unlock()
+	select {
+	case l := <-acquired:
+		defer l.Unlock()
+	case <-time.After(time.Second):
+		t.Fatalf("Shared not acquired after Unlock")
+	}
+
+	// Shared locks do not exclude each other.
+	other, err := Shared(path)
+	if err != nil {
+		t.Fatalf("second Shared: %v", err)
+	}
+	other.Unlock()
+}
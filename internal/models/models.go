@@ -1,6 +1,7 @@
 package models
 
 import (
+	"slices"
 	"time"
 )
 
@@ -20,8 +21,121 @@ type TimeEntry struct {
 	EndTime     time.Time `json:"end_time"`     // Zero if running
 	Duration    int64     `json:"duration_sec"` // Calculated on stop
 	Tags        []string  `json:"tags"`
-	State       int       `json:"state"`       // running, paused, stopped
-	Accumulated int64     `json:"accumulated"` // accumulated seconds before current run session
+	State       int       `json:"state"`               // running, paused, stopped
+	Accumulated int64     `json:"accumulated"`         // accumulated seconds before current run session
+	Segments    []Segment `json:"segments,omitempty"`  // when the work happened, one per run session
+	CreatedAt   time.Time `json:"created_at,omitzero"` // set by the store on the first save
+	UpdatedAt   time.Time `json:"updated_at,omitzero"` // set by the store on every save
+
+	// NonBillable marks internal work, which is never billed.
+	NonBillable bool `json:"non_billable,omitempty"`
+
+	// FocusSessions counts the Pomodoro work sessions completed on the entry.
+	FocusSessions int `json:"focus_sessions,omitempty"`
+}
+
+// Segment is one uninterrupted stretch of work on an entry, from a start or
+// resume to the following pause or stop.
+type Segment struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"` // Zero while running
+}
+
+// Duration returns the length of the segment, counting an open one up to now.
+func (s Segment) Duration(now time.Time) time.Duration {
+	if s.End.IsZero() {
+		return now.Sub(s.Start)
+	}
+	return s.End.Sub(s.Start)
+}
+
+// BeginSegment opens a new segment at t. Segments are copied on write, so a
+// previous copy of the entry is left untouched.
+func (e *TimeEntry) BeginSegment(t time.Time) {
+	e.Segments = append(slices.Clip(e.Segments), Segment{Start: t})
+}
+
+// EndSegment closes the open segment, if any, at t.
+func (e *TimeEntry) EndSegment(t time.Time) {
+	n := len(e.Segments)
+	if n == 0 || !e.Segments[n-1].End.IsZero() {
+		return
+	}
+	segments := slices.Clone(e.Segments)
+	segments[n-1].End = t
+	e.Segments = segments
+}
+
+// NormalizeSegments gives a stopped entry a single segment covering its
+// tracked duration from StartTime when it has none, as entries saved before
+// segments existed, or when its segments no longer agree with StartTime and
+// Duration because they were edited by code unaware of them. Active entries
+// are left to the timer, which knows the current run session.
+func (e *TimeEntry) NormalizeSegments() {
+	if e.EndTime.IsZero() {
+		return
+	}
+	if len(e.Segments) > 0 && e.Segments[0].Start.Equal(e.StartTime) {
+		var total time.Duration
+		for _, s := range e.Segments {
+			total += s.Duration(e.EndTime)
+		}
+		// Durations are counted in whole seconds per run session.
+		diff := total - time.Duration(e.Duration)*time.Second
+		if diff.Abs() <= time.Duration(len(e.Segments))*time.Second {
+			return
+		}
+	}
+	e.Segments = []Segment{{
+		Start: e.StartTime,
+		End:   e.StartTime.Add(time.Duration(e.Duration) * time.Second),
+	}}
+}
+
+// TrackedDuration returns the time worked on e up to now. Stopped entries
+// report their Duration; active ones add up their segments.
+func (e TimeEntry) TrackedDuration(now time.Time) time.Duration {
+	if !e.EndTime.IsZero() {
+		return time.Duration(e.Duration) * time.Second
+	}
+	if len(e.Segments) == 0 {
+		return now.Sub(e.StartTime)
+	}
+	var total time.Duration
+	for _, s := range e.Segments {
+		total += s.Duration(now)
+	}
+	return total
+}
+
+// WorkedUntil returns when work on e last stopped: EndTime for stopped
+// entries, the end of the last segment for paused ones and now for running
+// ones.
+func (e TimeEntry) WorkedUntil(now time.Time) time.Time {
+	if !e.EndTime.IsZero() {
+		return e.EndTime
+	}
+	if n := len(e.Segments); n > 0 && !e.Segments[n-1].End.IsZero() {
+		return e.Segments[n-1].End
+	}
+	return now
+}
+
+// Actions recorded in the entry change log.
+const (
+	EntryCreated = "create"
+	EntryUpdated = "update"
+	EntryDeleted = "delete"
+)
+
+// EntryChange is one record of the append-only entry change log: the
+// versions of an entry before and after a save or delete.
+type EntryChange struct {
+	EntryID string     `json:"entry_id"`
+	At      time.Time  `json:"at"`
+	Action  string     `json:"action"`
+	Before  *TimeEntry `json:"before,omitempty"` // nil when created
+	After   *TimeEntry `json:"after,omitempty"`  // nil when deleted
 }
 
 // Project represents a client or category.
@@ -33,4 +147,97 @@ type Project struct {
 	Description string    `json:"description"`
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
+
+	// Client details printed on invoices.
+	ClientAddress string `json:"client_address,omitempty"`
+	ClientEmail   string `json:"client_email,omitempty"`
+	ClientTaxID   string `json:"client_tax_id,omitempty"`
+
+	// Billing of the project. Zero values fall back to the global settings.
+	HourlyRate float64            `json:"hourly_rate,omitempty"`
+	MaxHours   float64            `json:"max_hours,omitempty"` // monthly overtime threshold, in every threshold mode
+	ExtraRate  float64            `json:"extra_rate,omitempty"`
+	Currency   string             `json:"currency,omitempty"`
+	TagRates   map[string]float64 `json:"tag_rates,omitempty"` // hourly rate of the entries with the tag
+	// NonBillable marks internal projects, whose time is never billed.
+	NonBillable bool `json:"non_billable,omitempty"`
+
+	// Budget of the project in hours or in money at its rates; none if zero.
+	// A monthly budget starts again every calendar month.
+	Budget        float64 `json:"budget,omitempty"`
+	BudgetType    string  `json:"budget_type,omitempty"` // BudgetHours or BudgetMoney
+	BudgetMonthly bool    `json:"budget_monthly,omitempty"`
+	// TaskEstimates are the hours estimated for tasks, by description.
+	TaskEstimates map[string]float64 `json:"task_estimates,omitempty"`
+}
+
+// Budget types.
+const (
+	BudgetHours = "hours"
+	BudgetMoney = "money"
+)
+
+// InvoiceParty is the issuer or the recipient of an invoice.
+type InvoiceParty struct {
+	Name    string `json:"name"`
+	Address string `json:"address,omitempty"`
+	Email   string `json:"email,omitempty"`
+	TaxID   string `json:"tax_id,omitempty"`
+}
+
+// InvoiceLine is a billed item: a project or a task.
+type InvoiceLine struct {
+	Description string  `json:"description"`
+	Hours       float64 `json:"hours"`
+	Rate        float64 `json:"rate"`
+	Amount      float64 `json:"amount"`
+}
+
+// Invoice is an issued invoice. Parties and lines are copied when it is
+// issued, so later changes to projects or settings do not alter it.
+type Invoice struct {
+	ID           string        `json:"id"`
+	Number       string        `json:"number"`
+	Sequence     int           `json:"sequence"`
+	IssueDate    time.Time     `json:"issue_date"`
+	DueDate      time.Time     `json:"due_date"`
+	PeriodStart  time.Time     `json:"period_start"`
+	PeriodEnd    time.Time     `json:"period_end"`
+	Business     InvoiceParty  `json:"business"`
+	Client       InvoiceParty  `json:"client"`
+	Currency     string        `json:"currency,omitempty"`
+	TaxPercent   float64       `json:"tax_percent,omitempty"`
+	PaymentTerms string        `json:"payment_terms,omitempty"`
+	Lines        []InvoiceLine `json:"lines"`
+	Paid         bool          `json:"paid"`
+	PaidAt       time.Time     `json:"paid_at,omitzero"`
+}
+
+// Subtotal is the sum of the line amounts, before tax.
+func (inv Invoice) Subtotal() float64 {
+	var total float64
+	for _, l := range inv.Lines {
+		total += l.Amount
+	}
+	return total
+}
+
+// Tax is the tax charged on the subtotal.
+func (inv Invoice) Tax() float64 {
+	return inv.Subtotal() * inv.TaxPercent / 100
+}
+
+// Total is the amount due.
+func (inv Invoice) Total() float64 {
+	return inv.Subtotal() + inv.Tax()
+}
+
+// Overdue reports whether the invoice is unpaid after its due date, the
+// last day to pay.
+func (inv Invoice) Overdue(now time.Time) bool {
+	if inv.Paid || inv.DueDate.IsZero() {
+		return false
+	}
+	y, m, d := inv.DueDate.Date()
+	return !now.Before(time.Date(y, m, d+1, 0, 0, 0, 0, inv.DueDate.Location()))
 }
@@ -0,0 +1,116 @@
+package service
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/highercomve/tasktracker/internal/models"
+	"github.com/highercomve/tasktracker/internal/session"
+)
+
+// Policies for the running task while the session is locked or the system
+// asleep.
+const (
+	AwayKeep  = "keep"  // keep tracking the time away
+	AwayPause = "pause" // pause the task when the user leaves, resume it on return
+	AwayAsk   = "ask"   // ask on return what to do with the time away
+)
+
+// AwayGuard applies the away policy to the running task as the session
+// locks, sleeps, wakes and unlocks. The user is away from the first lock or
+// suspend until neither holds anymore.
+type AwayGuard struct {
+	timer  *Timer
+	policy func() string
+
+	mu     sync.Mutex
+	locked bool
+	asleep bool
+	since  time.Time // when the user left, zero while present
+	taskID string    // the task running when the user left
+	action string    // the policy applied when the user left
+}
+
+// NewAwayGuard returns a guard of the tasks of timer. policy is called
+// each time the user leaves, so changes to the settings apply without a
+// restart.
+func NewAwayGuard(timer *Timer, policy func() string) *AwayGuard {
+	return &AwayGuard{timer: timer, policy: policy}
+}
+
+// Watch handles the events of src, calling ask with the time the user left
+// when they come back to a task still running under AwayAsk.
+func (g *AwayGuard) Watch(src session.Source, ask func(since time.Time)) error {
+	return src.Watch(func(ev session.Event) {
+		since, ok, err := g.Handle(ev)
+		if err != nil {
+			fmt.Printf("warning: failed to apply the away policy on %s: %v\n", ev.Kind, err)
+		}
+		if ok {
+			ask(since)
+		}
+	})
+}
+
+// Handle applies the policy to ev. It returns the time the user left, and
+// true, when they are back to the task they left running and should be
+// asked about the time away.
+func (g *AwayGuard) Handle(ev session.Event) (time.Time, bool, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	wasAway := g.locked || g.asleep
+	switch ev.Kind {
+	case session.Locked, session.Unlocked:
+		g.locked = ev.Kind == session.Locked
+	case session.Sleeping, session.Woke:
+		g.asleep = ev.Kind == session.Sleeping
+	}
+	away := g.locked || g.asleep
+
+	switch {
+	case away && !wasAway:
+		return time.Time{}, false, g.leaveLocked(ev.At)
+	case !away && wasAway:
+		return g.returnLocked()
+	}
+	return time.Time{}, false, nil
+}
+
+// leaveLocked applies the policy as the user leaves at at.
+func (g *AwayGuard) leaveLocked(at time.Time) error {
+	g.since, g.taskID, g.action = at, "", AwayKeep
+	active, ok := g.timer.Active()
+	if !ok || active.State != models.TaskStateRunning {
+		return nil
+	}
+	g.taskID = active.ID
+	switch g.policy() {
+	case AwayPause:
+		if err := g.timer.PauseAt(at); err != nil {
+			return err
+		}
+		g.action = AwayPause
+	case AwayAsk:
+		g.action = AwayAsk
+	}
+	return nil
+}
+
+// returnLocked undoes the pause of AwayPause, or tells to ask under
+// AwayAsk, when the task left is still active.
+func (g *AwayGuard) returnLocked() (time.Time, bool, error) {
+	since, taskID, action := g.since, g.taskID, g.action
+	g.since, g.taskID, g.action = time.Time{}, "", ""
+	if taskID == "" || g.timer.ActiveID() != taskID {
+		return time.Time{}, false, nil
+	}
+	switch state := g.timer.State(); {
+	case action == AwayPause && state == models.TaskStatePaused:
+		return time.Time{}, false, g.timer.Resume()
+	case action == AwayAsk && state == models.TaskStateRunning:
+		return since, true, nil
+	}
+	return time.Time{}, false, nil
+}
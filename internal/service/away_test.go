@@ -0,0 +1,132 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/highercomve/tasktracker/internal/models"
+	"github.com/highercomve/tasktracker/internal/session"
+)
+
+func TestAwayGuard(t *testing.T) {
+	start := time.Date(2026, 3, 10, 9, 0, 0, 0, time.UTC)
+	lock := start.Add(time.Hour)
+
+	tests := []struct {
+		name   string
+		policy string
+		// events are sent a minute apart from lock, the time at which the
+		// first one happens.
+		events         []session.Kind
+		expectState    int
+		expectDuration time.Duration // tracked once all the events are sent
+		expectAsk      bool
+	}{
+		{
+			name:           "Keep",
+			policy:         AwayKeep,
+			events:         []session.Kind{session.Locked, session.Unlocked},
+			expectState:    models.TaskStateRunning,
+			expectDuration: 61 * time.Minute,
+		},
+		{
+			name:           "Pause on lock",
+			policy:         AwayPause,
+			events:         []session.Kind{session.Locked, session.Unlocked},
+			expectState:    models.TaskStateRunning,
+			expectDuration: time.Hour,
+		},
+		{
+			name:           "Pause while still locked after resume",
+			policy:         AwayPause,
+			events:         []session.Kind{session.Locked, session.Sleeping, session.Woke},
+			expectState:    models.TaskStatePaused,
+			expectDuration: time.Hour,
+		},
+		{
+			name:           "Pause through a suspend",
+			policy:         AwayPause,
+			events:         []session.Kind{session.Locked, session.Sleeping, session.Woke, session.Unlocked},
+			expectState:    models.TaskStateRunning,
+			expectDuration: time.Hour,
+		},
+		{
+			name:           "Ask on resume",
+			policy:         AwayAsk,
+			events:         []session.Kind{session.Sleeping, session.Woke},
+			expectState:    models.TaskStateRunning,
+			expectDuration: 61 * time.Minute,
+			expectAsk:      true,
+		},
+		{
+			name:           "Repeated locks",
+			policy:         AwayAsk,
+			events:         []session.Kind{session.Locked, session.Locked, session.Unlocked},
+			expectState:    models.TaskStateRunning,
+			expectDuration: 62 * time.Minute,
+			expectAsk:      true,
+		},
+		{
+			name:           "Resume without suspend",
+			policy:         AwayPause,
+			events:         []session.Kind{session.Woke, session.Unlocked},
+			expectState:    models.TaskStateRunning,
+			expectDuration: 61 * time.Minute,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			timer, _, clock := newTestTimer(t, start)
+			if _, err := timer.Start("Write code", "", nil); err != nil {
+				t.Fatal(err)
+			}
+			src := &session.Fake{}
+			var asked []time.Time
+			guard := NewAwayGuard(timer, func() string { return tt.policy })
+			if err := guard.Watch(src, func(since time.Time) { asked = append(asked, since) }); err != nil {
+				t.Fatal(err)
+			}
+
+			clock.Advance(time.Hour)
+			for i, kind := range tt.events {
+				if i > 0 {
+					clock.Advance(time.Minute)
+				}
+				// Events may be handled late, e.g. on resume
+				src.Send(kind, lock.Add(time.Duration(i)*time.Minute))
+			}
+
+			if state := timer.State(); state != tt.expectState {
+				t.Errorf("expected state %d, got %d", tt.expectState, state)
+			}
+			if elapsed := timer.Elapsed(); elapsed != tt.expectDuration {
+				t.Errorf("expected %v tracked, got %v", tt.expectDuration, elapsed)
+			}
+			if tt.expectAsk != (len(asked) == 1) || len(asked) > 1 || (tt.expectAsk && !asked[0].Equal(lock)) {
+				t.Errorf("asked %v", asked)
+			}
+		})
+	}
+}
+
+func TestAwayGuardChangedTask(t *testing.T) {
+	start := time.Date(2026, 3, 10, 9, 0, 0, 0, time.UTC)
+	timer, _, clock := newTestTimer(t, start)
+	if _, err := timer.Start("Write code", "", nil); err != nil {
+		t.Fatal(err)
+	}
+	guard := NewAwayGuard(timer, func() string { return AwayAsk })
+
+	clock.Advance(time.Hour)
+	if _, ok, _ := guard.Handle(session.Event{Kind: session.Locked, At: clock.Now()}); ok {
+		t.Error("asked on lock")
+	}
+	// The task was stopped elsewhere, e.g. by another instance
+	timer.Stop()
+	timer.Start("Review", "", nil)
+	clock.Advance(time.Minute)
+	if _, ok, err := guard.Handle(session.Event{Kind: session.Unlocked, At: clock.Now()}); ok || err != nil {
+		t.Errorf("asked about another task: %v", err)
+	}
+}
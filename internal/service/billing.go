@@ -1,7 +1,11 @@
 package service
 
 import (
+	"maps"
+	"sort"
 	"time"
+
+	"github.com/highercomve/tasktracker/internal/models"
 )
 
 // BillingConfig holds the configuration for billing calculations.
@@ -9,59 +13,299 @@ type BillingConfig struct {
 	HourlyRate float64
 	MaxHours   float64
 	ExtraRate  float64
+	// Currency labels the amounts; empty if none is set.
+	Currency string
+	// TagRates replace HourlyRate for the entries with the tag.
+	TagRates map[string]float64
+	// Rounding turns tracked time into billable time.
+	Rounding RoundingRule
+	// MaxHours is the monthly threshold; ThresholdMode tells the periods it
+	// is split into, and WorkCalendar the days worked for
+	// ThresholdWorkingDay.
+	ThresholdMode string
+	WorkCalendar  WorkCalendar
+}
+
+// Rounding modes.
+const (
+	RoundNone    = ""
+	RoundUp      = "up"
+	RoundDown    = "down"
+	RoundNearest = "nearest"
+)
+
+// What is rounded.
+const (
+	RoundPerEntry = "entry"
+	RoundPerDay   = "day"
+	RoundPerTotal = "total"
+)
+
+// RoundingRule describes how tracked time is billed, such as in 15-minute
+// increments rounded up with a minimum of 30 minutes per entry.
+type RoundingRule struct {
+	Mode      string        // RoundUp, RoundDown or RoundNearest; no rounding if empty
+	Increment time.Duration // rounding step, such as 6 or 15 minutes
+	// Scope is what is rounded: each entry, each day or the total of a
+	// project. Entries are rounded if empty.
+	Scope string
+	// Minimum is the least billed for each entry, day or total with any
+	// time.
+	Minimum time.Duration
+}
+
+// Round rounds d to the increment of the rule and raises it to the minimum.
+func (r RoundingRule) Round(d time.Duration) time.Duration {
+	if d <= 0 {
+		return 0
+	}
+	if r.Increment > 0 {
+		switch r.Mode {
+		case RoundUp:
+			if t := d.Truncate(r.Increment); t < d {
+				d = t + r.Increment
+			}
+		case RoundDown:
+			d = d.Truncate(r.Increment)
+		case RoundNearest:
+			d = d.Round(r.Increment)
+		}
+	}
+	return max(d, r.Minimum)
+}
+
+// Billable returns the billable time of entries, rounding each entry, the
+// total of each day or the whole total as the scope says. Entries are
+// expected to be split by day, as returned by SplitByDay.
+func (r RoundingRule) Billable(entries []models.TimeEntry, now time.Time) time.Duration {
+	var billable time.Duration
+	switch r.Scope {
+	case RoundPerTotal:
+		for _, e := range entries {
+			billable += e.TrackedDuration(now)
+		}
+		return r.Round(billable)
+	case RoundPerDay:
+		var days []string
+		byDay := make(map[string]time.Duration)
+		for _, e := range entries {
+			day := e.StartTime.Format("2006-01-02")
+			if _, ok := byDay[day]; !ok {
+				days = append(days, day)
+			}
+			byDay[day] += e.TrackedDuration(now)
+		}
+		for _, day := range days {
+			billable += r.Round(byDay[day])
+		}
+	default:
+		for _, e := range entries {
+			billable += r.Round(e.TrackedDuration(now))
+		}
+	}
+	return billable
+}
+
+// IsBillable reports whether e is billed: neither the entry nor its project
+// are marked as not billable.
+func IsBillable(e models.TimeEntry, projects []models.Project) bool {
+	if e.NonBillable {
+		return false
+	}
+	p := FindProjectByID(projects, e.ProjectID)
+	return p == nil || !p.NonBillable
+}
+
+// BillableEntries returns the entries that are billed.
+func BillableEntries(entries []models.TimeEntry, projects []models.Project) []models.TimeEntry {
+	var billable []models.TimeEntry
+	for _, e := range entries {
+		if IsBillable(e, projects) {
+			billable = append(billable, e)
+		}
+	}
+	return billable
+}
+
+// BillableTime returns the billable time of entries: that of the billable
+// entries, rounded project by project, as it is billed.
+func BillableTime(entries []models.TimeEntry, projects []models.Project, rule RoundingRule, now time.Time) time.Duration {
+	byProject := make(map[string][]models.TimeEntry)
+	for _, e := range BillableEntries(entries, projects) {
+		byProject[e.ProjectID] = append(byProject[e.ProjectID], e)
+	}
+	var billable time.Duration
+	for _, projectEntries := range byProject {
+		billable += rule.Billable(projectEntries, now)
+	}
+	return billable
+}
+
+// ForProject returns the billing settings of project p: its own rates,
+// threshold and currency where set, those of c otherwise. Unassigned time,
+// with a nil p, is billed with c.
+func (c BillingConfig) ForProject(p *models.Project) BillingConfig {
+	if p == nil {
+		return c
+	}
+	if p.HourlyRate > 0 {
+		c.HourlyRate = p.HourlyRate
+	}
+	if p.MaxHours > 0 {
+		c.MaxHours = p.MaxHours
+	}
+	if p.ExtraRate > 0 {
+		c.ExtraRate = p.ExtraRate
+	}
+	if p.Currency != "" {
+		c.Currency = p.Currency
+	}
+	if len(p.TagRates) > 0 {
+		rates := maps.Clone(c.TagRates)
+		if rates == nil {
+			rates = make(map[string]float64, len(p.TagRates))
+		}
+		maps.Copy(rates, p.TagRates)
+		c.TagRates = rates
+	}
+	return c
+}
+
+// RateFor returns the hourly rate of an entry with tags: the rate of its
+// first tag that has one, HourlyRate otherwise.
+func (c BillingConfig) RateFor(tags []string) float64 {
+	for _, t := range tags {
+		if rate := c.TagRates[t]; rate > 0 {
+			return rate
+		}
+	}
+	return c.HourlyRate
+}
+
+// Amount returns what entries are worth at their standard rates, without
+// overtime.
+func (c BillingConfig) Amount(entries []models.TimeEntry, now time.Time) float64 {
+	var amount float64
+	for _, e := range entries {
+		amount += e.TrackedDuration(now).Hours() * c.RateFor(e.Tags)
+	}
+	return amount
 }
 
 // BillingResult holds the results of billing calculations.
 type BillingResult struct {
-	TotalCost    float64
-	StandardCost float64
-	ExtraCost    float64
-	IsProRated   bool
-	Threshold    float64
+	TotalCost     float64
+	StandardCost  float64
+	ExtraCost     float64
+	IsProRated    bool
+	Threshold     float64
+	Billable      time.Duration
+	StandardHours float64
+	ExtraHours    float64
+	// Buckets break the result down by the periods of the threshold mode,
+	// as returned by CalculatePeriodBilling.
+	Buckets []BucketResult
 }
 
-// CalculateBilling calculates the cost based on duration and report period.
-// periodDays is the number of days in the report period.
-// It assumes a standard billing month of 28 days (4 weeks) for pro-rating as per user request.
+// CalculateBilling bills totalDuration worked over a period of periodDays
+// days, pro-rating MaxHours over 28-day months as ThresholdProRated does.
+// Without a period, MaxHours applies as it is. The configured rounding and
+// threshold mode are not used.
 func CalculateBilling(totalDuration time.Duration, config BillingConfig, periodDays int) BillingResult {
-	if config.HourlyRate <= 0 {
-		return BillingResult{}
+	config.Rounding, config.ThresholdMode = RoundingRule{}, ThresholdProRated
+	var start, end time.Time
+	day := time.Date(2000, 1, 1, 0, 0, 0, 0, time.Local)
+	if periodDays > 0 {
+		start, end = day, day.AddDate(0, 0, periodDays-1)
+	}
+	entry := models.TimeEntry{
+		StartTime: day,
+		EndTime:   day.Add(totalDuration),
+		Duration:  int64(totalDuration / time.Second),
 	}
+	return CalculatePeriodBilling([]models.TimeEntry{entry}, config, start, end, day)
+}
 
-	totalHours := totalDuration.Hours()
-	
-	// Default threshold is the configured monthly max
-	threshold := config.MaxHours
-	isProRated := false
+// ProjectBilling is the money billed for the time of one project.
+type ProjectBilling struct {
+	ProjectID string // empty for unassigned time
+	Currency  string
+	// Duration is the time tracked on billable entries.
+	Duration time.Duration
+	BillingResult
+}
 
-	// If a period is specified, pro-rate the max hours
-	if periodDays > 0 && config.MaxHours > 0 {
-		// Use 28 days as a "month" to match user's 4-week logic
-		threshold = (config.MaxHours / 28.0) * float64(periodDays)
-		isProRated = true
+// CalculateProjectBilling bills the billable time worked on each project
+// from the day of start to the day of end with the project's own settings,
+// as returned by global.ForProject, and CalculatePeriodBilling. Entries are
+// split by day, as returned by SplitByDay. Projects without any rate are
+// left out. Results follow the order of projects, with unassigned time last.
+func CalculateProjectBilling(entries []models.TimeEntry, projects []models.Project, global BillingConfig, start, end, now time.Time) []ProjectBilling {
+	byProject := make(map[string][]models.TimeEntry)
+	for _, e := range BillableEntries(entries, projects) {
+		byProject[e.ProjectID] = append(byProject[e.ProjectID], e)
 	}
-
-	var standardHours, extraHours float64
-	if threshold > 0 && totalHours > threshold {
-		standardHours = threshold
-		extraHours = totalHours - threshold
-	} else {
-		standardHours = totalHours
-		extraHours = 0
+	ids := make([]string, 0, len(byProject))
+	for _, p := range projects {
+		if _, ok := byProject[p.ID]; ok {
+			ids = append(ids, p.ID)
+		}
+	}
+	// Time of unknown projects is billed as unassigned.
+	for id, projectEntries := range byProject {
+		if id != "" && FindProjectByID(projects, id) == nil {
+			byProject[""] = append(byProject[""], projectEntries...)
+		}
+	}
+	if _, ok := byProject[""]; ok {
+		ids = append(ids, "")
 	}
 
-	standardCost := standardHours * config.HourlyRate
-	extraRate := config.ExtraRate
-	if extraRate <= 0 {
-		extraRate = config.HourlyRate // Default to standard rate if not set
+	var result []ProjectBilling
+	for _, id := range ids {
+		config := global.ForProject(FindProjectByID(projects, id))
+		var duration time.Duration
+		for _, e := range byProject[id] {
+			duration += e.TrackedDuration(now)
+		}
+		billing := CalculatePeriodBilling(byProject[id], config, start, end, now)
+		if len(billing.Buckets) == 0 {
+			continue
+		}
+		result = append(result, ProjectBilling{
+			ProjectID:     id,
+			Currency:      config.Currency,
+			Duration:      duration,
+			BillingResult: billing,
+		})
 	}
-	extraCost := extraHours * extraRate
+	return result
+}
+
+// CurrencyTotal sums the billing of the projects charged in one currency.
+type CurrencyTotal struct {
+	Currency     string
+	TotalCost    float64
+	StandardCost float64
+	ExtraCost    float64
+}
 
-	return BillingResult{
-		TotalCost:    standardCost + extraCost,
-		StandardCost: standardCost,
-		ExtraCost:    extraCost,
-		IsProRated:   isProRated,
-		Threshold:    threshold,
+// CurrencyTotals returns the subtotals of costs per currency, sorted by
+// currency.
+func CurrencyTotals(costs []ProjectBilling) []CurrencyTotal {
+	var totals []CurrencyTotal
+	for _, c := range costs {
+		i := 0
+		for i < len(totals) && totals[i].Currency != c.Currency {
+			i++
+		}
+		if i == len(totals) {
+			totals = append(totals, CurrencyTotal{Currency: c.Currency})
+		}
+		totals[i].TotalCost += c.TotalCost
+		totals[i].StandardCost += c.StandardCost
+		totals[i].ExtraCost += c.ExtraCost
 	}
+	sort.Slice(totals, func(i, j int) bool { return totals[i].Currency < totals[j].Currency })
+	return totals
 }
@@ -3,6 +3,8 @@ package service
 import (
 	"testing"
 	"time"
+
+	"github.com/highercomve/tasktracker/internal/models"
 )
 
 func TestCalculateBilling(t *testing.T) {
@@ -84,13 +86,13 @@ func TestCalculateBilling(t *testing.T) {
 				currentConfig.MaxHours = 0
 			}
 			res := CalculateBilling(tt.totalDuration, currentConfig, tt.periodDays)
-			if (res.TotalCost - tt.expectTotal) > 0.0001 || (res.TotalCost - tt.expectTotal) < -0.0001 {
+			if (res.TotalCost-tt.expectTotal) > 0.0001 || (res.TotalCost-tt.expectTotal) < -0.0001 {
 				t.Errorf("TotalCost: expected %v, got %v", tt.expectTotal, res.TotalCost)
 			}
-			if (res.StandardCost - tt.expectStandard) > 0.0001 || (res.StandardCost - tt.expectStandard) < -0.0001 {
+			if (res.StandardCost-tt.expectStandard) > 0.0001 || (res.StandardCost-tt.expectStandard) < -0.0001 {
 				t.Errorf("StandardCost: expected %v, got %v", tt.expectStandard, res.StandardCost)
 			}
-			if (res.ExtraCost - tt.expectExtra) > 0.0001 || (res.ExtraCost - tt.expectExtra) < -0.0001 {
+			if (res.ExtraCost-tt.expectExtra) > 0.0001 || (res.ExtraCost-tt.expectExtra) < -0.0001 {
 				t.Errorf("ExtraCost: expected %v, got %v", tt.expectExtra, res.ExtraCost)
 			}
 			if res.IsProRated != tt.expectProRated {
@@ -99,3 +101,210 @@ func TestCalculateBilling(t *testing.T) {
 		})
 	}
 }
+
+func TestBillingForProject(t *testing.T) {
+	global := BillingConfig{HourlyRate: 30, MaxHours: 48, ExtraRate: 50, Currency: "EUR", TagRates: map[string]float64{"support": 20}}
+	project := &models.Project{HourlyRate: 90, Currency: "USD", TagRates: map[string]float64{"design": 120}}
+
+	got := global.ForProject(project)
+	if got.HourlyRate != 90 || got.MaxHours != 48 || got.ExtraRate != 50 || got.Currency != "USD" {
+		t.Errorf("ForProject: %+v", got)
+	}
+	if r := got.RateFor([]string{"other", "design"}); r != 120 {
+		t.Errorf("design rate: %v", r)
+	}
+	if r := got.RateFor([]string{"support"}); r != 20 {
+		t.Errorf("global tag rate: %v", r)
+	}
+	if r := got.RateFor(nil); r != 90 {
+		t.Errorf("project rate: %v", r)
+	}
+	if len(global.TagRates) != 1 {
+		t.Errorf("global tag rates modified: %v", global.TagRates)
+	}
+	if got := global.ForProject(nil); got.HourlyRate != 30 || got.Currency != "EUR" {
+		t.Errorf("unassigned: %+v", got)
+	}
+}
+
+func TestCalculateProjectBilling(t *testing.T) {
+	projects := []models.Project{
+		{ID: "a", Name: "A", HourlyRate: 100, Currency: "USD", TagRates: map[string]float64{"design": 200}},
+		{ID: "b", Name: "B"},
+		{ID: "c", Name: "C", MaxHours: 28, ExtraRate: 60},
+	}
+	global := BillingConfig{HourlyRate: 40, Currency: "EUR"}
+	start := time.Date(2024, 5, 6, 9, 0, 0, 0, time.UTC)
+	entries := []models.TimeEntry{
+		projectEntry("c", start, 3*time.Hour),
+		projectEntry("a", start, time.Hour),
+		projectEntry("a", start, time.Hour),
+		projectEntry("b", start, 2*time.Hour),
+		projectEntry("", start, time.Hour),
+		projectEntry("gone", start, time.Hour),
+	}
+	entries[2].Tags = []string{"design"}
+
+	// A 1-day period gives C a threshold of 1h.
+	costs := CalculateProjectBilling(entries, projects, global, start, start, start.Add(24*time.Hour))
+	if len(costs) != 4 {
+		t.Fatalf("expected 4 projects, got %+v", costs)
+	}
+	want := []struct {
+		id       string
+		currency string
+		total    float64
+		extra    float64
+	}{
+		{"a", "USD", 300, 0},
+		{"b", "EUR", 80, 0},
+		{"c", "EUR", 40 + 2*60, 120},
+		{"", "EUR", 80, 0},
+	}
+	for i, w := range want {
+		c := costs[i]
+		if c.ProjectID != w.id || c.Currency != w.currency || c.TotalCost != w.total || c.ExtraCost != w.extra {
+			t.Errorf("cost %d: expected %+v, got %+v", i, w, c)
+		}
+	}
+
+	totals := CurrencyTotals(costs)
+	if len(totals) != 2 || totals[0].Currency != "EUR" || totals[0].TotalCost != 320 || totals[0].ExtraCost != 120 ||
+		totals[1].Currency != "USD" || totals[1].TotalCost != 300 {
+		t.Errorf("currency totals: %+v", totals)
+	}
+
+	if costs := CalculateProjectBilling(entries, projects, BillingConfig{}, start, start, start.Add(24*time.Hour)); len(costs) != 1 || costs[0].ProjectID != "a" {
+		t.Errorf("without a global rate only A is billed: %+v", costs)
+	}
+}
+
+func TestRoundingRuleRound(t *testing.T) {
+	tests := []struct {
+		name     string
+		rule     RoundingRule
+		duration time.Duration
+		expected time.Duration
+	}{
+		{"No rounding", RoundingRule{}, 7 * time.Minute, 7 * time.Minute},
+		{"Mode without increment", RoundingRule{Mode: RoundUp}, 7 * time.Minute, 7 * time.Minute},
+		{"Up to 15 minutes", RoundingRule{Mode: RoundUp, Increment: 15 * time.Minute}, 16 * time.Minute, 30 * time.Minute},
+		{"Up on an increment", RoundingRule{Mode: RoundUp, Increment: 15 * time.Minute}, 30 * time.Minute, 30 * time.Minute},
+		{"Up a second", RoundingRule{Mode: RoundUp, Increment: 6 * time.Minute}, 6*time.Minute + time.Second, 12 * time.Minute},
+		{"Down to 15 minutes", RoundingRule{Mode: RoundDown, Increment: 15 * time.Minute}, 29 * time.Minute, 15 * time.Minute},
+		{"Down to nothing", RoundingRule{Mode: RoundDown, Increment: 15 * time.Minute}, 14 * time.Minute, 0},
+		{"Nearest below half", RoundingRule{Mode: RoundNearest, Increment: 6 * time.Minute}, 8 * time.Minute, 6 * time.Minute},
+		{"Nearest at half", RoundingRule{Mode: RoundNearest, Increment: 6 * time.Minute}, 9 * time.Minute, 12 * time.Minute},
+		{"Minimum", RoundingRule{Minimum: 30 * time.Minute}, 10 * time.Minute, 30 * time.Minute},
+		{"Minimum after rounding down", RoundingRule{Mode: RoundDown, Increment: 15 * time.Minute, Minimum: 15 * time.Minute}, 5 * time.Minute, 15 * time.Minute},
+		{"Over the minimum", RoundingRule{Mode: RoundUp, Increment: 15 * time.Minute, Minimum: 30 * time.Minute}, 40 * time.Minute, 45 * time.Minute},
+		{"No time, no minimum", RoundingRule{Minimum: 30 * time.Minute}, 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.rule.Round(tt.duration); got != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestRoundingRuleBillable(t *testing.T) {
+	day := time.Date(2024, 5, 6, 9, 0, 0, 0, time.UTC)
+	// 10 + 10 minutes on the first day, 20 on the second.
+	entries := []models.TimeEntry{
+		projectEntry("", day, 10*time.Minute),
+		projectEntry("", day.Add(time.Hour), 10*time.Minute),
+		projectEntry("", day.AddDate(0, 0, 1), 20*time.Minute),
+	}
+	up := RoundingRule{Mode: RoundUp, Increment: 15 * time.Minute}
+
+	tests := []struct {
+		name     string
+		scope    string
+		minimum  time.Duration
+		expected time.Duration
+	}{
+		{"Per entry", RoundPerEntry, 0, 15*time.Minute + 15*time.Minute + 30*time.Minute},
+		{"Default scope is per entry", "", 0, 60 * time.Minute},
+		{"Per day", RoundPerDay, 0, 30*time.Minute + 30*time.Minute},
+		{"Per total", RoundPerTotal, 0, 45 * time.Minute},
+		{"Per entry with minimum", RoundPerEntry, 20 * time.Minute, 20*time.Minute + 20*time.Minute + 30*time.Minute},
+		{"Per total with minimum", RoundPerTotal, time.Hour, time.Hour},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule := up
+			rule.Scope, rule.Minimum = tt.scope, tt.minimum
+			if got := rule.Billable(entries, day.AddDate(0, 0, 2)); got != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestBillableTime(t *testing.T) {
+	projects := []models.Project{{ID: "client"}, {ID: "internal", NonBillable: true}}
+	start := time.Date(2024, 5, 6, 9, 0, 0, 0, time.UTC)
+	entries := []models.TimeEntry{
+		projectEntry("client", start, 50*time.Minute),
+		projectEntry("client", start, 30*time.Minute),
+		projectEntry("internal", start, time.Hour),
+		projectEntry("", start, 5*time.Minute),
+	}
+	entries[1].NonBillable = true
+
+	tests := []struct {
+		name     string
+		rule     RoundingRule
+		expected time.Duration
+	}{
+		{"Raw", RoundingRule{}, 55 * time.Minute},
+		{"Total of each project", RoundingRule{Mode: RoundUp, Increment: time.Hour, Scope: RoundPerTotal}, 2 * time.Hour},
+		{"Minimum per entry", RoundingRule{Minimum: 15 * time.Minute}, 65 * time.Minute},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := BillableTime(entries, projects, tt.rule, start.Add(time.Hour)); got != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestCalculateProjectBillingRounding(t *testing.T) {
+	projects := []models.Project{{ID: "a", Name: "A"}, {ID: "b", Name: "B", NonBillable: true}}
+	start := time.Date(2024, 5, 6, 9, 0, 0, 0, time.UTC)
+	entries := []models.TimeEntry{projectEntry("a", start, 20*time.Minute), projectEntry("a", start, 50*time.Minute), projectEntry("b", start, time.Hour)}
+
+	tests := []struct {
+		name         string
+		rule         RoundingRule
+		expectBilled time.Duration
+		expectTotal  float64
+	}{
+		{"Raw time", RoundingRule{}, 70 * time.Minute, 70},
+		{"Entries up to 30 minutes", RoundingRule{Mode: RoundUp, Increment: 30 * time.Minute}, 90 * time.Minute, 90},
+		{"Total down to an hour", RoundingRule{Mode: RoundDown, Increment: time.Hour, Scope: RoundPerTotal}, time.Hour, 60},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := BillingConfig{HourlyRate: 60, Rounding: tt.rule}
+			costs := CalculateProjectBilling(entries, projects, config, time.Time{}, time.Time{}, start.Add(2*time.Hour))
+			if len(costs) != 1 {
+				t.Fatalf("expected only A to be billed, got %+v", costs)
+			}
+			c := costs[0]
+			if c.Duration != 70*time.Minute || c.Billable != tt.expectBilled {
+				t.Errorf("durations: %v raw, %v billable", c.Duration, c.Billable)
+			}
+			if diff := c.TotalCost - tt.expectTotal; diff > 0.0001 || diff < -0.0001 {
+				t.Errorf("TotalCost: expected %v, got %v", tt.expectTotal, c.TotalCost)
+			}
+		})
+	}
+}
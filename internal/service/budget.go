@@ -0,0 +1,164 @@
+package service
+
+import (
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/highercomve/tasktracker/internal/models"
+)
+
+// BudgetVelocityDays is the number of recent days the burn rate of a budget
+// is measured over.
+const BudgetVelocityDays = 14
+
+// Budget alert levels, by the share of the budget used.
+const (
+	BudgetAlertNone = iota
+	BudgetAlertWarning
+	BudgetAlertExceeded
+)
+
+// BudgetWarningFraction is the share of a budget that raises a warning.
+const BudgetWarningFraction = 0.8
+
+// BudgetStatus is the use of the budget of a project.
+type BudgetStatus struct {
+	// Type is models.BudgetHours or models.BudgetMoney, the unit of Budget,
+	// Used, Remaining and Velocity.
+	Type      string
+	Budget    float64
+	Used      float64
+	Remaining float64 // negative once exceeded
+	// Start and End bound the month of a monthly budget, End being the
+	// first day of the next month. Both are zero for other budgets.
+	Start, End time.Time
+	// Velocity is the budget used per day over the last BudgetVelocityDays.
+	Velocity float64
+	// BurnOut is the projected day the budget runs out at Velocity. It is
+	// zero when nothing was used recently, when the budget is already spent
+	// or when a monthly budget lasts the month.
+	BurnOut time.Time
+}
+
+// Fraction returns the share of the budget used.
+func (s BudgetStatus) Fraction() float64 {
+	if s.Budget <= 0 {
+		return 0
+	}
+	return s.Used / s.Budget
+}
+
+// AlertLevel returns BudgetAlertExceeded once the budget is spent,
+// BudgetAlertWarning from BudgetWarningFraction on and BudgetAlertNone
+// otherwise.
+func (s BudgetStatus) AlertLevel() int {
+	switch f := s.Fraction(); {
+	case s.Budget > 0 && f >= 1:
+		return BudgetAlertExceeded
+	case f >= BudgetWarningFraction:
+		return BudgetAlertWarning
+	}
+	return BudgetAlertNone
+}
+
+// ProjectBudget returns the status of the budget of p at now, or false if
+// it has none. entries are those of the project; running ones count up to
+// now. Hours budgets count all the time tracked, money budgets what the
+// billable time is billed at the project's settings, as returned by
+// config.ForProject.
+func ProjectBudget(p models.Project, entries []models.TimeEntry, config BillingConfig, now time.Time) (BudgetStatus, bool) {
+	if p.Budget <= 0 {
+		return BudgetStatus{}, false
+	}
+	status := BudgetStatus{Type: p.BudgetType, Budget: p.Budget}
+	if status.Type != models.BudgetMoney {
+		status.Type = models.BudgetHours
+	}
+	from, to := BudgetPeriod(p, now)
+	if p.BudgetMonthly {
+		status.Start, status.End = from, to.AddDate(0, 0, 1)
+	}
+	config = config.ForProject(&p)
+	status.Used = budgetUse(status.Type, entries, p, config, from, to, now)
+	status.Remaining = status.Budget - status.Used
+
+	recent := startOfDay(now).AddDate(0, 0, 1-BudgetVelocityDays)
+	status.Velocity = budgetUse(status.Type, entries, p, config, recent, now, now) / BudgetVelocityDays
+	if status.Velocity > 0 && status.Remaining > 0 {
+		days := status.Remaining / status.Velocity
+		status.BurnOut = now.Add(time.Duration(days * 24 * float64(time.Hour)))
+		if p.BudgetMonthly && !status.BurnOut.Before(status.End) {
+			status.BurnOut = time.Time{}
+		}
+	}
+	return status, true
+}
+
+// BudgetPeriod returns the first and last day of the month of now for a
+// monthly budget of p: the days its use is counted over. Both are zero for
+// other budgets, which count all the time of the project.
+func BudgetPeriod(p models.Project, now time.Time) (from, to time.Time) {
+	if !p.BudgetMonthly {
+		return time.Time{}, time.Time{}
+	}
+	from = time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+	return from, from.AddDate(0, 1, -1)
+}
+
+// budgetUse returns the hours or money used by the entries of p worked from
+// the day of from to the day of to.
+func budgetUse(budgetType string, entries []models.TimeEntry, p models.Project, config BillingConfig, from, to, now time.Time) float64 {
+	days := SplitByDay(entries, from, to, now)
+	if budgetType == models.BudgetMoney {
+		return CalculatePeriodBilling(BillableEntries(days, []models.Project{p}), config, from, to, now).TotalCost
+	}
+	var used time.Duration
+	for _, e := range days {
+		used += e.TrackedDuration(now)
+	}
+	return used.Hours()
+}
+
+// GetProjectsWithBudgets returns the stats of GetProjectsWithStats with the
+// budget of the projects that have one.
+func GetProjectsWithBudgets(projects []models.Project, entries []models.TimeEntry, config BillingConfig, now time.Time) []ProjectStats {
+	stats := GetProjectsWithStats(projects, entries)
+	for i := range stats {
+		p := FindProjectByID(projects, stats[i].ProjectID)
+		projectEntries := slices.DeleteFunc(slices.Clone(entries), func(e models.TimeEntry) bool { return e.ProjectID != p.ID })
+		if status, ok := ProjectBudget(*p, projectEntries, config, now); ok {
+			stats[i].Budget = &status
+		}
+	}
+	return stats
+}
+
+// TaskEstimate compares the time tracked on a task with its estimate.
+type TaskEstimate struct {
+	Description string
+	Estimate    time.Duration
+	Tracked     time.Duration
+}
+
+// Over reports whether more time than estimated was tracked.
+func (t TaskEstimate) Over() bool {
+	return t.Tracked > t.Estimate
+}
+
+// TaskEstimates returns the tasks of p with an estimate, sorted by
+// description, with the time tracked on them in entries.
+func TaskEstimates(p models.Project, entries []models.TimeEntry, now time.Time) []TaskEstimate {
+	var tasks []TaskEstimate
+	for desc, hours := range p.TaskEstimates {
+		task := TaskEstimate{Description: desc, Estimate: time.Duration(hours * float64(time.Hour))}
+		for _, e := range entries {
+			if e.ProjectID == p.ID && e.Description == desc {
+				task.Tracked += e.TrackedDuration(now)
+			}
+		}
+		tasks = append(tasks, task)
+	}
+	sort.Slice(tasks, func(i, j int) bool { return tasks[i].Description < tasks[j].Description })
+	return tasks
+}
@@ -0,0 +1,110 @@
+package service
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/highercomve/tasktracker/internal/models"
+)
+
+func TestProjectBudget(t *testing.T) {
+	now := time.Date(2024, 5, 20, 12, 0, 0, 0, time.Local)
+	// 14h in the last two weeks, 6h before, 2h of it last month.
+	entries := []models.TimeEntry{
+		projectEntry("p", date(2024, 4, 29), 2*time.Hour),
+		projectEntry("p", date(2024, 5, 2), 4*time.Hour),
+		projectEntry("p", date(2024, 5, 10), 7*time.Hour),
+		projectEntry("p", date(2024, 5, 17), 7*time.Hour),
+	}
+
+	if _, ok := ProjectBudget(models.Project{ID: "p"}, entries, BillingConfig{}, now); ok {
+		t.Error("project without a budget has one")
+	}
+
+	tests := []struct {
+		name          string
+		project       models.Project
+		config        BillingConfig
+		expectUsed    float64
+		expectBurnOut time.Time
+		expectLevel   int
+	}{
+		{
+			name:          "Hours",
+			project:       models.Project{ID: "p", Budget: 25},
+			expectUsed:    20,
+			expectBurnOut: now.Add(5 * 24 * time.Hour),
+			expectLevel:   BudgetAlertWarning,
+		},
+		{
+			name:        "Monthly hours",
+			project:     models.Project{ID: "p", Budget: 30, BudgetMonthly: true},
+			expectUsed:  18,
+			expectLevel: BudgetAlertNone,
+			// 12h left at 1h a day lasts past the end of the month.
+		},
+		{
+			name:        "Money",
+			project:     models.Project{ID: "p", Budget: 1000, BudgetType: models.BudgetMoney, HourlyRate: 50},
+			expectUsed:  1000,
+			expectLevel: BudgetAlertExceeded,
+		},
+		{
+			name:          "Monthly money at the global rate",
+			project:       models.Project{ID: "p", Budget: 1000, BudgetType: models.BudgetMoney, BudgetMonthly: true},
+			config:        BillingConfig{HourlyRate: 40},
+			expectUsed:    720,
+			expectBurnOut: now.Add(7 * 24 * time.Hour),
+			expectLevel:   BudgetAlertNone,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, ok := ProjectBudget(tt.project, entries, tt.config, now)
+			if !ok {
+				t.Fatal("no budget")
+			}
+			if math.Abs(status.Used-tt.expectUsed) > 0.0001 || math.Abs(status.Remaining-(tt.project.Budget-tt.expectUsed)) > 0.0001 {
+				t.Errorf("used %v, remaining %v", status.Used, status.Remaining)
+			}
+			if !status.BurnOut.Equal(tt.expectBurnOut) {
+				t.Errorf("burn-out: expected %v, got %v", tt.expectBurnOut, status.BurnOut)
+			}
+			if level := status.AlertLevel(); level != tt.expectLevel {
+				t.Errorf("alert level: expected %d, got %d", tt.expectLevel, level)
+			}
+			if tt.project.BudgetMonthly && (!status.Start.Equal(date(2024, 5, 1)) || !status.End.Equal(date(2024, 6, 1))) {
+				t.Errorf("month: %v - %v", status.Start, status.End)
+			}
+		})
+	}
+}
+
+func TestGetProjectsWithBudgets(t *testing.T) {
+	projects := []models.Project{{ID: "a", Name: "A", Budget: 10}, {ID: "b", Name: "B"}}
+	entries := []models.TimeEntry{hoursOn(date(2024, 5, 2), 4), hoursOn(date(2024, 5, 3), 3)}
+	entries[0].ProjectID, entries[1].ProjectID = "a", "b"
+
+	stats := GetProjectsWithBudgets(projects, entries, BillingConfig{}, date(2024, 5, 4))
+	if len(stats) != 2 || stats[0].Budget == nil || stats[0].Budget.Used != 4 || stats[1].Budget != nil {
+		t.Errorf("stats: %+v", stats)
+	}
+}
+
+func TestTaskEstimates(t *testing.T) {
+	p := models.Project{ID: "p", TaskEstimates: map[string]float64{"Design": 2, "Build": 5}}
+	entries := []models.TimeEntry{hoursOn(date(2024, 5, 2), 1.5), hoursOn(date(2024, 5, 3), 1), hoursOn(date(2024, 5, 3), 3)}
+	entries[0].ProjectID, entries[0].Description = "p", "Design"
+	entries[1].ProjectID, entries[1].Description = "p", "Design"
+	entries[2].ProjectID, entries[2].Description = "other", "Build"
+
+	tasks := TaskEstimates(p, entries, date(2024, 5, 4))
+	if len(tasks) != 2 || tasks[0].Description != "Build" || tasks[0].Tracked != 0 || tasks[0].Over() {
+		t.Errorf("build: %+v", tasks)
+	}
+	if tasks[1].Tracked != 150*time.Minute || !tasks[1].Over() {
+		t.Errorf("design: %+v", tasks[1])
+	}
+}
@@ -0,0 +1,429 @@
+package service
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/highercomve/tasktracker/internal/models"
+)
+
+// CSV columns. Each names a field of an entry, or of its project.
+const (
+	CSVColumnGroup       = "group" // report group; only written when grouping
+	CSVColumnDate        = "date"
+	CSVColumnStart       = "start"
+	CSVColumnEnd         = "end"
+	CSVColumnDuration    = "duration"
+	CSVColumnDescription = "description"
+	CSVColumnProject     = "project"
+	CSVColumnClient      = "client"
+	CSVColumnTags        = "tags"
+)
+
+// CSVColumns lists the columns that can be exported and imported, in their
+// default order.
+var CSVColumns = []string{
+	CSVColumnDate,
+	CSVColumnStart,
+	CSVColumnEnd,
+	CSVColumnDuration,
+	CSVColumnDescription,
+	CSVColumnProject,
+	CSVColumnClient,
+	CSVColumnTags,
+}
+
+// Duration formats of the duration column.
+const (
+	DurationClock   = "clock"   // 1:30:00
+	DurationDecimal = "decimal" // 1.50 hours
+	DurationMinutes = "minutes" // 90
+)
+
+const (
+	csvDateLayout = "2006-01-02"
+	csvTimeLayout = "15:04:05"
+)
+
+// CSVOptions controls the layout of exported and imported CSV files.
+type CSVOptions struct {
+	Columns        []string // export only; defaults to CSVColumns
+	Delimiter      rune     // defaults to ','
+	DurationFormat string   // defaults to DurationClock
+}
+
+func (o CSVOptions) delimiter() rune {
+	if o.Delimiter == 0 {
+		return ','
+	}
+	return o.Delimiter
+}
+
+// ExportCSV writes entries to w, one row per entry after a header row. With
+// groupBy set, a group column comes first and rows are ordered by group, as
+// in the reports.
+func ExportCSV(w io.Writer, entries []models.TimeEntry, projects []models.Project, groupBy string, opts CSVOptions) error {
+	columns := opts.Columns
+	if len(columns) == 0 {
+		columns = CSVColumns
+	}
+	grouped := groupBy != "" && groupBy != GroupByNone
+	if grouped {
+		columns = append([]string{CSVColumnGroup}, columns...)
+	}
+
+	rows := make([]models.TimeEntry, len(entries))
+	copy(rows, entries)
+	groupOf := func(e models.TimeEntry) (key, title string) {
+		if groupBy == GroupByProject {
+			if p := FindProjectByID(projects, e.ProjectID); p != nil {
+				return p.Name, p.Name
+			}
+			return "", "Unassigned"
+		}
+		return GetGroupKey(e.StartTime, groupBy), GetGroupTitle(e.StartTime, groupBy)
+	}
+	if grouped {
+		sort.SliceStable(rows, func(i, j int) bool {
+			ki, _ := groupOf(rows[i])
+			kj, _ := groupOf(rows[j])
+			return ki < kj
+		})
+	}
+
+	cw := csv.NewWriter(w)
+	cw.Comma = opts.delimiter()
+	if err := cw.Write(columns); err != nil {
+		return err
+	}
+	for _, e := range rows {
+		project := FindProjectByID(projects, e.ProjectID)
+		record := make([]string, len(columns))
+		for i, col := range columns {
+			switch col {
+			case CSVColumnGroup:
+				_, record[i] = groupOf(e)
+			case CSVColumnDate:
+				record[i] = e.StartTime.Format(csvDateLayout)
+			case CSVColumnStart:
+				record[i] = e.StartTime.Format(csvTimeLayout)
+			case CSVColumnEnd:
+				if !e.EndTime.IsZero() {
+					record[i] = e.EndTime.Format(csvTimeLayout)
+				}
+			case CSVColumnDuration:
+				record[i] = FormatCSVDuration(e.TrackedDuration(time.Now()), opts.DurationFormat, opts.delimiter())
+			case CSVColumnDescription:
+				record[i] = e.Description
+			case CSVColumnProject:
+				if project != nil {
+					record[i] = project.Name
+				}
+			case CSVColumnClient:
+				if project != nil {
+					record[i] = project.Client
+				}
+			case CSVColumnTags:
+				record[i] = strings.Join(e.Tags, ", ")
+			default:
+				return fmt.Errorf("unknown CSV column %q", col)
+			}
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// FormatCSVDuration formats d in one of the duration formats. Decimal hours
+// use a decimal comma when the delimiter is not a comma, as spreadsheets in
+// such locales expect.
+func FormatCSVDuration(d time.Duration, format string, delimiter rune) string {
+	switch format {
+	case DurationDecimal:
+		s := strconv.FormatFloat(d.Hours(), 'f', 2, 64)
+		if delimiter != ',' {
+			s = strings.Replace(s, ".", ",", 1)
+		}
+		return s
+	case DurationMinutes:
+		return strconv.FormatInt(int64(d/time.Minute), 10)
+	default:
+		secs := int64(d / time.Second)
+		return fmt.Sprintf("%d:%02d:%02d", secs/3600, secs/60%60, secs%60)
+	}
+}
+
+// ParseCSVDuration reads a duration in the given format. Clock durations
+// also accept Go syntax such as "1h30m".
+func ParseCSVDuration(s, format string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	switch format {
+	case DurationDecimal:
+		hours, err := strconv.ParseFloat(strings.Replace(s, ",", ".", 1), 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+		return time.Duration(hours * float64(time.Hour)).Round(time.Second), nil
+	case DurationMinutes:
+		minutes, err := strconv.ParseFloat(strings.Replace(s, ",", ".", 1), 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+		return time.Duration(minutes * float64(time.Minute)).Round(time.Second), nil
+	}
+	if d, err := time.ParseDuration(s); err == nil {
+		return d, nil
+	}
+	parts := strings.Split(s, ":")
+	if len(parts) < 2 || len(parts) > 3 {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+	var total time.Duration
+	units := []time.Duration{time.Hour, time.Minute, time.Second}
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+		total += time.Duration(n) * units[i]
+	}
+	return total, nil
+}
+
+// CSVMapping maps CSV columns to the index of the file column holding them.
+// Columns missing from the file are absent.
+type CSVMapping map[string]int
+
+// csvAliases are the header names recognized for each column, lower case.
+var csvAliases = map[string][]string{
+	CSVColumnDate:        {"date", "day", "start date"},
+	CSVColumnStart:       {"start", "start time", "from", "begin"},
+	CSVColumnEnd:         {"end", "end time", "to", "stop", "finish"},
+	CSVColumnDuration:    {"duration", "time", "hours", "length"},
+	CSVColumnDescription: {"description", "task", "title", "name", "notes"},
+	CSVColumnProject:     {"project", "project name"},
+	CSVColumnClient:      {"client", "customer"},
+	CSVColumnTags:        {"tags", "tag", "category", "categories"},
+}
+
+// DetectCSVMapping guesses the mapping from the header row of a file.
+func DetectCSVMapping(header []string) CSVMapping {
+	m := CSVMapping{}
+	for i, h := range header {
+		h = strings.ToLower(strings.TrimSpace(h))
+		for _, col := range CSVColumns {
+			if _, taken := m[col]; taken {
+				continue
+			}
+			for _, alias := range csvAliases[col] {
+				if h == alias {
+					m[col] = i
+				}
+			}
+		}
+	}
+	return m
+}
+
+// SniffCSVDelimiter guesses the delimiter of a CSV file from its first line.
+func SniffCSVDelimiter(data []byte) rune {
+	line, _, _ := bytes.Cut(data, []byte("\n"))
+	best, count := ',', 0
+	for _, d := range []rune{',', ';', '\t'} {
+		if n := bytes.Count(line, []byte(string(d))); n > count {
+			best, count = d, n
+		}
+	}
+	return best
+}
+
+// ReadCSV reads all rows of a CSV file. The first row is the header.
+func ReadCSV(r io.Reader, opts CSVOptions) (header []string, records [][]string, err error) {
+	cr := csv.NewReader(r)
+	cr.Comma = opts.delimiter()
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+	rows, err := cr.ReadAll()
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(rows) == 0 {
+		return nil, nil, errors.New("empty CSV file")
+	}
+	// Drop the byte order mark some spreadsheets write.
+	rows[0][0] = strings.TrimPrefix(rows[0][0], "\ufeff")
+	return rows[0], rows[1:], nil
+}
+
+// CSVRowError is a row that could not be imported. Line counts the header
+// as line 1.
+type CSVRowError struct {
+	Line int
+	Err  error
+}
+
+func (e CSVRowError) Error() string {
+	return fmt.Sprintf("line %d: %v", e.Line, e.Err)
+}
+
+// ParseCSVEntries turns records into stopped entries using mapping. Projects
+// are looked up by name; the ones missing from projects are returned to be
+// created, once each. Rows that cannot be read are reported and skipped.
+func ParseCSVEntries(records [][]string, mapping CSVMapping, projects []models.Project, opts CSVOptions) ([]models.TimeEntry, []models.Project, []CSVRowError) {
+	var entries []models.TimeEntry
+	var created []models.Project
+	var rowErrors []CSVRowError
+
+	projectID := func(name, client string) string {
+		if name == "" {
+			return ""
+		}
+		if p := FindProjectByName(projects, name); p != nil {
+			return p.ID
+		}
+		if p := FindProjectByName(created, name); p != nil {
+			return p.ID
+		}
+		p := CreateProject(name, "", "")
+		p.Client = client
+		created = append(created, p)
+		return p.ID
+	}
+
+	for i, record := range records {
+		field := func(col string) string {
+			idx, ok := mapping[col]
+			if !ok || idx < 0 || idx >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[idx])
+		}
+
+		e, err := parseCSVEntry(field, opts)
+		if err != nil {
+			rowErrors = append(rowErrors, CSVRowError{Line: i + 2, Err: err})
+			continue
+		}
+		e.ProjectID = projectID(field(CSVColumnProject), field(CSVColumnClient))
+		entries = append(entries, e)
+	}
+	return entries, created, rowErrors
+}
+
+func parseCSVEntry(field func(string) string, opts CSVOptions) (models.TimeEntry, error) {
+	date := field(CSVColumnDate)
+	start, err := parseCSVTime(date, field(CSVColumnStart))
+	if err != nil {
+		return models.TimeEntry{}, err
+	}
+
+	var duration time.Duration
+	if s := field(CSVColumnDuration); s != "" {
+		if duration, err = ParseCSVDuration(s, opts.DurationFormat); err != nil {
+			return models.TimeEntry{}, err
+		}
+	}
+	var end time.Time
+	if s := field(CSVColumnEnd); s != "" {
+		if end, err = parseCSVTime(start.Format(csvDateLayout), s); err != nil {
+			return models.TimeEntry{}, err
+		}
+		if end.Before(start) {
+			// A time of day before the start is on the next day.
+			end = end.AddDate(0, 0, 1)
+		}
+		if duration == 0 {
+			duration = end.Sub(start)
+		}
+	} else {
+		end = start.Add(duration)
+	}
+	if duration <= 0 {
+		return models.TimeEntry{}, errors.New("missing end time or duration")
+	}
+
+	var tags []string
+	for _, tag := range strings.FieldsFunc(field(CSVColumnTags), func(r rune) bool { return r == ',' || r == ';' }) {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+
+	return models.TimeEntry{
+		ID:          uuid.New().String(),
+		Description: field(CSVColumnDescription),
+		Tags:        tags,
+		StartTime:   start,
+		EndTime:     end,
+		Duration:    int64(duration / time.Second),
+		State:       models.TaskStateStopped,
+	}, nil
+}
+
+// csvDateTimeLayouts are the layouts accepted for a start or end column
+// holding both date and time.
+var csvDateTimeLayouts = []string{
+	time.RFC3339,
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+}
+
+// parseCSVTime reads a point in local time from a time column, which may
+// hold a full date and time, or a time of day on date.
+func parseCSVTime(date, value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, errors.New("missing start time")
+	}
+	for _, layout := range csvDateTimeLayouts {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	if date == "" {
+		return time.Time{}, fmt.Errorf("invalid time %q", value)
+	}
+	for _, layout := range []string{csvTimeLayout, "15:04"} {
+		if t, err := time.ParseInLocation(csvDateLayout+" "+layout, date+" "+value, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date or time %q %q", date, value)
+}
+
+// DedupEntries drops the incoming entries that repeat one already in
+// existing, or an earlier incoming one: same start second, description and
+// duration. It returns the rest and how many were dropped.
+func DedupEntries(incoming, existing []models.TimeEntry) ([]models.TimeEntry, int) {
+	key := func(e models.TimeEntry) string {
+		return fmt.Sprintf("%d|%s|%d", e.StartTime.Unix(), e.Description, e.Duration)
+	}
+	seen := make(map[string]bool, len(existing)+len(incoming))
+	for _, e := range existing {
+		seen[key(e)] = true
+	}
+	var fresh []models.TimeEntry
+	duplicates := 0
+	for _, e := range incoming {
+		k := key(e)
+		if seen[k] {
+			duplicates++
+			continue
+		}
+		seen[k] = true
+		fresh = append(fresh, e)
+	}
+	return fresh, duplicates
+}
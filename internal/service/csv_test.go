@@ -0,0 +1,111 @@
+package service
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/highercomve/tasktracker/internal/models"
+)
+
+func TestCSVRoundTrip(t *testing.T) {
+	projects := []models.Project{{ID: "p1", Name: "Website", Client: "ACME"}}
+	start := time.Date(2024, 5, 6, 9, 0, 0, 0, time.Local)
+	entries := []models.TimeEntry{
+		{ID: "a", Description: "Design", ProjectID: "p1", Tags: []string{"ux", "draft"}, StartTime: start, EndTime: start.Add(90 * time.Minute), Duration: 5400, State: models.TaskStateStopped},
+		{ID: "b", Description: "Call, with \"quotes\"", StartTime: start.Add(3 * time.Hour), EndTime: start.Add(3*time.Hour + 15*time.Minute), Duration: 900, State: models.TaskStateStopped},
+	}
+
+	for _, format := range []string{DurationClock, DurationDecimal, DurationMinutes} {
+		for _, delim := range []rune{',', ';', '\t'} {
+			opts := CSVOptions{Delimiter: delim, DurationFormat: format}
+			var buf bytes.Buffer
+			if err := ExportCSV(&buf, entries, projects, GroupByNone, opts); err != nil {
+				t.Fatalf("ExportCSV(%s, %q): %v", format, delim, err)
+			}
+
+			header, records, err := ReadCSV(&buf, opts)
+			if err != nil {
+				t.Fatalf("ReadCSV: %v", err)
+			}
+			mapping := DetectCSVMapping(header)
+			if len(mapping) != len(CSVColumns) {
+				t.Fatalf("mapping: %v", mapping)
+			}
+			got, created, rowErrors := ParseCSVEntries(records, mapping, projects, opts)
+			if len(rowErrors) > 0 || len(created) != 0 || len(got) != 2 {
+				t.Fatalf("%s/%q: entries %d, created %v, errors %v", format, delim, len(got), created, rowErrors)
+			}
+			for i, e := range got {
+				want := entries[i]
+				if e.Description != want.Description || e.ProjectID != want.ProjectID || !e.StartTime.Equal(want.StartTime) ||
+					!e.EndTime.Equal(want.EndTime) || e.Duration != want.Duration || strings.Join(e.Tags, "|") != strings.Join(want.Tags, "|") {
+					t.Errorf("%s/%q: row %d: expected %+v, got %+v", format, delim, i, want, e)
+				}
+			}
+		}
+	}
+}
+
+func TestCSVExportColumnsAndGrouping(t *testing.T) {
+	day1 := time.Date(2024, 5, 7, 9, 0, 0, 0, time.Local)
+	day0 := day1.AddDate(0, 0, -1)
+	entries := []models.TimeEntry{
+		{Description: "Later", StartTime: day1, EndTime: day1.Add(time.Hour), Duration: 3600},
+		{Description: "Earlier", StartTime: day0, EndTime: day0.Add(time.Hour), Duration: 3600},
+	}
+	var buf bytes.Buffer
+	opts := CSVOptions{Columns: []string{CSVColumnDescription, CSVColumnDuration}, DurationFormat: DurationDecimal}
+	if err := ExportCSV(&buf, entries, nil, GroupByDay, opts); err != nil {
+		t.Fatalf("ExportCSV: %v", err)
+	}
+	want := "group,description,duration\n" +
+		"\"Monday, 06 May 2024\",Earlier,1.00\n" +
+		"\"Tuesday, 07 May 2024\",Later,1.00\n"
+	if buf.String() != want {
+		t.Errorf("expected\n%s\ngot\n%s", want, buf.String())
+	}
+}
+
+func TestParseCSVEntries(t *testing.T) {
+	header := []string{"Date", "From", "To", "Hours", "Task", "Project", "Client"}
+	records := [][]string{
+		{"2024-05-06", "22:00", "02:00", "", "Night shift", "Ops", "Initech"},
+		{"2024-05-06", "2024-05-06 09:00:00", "", "1:30", "Standup", "Ops", ""},
+		{"2024-05-06", "09:00", "", "", "No end", "", ""},
+		{"bad", "", "", "", "", "", ""},
+	}
+	got, created, rowErrors := ParseCSVEntries(records, DetectCSVMapping(header), nil, CSVOptions{})
+
+	if len(created) != 1 || created[0].Name != "Ops" || created[0].Client != "Initech" {
+		t.Fatalf("created projects: %+v", created)
+	}
+	if len(got) != 2 {
+		t.Fatalf("expected 2 entries, got %d", len(got))
+	}
+	if got[0].Duration != 4*3600 || got[0].EndTime.Day() != 7 || got[0].ProjectID != created[0].ID {
+		t.Errorf("night shift: %+v", got[0])
+	}
+	if got[1].Duration != 5400 || !got[1].EndTime.Equal(got[1].StartTime.Add(90*time.Minute)) || got[1].ProjectID != created[0].ID {
+		t.Errorf("standup: %+v", got[1])
+	}
+	if len(rowErrors) != 2 || rowErrors[0].Line != 4 || rowErrors[1].Line != 5 {
+		t.Errorf("row errors: %v", rowErrors)
+	}
+}
+
+func TestDedupEntries(t *testing.T) {
+	start := time.Date(2024, 5, 6, 9, 0, 0, 0, time.Local)
+	existing := []models.TimeEntry{{ID: "old", Description: "Design", StartTime: start, Duration: 3600}}
+	incoming := []models.TimeEntry{
+		{ID: "1", Description: "Design", StartTime: start, Duration: 3600},               // already stored
+		{ID: "2", Description: "Design", StartTime: start, Duration: 1800},               // different duration
+		{ID: "3", Description: "Review", StartTime: start.Add(time.Hour), Duration: 600}, // new
+		{ID: "4", Description: "Review", StartTime: start.Add(time.Hour), Duration: 600}, // repeated in file
+	}
+	fresh, duplicates := DedupEntries(incoming, existing)
+	if duplicates != 2 || len(fresh) != 2 || fresh[0].ID != "2" || fresh[1].ID != "3" {
+		t.Errorf("expected [2 3] and 2 duplicates, got %v and %d", fresh, duplicates)
+	}
+}
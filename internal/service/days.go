@@ -0,0 +1,87 @@
+package service
+
+import (
+	"time"
+
+	"github.com/highercomve/tasktracker/internal/models"
+)
+
+// SplitByDay cuts entries at midnight, so that each returned piece covers a
+// single calendar day, and drops the parts that fall outside the days from
+// start to end. A zero start or end leaves that side open. Pieces keep the
+// ID of their entry and carry the segments, StartTime, EndTime and Duration
+// of their own day; the piece of a running entry that reaches now stays
+// open, with a zero EndTime. Totals and groupings computed from the pieces
+// attribute time to the day it was worked on.
+func SplitByDay(entries []models.TimeEntry, start, end, now time.Time) []models.TimeEntry {
+	var from, to time.Time
+	if !start.IsZero() {
+		from = startOfDay(start)
+	}
+	if !end.IsZero() {
+		to = startOfDay(end).AddDate(0, 0, 1)
+	}
+
+	var pieces []models.TimeEntry
+	for _, e := range entries {
+		segments := e.Segments
+		if len(segments) == 0 {
+			segments = []models.Segment{{Start: e.StartTime, End: e.EndTime}}
+		}
+
+		byDay := map[string][]models.Segment{}
+		var days []string
+		for _, seg := range segments {
+			for _, part := range splitSegment(seg, now) {
+				if (!from.IsZero() && part.Start.Before(from)) || (!to.IsZero() && !part.Start.Before(to)) {
+					continue
+				}
+				day := part.Start.Format("2006-01-02")
+				if _, ok := byDay[day]; !ok {
+					days = append(days, day)
+				}
+				byDay[day] = append(byDay[day], part)
+			}
+		}
+
+		for _, day := range days {
+			piece := e
+			piece.Segments = byDay[day]
+			piece.StartTime = piece.Segments[0].Start
+			if last := piece.Segments[len(piece.Segments)-1]; last.End.IsZero() {
+				piece.EndTime, piece.Duration = time.Time{}, 0
+			} else {
+				piece.EndTime, piece.Duration = last.End, 0
+				for _, seg := range piece.Segments {
+					piece.Duration += int64(seg.Duration(now) / time.Second)
+				}
+			}
+			pieces = append(pieces, piece)
+		}
+	}
+	return pieces
+}
+
+// splitSegment cuts seg at each midnight it spans. An open segment is
+// counted up to now and the last part stays open.
+func splitSegment(seg models.Segment, now time.Time) []models.Segment {
+	end := seg.End
+	if end.IsZero() {
+		end = now
+	}
+	var parts []models.Segment
+	for cur := seg.Start; ; {
+		next := startOfDay(cur).AddDate(0, 0, 1)
+		if !next.Before(end) {
+			parts = append(parts, models.Segment{Start: cur, End: seg.End})
+			return parts
+		}
+		parts = append(parts, models.Segment{Start: cur, End: next})
+		cur = next
+	}
+}
+
+func startOfDay(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+}
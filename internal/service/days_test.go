@@ -0,0 +1,104 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/highercomve/tasktracker/internal/models"
+)
+
+func TestSplitByDay(t *testing.T) {
+	at := func(day, h int) time.Time { return time.Date(2024, 3, day, h, 0, 0, 0, time.UTC) }
+	stopped := func(id string, segs ...models.Segment) models.TimeEntry {
+		e := models.TimeEntry{ID: id, Tags: []string{"ops"}, State: models.TaskStateStopped, Segments: segs}
+		e.StartTime, e.EndTime = segs[0].Start, segs[len(segs)-1].End
+		for _, s := range segs {
+			e.Duration += int64(s.Duration(time.Time{}).Seconds())
+		}
+		return e
+	}
+	nightShift := stopped("night", models.Segment{Start: at(4, 22), End: at(5, 2)})
+
+	tests := []struct {
+		name       string
+		entries    []models.TimeEntry
+		start, end time.Time
+		now        time.Time
+		want       map[string]time.Duration // day -> tracked time
+	}{
+		{
+			name:    "night shift",
+			entries: []models.TimeEntry{nightShift},
+			want:    map[string]time.Duration{"2024-03-04": 2 * time.Hour, "2024-03-05": 2 * time.Hour},
+		},
+		{
+			name:    "clipped to the range",
+			entries: []models.TimeEntry{nightShift},
+			start:   at(5, 0), end: at(5, 0),
+			want: map[string]time.Duration{"2024-03-05": 2 * time.Hour},
+		},
+		{
+			name: "pause over midnight",
+			entries: []models.TimeEntry{stopped("paused",
+				models.Segment{Start: at(4, 20), End: at(4, 23)},
+				models.Segment{Start: at(5, 8), End: at(5, 9)},
+			)},
+			want: map[string]time.Duration{"2024-03-04": 3 * time.Hour, "2024-03-05": time.Hour},
+		},
+		{
+			name: "several days",
+			entries: []models.TimeEntry{stopped("long",
+				models.Segment{Start: at(4, 12), End: at(6, 6)},
+			)},
+			want: map[string]time.Duration{"2024-03-04": 12 * time.Hour, "2024-03-05": 24 * time.Hour, "2024-03-06": 6 * time.Hour},
+		},
+		{
+			name: "running",
+			entries: []models.TimeEntry{{
+				ID: "run", StartTime: at(4, 23), State: models.TaskStateRunning,
+				Segments: []models.Segment{{Start: at(4, 23)}},
+			}},
+			now:  at(5, 1),
+			want: map[string]time.Duration{"2024-03-04": time.Hour, "2024-03-05": time.Hour},
+		},
+		{
+			name:    "legacy entry without segments",
+			entries: []models.TimeEntry{{ID: "old", StartTime: at(4, 23), EndTime: at(5, 3), Duration: 4 * 3600}},
+			want:    map[string]time.Duration{"2024-03-04": time.Hour, "2024-03-05": 3 * time.Hour},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pieces := SplitByDay(tt.entries, tt.start, tt.end, tt.now)
+			got := map[string]time.Duration{}
+			for _, p := range pieces {
+				day := p.StartTime.Format("2006-01-02")
+				if _, dup := got[day]; dup {
+					t.Errorf("two pieces on %s", day)
+				}
+				got[day] = p.TrackedDuration(tt.now)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("expected days %v, got %v", tt.want, got)
+			}
+			for day, d := range tt.want {
+				if got[day] != d {
+					t.Errorf("%s: expected %v, got %v", day, d, got[day])
+				}
+			}
+		})
+	}
+
+	// Totals follow the pieces, so a range only counts its own hours.
+	pieces := SplitByDay([]models.TimeEntry{nightShift}, at(4, 0), at(4, 0), time.Time{})
+	if got := GetCategoryTotals(pieces)["ops"]; got != 2*time.Hour {
+		t.Errorf("category total: expected 2h, got %v", got)
+	}
+	if got := GetProjectTotals(pieces)["unassigned"]; got != 2*time.Hour {
+		t.Errorf("project total: expected 2h, got %v", got)
+	}
+	if pieces[0].ID != "night" || !pieces[0].EndTime.Equal(at(5, 0)) {
+		t.Errorf("piece: %+v", pieces[0])
+	}
+}
@@ -0,0 +1,125 @@
+package service
+
+import (
+	"time"
+
+	"github.com/highercomve/tasktracker/internal/models"
+)
+
+// Goals are the hours one aims to work.
+type Goals struct {
+	// Daily is the target of each day of the week, indexed by time.Weekday.
+	Daily [7]time.Duration
+	// Weekly is the target of each week. When no day has a target, it is
+	// spread evenly over the working days of Calendar.
+	Weekly time.Duration
+	// Calendar tells the working days and the holidays, which have no
+	// target.
+	Calendar WorkCalendar
+}
+
+// IsZero reports whether no target is set.
+func (g Goals) IsZero() bool {
+	return g.Weekly <= 0 && !g.hasDaily()
+}
+
+func (g Goals) hasDaily() bool {
+	for _, d := range g.Daily {
+		if d > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// DayTarget returns the time to work on day.
+func (g Goals) DayTarget(day time.Time) time.Duration {
+	if g.Calendar.IsHoliday(day) {
+		return 0
+	}
+	if g.hasDaily() {
+		return g.Daily[day.Weekday()]
+	}
+	if g.Weekly <= 0 || !g.Calendar.IsWorkingDay(day) {
+		return 0
+	}
+	days := len(g.Calendar.Weekdays)
+	if days == 0 {
+		days = 5
+	}
+	return g.Weekly / time.Duration(days)
+}
+
+// WeekTarget returns the time to work in the week of seven days from start:
+// Weekly when set, otherwise the sum of the targets of its days.
+func (g Goals) WeekTarget(start time.Time) time.Duration {
+	if g.Weekly > 0 {
+		return g.Weekly
+	}
+	var target time.Duration
+	for i := range 7 {
+		target += g.DayTarget(startOfDay(start).AddDate(0, 0, i))
+	}
+	return target
+}
+
+// DayGoal is the time worked on a day against its target.
+type DayGoal struct {
+	Day    time.Time
+	Target time.Duration
+	Actual time.Duration
+}
+
+// Diff returns the time over the target, negative when under it.
+func (d DayGoal) Diff() time.Duration {
+	return d.Actual - d.Target
+}
+
+// Progress returns the share of the target worked, 1 when there is no
+// target.
+func (d DayGoal) Progress() float64 {
+	if d.Target <= 0 {
+		return 1
+	}
+	return float64(d.Actual) / float64(d.Target)
+}
+
+// DayGoals returns the target and the time tracked of each day from the day
+// of start to the day of end. Entries are split by the days they were
+// worked on; running ones count up to now.
+func DayGoals(entries []models.TimeEntry, goals Goals, start, end, now time.Time) []DayGoal {
+	actual := make(map[string]time.Duration)
+	for _, e := range SplitByDay(entries, start, end, now) {
+		actual[e.StartTime.Format("2006-01-02")] += e.TrackedDuration(now)
+	}
+	var days []DayGoal
+	for day := startOfDay(start); !day.After(end); day = day.AddDate(0, 0, 1) {
+		days = append(days, DayGoal{
+			Day:    day,
+			Target: goals.DayTarget(day),
+			Actual: actual[day.Format("2006-01-02")],
+		})
+	}
+	return days
+}
+
+// GoalTotals returns the sums of the targets and of the time tracked of
+// days.
+func GoalTotals(days []DayGoal) DayGoal {
+	var total DayGoal
+	for _, d := range days {
+		total.Target += d.Target
+		total.Actual += d.Actual
+	}
+	return total
+}
+
+// FlexBalance returns the flex-time balance from the day of from to the day
+// of until: the time worked over the day targets minus the time under them,
+// carried from day to day and week to week.
+func FlexBalance(entries []models.TimeEntry, goals Goals, from, until, now time.Time) time.Duration {
+	if until.Before(from) {
+		return 0
+	}
+	return GoalTotals(DayGoals(entries, goals, from, until, now)).Diff()
+}
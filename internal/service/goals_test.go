@@ -0,0 +1,89 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/highercomve/tasktracker/internal/models"
+)
+
+func TestGoalsDayTarget(t *testing.T) {
+	daily := Goals{Calendar: WorkCalendar{Holidays: []time.Time{date(2024, 5, 1)}}}
+	for d := time.Monday; d <= time.Thursday; d++ {
+		daily.Daily[d] = 8 * time.Hour
+	}
+	daily.Daily[time.Friday] = 6 * time.Hour
+
+	tests := []struct {
+		name   string
+		goals  Goals
+		day    time.Time
+		expect time.Duration
+	}{
+		{"Monday", daily, date(2024, 5, 6), 8 * time.Hour},
+		{"Friday", daily, date(2024, 5, 10), 6 * time.Hour},
+		{"Weekend", daily, date(2024, 5, 11), 0},
+		{"Holiday", daily, date(2024, 5, 1), 0},
+		{"Weekly spread over working days", Goals{Weekly: 40 * time.Hour}, date(2024, 5, 6), 8 * time.Hour},
+		{"Weekly on a weekend", Goals{Weekly: 40 * time.Hour}, date(2024, 5, 5), 0},
+		{"Weekly over a custom week", Goals{Weekly: 30 * time.Hour, Calendar: WorkCalendar{Weekdays: []time.Weekday{time.Monday, time.Tuesday, time.Wednesday}}}, date(2024, 5, 8), 10 * time.Hour},
+		{"No goals", Goals{}, date(2024, 5, 6), 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.goals.DayTarget(tt.day); got != tt.expect {
+				t.Errorf("expected %v, got %v", tt.expect, got)
+			}
+		})
+	}
+
+	if got := daily.WeekTarget(date(2024, 5, 6)); got != 38*time.Hour {
+		t.Errorf("week target: expected 38h, got %v", got)
+	}
+	daily.Weekly = 35 * time.Hour
+	if got := daily.WeekTarget(date(2024, 5, 6)); got != 35*time.Hour {
+		t.Errorf("weekly target: expected 35h, got %v", got)
+	}
+}
+
+func TestDayGoalsAndFlexBalance(t *testing.T) {
+	var goals Goals
+	for d := time.Monday; d <= time.Friday; d++ {
+		goals.Daily[d] = 8 * time.Hour
+	}
+	// Friday night runs past midnight into Saturday.
+	late := models.TimeEntry{StartTime: time.Date(2024, 5, 10, 22, 0, 0, 0, time.Local)}
+	late.EndTime = late.StartTime.Add(4 * time.Hour)
+	late.Duration = int64(4 * time.Hour / time.Second)
+	entries := []models.TimeEntry{
+		hoursOn(date(2024, 5, 6), 9),
+		hoursOn(date(2024, 5, 7), 7.5),
+		hoursOn(date(2024, 5, 8), 8),
+		hoursOn(date(2024, 5, 9), 8),
+		hoursOn(date(2024, 5, 10), 5),
+		late,
+	}
+
+	days := DayGoals(entries, goals, date(2024, 5, 6), date(2024, 5, 12), date(2024, 6, 1))
+	if len(days) != 7 {
+		t.Fatalf("expected 7 days, got %d", len(days))
+	}
+	diffs := []time.Duration{time.Hour, -30 * time.Minute, 0, 0, -time.Hour, 2 * time.Hour, 0}
+	for i, want := range diffs {
+		if got := days[i].Diff(); got != want {
+			t.Errorf("%s: expected %v, got %v", days[i].Day.Format("Mon"), want, got)
+		}
+	}
+	if total := GoalTotals(days); total.Target != 40*time.Hour || total.Actual != 41*time.Hour+30*time.Minute {
+		t.Errorf("totals: %+v", total)
+	}
+
+	// The balance carries into the next week, which starts 2h short.
+	entries = append(entries, hoursOn(date(2024, 5, 13), 6))
+	if got := FlexBalance(entries, goals, date(2024, 5, 6), date(2024, 5, 13), date(2024, 6, 1)); got != -30*time.Minute {
+		t.Errorf("balance: expected -30m, got %v", got)
+	}
+	if got := FlexBalance(entries, goals, date(2024, 5, 13), date(2024, 5, 12), date(2024, 6, 1)); got != 0 {
+		t.Errorf("empty balance: %v", got)
+	}
+}
@@ -143,12 +143,7 @@ func GetCategoryTotals(entries []models.TimeEntry) map[string]time.Duration {
 			category = "Untagged"
 		}
 
-		var dur time.Duration
-		if e.EndTime.IsZero() {
-			dur = time.Since(e.StartTime)
-		} else {
-			dur = time.Duration(e.Duration) * time.Second
-		}
+		dur := e.TrackedDuration(time.Now())
 
 		totals[category] += dur
 	}
@@ -0,0 +1,108 @@
+package service
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/highercomve/tasktracker/internal/models"
+)
+
+// icsTimeLayout is the layout of UTC DATE-TIME values in iCalendar files.
+const icsTimeLayout = "20060102T150405Z"
+
+// ExportICS writes entries as an iCalendar file with one event per segment
+// of each entry, so tracked time can be laid next to meetings in a calendar
+// without the pauses. The project and tags go in the event description;
+// running entries end at now.
+func ExportICS(w io.Writer, entries []models.TimeEntry, projects []models.Project, now time.Time) error {
+	bw := bufio.NewWriter(w)
+	line := func(s string) {
+		bw.WriteString(foldICSLine(s))
+		bw.WriteString("\r\n")
+	}
+
+	line("BEGIN:VCALENDAR")
+	line("VERSION:2.0")
+	line("PRODID:-//TaskTracker//TaskTracker//EN")
+	line("CALSCALE:GREGORIAN")
+	for _, e := range entries {
+		stamp := e.UpdatedAt
+		if stamp.IsZero() {
+			stamp = now
+		}
+		var details []string
+		project := FindProjectByID(projects, e.ProjectID)
+		if project != nil {
+			details = append(details, "Project: "+project.Name)
+			if project.Client != "" {
+				details = append(details, "Client: "+project.Client)
+			}
+		}
+		if len(e.Tags) > 0 {
+			details = append(details, "Tags: "+strings.Join(e.Tags, ", "))
+		}
+
+		var tags []string
+		for _, tag := range e.Tags {
+			tags = append(tags, escapeICSText(tag))
+		}
+
+		e.NormalizeSegments()
+		segments := e.Segments
+		if len(segments) == 0 {
+			segments = []models.Segment{{Start: e.StartTime, End: e.WorkedUntil(now)}}
+		}
+		for n, seg := range segments {
+			end := seg.End
+			if end.IsZero() {
+				end = now
+			}
+			line("BEGIN:VEVENT")
+			// Numbered, so each segment keeps its UID as the entry grows
+			line(fmt.Sprintf("UID:%s-%d@tasktracker", e.ID, n+1))
+			line("DTSTAMP:" + stamp.UTC().Format(icsTimeLayout))
+			line("DTSTART:" + seg.Start.UTC().Format(icsTimeLayout))
+			line("DTEND:" + end.UTC().Format(icsTimeLayout))
+			line("SUMMARY:" + escapeICSText(e.Description))
+			if len(details) > 0 {
+				line("DESCRIPTION:" + escapeICSText(strings.Join(details, "\n")))
+			}
+			if len(tags) > 0 {
+				line("CATEGORIES:" + strings.Join(tags, ","))
+			}
+			line("TRANSP:TRANSPARENT")
+			line("END:VEVENT")
+		}
+	}
+	line("END:VCALENDAR")
+	return bw.Flush()
+}
+
+// escapeICSText escapes a TEXT value.
+func escapeICSText(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`).Replace(s)
+}
+
+// foldICSLine splits lines longer than 75 octets, without breaking UTF-8
+// sequences, as RFC 5545 asks.
+func foldICSLine(s string) string {
+	const limit = 75
+	if len(s) <= limit {
+		return s
+	}
+	var b strings.Builder
+	width := 0
+	for _, r := range s {
+		n := len(string(r))
+		if width+n > limit {
+			b.WriteString("\r\n ")
+			width = 1
+		}
+		b.WriteRune(r)
+		width += n
+	}
+	return b.String()
+}
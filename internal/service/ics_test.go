@@ -0,0 +1,71 @@
+package service
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/highercomve/tasktracker/internal/models"
+)
+
+func TestExportICS(t *testing.T) {
+	now := time.Date(2024, 5, 6, 12, 0, 0, 0, time.UTC)
+	start := time.Date(2024, 5, 6, 9, 0, 0, 0, time.UTC)
+	projects := []models.Project{{ID: "p1", Name: "Website", Client: "ACME"}}
+	entries := []models.TimeEntry{
+		{ID: "a", Description: "Review; fix, ship", ProjectID: "p1", Tags: []string{"ux", "qa"}, StartTime: start, EndTime: start.Add(time.Hour), Duration: 3600, State: models.TaskStateStopped},
+		{ID: "b", Description: strings.Repeat("long ", 30), StartTime: start.Add(2 * time.Hour), State: models.TaskStateRunning},
+	}
+
+	var buf bytes.Buffer
+	if err := ExportICS(&buf, entries, projects, now); err != nil {
+		t.Fatalf("ExportICS: %v", err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		"BEGIN:VCALENDAR\r\n",
+		"UID:a-1@tasktracker\r\n",
+		"DTSTART:20240506T090000Z\r\nDTEND:20240506T100000Z\r\n",
+		`SUMMARY:Review\; fix\, ship` + "\r\n",
+		`DESCRIPTION:Project: Website\nClient: ACME\nTags: ux\, qa` + "\r\n",
+		"CATEGORIES:ux,qa\r\n",
+		"DTSTART:20240506T110000Z\r\nDTEND:20240506T120000Z\r\n", // running, ends now
+		"END:VCALENDAR\r\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %q in\n%s", want, out)
+		}
+	}
+	for _, l := range strings.Split(out, "\r\n") {
+		if len(l) > 75 {
+			t.Errorf("line not folded: %q", l)
+		}
+	}
+}
+
+func TestExportICSPausedEntry(t *testing.T) {
+	now := time.Date(2024, 5, 6, 18, 0, 0, 0, time.UTC)
+	start := time.Date(2024, 5, 6, 9, 0, 0, 0, time.UTC)
+	entry := models.TimeEntry{
+		ID: "lunch", Description: "Paused over lunch", StartTime: start, EndTime: start.Add(8 * time.Hour), Duration: 7 * 3600, State: models.TaskStateStopped,
+		Segments: []models.Segment{{Start: start, End: start.Add(3 * time.Hour)}, {Start: start.Add(4 * time.Hour), End: start.Add(8 * time.Hour)}},
+	}
+
+	var buf bytes.Buffer
+	if err := ExportICS(&buf, []models.TimeEntry{entry}, nil, now); err != nil {
+		t.Fatalf("ExportICS: %v", err)
+	}
+	out := buf.String()
+	if n := strings.Count(out, "BEGIN:VEVENT"); n != 2 {
+		t.Errorf("expected one event per segment, got %d", n)
+	}
+	for _, want := range []string{
+		"UID:lunch-1@tasktracker\r\nDTSTAMP:20240506T180000Z\r\nDTSTART:20240506T090000Z\r\nDTEND:20240506T120000Z\r\n",
+		"UID:lunch-2@tasktracker\r\nDTSTAMP:20240506T180000Z\r\nDTSTART:20240506T130000Z\r\nDTEND:20240506T170000Z\r\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %q in\n%s", want, out)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package service
+
+import (
+	"slices"
+	"time"
+
+	"github.com/highercomve/tasktracker/internal/models"
+)
+
+// ImportStorage is the subset of the storage API imports depend on.
+type ImportStorage interface {
+	LoadEntriesForRange(start, end time.Time) ([]models.TimeEntry, error)
+	SaveEntry(entry models.TimeEntry) error
+	DeleteEntry(entry models.TimeEntry) error
+	UpdateProjects(update func([]models.Project) ([]models.Project, error)) error
+}
+
+// ImportPlan is a set of entries read from another source, checked against
+// the stored ones and ready to be written by Import.
+type ImportPlan struct {
+	Entries []models.TimeEntry
+	// Projects are the projects to create for the entries.
+	Projects []models.Project
+	// Duplicates counts the entries dropped because they were already
+	// stored or repeated.
+	Duplicates int
+}
+
+// PlanImport deduplicates entries against those stored over the same days
+// and keeps only the created projects some remaining entry uses.
+func PlanImport(s ImportStorage, entries []models.TimeEntry, created []models.Project) (ImportPlan, error) {
+	var plan ImportPlan
+	if len(entries) == 0 {
+		return plan, nil
+	}
+	first, last := entries[0].StartTime, entries[0].StartTime
+	for _, e := range entries {
+		if e.StartTime.Before(first) {
+			first = e.StartTime
+		}
+		if e.StartTime.After(last) {
+			last = e.StartTime
+		}
+	}
+	existing, err := s.LoadEntriesForRange(first, last)
+	if err != nil {
+		return plan, err
+	}
+	plan.Entries, plan.Duplicates = DedupEntries(entries, existing)
+
+	for _, p := range created {
+		if slices.ContainsFunc(plan.Entries, func(e models.TimeEntry) bool { return e.ProjectID == p.ID }) {
+			plan.Projects = append(plan.Projects, p)
+		}
+	}
+	return plan, nil
+}
+
+// Import writes the projects and entries of plan.
+func Import(s ImportStorage, plan ImportPlan) error {
+	if len(plan.Projects) > 0 {
+		err := s.UpdateProjects(func(projects []models.Project) ([]models.Project, error) {
+			for _, p := range plan.Projects {
+				if FindProjectByID(projects, p.ID) == nil {
+					projects = append(projects, p)
+				}
+			}
+			return projects, nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	for _, e := range plan.Entries {
+		if err := s.SaveEntry(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RevertImport deletes what Import wrote for plan.
+func RevertImport(s ImportStorage, plan ImportPlan) error {
+	for _, e := range plan.Entries {
+		if err := s.DeleteEntry(e); err != nil {
+			return err
+		}
+	}
+	if len(plan.Projects) == 0 {
+		return nil
+	}
+	return s.UpdateProjects(func(projects []models.Project) ([]models.Project, error) {
+		for _, p := range plan.Projects {
+			projects, _ = DeleteProject(projects, p.ID)
+		}
+		return projects, nil
+	})
+}
@@ -0,0 +1,52 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/highercomve/tasktracker/internal/models"
+	"github.com/highercomve/tasktracker/internal/store"
+)
+
+func TestImportPlan(t *testing.T) {
+	s := store.NewStorage(t.TempDir())
+	start := time.Date(2024, 5, 6, 9, 0, 0, 0, time.Local)
+	stored := models.TimeEntry{ID: "stored", Description: "Design", StartTime: start, EndTime: start.Add(time.Hour), Duration: 3600, State: models.TaskStateStopped}
+	s.SaveEntry(stored)
+
+	ops := CreateProject("Ops", "", "")
+	unused := CreateProject("Unused", "", "")
+	incoming := []models.TimeEntry{
+		{ID: "dup", Description: "Design", ProjectID: unused.ID, StartTime: start, EndTime: start.Add(time.Hour), Duration: 3600, State: models.TaskStateStopped},
+		{ID: "new", Description: "Deploy", ProjectID: ops.ID, StartTime: start.AddDate(0, 0, 1), EndTime: start.AddDate(0, 0, 1).Add(time.Hour), Duration: 3600, State: models.TaskStateStopped},
+	}
+
+	plan, err := PlanImport(s, incoming, []models.Project{ops, unused})
+	if err != nil {
+		t.Fatalf("PlanImport: %v", err)
+	}
+	if plan.Duplicates != 1 || len(plan.Entries) != 1 || plan.Entries[0].ID != "new" {
+		t.Fatalf("plan entries: %+v", plan)
+	}
+	if len(plan.Projects) != 1 || plan.Projects[0].ID != ops.ID {
+		t.Fatalf("plan projects: %+v", plan.Projects)
+	}
+
+	if err := Import(s, plan); err != nil {
+		t.Fatalf("Import: %v", err)
+	}
+	entries, _ := s.LoadEntriesForRange(start, start.AddDate(0, 0, 1))
+	projects, _ := s.LoadProjects()
+	if len(entries) != 2 || len(projects) != 1 {
+		t.Fatalf("after import: %d entries, %d projects", len(entries), len(projects))
+	}
+
+	if err := RevertImport(s, plan); err != nil {
+		t.Fatalf("RevertImport: %v", err)
+	}
+	entries, _ = s.LoadEntriesForRange(start, start.AddDate(0, 0, 1))
+	projects, _ = s.LoadProjects()
+	if len(entries) != 1 || entries[0].ID != "stored" || len(projects) != 0 {
+		t.Errorf("after revert: %d entries, %d projects", len(entries), len(projects))
+	}
+}
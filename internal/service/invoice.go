@@ -0,0 +1,258 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/highercomve/tasktracker/internal/models"
+)
+
+// Invoice line items.
+const (
+	InvoiceLinesByProject = "project" // one line per project
+	InvoiceLinesByTask    = "task"    // one line per task description and project
+)
+
+// DefaultInvoicePrefix starts the invoice numbers when no prefix is set.
+const DefaultInvoicePrefix = "INV-"
+
+var (
+	// ErrNoHourlyRate is returned when invoicing time without a rate.
+	ErrNoHourlyRate = errors.New("no hourly rate is configured")
+	// ErrNothingToInvoice is returned when the client has no finished
+	// entries in the period.
+	ErrNothingToInvoice = errors.New("no tracked time to invoice for this client and period")
+	// ErrMixedCurrencies is returned when the client's projects are billed
+	// in different currencies, which cannot go on one invoice.
+	ErrMixedCurrencies = errors.New("the client's projects are billed in different currencies")
+)
+
+// InvoiceOptions describes the invoice BuildInvoice issues.
+type InvoiceOptions struct {
+	// Client is the client billed, as returned by InvoiceClients.
+	Client string
+	// Start and End are the first and last days billed.
+	Start, End time.Time
+	// LinesBy is InvoiceLinesByProject or InvoiceLinesByTask.
+	LinesBy string
+	// Billing is the global configuration, overridden by each project.
+	Billing  BillingConfig
+	Business models.InvoiceParty
+	// Prefix starts the invoice number; DefaultInvoicePrefix if empty.
+	Prefix       string
+	TaxPercent   float64
+	DueDays      int
+	PaymentTerms string
+	// OvertimeLabel describes the lines charging the extra rate over the
+	// threshold of a project.
+	OvertimeLabel string
+	// RoundingLabel describes the lines adjusting the time of a project to
+	// its rounded total, when days or totals are rounded.
+	RoundingLabel string
+}
+
+// InvoiceClients returns the clients that can be invoiced, sorted: the
+// Client of the projects, or the project name for projects without one.
+func InvoiceClients(projects []models.Project) []string {
+	var clients []string
+	for _, p := range projects {
+		name := invoiceClient(p)
+		if name != "" && !slices.Contains(clients, name) {
+			clients = append(clients, name)
+		}
+	}
+	sort.Strings(clients)
+	return clients
+}
+
+func invoiceClient(p models.Project) string {
+	if p.Client != "" {
+		return p.Client
+	}
+	return p.Name
+}
+
+// ClientDetails returns the details of client found on its projects.
+func ClientDetails(projects []models.Project, client string) models.InvoiceParty {
+	party := models.InvoiceParty{Name: client}
+	for _, p := range projects {
+		if invoiceClient(p) != client {
+			continue
+		}
+		if party.Address == "" {
+			party.Address = p.ClientAddress
+		}
+		if party.Email == "" {
+			party.Email = p.ClientEmail
+		}
+		if party.TaxID == "" {
+			party.TaxID = p.ClientTaxID
+		}
+	}
+	return party
+}
+
+// NextInvoiceNumber returns the sequence and number of the next invoice.
+// Numbers keep increasing across prefixes, so none is ever issued twice.
+func NextInvoiceNumber(invoices []models.Invoice, prefix string) (int, string) {
+	if prefix == "" {
+		prefix = DefaultInvoicePrefix
+	}
+	seq := 1
+	for _, inv := range invoices {
+		seq = max(seq, inv.Sequence+1)
+	}
+	return seq, fmt.Sprintf("%s%04d", prefix, seq)
+}
+
+// BuildInvoice bills the finished entries of the client's projects between
+// opts.Start and opts.End. entries are those loaded for the period, such as
+// from LoadEntriesForRange; time is attributed to the days it was worked on.
+// Each project is billed with its own rates, so tags with a rate of their
+// own get lines of their own. Entries that are not billable are left out,
+// and time is rounded by the rule of the configuration. The invoice gets the
+// number following the issued invoices.
+func BuildInvoice(entries []models.TimeEntry, projects []models.Project, issued []models.Invoice, opts InvoiceOptions, now time.Time) (models.Invoice, error) {
+	type line struct {
+		description string
+		rate        float64
+		duration    time.Duration
+	}
+	var (
+		lines    []*line
+		byKey    = map[string]*line{}
+		billed   []models.TimeEntry
+		currency string
+		// Time on the lines and its value at the standard rates, by project
+		lined  = map[string]time.Duration{}
+		amount = map[string]float64{}
+	)
+	// Running entries are billed once they are stopped.
+	finished := slices.DeleteFunc(slices.Clone(entries), func(e models.TimeEntry) bool { return e.EndTime.IsZero() })
+	for _, e := range SplitByDay(finished, opts.Start, opts.End, now) {
+		project := FindProjectByID(projects, e.ProjectID)
+		if project == nil || invoiceClient(*project) != opts.Client || !IsBillable(e, projects) {
+			continue
+		}
+		config := opts.Billing.ForProject(project)
+		if len(billed) == 0 {
+			currency = config.Currency
+		} else if config.Currency != currency {
+			return models.Invoice{}, ErrMixedCurrencies
+		}
+		billed = append(billed, e)
+
+		rate := config.RateFor(e.Tags)
+		if rate <= 0 {
+			return models.Invoice{}, ErrNoHourlyRate
+		}
+		key, description := fmt.Sprintf("%s\x00%g", project.ID, rate), project.Name
+		if opts.LinesBy == InvoiceLinesByTask {
+			key += "\x00" + e.Description
+			description = fmt.Sprintf("%s (%s)", e.Description, project.Name)
+		}
+		l := byKey[key]
+		if l == nil {
+			l = &line{description: description, rate: rate}
+			byKey[key] = l
+			lines = append(lines, l)
+		}
+		d := e.TrackedDuration(now)
+		amount[project.ID] += d.Hours() * rate
+		if s := config.Rounding.Scope; s == "" || s == RoundPerEntry {
+			d = config.Rounding.Round(d)
+		}
+		l.duration += d
+		lined[project.ID] += d
+	}
+	if len(lines) == 0 {
+		return models.Invoice{}, ErrNothingToInvoice
+	}
+
+	seq, number := NextInvoiceNumber(issued, opts.Prefix)
+	issue := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	inv := models.Invoice{
+		ID:           uuid.New().String(),
+		Number:       number,
+		Sequence:     seq,
+		IssueDate:    issue,
+		DueDate:      issue.AddDate(0, 0, opts.DueDays),
+		PeriodStart:  opts.Start,
+		PeriodEnd:    opts.End,
+		Business:     opts.Business,
+		Client:       ClientDetails(projects, opts.Client),
+		Currency:     currency,
+		TaxPercent:   opts.TaxPercent,
+		PaymentTerms: opts.PaymentTerms,
+	}
+	for _, l := range lines {
+		hours := l.duration.Hours()
+		inv.Lines = append(inv.Lines, models.InvoiceLine{
+			Description: l.description,
+			Hours:       hours,
+			Rate:        l.rate,
+			Amount:      roundCents(hours * l.rate),
+		})
+	}
+
+	// Rounded days or totals adjust the time of a project, and hours over
+	// its threshold are charged the extra rate, as in the billing summary of
+	// the reports.
+	overtimeLabel, roundingLabel := opts.OvertimeLabel, opts.RoundingLabel
+	if overtimeLabel == "" {
+		overtimeLabel = "Overtime"
+	}
+	if roundingLabel == "" {
+		roundingLabel = "Rounding"
+	}
+	for _, b := range CalculateProjectBilling(billed, projects, opts.Billing, opts.Start, opts.End, now) {
+		name := FindProjectByID(projects, b.ProjectID).Name
+		rate := amount[b.ProjectID] / b.Duration.Hours()
+		if diff := (b.Billable - lined[b.ProjectID]).Hours(); diff != 0 {
+			inv.Lines = append(inv.Lines, models.InvoiceLine{
+				Description: fmt.Sprintf("%s (%s)", roundingLabel, name),
+				Hours:       diff,
+				Rate:        roundCents(rate),
+				Amount:      roundCents(diff * rate),
+			})
+		}
+
+		if b.ExtraHours <= 0 {
+			continue
+		}
+		// The lines charged every hour at the standard rates already.
+		var premium float64
+		for _, bucket := range b.Buckets {
+			premium += bucket.ExtraCost - bucket.ExtraHours*bucket.Rate
+		}
+		if premium = roundCents(premium); premium == 0 {
+			continue
+		}
+		inv.Lines = append(inv.Lines, models.InvoiceLine{
+			Description: fmt.Sprintf("%s (%s)", overtimeLabel, name),
+			Hours:       b.ExtraHours,
+			Rate:        premium / b.ExtraHours,
+			Amount:      premium,
+		})
+	}
+	return inv, nil
+}
+
+// FormatMoney formats an amount with two decimals and its currency.
+func FormatMoney(amount float64, currency string) string {
+	s := fmt.Sprintf("%.2f", amount)
+	if currency = strings.TrimSpace(currency); currency != "" {
+		s += " " + currency
+	}
+	return s
+}
+
+func roundCents(v float64) float64 {
+	return math.Round(v*100) / 100
+}
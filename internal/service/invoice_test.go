@@ -0,0 +1,149 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/highercomve/tasktracker/internal/models"
+)
+
+func TestNextInvoiceNumber(t *testing.T) {
+	if seq, number := NextInvoiceNumber(nil, ""); seq != 1 || number != "INV-0001" {
+		t.Errorf("first invoice: %d %s", seq, number)
+	}
+	issued := []models.Invoice{{Sequence: 7}, {Sequence: 3}}
+	if seq, number := NextInvoiceNumber(issued, "2024/"); seq != 8 || number != "2024/0008" {
+		t.Errorf("next invoice: %d %s", seq, number)
+	}
+}
+
+func TestBuildInvoice(t *testing.T) {
+	projects := []models.Project{
+		{ID: "web", Name: "Website", Client: "ACME", ClientAddress: "1 Road", ClientTaxID: "X1", Currency: "EUR"},
+		{ID: "app", Name: "App", Client: "ACME", ClientEmail: "billing@acme.test", Currency: "EUR"},
+		{ID: "own", Name: "Internal"},
+	}
+	day := time.Date(2024, 5, 6, 9, 0, 0, 0, time.Local)
+	entries := []models.TimeEntry{
+		projectEntry("web", day, 2*time.Hour),
+		projectEntry("web", day.AddDate(0, 0, 1), 90*time.Minute),
+		projectEntry("app", day.Add(3*time.Hour), time.Hour),
+		projectEntry("own", day.Add(5*time.Hour), 4*time.Hour),
+		{ID: "running", Description: "Running", ProjectID: "web", StartTime: day.Add(6 * time.Hour), State: models.TaskStateRunning},
+	}
+	for i, description := range []string{"Design", "Design", "Build", "Admin"} {
+		entries[i].Description = description
+	}
+	opts := InvoiceOptions{
+		Client:     "ACME",
+		Start:      day.AddDate(0, 0, -1),
+		End:        day.AddDate(0, 0, 5),
+		LinesBy:    InvoiceLinesByProject,
+		Billing:    BillingConfig{HourlyRate: 50},
+		TaxPercent: 21,
+		DueDays:    30,
+	}
+	now := day.AddDate(0, 0, 7)
+	issued := []models.Invoice{{Sequence: 4}}
+
+	inv, err := BuildInvoice(entries, projects, issued, opts, now)
+	if err != nil {
+		t.Fatalf("BuildInvoice: %v", err)
+	}
+	if inv.Currency != "EUR" {
+		t.Errorf("currency: %q", inv.Currency)
+	}
+	if inv.Number != "INV-0005" || inv.Sequence != 5 || !inv.DueDate.Equal(inv.IssueDate.AddDate(0, 0, 30)) {
+		t.Errorf("numbering or dates: %+v", inv)
+	}
+	if c := inv.Client; c.Name != "ACME" || c.Address != "1 Road" || c.Email != "billing@acme.test" || c.TaxID != "X1" {
+		t.Errorf("client: %+v", c)
+	}
+	if len(inv.Lines) != 2 || inv.Lines[0].Description != "Website" || inv.Lines[0].Amount != 175 || inv.Lines[1].Amount != 50 {
+		t.Fatalf("lines by project: %+v", inv.Lines)
+	}
+	if inv.Subtotal() != 225 || inv.Tax() != 47.25 || inv.Total() != 272.25 {
+		t.Errorf("totals: %v %v %v", inv.Subtotal(), inv.Tax(), inv.Total())
+	}
+
+	opts.LinesBy = InvoiceLinesByTask
+	inv, _ = BuildInvoice(entries, projects, issued, opts, now)
+	if len(inv.Lines) != 2 || inv.Lines[0].Description != "Design (Website)" || inv.Lines[0].Hours != 3.5 {
+		t.Errorf("lines by task: %+v", inv.Lines)
+	}
+
+	// With 12h a month, the threshold of a 7-day period is 3h per project:
+	// 0.5h of the website is billed extra.
+	opts.LinesBy = InvoiceLinesByProject
+	opts.Billing = BillingConfig{HourlyRate: 50, MaxHours: 12, ExtraRate: 80}
+	inv, _ = BuildInvoice(entries, projects, issued, opts, now)
+	if len(inv.Lines) != 3 || inv.Lines[2].Description != "Overtime (Website)" || inv.Lines[2].Amount != 15 || inv.Subtotal() != 240 {
+		t.Errorf("overtime: %+v", inv.Lines)
+	}
+
+	// The app has its own rate, and design work on the website another.
+	projects[0].TagRates = map[string]float64{"design": 70}
+	projects[1].HourlyRate = 60
+	entries[1].Tags = []string{"design"}
+	opts.Billing = BillingConfig{HourlyRate: 50}
+	inv, _ = BuildInvoice(entries, projects, issued, opts, now)
+	if len(inv.Lines) != 3 || inv.Lines[1].Rate != 70 || inv.Lines[1].Amount != 105 || inv.Lines[2].Amount != 60 {
+		t.Errorf("project and tag rates: %+v", inv.Lines)
+	}
+
+	projects[1].Currency = "USD"
+	if _, err := BuildInvoice(entries, projects, issued, opts, now); !errors.Is(err, ErrMixedCurrencies) {
+		t.Errorf("expected ErrMixedCurrencies, got %v", err)
+	}
+	projects[1].Currency = "EUR"
+	projects[1].HourlyRate = 0
+
+	// Design work is rounded up to the hour, and the app is internal work.
+	projects[1].NonBillable = true
+	opts.Billing.Rounding = RoundingRule{Mode: RoundUp, Increment: time.Hour}
+	inv, _ = BuildInvoice(entries, projects, issued, opts, now)
+	if len(inv.Lines) != 2 || inv.Lines[0].Hours != 2 || inv.Lines[1].Hours != 2 || inv.Subtotal() != 240 {
+		t.Errorf("rounding per entry: %+v", inv.Lines)
+	}
+	// Rounding the total adds a line for the difference, at the average rate.
+	opts.Billing.Rounding.Scope = RoundPerTotal
+	inv, _ = BuildInvoice(entries, projects, issued, opts, now)
+	if len(inv.Lines) != 3 || inv.Lines[2].Description != "Rounding (Website)" || inv.Lines[2].Hours != 0.5 || inv.Lines[2].Amount != 29.29 {
+		t.Errorf("rounding the total: %+v", inv.Lines)
+	}
+	projects[1].NonBillable = false
+	opts.Billing.Rounding = RoundingRule{}
+
+	opts.Client = "Nobody"
+	if _, err := BuildInvoice(entries, projects, issued, opts, now); !errors.Is(err, ErrNothingToInvoice) {
+		t.Errorf("expected ErrNothingToInvoice, got %v", err)
+	}
+	opts.Client = "ACME"
+	opts.Billing = BillingConfig{}
+	if _, err := BuildInvoice(entries, projects, issued, opts, now); !errors.Is(err, ErrNoHourlyRate) {
+		t.Errorf("expected ErrNoHourlyRate, got %v", err)
+	}
+}
+
+func TestInvoiceOverdue(t *testing.T) {
+	inv := models.Invoice{DueDate: time.Date(2024, 6, 5, 0, 0, 0, 0, time.Local)}
+	if inv.Overdue(time.Date(2024, 6, 5, 18, 0, 0, 0, time.Local)) {
+		t.Error("overdue on its due date")
+	}
+	if !inv.Overdue(time.Date(2024, 6, 6, 0, 0, 0, 0, time.Local)) {
+		t.Error("not overdue the day after")
+	}
+	inv.Paid = true
+	if inv.Overdue(time.Date(2024, 7, 1, 0, 0, 0, 0, time.Local)) {
+		t.Error("paid invoice overdue")
+	}
+}
+
+func TestInvoiceClients(t *testing.T) {
+	projects := []models.Project{{Name: "Zeta", Client: "ACME"}, {Name: "Alpha"}, {Name: "Beta", Client: "ACME"}}
+	got := InvoiceClients(projects)
+	if len(got) != 2 || got[0] != "ACME" || got[1] != "Alpha" {
+		t.Errorf("clients: %v", got)
+	}
+}
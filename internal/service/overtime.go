@@ -0,0 +1,231 @@
+package service
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/highercomve/tasktracker/internal/models"
+)
+
+// Threshold modes: the periods overtime is computed in. MaxHours is a
+// monthly threshold in every mode and is split among the periods.
+const (
+	// ThresholdProRated pro-rates MaxHours over 28-day months.
+	ThresholdProRated   = ""
+	ThresholdMonth      = "month"       // per calendar month
+	ThresholdWeek       = "week"        // per ISO week, a quarter of MaxHours
+	ThresholdDay        = "day"         // per day, MaxHours over the days of the month
+	ThresholdWorkingDay = "working_day" // per working day, MaxHours over the working days of the month; time on other days is all overtime
+)
+
+// WorkCalendar tells the days worked, for ThresholdWorkingDay.
+type WorkCalendar struct {
+	// Weekdays are the days of the week worked; Monday to Friday if empty.
+	Weekdays []time.Weekday
+	// Holidays are the dates not worked, whatever their weekday.
+	Holidays []time.Time
+}
+
+// IsHoliday reports whether day is one of the holidays.
+func (c WorkCalendar) IsHoliday(day time.Time) bool {
+	for _, h := range c.Holidays {
+		if y, m, d := h.Date(); day.Year() == y && day.Month() == m && day.Day() == d {
+			return true
+		}
+	}
+	return false
+}
+
+// WorkingDays returns the number of days worked in the month of day.
+func (c WorkCalendar) WorkingDays(day time.Time) int {
+	n := 0
+	first := time.Date(day.Year(), day.Month(), 1, 0, 0, 0, 0, day.Location())
+	for d := first; d.Month() == first.Month(); d = d.AddDate(0, 0, 1) {
+		if c.IsWorkingDay(d) {
+			n++
+		}
+	}
+	return n
+}
+
+// IsWorkingDay reports whether day is worked.
+func (c WorkCalendar) IsWorkingDay(day time.Time) bool {
+	if c.IsHoliday(day) {
+		return false
+	}
+	if len(c.Weekdays) == 0 {
+		return day.Weekday() != time.Saturday && day.Weekday() != time.Sunday
+	}
+	return slices.Contains(c.Weekdays, day.Weekday())
+}
+
+// BucketResult is the billing of the time worked in one period of the
+// threshold mode.
+type BucketResult struct {
+	// Label names the period, such as 2024-05, 2024-W19 or 2024-05-06. It
+	// is empty for ThresholdProRated, billed as a single period.
+	Label string
+	// Start is the first day of the range in the period and End the day
+	// after its last one.
+	Start, End time.Time
+	Billable   time.Duration
+	// Threshold is the hours at the standard rate, pro-rated when the range
+	// covers part of the period.
+	Threshold float64
+	// Rate is the average standard rate of the entries.
+	Rate          float64
+	StandardHours float64
+	ExtraHours    float64
+	StandardCost  float64
+	ExtraCost     float64
+}
+
+// CalculatePeriodBilling bills the entries worked from the day of start to
+// the day of end, split by day as returned by SplitByDay. The time is
+// rounded by the rule of the configuration and split into the periods of
+// its threshold mode, each with its own threshold, so overtime in a busy
+// week is not offset by a quiet one. A zero start or end bills from the
+// first or up to the last day worked. The standard hours of a period are
+// charged at the average of the rates of its entries.
+func CalculatePeriodBilling(entries []models.TimeEntry, config BillingConfig, start, end, now time.Time) BillingResult {
+	if len(entries) == 0 {
+		return BillingResult{}
+	}
+	from, to := start, end
+	for _, e := range entries {
+		day := startOfDay(e.StartTime)
+		if start.IsZero() && (from.IsZero() || day.Before(from)) {
+			from = day
+		}
+		if end.IsZero() && (to.IsZero() || day.After(to)) {
+			to = day
+		}
+	}
+	buckets := periodBuckets(config, startOfDay(from), startOfDay(to))
+	if config.ThresholdMode == ThresholdProRated && (start.IsZero() || end.IsZero()) {
+		// Without a period, the threshold is the monthly one.
+		buckets[0].Threshold = config.MaxHours
+	}
+
+	byBucket := make([][]models.TimeEntry, len(buckets))
+	for _, e := range entries {
+		day := startOfDay(e.StartTime)
+		for i, b := range buckets {
+			if !day.Before(b.Start) && day.Before(b.End) {
+				byBucket[i] = append(byBucket[i], e)
+				break
+			}
+		}
+	}
+
+	var result BillingResult
+	for i, b := range buckets {
+		var raw time.Duration
+		for _, e := range byBucket[i] {
+			raw += e.TrackedDuration(now)
+		}
+		if raw <= 0 {
+			continue
+		}
+		b.Rate = config.Amount(byBucket[i], now) / raw.Hours()
+		if b.Rate <= 0 {
+			continue
+		}
+		b.Billable = config.Rounding.Billable(byBucket[i], now)
+		hours := b.Billable.Hours()
+		b.StandardHours = hours
+		if config.MaxHours > 0 && hours > b.Threshold {
+			b.StandardHours, b.ExtraHours = b.Threshold, hours-b.Threshold
+		}
+		extraRate := config.ExtraRate
+		if extraRate <= 0 {
+			extraRate = b.Rate
+		}
+		b.StandardCost = b.StandardHours * b.Rate
+		b.ExtraCost = b.ExtraHours * extraRate
+
+		result.Billable += b.Billable
+		result.Threshold += b.Threshold
+		result.StandardHours += b.StandardHours
+		result.ExtraHours += b.ExtraHours
+		result.StandardCost += b.StandardCost
+		result.ExtraCost += b.ExtraCost
+		result.Buckets = append(result.Buckets, *b)
+	}
+	result.TotalCost = result.StandardCost + result.ExtraCost
+	if config.MaxHours > 0 {
+		for _, b := range buckets {
+			partial := b.days() != periodDays(config.ThresholdMode, b.Start)
+			if config.ThresholdMode == ThresholdProRated {
+				partial = !start.IsZero() && !end.IsZero()
+			}
+			result.IsProRated = result.IsProRated || partial
+		}
+	}
+	return result
+}
+
+// periodBuckets returns the periods of the threshold mode covering the days
+// from to to, with their share of the monthly threshold.
+func periodBuckets(config BillingConfig, from, to time.Time) []*BucketResult {
+	var buckets []*BucketResult
+	for day := from; !day.After(to); day = day.AddDate(0, 0, 1) {
+		label := periodLabel(config.ThresholdMode, day)
+		if n := len(buckets); n == 0 || buckets[n-1].Label != label {
+			buckets = append(buckets, &BucketResult{Label: label, Start: day})
+		}
+		buckets[len(buckets)-1].End = day.AddDate(0, 0, 1)
+	}
+	for _, b := range buckets {
+		monthDays := float64(periodDays(ThresholdMonth, b.Start))
+		switch config.ThresholdMode {
+		case ThresholdWorkingDay:
+			if config.WorkCalendar.IsWorkingDay(b.Start) {
+				b.Threshold = config.MaxHours / float64(config.WorkCalendar.WorkingDays(b.Start))
+			}
+		case ThresholdDay:
+			b.Threshold = config.MaxHours / monthDays
+		case ThresholdMonth:
+			// Months the range covers in part get a part of the threshold.
+			b.Threshold = config.MaxHours * float64(b.days()) / monthDays
+		default:
+			// Weeks and the pro-rated period take MaxHours as 28 days' worth.
+			b.Threshold = config.MaxHours * float64(b.days()) / 28
+		}
+	}
+	return buckets
+}
+
+// days returns the number of days of the range in the period.
+func (b BucketResult) days() int {
+	return int(b.End.Sub(b.Start).Hours()/24 + 0.5)
+}
+
+// periodLabel names the period of the threshold mode day is in.
+func periodLabel(mode string, day time.Time) string {
+	switch mode {
+	case ThresholdMonth:
+		return day.Format("2006-01")
+	case ThresholdWeek:
+		y, w := day.ISOWeek()
+		return fmt.Sprintf("%d-W%02d", y, w)
+	case ThresholdDay, ThresholdWorkingDay:
+		return day.Format("2006-01-02")
+	}
+	return ""
+}
+
+// periodDays returns the number of days in the period of the mode day is
+// in; 28 for ThresholdProRated.
+func periodDays(mode string, day time.Time) int {
+	switch mode {
+	case ThresholdMonth:
+		return time.Date(day.Year(), day.Month()+1, 0, 0, 0, 0, 0, day.Location()).Day()
+	case ThresholdWeek:
+		return 7
+	case ThresholdDay, ThresholdWorkingDay:
+		return 1
+	}
+	return 28
+}
@@ -0,0 +1,167 @@
+package service
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/highercomve/tasktracker/internal/models"
+)
+
+func hoursOn(day time.Time, hours float64) models.TimeEntry {
+	return projectEntry("", day.Add(9*time.Hour), time.Duration(hours*float64(time.Hour)))
+}
+
+// projectEntry returns an entry of project worked for d from start.
+func projectEntry(project string, start time.Time, d time.Duration) models.TimeEntry {
+	return models.TimeEntry{ProjectID: project, StartTime: start, EndTime: start.Add(d), Duration: int64(d / time.Second)}
+}
+
+func date(y int, m time.Month, d int) time.Time {
+	return time.Date(y, m, d, 0, 0, 0, 0, time.Local)
+}
+
+func TestCalculatePeriodBilling(t *testing.T) {
+	type bucket struct {
+		label      string
+		threshold  float64
+		extraHours float64
+	}
+	tests := []struct {
+		name        string
+		mode        string
+		calendar    WorkCalendar
+		maxHours    float64
+		start, end  time.Time
+		entries     []models.TimeEntry
+		expectExtra float64
+		proRated    bool
+		buckets     []bucket
+	}{
+		{
+			name:     "Calendar months are billed apart",
+			mode:     ThresholdMonth,
+			maxHours: 10,
+			start:    date(2024, 5, 1), end: date(2024, 6, 30),
+			entries:     []models.TimeEntry{hoursOn(date(2024, 5, 2), 12), hoursOn(date(2024, 6, 3), 8)},
+			expectExtra: 2 * 50,
+			buckets:     []bucket{{"2024-05", 10, 2}, {"2024-06", 10, 0}},
+		},
+		{
+			name:     "Part of a month",
+			mode:     ThresholdMonth,
+			maxHours: 31,
+			start:    date(2024, 5, 17), end: date(2024, 5, 31),
+			entries:     []models.TimeEntry{hoursOn(date(2024, 5, 20), 16)},
+			expectExtra: 1 * 50,
+			proRated:    true,
+			buckets:     []bucket{{"2024-05", 15, 1}},
+		},
+		{
+			name:     "ISO weeks across the new year",
+			mode:     ThresholdWeek,
+			maxHours: 20,
+			start:    date(2024, 12, 23), end: date(2025, 1, 5),
+			entries:     []models.TimeEntry{hoursOn(date(2024, 12, 23), 6), hoursOn(date(2025, 1, 1), 4), hoursOn(date(2024, 12, 31), 2)},
+			expectExtra: 2 * 50,
+			buckets:     []bucket{{"2024-W52", 5, 1}, {"2025-W01", 5, 1}},
+		},
+		{
+			name:     "Days share the monthly threshold",
+			mode:     ThresholdDay,
+			maxHours: 62,
+			start:    date(2024, 5, 6), end: date(2024, 5, 8),
+			entries:     []models.TimeEntry{hoursOn(date(2024, 5, 6), 3), hoursOn(date(2024, 5, 7), 1)},
+			expectExtra: 1 * 50,
+			buckets:     []bucket{{"2024-05-06", 2, 1}, {"2024-05-07", 2, 0}},
+		},
+		{
+			name:     "Weekends and holidays are overtime",
+			mode:     ThresholdWorkingDay,
+			calendar: WorkCalendar{Holidays: []time.Time{date(2024, 5, 1)}},
+			maxHours: 176, // 22 working days in April
+			start:    date(2024, 4, 29), end: date(2024, 5, 5),
+			entries:     []models.TimeEntry{hoursOn(date(2024, 4, 30), 9), hoursOn(date(2024, 5, 1), 2), hoursOn(date(2024, 5, 4), 3)},
+			expectExtra: 6 * 50,
+			buckets:     []bucket{{"2024-04-30", 8, 1}, {"2024-05-01", 0, 2}, {"2024-05-04", 0, 3}},
+		},
+		{
+			name:     "Working days of a custom calendar",
+			mode:     ThresholdWorkingDay,
+			calendar: WorkCalendar{Weekdays: []time.Weekday{time.Saturday}},
+			maxHours: 32, // 4 Saturdays in May
+			start:    date(2024, 5, 3), end: date(2024, 5, 4),
+			entries:     []models.TimeEntry{hoursOn(date(2024, 5, 3), 1), hoursOn(date(2024, 5, 4), 3)},
+			expectExtra: 1 * 50,
+			buckets:     []bucket{{"2024-05-03", 0, 1}, {"2024-05-04", 8, 0}},
+		},
+		{
+			name:     "Pro-rated over 28 days",
+			mode:     ThresholdProRated,
+			maxHours: 28,
+			start:    date(2024, 5, 1), end: date(2024, 5, 7),
+			entries:     []models.TimeEntry{hoursOn(date(2024, 5, 2), 9)},
+			expectExtra: 2 * 50,
+			proRated:    true,
+			buckets:     []bucket{{"", 7, 2}},
+		},
+		{
+			name:        "Monthly threshold without a range",
+			mode:        ThresholdProRated,
+			maxHours:    28,
+			entries:     []models.TimeEntry{hoursOn(date(2024, 5, 2), 20), hoursOn(date(2024, 5, 9), 10)},
+			expectExtra: 2 * 50,
+			buckets:     []bucket{{"", 28, 2}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := BillingConfig{HourlyRate: 30, ExtraRate: 50, MaxHours: tt.maxHours, ThresholdMode: tt.mode, WorkCalendar: tt.calendar}
+			entries := SplitByDay(tt.entries, tt.start, tt.end, tt.start.AddDate(1, 0, 0))
+			res := CalculatePeriodBilling(entries, config, tt.start, tt.end, tt.start.AddDate(1, 0, 0))
+			if math.Abs(res.ExtraCost-tt.expectExtra) > 0.0001 {
+				t.Errorf("ExtraCost: expected %v, got %v", tt.expectExtra, res.ExtraCost)
+			}
+			if res.IsProRated != tt.proRated {
+				t.Errorf("IsProRated: expected %v, got %v", tt.proRated, res.IsProRated)
+			}
+			if len(res.Buckets) != len(tt.buckets) {
+				t.Fatalf("expected %d buckets, got %+v", len(tt.buckets), res.Buckets)
+			}
+			for i, want := range tt.buckets {
+				got := res.Buckets[i]
+				if got.Label != want.label || math.Abs(got.Threshold-want.threshold) > 0.0001 || math.Abs(got.ExtraHours-want.extraHours) > 0.0001 {
+					t.Errorf("bucket %d: expected %+v, got %+v", i, want, got)
+				}
+			}
+		})
+	}
+}
+
+func TestThresholdIsMonthly(t *testing.T) {
+	start, end := date(2024, 5, 1), date(2024, 5, 31)
+	var entries []models.TimeEntry
+	for day := start; !day.After(end); day = day.AddDate(0, 0, 1) {
+		entries = append(entries, hoursOn(day, 1))
+	}
+	for _, mode := range []string{ThresholdMonth, ThresholdDay, ThresholdWorkingDay} {
+		config := BillingConfig{HourlyRate: 30, MaxHours: 100, ThresholdMode: mode}
+		res := CalculatePeriodBilling(entries, config, start, end, end.AddDate(0, 1, 0))
+		if math.Abs(res.Threshold-100) > 0.0001 {
+			t.Errorf("%q: expected a threshold of 100 for the month, got %v", mode, res.Threshold)
+		}
+	}
+}
+
+func TestCalculatePeriodBillingMatchesLegacy(t *testing.T) {
+	config := BillingConfig{HourlyRate: 30, MaxHours: 48, ExtraRate: 50}
+	start, end := date(2024, 2, 1), date(2024, 2, 28)
+	entries := []models.TimeEntry{hoursOn(date(2024, 2, 5), 30), hoursOn(date(2024, 2, 20), 20)}
+
+	got := CalculatePeriodBilling(entries, config, start, end, end.AddDate(0, 1, 0))
+	want := CalculateBilling(50*time.Hour, config, 28)
+	if math.Abs(got.TotalCost-want.TotalCost) > 0.0001 || math.Abs(got.ExtraCost-want.ExtraCost) > 0.0001 || got.IsProRated != want.IsProRated {
+		t.Errorf("expected %+v, got %+v", want, got)
+	}
+}
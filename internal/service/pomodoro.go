@@ -0,0 +1,155 @@
+package service
+
+import (
+	"sync"
+	"time"
+)
+
+// PomodoroPhase is the part of a focus cycle under way.
+type PomodoroPhase int
+
+const (
+	PomodoroOff PomodoroPhase = iota
+	PomodoroWork
+	PomodoroShortBreak
+	PomodoroLongBreak
+)
+
+// IsBreak reports whether the phase is a short or a long break.
+func (p PomodoroPhase) IsBreak() bool {
+	return p == PomodoroShortBreak || p == PomodoroLongBreak
+}
+
+// PomodoroConfig sets the length of the phases of a focus cycle. Zero
+// values take the classic 25 minutes of work, 5 minute breaks and a 15
+// minute break every 4 work sessions.
+type PomodoroConfig struct {
+	Work       time.Duration
+	ShortBreak time.Duration
+	LongBreak  time.Duration
+	// LongBreakEvery is the number of work sessions before a long break.
+	LongBreakEvery int
+}
+
+func (c PomodoroConfig) withDefaults() PomodoroConfig {
+	if c.Work <= 0 {
+		c.Work = 25 * time.Minute
+	}
+	if c.ShortBreak <= 0 {
+		c.ShortBreak = 5 * time.Minute
+	}
+	if c.LongBreak <= 0 {
+		c.LongBreak = 15 * time.Minute
+	}
+	if c.LongBreakEvery <= 0 {
+		c.LongBreakEvery = 4
+	}
+	return c
+}
+
+// PomodoroStatus is the state of a focus session.
+type PomodoroStatus struct {
+	Phase     PomodoroPhase
+	Remaining time.Duration // left in the phase
+	Cycles    int           // work sessions completed since Start
+}
+
+// PomodoroTransition is the move from one phase to the next.
+type PomodoroTransition struct {
+	From, To PomodoroPhase
+	Cycles   int // work sessions completed, including one ending now
+}
+
+// Pomodoro runs focus sessions: work sessions separated by short breaks,
+// with a long break every few sessions. It only keeps time; its owner
+// pauses and resumes the tracked task on the transitions returned by
+// Advance. It is safe for concurrent use.
+type Pomodoro struct {
+	clock Clock
+
+	mu       sync.Mutex
+	config   PomodoroConfig
+	phase    PomodoroPhase
+	phaseEnd time.Time
+	cycles   int
+}
+
+// NewPomodoro returns a stopped Pomodoro.
+func NewPomodoro(config PomodoroConfig, clock Clock) *Pomodoro {
+	if clock == nil {
+		clock = SystemClock
+	}
+	return &Pomodoro{clock: clock, config: config.withDefaults()}
+}
+
+// SetConfig changes the phase lengths from the next phase on.
+func (p *Pomodoro) SetConfig(config PomodoroConfig) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.config = config.withDefaults()
+}
+
+// Start begins a work session, counting cycles from zero.
+func (p *Pomodoro) Start() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.cycles = 0
+	p.enterLocked(PomodoroWork)
+}
+
+// Stop ends the focus session.
+func (p *Pomodoro) Stop() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.phase = PomodoroOff
+	p.phaseEnd = time.Time{}
+}
+
+// Status returns the current phase and the time left in it.
+func (p *Pomodoro) Status() PomodoroStatus {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	s := PomodoroStatus{Phase: p.phase, Cycles: p.cycles}
+	if p.phase != PomodoroOff {
+		s.Remaining = max(p.phaseEnd.Sub(p.clock.Now()), 0)
+	}
+	return s
+}
+
+// Advance moves to the next phase once the current one is over: from work
+// to a break, counting the completed session, and from a break back to
+// work. It reports false while the phase goes on or when stopped. The next
+// phase runs from now, so phases missed while the computer slept are not
+// chained.
+func (p *Pomodoro) Advance() (PomodoroTransition, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.phase == PomodoroOff || p.clock.Now().Before(p.phaseEnd) {
+		return PomodoroTransition{}, false
+	}
+	t := PomodoroTransition{From: p.phase}
+	if p.phase == PomodoroWork {
+		p.cycles++
+		t.To = PomodoroShortBreak
+		if p.cycles%p.config.LongBreakEvery == 0 {
+			t.To = PomodoroLongBreak
+		}
+	} else {
+		t.To = PomodoroWork
+	}
+	t.Cycles = p.cycles
+	p.enterLocked(t.To)
+	return t, true
+}
+
+func (p *Pomodoro) enterLocked(phase PomodoroPhase) {
+	length := p.config.Work
+	switch phase {
+	case PomodoroShortBreak:
+		length = p.config.ShortBreak
+	case PomodoroLongBreak:
+		length = p.config.LongBreak
+	}
+	p.phase = phase
+	p.phaseEnd = p.clock.Now().Add(length)
+}
@@ -0,0 +1,61 @@
+package service
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPomodoroCycles(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2026, 3, 10, 9, 0, 0, 0, time.UTC)}
+	p := NewPomodoro(PomodoroConfig{Work: 20 * time.Minute, LongBreakEvery: 2}, clock)
+
+	if _, ok := p.Advance(); ok {
+		t.Fatal("stopped Pomodoro advanced")
+	}
+	p.Start()
+
+	steps := []struct {
+		after  time.Duration
+		expect PomodoroTransition
+	}{
+		{20 * time.Minute, PomodoroTransition{PomodoroWork, PomodoroShortBreak, 1}},
+		{5 * time.Minute, PomodoroTransition{PomodoroShortBreak, PomodoroWork, 1}},
+		{20 * time.Minute, PomodoroTransition{PomodoroWork, PomodoroLongBreak, 2}},
+		{15 * time.Minute, PomodoroTransition{PomodoroLongBreak, PomodoroWork, 2}},
+	}
+	for i, step := range steps {
+		clock.Advance(step.after - time.Second)
+		if _, ok := p.Advance(); ok {
+			t.Fatalf("step %d: advanced early", i)
+		}
+		if s := p.Status(); s.Remaining != time.Second {
+			t.Errorf("step %d: remaining %v", i, s.Remaining)
+		}
+		clock.Advance(time.Second)
+		got, ok := p.Advance()
+		if !ok || got != step.expect {
+			t.Errorf("step %d: expected %+v, got %+v (%v)", i, step.expect, got, ok)
+		}
+	}
+
+	p.Stop()
+	if s := p.Status(); s.Phase != PomodoroOff || s.Remaining != 0 {
+		t.Errorf("stopped: %+v", s)
+	}
+}
+
+func TestTimerAddFocusSession(t *testing.T) {
+	start := time.Date(2026, 3, 10, 9, 0, 0, 0, time.UTC)
+	timer, s, _ := newTestTimer(t, start)
+
+	if err := timer.AddFocusSession(); err != ErrNoActiveTask {
+		t.Errorf("expected ErrNoActiveTask, got %v", err)
+	}
+	entry, _ := timer.Start("Focus", "", nil)
+	timer.AddFocusSession()
+	timer.AddFocusSession()
+	stopped, _ := timer.Stop()
+	if stopped.FocusSessions != 2 || findEntry(t, s, start, entry.ID).FocusSessions != 2 {
+		t.Errorf("expected 2 focus sessions, got %d", stopped.FocusSessions)
+	}
+}
@@ -89,12 +89,7 @@ func GetProjectTotals(entries []models.TimeEntry) map[string]time.Duration {
 	totals := make(map[string]time.Duration)
 
 	for _, e := range entries {
-		var dur time.Duration
-		if e.EndTime.IsZero() {
-			dur = time.Since(e.StartTime)
-		} else {
-			dur = time.Duration(e.Duration) * time.Second
-		}
+		dur := e.TrackedDuration(time.Now())
 
 		projectID := e.ProjectID
 		if projectID == "" {
@@ -156,6 +151,13 @@ type ProjectStats struct {
 	Description string
 	CreatedAt   time.Time
 	UpdatedAt   time.Time
+	// Amount is what the time is worth at the project's standard rates, in
+	// Currency. It is set by the caller, which knows the billing settings.
+	Amount   float64
+	Currency string
+	// Budget is the use of the project's budget, nil if it has none; see
+	// GetProjectsWithBudgets.
+	Budget *BudgetStatus
 }
 
 func GetProjectsWithStats(projects []models.Project, entries []models.TimeEntry) []ProjectStats {
@@ -0,0 +1,185 @@
+package service
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/highercomve/tasktracker/internal/models"
+)
+
+// Kinds of reminders.
+const (
+	ReminderNoTimer       = "no_timer"       // no timer running during work hours
+	ReminderLongRunning   = "long_running"   // the timer has been running for long
+	ReminderDaySummary    = "day_summary"    // the time tracked today
+	ReminderForgottenTask = "forgotten_task" // a task from an earlier day is still active
+)
+
+// ReminderRules configures the reminders. A zero duration turns off its
+// rule.
+type ReminderRules struct {
+	// NoTimer is how long no timer may run during work hours before a
+	// reminder.
+	NoTimer time.Duration
+	// WorkStart and WorkEnd bound the work hours, as offsets from midnight,
+	// on the working days of Calendar.
+	WorkStart, WorkEnd time.Duration
+	Calendar           WorkCalendar
+	// LongRunning is how long a task may run before a reminder.
+	LongRunning time.Duration
+	// DaySummaryAt is the time of day, as an offset from midnight, of the
+	// summary of the day.
+	DaySummary   bool
+	DaySummaryAt time.Duration
+	// ForgottenTask reminds of a task started on an earlier day that is
+	// still running or paused.
+	ForgottenTask bool
+}
+
+// Reminder is a notification due.
+type Reminder struct {
+	Kind string
+	// Entry is the active task, for ReminderLongRunning and
+	// ReminderForgottenTask.
+	Entry models.TimeEntry
+	// Since is when the timer last stopped, or work started, for
+	// ReminderNoTimer.
+	Since time.Time
+	// Total and Entries are the time and the number of entries tracked
+	// today, for ReminderDaySummary.
+	Total   time.Duration
+	Entries int
+}
+
+// ReminderStorage is the subset of the storage API the reminders depend on.
+type ReminderStorage interface {
+	LoadEntriesForRange(start, end time.Time) ([]models.TimeEntry, error)
+}
+
+// ReminderScheduler checks the reminder rules on its own goroutine and
+// notifies each reminder once.
+type ReminderScheduler struct {
+	storage ReminderStorage
+	timer   *Timer
+	clock   Clock
+	rules   func() ReminderRules
+	notify  func(Reminder)
+
+	mu   sync.Mutex
+	sent map[string]bool // keys of the reminders notified
+	stop chan struct{}
+}
+
+// NewReminderScheduler returns a stopped scheduler. rules is called on each
+// check, so changes to the settings apply without a restart; notify runs on
+// the scheduler goroutine.
+func NewReminderScheduler(s ReminderStorage, timer *Timer, clock Clock, rules func() ReminderRules, notify func(Reminder)) *ReminderScheduler {
+	if clock == nil {
+		clock = SystemClock
+	}
+	return &ReminderScheduler{
+		storage: s,
+		timer:   timer,
+		clock:   clock,
+		rules:   rules,
+		notify:  notify,
+		sent:    make(map[string]bool),
+	}
+}
+
+// Start checks the rules every interval until Stop.
+func (s *ReminderScheduler) Start(interval time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stop != nil {
+		return
+	}
+	stop := make(chan struct{})
+	s.stop = stop
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				for _, r := range s.Check() {
+					s.notify(r)
+				}
+			}
+		}
+	}()
+}
+
+// Stop ends the checks started by Start.
+func (s *ReminderScheduler) Stop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stop != nil {
+		close(s.stop)
+		s.stop = nil
+	}
+}
+
+// Check returns the reminders due now that were not returned before.
+func (s *ReminderScheduler) Check() []Reminder {
+	rules := s.rules()
+	now := s.clock.Now()
+	today := startOfDay(now)
+	active, running := s.timer.Active()
+
+	var due []Reminder
+	add := func(key string, r Reminder) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if !s.sent[key] {
+			s.sent[key] = true
+			due = append(due, r)
+		}
+	}
+
+	if rules.ForgottenTask && running && active.StartTime.Before(today) {
+		add(ReminderForgottenTask+"|"+active.ID, Reminder{Kind: ReminderForgottenTask, Entry: active})
+	}
+	if rules.LongRunning > 0 && running && active.State == models.TaskStateRunning && active.TrackedDuration(now) >= rules.LongRunning {
+		add(ReminderLongRunning+"|"+active.ID, Reminder{Kind: ReminderLongRunning, Entry: active})
+	}
+
+	needEntries := (rules.NoTimer > 0 && !running) || rules.DaySummary
+	if !needEntries {
+		return due
+	}
+	entries, err := s.storage.LoadEntriesForRange(today, today)
+	if err != nil {
+		fmt.Printf("warning: failed to load entries for reminders: %v\n", err)
+		return due
+	}
+	entries = SplitByDay(entries, today, today, now)
+
+	if rules.NoTimer > 0 && !running && rules.Calendar.IsWorkingDay(today) {
+		workStart, workEnd := today.Add(rules.WorkStart), today.Add(rules.WorkEnd)
+		since := workStart
+		for _, e := range entries {
+			if until := e.WorkedUntil(now); until.After(since) {
+				since = until
+			}
+		}
+		if now.Before(workEnd) && now.Sub(since) >= rules.NoTimer {
+			add(fmt.Sprintf("%s|%d", ReminderNoTimer, since.Unix()), Reminder{Kind: ReminderNoTimer, Since: since})
+		}
+	}
+
+	if rules.DaySummary && !now.Before(today.Add(rules.DaySummaryAt)) && len(entries) > 0 {
+		r := Reminder{Kind: ReminderDaySummary}
+		ids := make(map[string]bool)
+		for _, e := range entries {
+			r.Total += e.TrackedDuration(now)
+			ids[e.ID] = true
+		}
+		r.Entries = len(ids)
+		add(ReminderDaySummary+"|"+today.Format("2006-01-02"), r)
+	}
+	return due
+}
@@ -0,0 +1,93 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/highercomve/tasktracker/internal/models"
+)
+
+func TestReminderScheduler(t *testing.T) {
+	// Tuesday 8:00
+	timer, s, clock := newTestTimer(t, time.Date(2026, 3, 10, 8, 0, 0, 0, time.Local))
+	rules := ReminderRules{
+		NoTimer:       30 * time.Minute,
+		WorkStart:     9 * time.Hour,
+		WorkEnd:       17 * time.Hour,
+		LongRunning:   3 * time.Hour,
+		DaySummary:    true,
+		DaySummaryAt:  18 * time.Hour,
+		ForgottenTask: true,
+	}
+	scheduler := NewReminderScheduler(s, timer, clock, func() ReminderRules { return rules }, nil)
+	kinds := func(due []Reminder) []string {
+		var k []string
+		for _, r := range due {
+			k = append(k, r.Kind)
+		}
+		return k
+	}
+	expect := func(step string, want ...string) []Reminder {
+		t.Helper()
+		due := scheduler.Check()
+		got := kinds(due)
+		if len(got) != len(want) {
+			t.Fatalf("%s: expected %v, got %v", step, want, got)
+		}
+		for i := range want {
+			if got[i] != want[i] {
+				t.Fatalf("%s: expected %v, got %v", step, want, got)
+			}
+		}
+		return due
+	}
+
+	expect("before work hours")
+	clock.Advance(89 * time.Minute)
+	expect("29 minutes into work hours")
+	clock.Advance(time.Minute)
+	due := expect("30 minutes into work hours", ReminderNoTimer)
+	if !due[0].Since.Equal(time.Date(2026, 3, 10, 9, 0, 0, 0, time.Local)) {
+		t.Errorf("since: %v", due[0].Since)
+	}
+	expect("already reminded")
+
+	timer.Start("Work", "", nil)
+	clock.Advance(3 * time.Hour)
+	expect("running for 3 hours", ReminderLongRunning)
+	timer.Stop()
+
+	clock.Advance(29 * time.Minute)
+	expect("29 minutes after the stop")
+	clock.Advance(time.Minute)
+	expect("30 minutes after the stop", ReminderNoTimer)
+
+	clock.Advance(6 * time.Hour) // 18:00
+	due = expect("end of the day", ReminderDaySummary)
+	if due[0].Total != 3*time.Hour || due[0].Entries != 1 {
+		t.Errorf("summary: %+v", due[0])
+	}
+
+	timer.Start("Late", "", nil)
+	clock.Advance(8 * time.Hour) // past midnight
+	due = expect("the next day", ReminderForgottenTask, ReminderLongRunning)
+	if due[0].Entry.Description != "Late" {
+		t.Errorf("forgotten entry: %+v", due[0].Entry)
+	}
+	timer.Pause()
+
+	// Saturday: no work hours
+	clock.Advance(4 * 24 * time.Hour)
+	timer.Stop()
+	expect("weekend")
+}
+
+func TestReminderRulesOff(t *testing.T) {
+	timer, s, clock := newTestTimer(t, time.Date(2026, 3, 9, 12, 0, 0, 0, time.Local))
+	scheduler := NewReminderScheduler(s, timer, clock, func() ReminderRules { return ReminderRules{} }, nil)
+	s.SaveEntry(models.TimeEntry{ID: "e", StartTime: clock.Now().Add(-time.Hour), EndTime: clock.Now(), Duration: 3600})
+	clock.Advance(12 * time.Hour)
+	if due := scheduler.Check(); len(due) != 0 {
+		t.Errorf("expected no reminders, got %+v", due)
+	}
+}
@@ -0,0 +1,42 @@
+package service
+
+import (
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/highercomve/tasktracker/internal/models"
+)
+
+// ErrInvalidSegments is returned by SetSegments for segments that are empty,
+// end before they start or overlap each other.
+var ErrInvalidSegments = errors.New("segments must end after they start and must not overlap")
+
+// SetSegments replaces the segments of a stopped entry, as edited by the
+// user, and derives StartTime, EndTime and Duration from them. The segments
+// are sorted by start.
+func SetSegments(e *models.TimeEntry, segments []models.Segment) error {
+	if len(segments) == 0 {
+		return ErrInvalidSegments
+	}
+	sorted := slices.Clone(segments)
+	slices.SortFunc(sorted, func(a, b models.Segment) int { return a.Start.Compare(b.Start) })
+
+	var total int64
+	for i, s := range sorted {
+		if !s.End.After(s.Start) {
+			return ErrInvalidSegments
+		}
+		if i > 0 && s.Start.Before(sorted[i-1].End) {
+			return ErrInvalidSegments
+		}
+		total += int64(s.End.Sub(s.Start) / time.Second)
+	}
+
+	e.Segments = sorted
+	e.StartTime = sorted[0].Start
+	e.EndTime = sorted[len(sorted)-1].End
+	e.Duration = total
+	e.State = models.TaskStateStopped
+	return nil
+}
@@ -0,0 +1,55 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/highercomve/tasktracker/internal/models"
+)
+
+func TestSetSegments(t *testing.T) {
+	at := func(h, m int) time.Time { return time.Date(2024, 3, 4, h, m, 0, 0, time.Local) }
+	seg := func(h1, m1, h2, m2 int) models.Segment { return models.Segment{Start: at(h1, m1), End: at(h2, m2)} }
+
+	tests := []struct {
+		name     string
+		segments []models.Segment
+		wantErr  bool
+		start    time.Time
+		end      time.Time
+		duration int64
+	}{
+		{"single", []models.Segment{seg(9, 0, 10, 30)}, false, at(9, 0), at(10, 30), 5400},
+		{"sorted by start", []models.Segment{seg(13, 0, 14, 0), seg(9, 0, 10, 0)}, false, at(9, 0), at(14, 0), 7200},
+		{"touching", []models.Segment{seg(9, 0, 10, 0), seg(10, 0, 11, 0)}, false, at(9, 0), at(11, 0), 7200},
+		{"none", nil, true, time.Time{}, time.Time{}, 0},
+		{"ends before start", []models.Segment{seg(10, 0, 9, 0)}, true, time.Time{}, time.Time{}, 0},
+		{"empty", []models.Segment{seg(9, 0, 9, 0)}, true, time.Time{}, time.Time{}, 0},
+		{"overlapping", []models.Segment{seg(9, 0, 11, 0), seg(10, 0, 12, 0)}, true, time.Time{}, time.Time{}, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := models.TimeEntry{ID: "e", StartTime: at(8, 0), EndTime: at(18, 0), Duration: 36000}
+			err := SetSegments(&e, tt.segments)
+			if tt.wantErr {
+				if err != ErrInvalidSegments {
+					t.Fatalf("expected ErrInvalidSegments, got %v", err)
+				}
+				if e.Duration != 36000 || len(e.Segments) != 0 {
+					t.Errorf("entry changed on error: %+v", e)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("SetSegments: %v", err)
+			}
+			if !e.StartTime.Equal(tt.start) || !e.EndTime.Equal(tt.end) || e.Duration != tt.duration {
+				t.Errorf("got start %v, end %v, duration %d", e.StartTime, e.EndTime, e.Duration)
+			}
+			if e.State != models.TaskStateStopped {
+				t.Errorf("expected stopped state, got %q", e.State)
+			}
+		})
+	}
+}
@@ -0,0 +1,773 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/highercomve/tasktracker/internal/models"
+	"github.com/highercomve/tasktracker/internal/store"
+)
+
+var (
+	// ErrNoActiveTask is returned when an operation needs a running or paused task.
+	ErrNoActiveTask = errors.New("no active task")
+	// ErrTaskNotRunning is returned when pausing a task that is not running.
+	ErrTaskNotRunning = errors.New("task is not running")
+	// ErrTaskNotPaused is returned when resuming a task that is not paused.
+	ErrTaskNotPaused = errors.New("task is not paused")
+	// ErrTaskActive is returned when reopening a task while another one is active.
+	ErrTaskActive = errors.New("another task is active")
+)
+
+// Clock abstracts the current time so the timer can be tested deterministically.
+type Clock interface {
+	Now() time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time { return time.Now() }
+
+// SystemClock is the Clock backed by time.Now.
+var SystemClock Clock = systemClock{}
+
+// TimerStorage is the subset of the storage API the timer depends on.
+type TimerStorage interface {
+	LoadEntries(date time.Time) ([]models.TimeEntry, error)
+	SaveEntry(entry models.TimeEntry) error
+	LoadAppState() (store.AppState, error)
+	SaveAppState(state store.AppState) error
+	ClearAppState() error
+}
+
+// TimerEventType identifies a timer state transition.
+type TimerEventType int
+
+const (
+	TimerStarted TimerEventType = iota
+	TimerPaused
+	TimerResumed
+	TimerStopped
+	// TimerRestored is emitted when the active task is (re)loaded from storage.
+	TimerRestored
+	// TimerCleared is emitted when the active task is dropped without being stopped.
+	TimerCleared
+)
+
+// TimerEvent describes a change of the active task.
+type TimerEvent struct {
+	Type  TimerEventType
+	Entry models.TimeEntry
+}
+
+// Timer owns the running/paused/stopped state machine of the active task and
+// keeps the persisted entry and AppState in sync with it. It is safe for
+// concurrent use; the UI, tray and CLI are all thin clients of it.
+type Timer struct {
+	storage TimerStorage
+	clock   Clock
+
+	// transition runs the check-mutate-save sequences of the transitions
+	// one at a time, so callers on other goroutines (forwarded CLI commands,
+	// the idle, away and focus checks) act on each other's results. mu
+	// guards the fields for readers in between.
+	transition sync.Mutex
+	pending    []TimerEvent // events of the current transition
+
+	mu          sync.RWMutex
+	entry       models.TimeEntry // active entry as last persisted
+	lastStart   time.Time        // start of the current run session
+	accumulated int64            // seconds accumulated before the current run session
+	state       int
+
+	listenersMu sync.Mutex
+	listeners   []func(TimerEvent)
+}
+
+// NewTimer creates a stopped timer. Call Restore to pick up a task that was
+// active when the application last exited.
+func NewTimer(s TimerStorage, clock Clock) *Timer {
+	if clock == nil {
+		clock = SystemClock
+	}
+	return &Timer{
+		storage: s,
+		clock:   clock,
+		state:   models.TaskStateStopped,
+	}
+}
+
+// Subscribe registers fn to be called after every state transition.
+// Listeners run synchronously on the goroutine that caused the transition,
+// once it is over.
+func (t *Timer) Subscribe(fn func(TimerEvent)) {
+	t.listenersMu.Lock()
+	defer t.listenersMu.Unlock()
+	t.listeners = append(t.listeners, fn)
+}
+
+// begin starts a transition, waiting for the one under way to end.
+func (t *Timer) begin() {
+	t.transition.Lock()
+}
+
+// end ends the transition and notifies the listeners of its events, once
+// released so that listeners may drive the timer in turn.
+func (t *Timer) end() {
+	events := t.pending
+	t.pending = nil
+	t.transition.Unlock()
+	for _, ev := range events {
+		t.notify(ev)
+	}
+}
+
+// emit queues an event of the current transition.
+func (t *Timer) emit(eventType TimerEventType, entry models.TimeEntry) {
+	t.pending = append(t.pending, TimerEvent{Type: eventType, Entry: entry})
+}
+
+func (t *Timer) notify(ev TimerEvent) {
+	t.listenersMu.Lock()
+	listeners := make([]func(TimerEvent), len(t.listeners))
+	copy(listeners, t.listeners)
+	t.listenersMu.Unlock()
+
+	for _, fn := range listeners {
+		fn(ev)
+	}
+}
+
+// Now returns the current time according to the timer's clock.
+func (t *Timer) Now() time.Time {
+	return t.clock.Now()
+}
+
+// State returns the state of the active task, or TaskStateStopped if none.
+func (t *Timer) State() int {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.state
+}
+
+// ActiveID returns the ID of the active task, or "" if none.
+func (t *Timer) ActiveID() string {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.entry.ID
+}
+
+// Active returns a snapshot of the active entry, with State and Accumulated
+// reflecting the in-memory values.
+func (t *Timer) Active() (models.TimeEntry, bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	if t.entry.ID == "" {
+		return models.TimeEntry{}, false
+	}
+	entry := t.entry
+	entry.State = t.state
+	entry.Accumulated = t.accumulated
+	return entry, true
+}
+
+// LastStart returns the start of the current run session.
+func (t *Timer) LastStart() time.Time {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.lastStart
+}
+
+// Elapsed returns the tracked time of the active task, including the current
+// run session if it is running.
+func (t *Timer) Elapsed() time.Duration {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.elapsedLocked(t.clock.Now())
+}
+
+func (t *Timer) elapsedLocked(now time.Time) time.Duration {
+	if t.entry.ID == "" {
+		return 0
+	}
+	dur := time.Duration(t.accumulated) * time.Second
+	if t.state == models.TaskStateRunning {
+		dur += now.Sub(t.lastStart)
+	}
+	return dur
+}
+
+// Restore loads the active task from the persisted AppState on startup. It
+// falls back to today's entries for an unfinished task written by versions
+// that predate AppState, and migrates it. Restoring with no active task
+// leaves the timer stopped.
+func (t *Timer) Restore() error {
+	t.begin()
+	defer t.end()
+	return t.restore(true)
+}
+
+// Reload loads the active task from the persisted AppState again, after it
+// changed elsewhere, such as in another instance. Unlike Restore it never
+// takes up an entry the AppState does not name.
+func (t *Timer) Reload() error {
+	t.begin()
+	defer t.end()
+	return t.restore(false)
+}
+
+func (t *Timer) restore(legacy bool) error {
+	state, err := t.storage.LoadAppState()
+	if err == nil && state.ActiveTaskID != "" {
+		entries, err := t.storage.LoadEntries(state.ActiveTaskDate)
+		if err != nil {
+			return err
+		}
+		found := false
+		for _, e := range entries {
+			if e.ID != state.ActiveTaskID {
+				continue
+			}
+			found = true
+			if e.State == models.TaskStateStopped {
+				// Stopped elsewhere without clearing the state.
+				break
+			}
+			// If state is blank (legacy), assume running
+			if e.State == models.TaskStateNone {
+				e.State = models.TaskStateRunning
+			}
+			migrateActiveSegments(&e, state.LastStartTime)
+			t.mu.Lock()
+			t.setActiveLocked(e, state.LastStartTime, e.Accumulated, e.State)
+			t.mu.Unlock()
+			t.emit(TimerRestored, e)
+			return nil
+		}
+		if !found {
+			// Entry not found - log and clear state
+			fmt.Printf("warning: active task %s not found; clearing state\n", state.ActiveTaskID)
+		}
+		t.clearState()
+	}
+
+	// Fallback: Check today's entries for any running task (legacy support)
+	var entries []models.TimeEntry
+	if legacy {
+		if entries, err = t.storage.LoadEntries(t.clock.Now()); err != nil {
+			return err
+		}
+	}
+	for _, e := range entries {
+		if e.EndTime.IsZero() && e.State != models.TaskStateStopped {
+			if e.State == models.TaskStatePaused {
+				// Paused entries postdate AppState: keep them as they are
+				migrateActiveSegments(&e, time.Time{})
+				t.mu.Lock()
+				t.setActiveLocked(e, time.Time{}, e.Accumulated, models.TaskStatePaused)
+				t.mu.Unlock()
+			} else {
+				e.State = models.TaskStateRunning
+				e.Accumulated = 0
+				migrateActiveSegments(&e, e.StartTime)
+				t.mu.Lock()
+				t.setActiveLocked(e, e.StartTime, 0, models.TaskStateRunning)
+				t.mu.Unlock()
+			}
+
+			// Save migrated state
+			if err := t.saveState(); err != nil {
+				return err
+			}
+			t.emit(TimerRestored, e)
+			return nil
+		}
+	}
+
+	t.mu.Lock()
+	wasActive := t.entry.ID != ""
+	t.setActiveLocked(models.TimeEntry{}, time.Time{}, 0, models.TaskStateStopped)
+	t.mu.Unlock()
+	if wasActive {
+		t.emit(TimerCleared, models.TimeEntry{})
+	}
+	return nil
+}
+
+// migrateActiveSegments rebuilds the segments of an active entry saved before
+// segments existed: the time banked by earlier pauses is placed at StartTime
+// and, if running, the current run session starts at lastStart.
+func migrateActiveSegments(e *models.TimeEntry, lastStart time.Time) {
+	if len(e.Segments) > 0 {
+		return
+	}
+	if e.Accumulated > 0 {
+		e.Segments = append(e.Segments, models.Segment{
+			Start: e.StartTime,
+			End:   e.StartTime.Add(time.Duration(e.Accumulated) * time.Second),
+		})
+	}
+	if e.State == models.TaskStateRunning {
+		e.BeginSegment(lastStart)
+	}
+}
+
+func (t *Timer) setActiveLocked(e models.TimeEntry, lastStart time.Time, accumulated int64, state int) {
+	t.entry = e
+	t.lastStart = lastStart
+	t.accumulated = accumulated
+	t.state = state
+}
+
+// saveState persists the active task reference, preserving unrelated fields
+// such as LastRunVersion.
+func (t *Timer) saveState() error {
+	t.mu.RLock()
+	id := t.entry.ID
+	originalStart := t.entry.StartTime
+	lastStart := t.lastStart
+	t.mu.RUnlock()
+
+	state, _ := t.storage.LoadAppState()
+	state.ActiveTaskID = id
+	state.ActiveTaskDate = originalStart
+	state.LastStartTime = lastStart
+	return t.storage.SaveAppState(state)
+}
+
+func (t *Timer) clearState() {
+	state, err := t.storage.LoadAppState()
+	if err != nil {
+		return
+	}
+	if state.LastRunVersion == "" {
+		t.storage.ClearAppState()
+		return
+	}
+	state.ActiveTaskID = ""
+	state.ActiveTaskDate = time.Time{}
+	state.LastStartTime = time.Time{}
+	t.storage.SaveAppState(state)
+}
+
+// Start begins tracking a new task. An active task is stopped first.
+func (t *Timer) Start(desc, projectID string, tags []string) (models.TimeEntry, error) {
+	t.begin()
+	defer t.end()
+
+	// If another task is running, stop it
+	if t.ActiveID() != "" {
+		if _, err := t.stop(t.clock.Now()); err != nil {
+			return models.TimeEntry{}, err
+		}
+	}
+
+	now := t.clock.Now()
+	entry := models.TimeEntry{
+		ID:          uuid.New().String(),
+		Description: desc,
+		ProjectID:   projectID,
+		Tags:        tags,
+		StartTime:   now,
+		State:       models.TaskStateRunning,
+		Accumulated: 0,
+		Segments:    []models.Segment{{Start: now}},
+	}
+
+	if err := t.storage.SaveEntry(entry); err != nil {
+		return models.TimeEntry{}, err
+	}
+
+	t.mu.Lock()
+	t.setActiveLocked(entry, now, 0, models.TaskStateRunning)
+	t.mu.Unlock()
+
+	if err := t.saveState(); err != nil {
+		return entry, err
+	}
+	t.emit(TimerStarted, entry)
+	return entry, nil
+}
+
+// updateActiveEntry writes the in-memory state and accumulated seconds to the
+// persisted entry. StartTime remains the original one.
+func (t *Timer) updateActiveEntry() (models.TimeEntry, error) {
+	t.mu.RLock()
+	entry := t.entry
+	entry.State = t.state
+	entry.Accumulated = t.accumulated
+	t.mu.RUnlock()
+
+	if err := t.storage.SaveEntry(entry); err != nil {
+		return entry, err
+	}
+	t.mu.Lock()
+	t.entry = entry
+	t.mu.Unlock()
+	return entry, nil
+}
+
+// Pause stops the current run session and banks its seconds.
+func (t *Timer) Pause() error {
+	return t.PauseAt(t.clock.Now())
+}
+
+// PauseAt pauses the running task as if Pause had been called at at, which
+// is kept within the current run session.
+func (t *Timer) PauseAt(at time.Time) error {
+	t.begin()
+	defer t.end()
+	return t.pauseAt(at)
+}
+
+func (t *Timer) pauseAt(at time.Time) error {
+	t.mu.Lock()
+	if t.entry.ID == "" {
+		t.mu.Unlock()
+		return ErrNoActiveTask
+	}
+	if t.state != models.TaskStateRunning {
+		t.mu.Unlock()
+		return ErrTaskNotRunning
+	}
+	now := t.inRunLocked(at, t.clock.Now())
+	prevAccumulated := t.accumulated
+	prevState := t.state
+	prevSegments := t.entry.Segments
+	t.accumulated += int64(now.Sub(t.lastStart).Seconds())
+	t.state = models.TaskStatePaused
+	t.entry.EndSegment(now)
+	t.mu.Unlock()
+
+	entry, err := t.updateActiveEntry()
+	if err != nil {
+		// Revert state on error
+		t.mu.Lock()
+		t.accumulated = prevAccumulated
+		t.state = prevState
+		t.entry.Segments = prevSegments
+		t.mu.Unlock()
+		return err
+	}
+	if err := t.saveState(); err != nil {
+		return err
+	}
+	t.emit(TimerPaused, entry)
+	return nil
+}
+
+// Resume starts a new run session of the paused task.
+func (t *Timer) Resume() error {
+	t.begin()
+	defer t.end()
+	return t.resume()
+}
+
+func (t *Timer) resume() error {
+	now := t.clock.Now()
+
+	t.mu.Lock()
+	if t.entry.ID == "" {
+		t.mu.Unlock()
+		return ErrNoActiveTask
+	}
+	if t.state != models.TaskStatePaused {
+		t.mu.Unlock()
+		return ErrTaskNotPaused
+	}
+	prevLastStart := t.lastStart
+	prevState := t.state
+	prevSegments := t.entry.Segments
+	t.lastStart = now
+	t.state = models.TaskStateRunning
+	t.entry.BeginSegment(now)
+	t.mu.Unlock()
+
+	entry, err := t.updateActiveEntry()
+	if err != nil {
+		// Revert state on error
+		t.mu.Lock()
+		t.lastStart = prevLastStart
+		t.state = prevState
+		t.entry.Segments = prevSegments
+		t.mu.Unlock()
+		return err
+	}
+	if err := t.saveState(); err != nil {
+		return err
+	}
+	t.emit(TimerResumed, entry)
+	return nil
+}
+
+// Toggle pauses a running task or resumes a paused one.
+func (t *Timer) Toggle() error {
+	t.begin()
+	defer t.end()
+
+	switch t.State() {
+	case models.TaskStateRunning:
+		return t.pauseAt(t.clock.Now())
+	case models.TaskStatePaused:
+		return t.resume()
+	}
+	return ErrNoActiveTask
+}
+
+// Stop finalizes the active task and returns the stored entry.
+func (t *Timer) Stop() (models.TimeEntry, error) {
+	t.begin()
+	defer t.end()
+	return t.stop(t.clock.Now())
+}
+
+// StopAt finalizes the running task as if it had been stopped at at, such
+// as when the user went idle, and returns the stored entry. at is kept
+// within the current run session.
+func (t *Timer) StopAt(at time.Time) (models.TimeEntry, error) {
+	t.begin()
+	defer t.end()
+
+	t.mu.RLock()
+	if t.entry.ID == "" {
+		t.mu.RUnlock()
+		return models.TimeEntry{}, ErrNoActiveTask
+	}
+	if t.state != models.TaskStateRunning {
+		t.mu.RUnlock()
+		return models.TimeEntry{}, ErrTaskNotRunning
+	}
+	at = t.inRunLocked(at, t.clock.Now())
+	t.mu.RUnlock()
+	return t.stop(at)
+}
+
+// inRunLocked returns at kept between the start of the current run session
+// and now.
+func (t *Timer) inRunLocked(at, now time.Time) time.Time {
+	if at.Before(t.lastStart) {
+		return t.lastStart
+	}
+	if at.After(now) {
+		return now
+	}
+	return at
+}
+
+// stop finalizes the active task at end.
+func (t *Timer) stop(end time.Time) (models.TimeEntry, error) {
+	t.mu.RLock()
+	if t.entry.ID == "" {
+		t.mu.RUnlock()
+		return models.TimeEntry{}, ErrNoActiveTask
+	}
+	entry := t.entry
+	// Calculate final duration
+	entry.Duration = int64(t.elapsedLocked(end).Seconds())
+	entry.Accumulated = t.accumulated // Optional: keep this for record
+	t.mu.RUnlock()
+
+	entry.EndTime = end
+	entry.State = models.TaskStateStopped
+	entry.EndSegment(end)
+
+	if err := t.storage.SaveEntry(entry); err != nil {
+		return models.TimeEntry{}, err
+	}
+
+	t.mu.Lock()
+	t.setActiveLocked(models.TimeEntry{}, time.Time{}, 0, models.TaskStateStopped)
+	t.mu.Unlock()
+	t.clearState()
+
+	t.emit(TimerStopped, entry)
+	return entry, nil
+}
+
+// Clear drops the active task without finalizing it, e.g. after the entry
+// itself was deleted.
+func (t *Timer) Clear() {
+	t.begin()
+	defer t.end()
+
+	t.mu.Lock()
+	t.setActiveLocked(models.TimeEntry{}, time.Time{}, 0, models.TaskStateStopped)
+	t.mu.Unlock()
+	t.clearState()
+	t.emit(TimerCleared, models.TimeEntry{})
+}
+
+// Reopen makes a stopped entry the active task again, undoing Stop. entry
+// carries the State and Accumulated it had while active, as returned by
+// Active, and lastStart is the start of its run session at the time, so the
+// time since the stop is tracked as if the task had never been stopped.
+func (t *Timer) Reopen(entry models.TimeEntry, lastStart time.Time) error {
+	t.begin()
+	defer t.end()
+
+	if t.ActiveID() != "" {
+		return ErrTaskActive
+	}
+	if entry.State != models.TaskStatePaused {
+		entry.State = models.TaskStateRunning
+	}
+	entry.EndTime = time.Time{}
+	entry.Duration = 0
+
+	if err := t.storage.SaveEntry(entry); err != nil {
+		return err
+	}
+
+	t.mu.Lock()
+	t.setActiveLocked(entry, lastStart, entry.Accumulated, entry.State)
+	t.mu.Unlock()
+
+	if err := t.saveState(); err != nil {
+		return err
+	}
+	t.emit(TimerRestored, entry)
+	return nil
+}
+
+// AddFocusSession records a completed focus session on the active task.
+func (t *Timer) AddFocusSession() error {
+	t.begin()
+	defer t.end()
+
+	t.mu.Lock()
+	if t.entry.ID == "" {
+		t.mu.Unlock()
+		return ErrNoActiveTask
+	}
+	t.entry.FocusSessions++
+	t.mu.Unlock()
+
+	if _, err := t.updateActiveEntry(); err != nil {
+		t.mu.Lock()
+		t.entry.FocusSessions--
+		t.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+// DiscardIdle drops the time from since to now from the running task: its
+// run session ends at since and a new one starts now. The time worked
+// before since is kept. since is kept within the current run session.
+func (t *Timer) DiscardIdle(since time.Time) error {
+	t.begin()
+	defer t.end()
+
+	_, err := t.discardIdle(since, t.clock.Now())
+	return err
+}
+
+// discardIdle is DiscardIdle at now. It returns the start of the span
+// dropped, once kept within the run session.
+func (t *Timer) discardIdle(since, now time.Time) (time.Time, error) {
+	t.mu.Lock()
+	if t.entry.ID == "" {
+		t.mu.Unlock()
+		return since, ErrNoActiveTask
+	}
+	if t.state != models.TaskStateRunning {
+		t.mu.Unlock()
+		return since, ErrTaskNotRunning
+	}
+	since = t.inRunLocked(since, now)
+	prevAccumulated := t.accumulated
+	prevLastStart := t.lastStart
+	prevSegments := t.entry.Segments
+	t.accumulated += int64(since.Sub(t.lastStart).Seconds())
+	t.lastStart = now
+	t.entry.EndSegment(since)
+	t.entry.BeginSegment(now)
+	t.mu.Unlock()
+
+	entry, err := t.updateActiveEntry()
+	if err != nil {
+		t.mu.Lock()
+		t.accumulated = prevAccumulated
+		t.lastStart = prevLastStart
+		t.entry.Segments = prevSegments
+		t.mu.Unlock()
+		return since, err
+	}
+	if err := t.saveState(); err != nil {
+		return since, err
+	}
+	t.emit(TimerResumed, entry)
+	return since, nil
+}
+
+// SplitIdle moves the time from since to now out of the running task into a
+// new stopped entry with the given description, project and tags, such as
+// a meeting held away from the computer, and returns it. The task keeps
+// running.
+func (t *Timer) SplitIdle(since time.Time, desc, projectID string, tags []string) (models.TimeEntry, error) {
+	t.begin()
+	defer t.end()
+
+	now := t.clock.Now()
+	since, err := t.discardIdle(since, now)
+	if err != nil {
+		return models.TimeEntry{}, err
+	}
+	if !since.Before(now) {
+		return models.TimeEntry{}, nil
+	}
+	entry := models.TimeEntry{
+		ID:          uuid.New().String(),
+		Description: desc,
+		ProjectID:   projectID,
+		Tags:        tags,
+		StartTime:   since,
+		EndTime:     now,
+		Duration:    int64(now.Sub(since).Seconds()),
+		State:       models.TaskStateStopped,
+		Segments:    []models.Segment{{Start: since, End: now}},
+	}
+	if err := t.storage.SaveEntry(entry); err != nil {
+		return models.TimeEntry{}, err
+	}
+	return entry, nil
+}
+
+// ResetRunStart restarts the current run session at the current time,
+// discarding the time tracked since the session started.
+func (t *Timer) ResetRunStart() error {
+	t.begin()
+	defer t.end()
+
+	t.mu.Lock()
+	if t.state != models.TaskStateRunning {
+		t.mu.Unlock()
+		return ErrTaskNotRunning
+	}
+	prevLastStart := t.lastStart
+	prevSegments := t.entry.Segments
+	t.lastStart = t.clock.Now()
+	if n := len(t.entry.Segments); n > 0 {
+		t.entry.Segments = slices.Clone(t.entry.Segments)
+		t.entry.Segments[n-1].Start = t.lastStart
+	}
+	t.mu.Unlock()
+
+	entry, err := t.updateActiveEntry()
+	if err != nil {
+		t.mu.Lock()
+		t.lastStart = prevLastStart
+		t.entry.Segments = prevSegments
+		t.mu.Unlock()
+		return err
+	}
+	if err := t.saveState(); err != nil {
+		return err
+	}
+	t.emit(TimerResumed, entry)
+	return nil
+}
@@ -0,0 +1,660 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/highercomve/tasktracker/internal/models"
+	"github.com/highercomve/tasktracker/internal/store"
+)
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time { return c.now }
+
+func (c *fakeClock) Advance(d time.Duration) { c.now = c.now.Add(d) }
+
+// failingStorage wraps a Storage and fails SaveEntry on demand.
+type failingStorage struct {
+	*store.Storage
+	failSave bool
+}
+
+func (f *failingStorage) SaveEntry(e models.TimeEntry) error {
+	if f.failSave {
+		return errors.New("disk full")
+	}
+	return f.Storage.SaveEntry(e)
+}
+
+func newTestTimer(t *testing.T, start time.Time) (*Timer, *store.Storage, *fakeClock) {
+	t.Helper()
+	s := store.NewStorage(t.TempDir())
+	clock := &fakeClock{now: start}
+	return NewTimer(s, clock), s, clock
+}
+
+func findEntry(t *testing.T, s *store.Storage, date time.Time, id string) models.TimeEntry {
+	t.Helper()
+	entries, err := s.LoadEntries(date)
+	if err != nil {
+		t.Fatalf("LoadEntries: %v", err)
+	}
+	for _, e := range entries {
+		if e.ID == id {
+			return e
+		}
+	}
+	t.Fatalf("entry %s not found on %s", id, date.Format("2006-01-02"))
+	return models.TimeEntry{}
+}
+
+func TestTimerLifecycle(t *testing.T) {
+	start := time.Date(2026, 3, 10, 9, 0, 0, 0, time.UTC)
+	timer, s, clock := newTestTimer(t, start)
+
+	entry, err := timer.Start("Write tests", "proj-1", []string{"dev"})
+	if err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	if timer.State() != models.TaskStateRunning {
+		t.Fatalf("expected running, got %d", timer.State())
+	}
+
+	clock.Advance(30 * time.Minute)
+	if got := timer.Elapsed(); got != 30*time.Minute {
+		t.Errorf("Elapsed while running: expected 30m, got %v", got)
+	}
+
+	if err := timer.Pause(); err != nil {
+		t.Fatalf("Pause: %v", err)
+	}
+	clock.Advance(time.Hour) // paused time must not count
+	if got := timer.Elapsed(); got != 30*time.Minute {
+		t.Errorf("Elapsed while paused: expected 30m, got %v", got)
+	}
+	if stored := findEntry(t, s, start, entry.ID); stored.State != models.TaskStatePaused || stored.Accumulated != 1800 {
+		t.Errorf("stored paused entry: state=%d accumulated=%d", stored.State, stored.Accumulated)
+	} else if len(stored.Segments) != 1 || !stored.Segments[0].End.Equal(start.Add(30*time.Minute)) {
+		t.Errorf("pause should close the first segment, got %v", stored.Segments)
+	}
+
+	if err := timer.Resume(); err != nil {
+		t.Fatalf("Resume: %v", err)
+	}
+	clock.Advance(15 * time.Minute)
+
+	stopped, err := timer.Stop()
+	if err != nil {
+		t.Fatalf("Stop: %v", err)
+	}
+	if stopped.Duration != 45*60 {
+		t.Errorf("Duration: expected %d, got %d", 45*60, stopped.Duration)
+	}
+	if !stopped.EndTime.Equal(clock.Now()) {
+		t.Errorf("EndTime: expected %v, got %v", clock.Now(), stopped.EndTime)
+	}
+	if timer.ActiveID() != "" || timer.State() != models.TaskStateStopped {
+		t.Errorf("timer should be stopped after Stop")
+	}
+
+	stored := findEntry(t, s, start, entry.ID)
+	if stored.State != models.TaskStateStopped || stored.Duration != 45*60 {
+		t.Errorf("stored entry: state=%d duration=%d", stored.State, stored.Duration)
+	}
+	wantSegments := []models.Segment{
+		{Start: start, End: start.Add(30 * time.Minute)},
+		{Start: start.Add(90 * time.Minute), End: start.Add(105 * time.Minute)},
+	}
+	if !sameSegments(stored.Segments, wantSegments) {
+		t.Errorf("segments: expected %v, got %v", wantSegments, stored.Segments)
+	}
+	if state, err := s.LoadAppState(); err == nil && state.ActiveTaskID != "" {
+		t.Errorf("app state should be cleared, got %+v", state)
+	}
+}
+
+func sameSegments(a, b []models.Segment) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !a[i].Start.Equal(b[i].Start) || !a[i].End.Equal(b[i].End) {
+			return false
+		}
+	}
+	return true
+}
+
+func TestTimerInvalidTransitions(t *testing.T) {
+	timer, _, _ := newTestTimer(t, time.Date(2026, 3, 10, 9, 0, 0, 0, time.UTC))
+
+	if err := timer.Pause(); !errors.Is(err, ErrNoActiveTask) {
+		t.Errorf("Pause without task: expected ErrNoActiveTask, got %v", err)
+	}
+	if err := timer.Resume(); !errors.Is(err, ErrNoActiveTask) {
+		t.Errorf("Resume without task: expected ErrNoActiveTask, got %v", err)
+	}
+	if _, err := timer.Stop(); !errors.Is(err, ErrNoActiveTask) {
+		t.Errorf("Stop without task: expected ErrNoActiveTask, got %v", err)
+	}
+
+	if _, err := timer.Start("Task", "", nil); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	if err := timer.Resume(); !errors.Is(err, ErrTaskNotPaused) {
+		t.Errorf("Resume while running: expected ErrTaskNotPaused, got %v", err)
+	}
+	if err := timer.Pause(); err != nil {
+		t.Fatalf("Pause: %v", err)
+	}
+	if err := timer.Pause(); !errors.Is(err, ErrTaskNotRunning) {
+		t.Errorf("Pause while paused: expected ErrTaskNotRunning, got %v", err)
+	}
+}
+
+func TestTimerStartStopsActiveTask(t *testing.T) {
+	start := time.Date(2026, 3, 10, 9, 0, 0, 0, time.UTC)
+	timer, s, clock := newTestTimer(t, start)
+
+	first, _ := timer.Start("First", "", nil)
+	clock.Advance(10 * time.Minute)
+	second, err := timer.Start("Second", "", nil)
+	if err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+
+	if stored := findEntry(t, s, start, first.ID); stored.State != models.TaskStateStopped || stored.Duration != 600 {
+		t.Errorf("first entry not stopped: state=%d duration=%d", stored.State, stored.Duration)
+	}
+	if timer.ActiveID() != second.ID {
+		t.Errorf("expected second task to be active")
+	}
+}
+
+func TestTimerCrashRecovery(t *testing.T) {
+	start := time.Date(2026, 3, 10, 9, 0, 0, 0, time.UTC)
+
+	t.Run("running", func(t *testing.T) {
+		timer, s, clock := newTestTimer(t, start)
+		entry, _ := timer.Start("Running task", "", nil)
+		clock.Advance(20 * time.Minute)
+
+		// Simulate a restart: a new timer on the same data folder.
+		clock.Advance(5 * time.Minute)
+		restored := NewTimer(s, clock)
+		if err := restored.Restore(); err != nil {
+			t.Fatalf("Restore: %v", err)
+		}
+		if restored.ActiveID() != entry.ID || restored.State() != models.TaskStateRunning {
+			t.Fatalf("expected running task %s, got %q state %d", entry.ID, restored.ActiveID(), restored.State())
+		}
+		// The time the app was down still counts while running.
+		if got := restored.Elapsed(); got != 25*time.Minute {
+			t.Errorf("Elapsed: expected 25m, got %v", got)
+		}
+	})
+
+	t.Run("paused", func(t *testing.T) {
+		timer, s, clock := newTestTimer(t, start)
+		entry, _ := timer.Start("Paused task", "", nil)
+		clock.Advance(20 * time.Minute)
+		timer.Pause()
+		clock.Advance(3 * time.Hour)
+
+		restored := NewTimer(s, clock)
+		if err := restored.Restore(); err != nil {
+			t.Fatalf("Restore: %v", err)
+		}
+		if restored.ActiveID() != entry.ID || restored.State() != models.TaskStatePaused {
+			t.Fatalf("expected paused task, got state %d", restored.State())
+		}
+		if got := restored.Elapsed(); got != 20*time.Minute {
+			t.Errorf("Elapsed: expected 20m, got %v", got)
+		}
+		if err := restored.Resume(); err != nil {
+			t.Fatalf("Resume after restore: %v", err)
+		}
+		clock.Advance(10 * time.Minute)
+		stopped, _ := restored.Stop()
+		if stopped.Duration != 30*60 {
+			t.Errorf("Duration: expected %d, got %d", 30*60, stopped.Duration)
+		}
+	})
+
+	t.Run("missing entry clears state", func(t *testing.T) {
+		_, s, clock := newTestTimer(t, start)
+		s.SaveAppState(store.AppState{ActiveTaskID: "gone", ActiveTaskDate: start, LastStartTime: start, LastRunVersion: "v1.0.0"})
+
+		restored := NewTimer(s, clock)
+		if err := restored.Restore(); err != nil {
+			t.Fatalf("Restore: %v", err)
+		}
+		if restored.ActiveID() != "" {
+			t.Errorf("expected no active task")
+		}
+		state, _ := s.LoadAppState()
+		if state.ActiveTaskID != "" || state.LastRunVersion != "v1.0.0" {
+			t.Errorf("expected cleared task and preserved version, got %+v", state)
+		}
+	})
+}
+
+func TestTimerReopen(t *testing.T) {
+	start := time.Date(2026, 3, 10, 9, 0, 0, 0, time.UTC)
+	timer, s, clock := newTestTimer(t, start)
+
+	entry, _ := timer.Start("Review", "", nil)
+	clock.Advance(20 * time.Minute)
+	active, _ := timer.Active()
+	lastStart := timer.LastStart()
+	if _, err := timer.Stop(); err != nil {
+		t.Fatalf("Stop: %v", err)
+	}
+
+	clock.Advance(5 * time.Minute)
+	if err := timer.Reopen(active, lastStart); err != nil {
+		t.Fatalf("Reopen: %v", err)
+	}
+	if timer.ActiveID() != entry.ID || timer.State() != models.TaskStateRunning {
+		t.Fatalf("reopened task not active: id=%s state=%d", timer.ActiveID(), timer.State())
+	}
+	if got := timer.Elapsed(); got != 25*time.Minute {
+		t.Errorf("Elapsed after reopen: expected 25m, got %v", got)
+	}
+	if stored := findEntry(t, s, start, entry.ID); !stored.EndTime.IsZero() || stored.State != models.TaskStateRunning {
+		t.Errorf("stored entry still stopped: %+v", stored)
+	}
+	if state, _ := s.LoadAppState(); state.ActiveTaskID != entry.ID {
+		t.Errorf("app state not restored: %+v", state)
+	}
+
+	if err := timer.Reopen(active, lastStart); !errors.Is(err, ErrTaskActive) {
+		t.Errorf("Reopen while active: expected ErrTaskActive, got %v", err)
+	}
+}
+
+func TestTimerCrossMidnight(t *testing.T) {
+	start := time.Date(2026, 3, 10, 23, 30, 0, 0, time.UTC)
+	timer, s, clock := newTestTimer(t, start)
+
+	entry, _ := timer.Start("Night shift", "", nil)
+	clock.Advance(45 * time.Minute) // 00:15 on the next day
+
+	// Restart after midnight: the entry lives in the start day's file.
+	restored := NewTimer(s, clock)
+	if err := restored.Restore(); err != nil {
+		t.Fatalf("Restore: %v", err)
+	}
+	if restored.ActiveID() != entry.ID {
+		t.Fatalf("expected task started before midnight to be restored")
+	}
+
+	clock.Advance(30 * time.Minute)
+	stopped, err := restored.Stop()
+	if err != nil {
+		t.Fatalf("Stop: %v", err)
+	}
+	if stopped.Duration != 75*60 {
+		t.Errorf("Duration: expected %d, got %d", 75*60, stopped.Duration)
+	}
+	stored := findEntry(t, s, start, entry.ID)
+	if stored.State != models.TaskStateStopped || stored.EndTime.Day() != 11 {
+		t.Errorf("stored entry: state=%d end=%v", stored.State, stored.EndTime)
+	}
+	if next, _ := s.LoadEntries(clock.Now()); len(next) != 0 {
+		t.Errorf("expected no entries filed under the second day, got %d", len(next))
+	}
+}
+
+func TestTimerLegacyEntries(t *testing.T) {
+	start := time.Date(2026, 3, 10, 9, 0, 0, 0, time.UTC)
+
+	t.Run("state zero with app state", func(t *testing.T) {
+		_, s, clock := newTestTimer(t, start)
+		legacy := models.TimeEntry{ID: "legacy", Description: "Old", StartTime: start}
+		s.SaveEntry(legacy)
+		s.SaveAppState(store.AppState{ActiveTaskID: "legacy", ActiveTaskDate: start, LastStartTime: start})
+		clock.Advance(time.Hour)
+
+		timer := NewTimer(s, clock)
+		if err := timer.Restore(); err != nil {
+			t.Fatalf("Restore: %v", err)
+		}
+		if timer.State() != models.TaskStateRunning {
+			t.Errorf("legacy entry with State == 0 should be running, got %d", timer.State())
+		}
+		if got := timer.Elapsed(); got != time.Hour {
+			t.Errorf("Elapsed: expected 1h, got %v", got)
+		}
+	})
+
+	t.Run("unfinished entry without app state", func(t *testing.T) {
+		_, s, clock := newTestTimer(t, start)
+		legacy := models.TimeEntry{ID: "legacy", Description: "Old", StartTime: start}
+		s.SaveEntry(legacy)
+		clock.Advance(2 * time.Hour)
+
+		timer := NewTimer(s, clock)
+		if err := timer.Restore(); err != nil {
+			t.Fatalf("Restore: %v", err)
+		}
+		if timer.ActiveID() != "legacy" || timer.State() != models.TaskStateRunning {
+			t.Fatalf("expected legacy entry to be running")
+		}
+		if got := timer.Elapsed(); got != 2*time.Hour {
+			t.Errorf("Elapsed: expected 2h, got %v", got)
+		}
+		state, err := s.LoadAppState()
+		if err != nil || state.ActiveTaskID != "legacy" {
+			t.Errorf("expected migrated app state, got %+v (%v)", state, err)
+		}
+	})
+
+	t.Run("paused entry without segments", func(t *testing.T) {
+		_, s, clock := newTestTimer(t, start)
+		legacy := models.TimeEntry{ID: "legacy", StartTime: start, State: models.TaskStatePaused, Accumulated: 1800}
+		s.SaveEntry(legacy)
+		s.SaveAppState(store.AppState{ActiveTaskID: "legacy", ActiveTaskDate: start, LastStartTime: start})
+		clock.Advance(time.Hour)
+
+		timer := NewTimer(s, clock)
+		if err := timer.Restore(); err != nil {
+			t.Fatalf("Restore: %v", err)
+		}
+		resumed := clock.Now()
+		timer.Resume()
+		clock.Advance(10 * time.Minute)
+		stopped, err := timer.Stop()
+		if err != nil {
+			t.Fatalf("Stop: %v", err)
+		}
+		want := []models.Segment{
+			{Start: start, End: start.Add(30 * time.Minute)},
+			{Start: resumed, End: clock.Now()},
+		}
+		if !sameSegments(stopped.Segments, want) || stopped.Duration != 40*60 {
+			t.Errorf("expected segments %v and 40m, got %v and %ds", want, stopped.Segments, stopped.Duration)
+		}
+	})
+
+	t.Run("paused entry without app state", func(t *testing.T) {
+		_, s, clock := newTestTimer(t, start)
+		paused := models.TimeEntry{ID: "paused", StartTime: start, State: models.TaskStatePaused, Accumulated: 1800}
+		s.SaveEntry(paused)
+		clock.Advance(time.Hour)
+
+		timer := NewTimer(s, clock)
+		if err := timer.Restore(); err != nil {
+			t.Fatalf("Restore: %v", err)
+		}
+		if timer.ActiveID() != "paused" || timer.State() != models.TaskStatePaused || timer.Elapsed() != 30*time.Minute {
+			t.Errorf("expected the entry to stay paused at 30m, got state %d and %v", timer.State(), timer.Elapsed())
+		}
+	})
+
+	t.Run("stopped entry referenced by state", func(t *testing.T) {
+		_, s, clock := newTestTimer(t, start)
+		done := models.TimeEntry{ID: "done", StartTime: start, EndTime: start.Add(time.Hour), Duration: 3600, State: models.TaskStateStopped}
+		s.SaveEntry(done)
+		s.SaveAppState(store.AppState{ActiveTaskID: "done", ActiveTaskDate: start, LastStartTime: start})
+
+		timer := NewTimer(s, clock)
+		if err := timer.Restore(); err != nil {
+			t.Fatalf("Restore: %v", err)
+		}
+		if timer.ActiveID() != "" {
+			t.Errorf("stopped entry must not be restored as active")
+		}
+	})
+}
+
+func TestTimerReload(t *testing.T) {
+	start := time.Date(2026, 3, 10, 9, 0, 0, 0, time.UTC)
+	timer, s, clock := newTestTimer(t, start)
+	entry, _ := timer.Start("Task", "", nil)
+	clock.Advance(30 * time.Minute)
+	timer.Pause()
+
+	// Another instance, or a sync tool, drops the state but not the entry
+	clock.Advance(time.Hour)
+	s.ClearAppState()
+	if err := timer.Reload(); err != nil {
+		t.Fatalf("Reload: %v", err)
+	}
+	if timer.ActiveID() != "" {
+		t.Errorf("reload took up an entry the state does not name")
+	}
+	if stored := findEntry(t, s, start, entry.ID); stored.State != models.TaskStatePaused || stored.Accumulated != 1800 {
+		t.Errorf("paused entry changed: %+v", stored)
+	}
+
+	// A task started elsewhere is picked up
+	other := NewTimer(s, clock)
+	started, _ := other.Start("Elsewhere", "", nil)
+	if err := timer.Reload(); err != nil {
+		t.Fatalf("Reload: %v", err)
+	}
+	if timer.ActiveID() != started.ID || timer.State() != models.TaskStateRunning {
+		t.Errorf("expected %s running, got %s", started.ID, timer.ActiveID())
+	}
+}
+
+func TestTimerEvents(t *testing.T) {
+	timer, _, clock := newTestTimer(t, time.Date(2026, 3, 10, 9, 0, 0, 0, time.UTC))
+
+	var got []TimerEventType
+	timer.Subscribe(func(ev TimerEvent) { got = append(got, ev.Type) })
+
+	timer.Start("Task", "", nil)
+	clock.Advance(time.Minute)
+	timer.Pause()
+	timer.Resume()
+	timer.Stop()
+	timer.Start("Another", "", nil)
+	timer.Clear()
+
+	want := []TimerEventType{TimerStarted, TimerPaused, TimerResumed, TimerStopped, TimerStarted, TimerCleared}
+	if len(got) != len(want) {
+		t.Fatalf("expected events %v, got %v", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("event %d: expected %v, got %v", i, want[i], got[i])
+		}
+	}
+}
+
+func TestTimerRevertsOnSaveError(t *testing.T) {
+	start := time.Date(2026, 3, 10, 9, 0, 0, 0, time.UTC)
+	s := &failingStorage{Storage: store.NewStorage(t.TempDir())}
+	clock := &fakeClock{now: start}
+	timer := NewTimer(s, clock)
+
+	timer.Start("Task", "", nil)
+	clock.Advance(10 * time.Minute)
+
+	s.failSave = true
+	if err := timer.Pause(); err == nil {
+		t.Fatalf("expected Pause to fail")
+	}
+	if timer.State() != models.TaskStateRunning {
+		t.Errorf("state should be reverted to running, got %d", timer.State())
+	}
+	if got := timer.Elapsed(); got != 10*time.Minute {
+		t.Errorf("Elapsed after failed pause: expected 10m, got %v", got)
+	}
+}
+
+func TestTimerIdleHandling(t *testing.T) {
+	start := time.Date(2026, 3, 10, 9, 0, 0, 0, time.UTC)
+	idleSince := start.Add(40 * time.Minute)
+
+	tests := []struct {
+		name string
+		// handle deals with the idle time at 10:00; the task, if still
+		// active, is stopped at 10:10.
+		handle           func(timer *Timer) error
+		expectDuration   time.Duration
+		expectEnd        time.Time
+		expectSegments   []models.Segment
+		expectSplit      time.Duration
+		expectSplitStart time.Time
+	}{
+		{
+			name:           "Keep",
+			handle:         func(*Timer) error { return nil },
+			expectDuration: 70 * time.Minute,
+			expectEnd:      start.Add(70 * time.Minute),
+			expectSegments: []models.Segment{{Start: start, End: start.Add(70 * time.Minute)}},
+		},
+		{
+			name:           "Discard",
+			handle:         func(timer *Timer) error { return timer.DiscardIdle(idleSince) },
+			expectDuration: 50 * time.Minute,
+			expectEnd:      start.Add(70 * time.Minute),
+			expectSegments: []models.Segment{{Start: start, End: idleSince}, {Start: start.Add(time.Hour), End: start.Add(70 * time.Minute)}},
+		},
+		{
+			name: "Stop at the start of the idle time",
+			handle: func(timer *Timer) error {
+				_, err := timer.StopAt(idleSince)
+				return err
+			},
+			expectDuration: 40 * time.Minute,
+			expectEnd:      idleSince,
+			expectSegments: []models.Segment{{Start: start, End: idleSince}},
+		},
+		{
+			name: "Split into a meeting",
+			handle: func(timer *Timer) error {
+				_, err := timer.SplitIdle(idleSince, "Meeting", "proj-2", []string{"meetings"})
+				return err
+			},
+			expectDuration:   50 * time.Minute,
+			expectEnd:        start.Add(70 * time.Minute),
+			expectSegments:   []models.Segment{{Start: start, End: idleSince}, {Start: start.Add(time.Hour), End: start.Add(70 * time.Minute)}},
+			expectSplit:      20 * time.Minute,
+			expectSplitStart: idleSince,
+		},
+		{
+			name:           "Idle since before the run session",
+			handle:         func(timer *Timer) error { return timer.DiscardIdle(start.Add(-time.Hour)) },
+			expectDuration: 10 * time.Minute,
+			expectEnd:      start.Add(70 * time.Minute),
+			expectSegments: []models.Segment{{Start: start, End: start}, {Start: start.Add(time.Hour), End: start.Add(70 * time.Minute)}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			timer, s, clock := newTestTimer(t, start)
+			entry, err := timer.Start("Write code", "proj-1", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			clock.Advance(time.Hour)
+			if err := tt.handle(timer); err != nil {
+				t.Fatalf("handle: %v", err)
+			}
+			clock.Advance(10 * time.Minute)
+			if timer.ActiveID() != "" {
+				if _, err := timer.Stop(); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			got := findEntry(t, s, start, entry.ID)
+			if time.Duration(got.Duration)*time.Second != tt.expectDuration || !got.EndTime.Equal(tt.expectEnd) {
+				t.Errorf("expected %v ending %v, got %v ending %v", tt.expectDuration, tt.expectEnd, time.Duration(got.Duration)*time.Second, got.EndTime)
+			}
+			if len(got.Segments) != len(tt.expectSegments) {
+				t.Fatalf("expected segments %v, got %v", tt.expectSegments, got.Segments)
+			}
+			for i, want := range tt.expectSegments {
+				if !got.Segments[i].Start.Equal(want.Start) || !got.Segments[i].End.Equal(want.End) {
+					t.Errorf("segment %d: expected %v, got %v", i, want, got.Segments[i])
+				}
+			}
+
+			entries, _ := s.LoadEntries(start)
+			var split *models.TimeEntry
+			for i := range entries {
+				if entries[i].ID != entry.ID {
+					split = &entries[i]
+				}
+			}
+			if tt.expectSplit == 0 {
+				if split != nil {
+					t.Errorf("unexpected entry %+v", split)
+				}
+				return
+			}
+			if split == nil {
+				t.Fatal("split entry not saved")
+			}
+			if split.Description != "Meeting" || split.ProjectID != "proj-2" || split.State != models.TaskStateStopped ||
+				time.Duration(split.Duration)*time.Second != tt.expectSplit || !split.StartTime.Equal(tt.expectSplitStart) {
+				t.Errorf("split entry: %+v", split)
+			}
+		})
+	}
+}
+
+func TestTimerIdleHandlingNeedsRunningTask(t *testing.T) {
+	timer, _, _ := newTestTimer(t, time.Date(2026, 3, 10, 9, 0, 0, 0, time.UTC))
+	if err := timer.DiscardIdle(time.Now()); err != ErrNoActiveTask {
+		t.Errorf("DiscardIdle: expected ErrNoActiveTask, got %v", err)
+	}
+	timer.Start("Task", "", nil)
+	timer.Pause()
+	if _, err := timer.StopAt(time.Now()); err != ErrTaskNotRunning {
+		t.Errorf("StopAt: expected ErrTaskNotRunning, got %v", err)
+	}
+	if _, err := timer.SplitIdle(time.Now(), "Meeting", "", nil); err != ErrTaskNotRunning {
+		t.Errorf("SplitIdle: expected ErrTaskNotRunning, got %v", err)
+	}
+}
+
+func TestTimerConcurrentStarts(t *testing.T) {
+	start := time.Date(2026, 3, 10, 9, 0, 0, 0, time.UTC)
+	s := store.NewStorage(t.TempDir())
+	timer := NewTimer(s, &fakeClock{now: start})
+	// Listeners may drive the timer in turn
+	timer.Subscribe(func(ev TimerEvent) {
+		if ev.Type == TimerStarted {
+			timer.State()
+		}
+	})
+
+	var wg sync.WaitGroup
+	for i := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := timer.Start(fmt.Sprintf("Task %d", i), "", nil); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	entries, err := s.LoadEntries(start)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var running []string
+	for _, e := range entries {
+		if e.State != models.TaskStateStopped {
+			running = append(running, e.ID)
+		}
+	}
+	if len(entries) != 8 || len(running) != 1 || running[0] != timer.ActiveID() {
+		t.Errorf("expected 8 entries with only the active one running, got %d with %v running, %s active", len(entries), running, timer.ActiveID())
+	}
+}
@@ -0,0 +1,133 @@
+package service
+
+import (
+	"errors"
+	"sync"
+)
+
+var (
+	// ErrNothingToUndo is returned by Undo when the stack is empty.
+	ErrNothingToUndo = errors.New("nothing to undo")
+	// ErrNothingToRedo is returned by Redo when no action has been undone.
+	ErrNothingToRedo = errors.New("nothing to redo")
+)
+
+// UndoAction is a change that has already been made and knows how to revert
+// and reapply itself.
+type UndoAction struct {
+	// Label describes the change to the user, e.g. "Task deleted".
+	Label string
+	Undo  func() error
+	Redo  func() error
+}
+
+// UndoEventType identifies a change of the undo stack.
+type UndoEventType int
+
+const (
+	ActionDone UndoEventType = iota
+	ActionUndone
+	ActionRedone
+)
+
+// UndoEvent describes a change of the undo stack.
+type UndoEvent struct {
+	Type   UndoEventType
+	Action UndoAction
+}
+
+// UndoStack records the destructive actions made through the UI so they can
+// be undone and redone in order. It is safe for concurrent use.
+type UndoStack struct {
+	mu     sync.Mutex
+	done   []UndoAction
+	undone []UndoAction
+	limit  int
+
+	listenersMu sync.Mutex
+	listeners   []func(UndoEvent)
+}
+
+// NewUndoStack creates a stack keeping the last limit actions.
+func NewUndoStack(limit int) *UndoStack {
+	return &UndoStack{limit: limit}
+}
+
+// Subscribe registers fn to be called after an action is pushed, undone or
+// redone. Listeners run synchronously on the calling goroutine.
+func (u *UndoStack) Subscribe(fn func(UndoEvent)) {
+	u.listenersMu.Lock()
+	defer u.listenersMu.Unlock()
+	u.listeners = append(u.listeners, fn)
+}
+
+func (u *UndoStack) emit(eventType UndoEventType, a UndoAction) {
+	u.listenersMu.Lock()
+	listeners := make([]func(UndoEvent), len(u.listeners))
+	copy(listeners, u.listeners)
+	u.listenersMu.Unlock()
+
+	for _, fn := range listeners {
+		fn(UndoEvent{Type: eventType, Action: a})
+	}
+}
+
+// Push records an action that has just been made. Anything undone before is
+// no longer redoable.
+func (u *UndoStack) Push(a UndoAction) {
+	u.mu.Lock()
+	u.done = append(u.done, a)
+	if u.limit > 0 && len(u.done) > u.limit {
+		u.done = u.done[len(u.done)-u.limit:]
+	}
+	u.undone = nil
+	u.mu.Unlock()
+
+	u.emit(ActionDone, a)
+}
+
+// CanUndo reports whether there is an action to undo.
+func (u *UndoStack) CanUndo() bool {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return len(u.done) > 0
+}
+
+// CanRedo reports whether there is an undone action to redo.
+func (u *UndoStack) CanRedo() bool {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return len(u.undone) > 0
+}
+
+// Undo reverts the most recent action. If reverting fails the action stays
+// on the stack.
+func (u *UndoStack) Undo() (UndoAction, error) {
+	return u.apply(&u.done, &u.undone, ErrNothingToUndo, ActionUndone, func(a UndoAction) error { return a.Undo() })
+}
+
+// Redo reapplies the most recently undone action.
+func (u *UndoStack) Redo() (UndoAction, error) {
+	return u.apply(&u.undone, &u.done, ErrNothingToRedo, ActionRedone, func(a UndoAction) error { return a.Redo() })
+}
+
+// apply runs the top action of from and moves it to to. The stack lock is
+// held throughout, so concurrent Undo and Redo calls run one at a time.
+func (u *UndoStack) apply(from, to *[]UndoAction, empty error, eventType UndoEventType, run func(UndoAction) error) (UndoAction, error) {
+	u.mu.Lock()
+	if len(*from) == 0 {
+		u.mu.Unlock()
+		return UndoAction{}, empty
+	}
+	a := (*from)[len(*from)-1]
+	if err := run(a); err != nil {
+		u.mu.Unlock()
+		return a, err
+	}
+	*from = (*from)[:len(*from)-1]
+	*to = append(*to, a)
+	u.mu.Unlock()
+
+	u.emit(eventType, a)
+	return a, nil
+}
@@ -0,0 +1,64 @@
+package service
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestUndoStack(t *testing.T) {
+	u := NewUndoStack(2)
+	var events []UndoEventType
+	u.Subscribe(func(e UndoEvent) { events = append(events, e.Type) })
+
+	value := 0
+	set := func(label string, from, to int) UndoAction {
+		value = to
+		return UndoAction{
+			Label: label,
+			Undo:  func() error { value = from; return nil },
+			Redo:  func() error { value = to; return nil },
+		}
+	}
+
+	if _, err := u.Undo(); !errors.Is(err, ErrNothingToUndo) {
+		t.Errorf("Undo on empty stack: got %v", err)
+	}
+	u.Push(set("one", 0, 1))
+	u.Push(set("two", 1, 2))
+	u.Push(set("three", 2, 3)) // drops "one"
+
+	if a, err := u.Undo(); err != nil || a.Label != "three" || value != 2 {
+		t.Fatalf("Undo: %+v %v value=%d", a, err, value)
+	}
+	if a, err := u.Undo(); err != nil || a.Label != "two" || value != 1 {
+		t.Fatalf("Undo: %+v %v value=%d", a, err, value)
+	}
+	if u.CanUndo() {
+		t.Errorf("stack limit not applied")
+	}
+	if a, err := u.Redo(); err != nil || a.Label != "two" || value != 2 {
+		t.Fatalf("Redo: %+v %v value=%d", a, err, value)
+	}
+
+	// A new action discards what was undone.
+	u.Push(set("four", 2, 4))
+	if u.CanRedo() {
+		t.Errorf("redo left after a new action")
+	}
+
+	// A failing undo keeps the action on the stack.
+	u.Push(UndoAction{Label: "broken", Undo: func() error { return errors.New("boom") }})
+	if _, err := u.Undo(); err == nil || !u.CanUndo() {
+		t.Errorf("failed undo: err=%v canUndo=%v", err, u.CanUndo())
+	}
+
+	want := []UndoEventType{ActionDone, ActionDone, ActionDone, ActionUndone, ActionUndone, ActionRedone, ActionDone, ActionDone}
+	if len(events) != len(want) {
+		t.Fatalf("events: expected %v, got %v", want, events)
+	}
+	for i := range want {
+		if events[i] != want[i] {
+			t.Errorf("event %d: expected %v, got %v", i, want[i], events[i])
+		}
+	}
+}
@@ -0,0 +1,101 @@
+// Package session tells when the desktop session locks or the system
+// suspends, and when the user is back.
+//
+// On Linux the events come from logind, over the system D-Bus, and from
+// the screensaver of the desktop, over the session bus. Elsewhere there is
+// no source yet.
+package session
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrUnavailable is returned by sources that cannot watch this session.
+var ErrUnavailable = errors.New("session events unavailable")
+
+// Kind is what happened to the session.
+type Kind int
+
+// Kinds of events.
+const (
+	Locked   Kind = iota // the screen locked
+	Unlocked             // the screen unlocked
+	Sleeping             // the system is about to suspend
+	Woke                 // the system resumed from suspend
+)
+
+// Away reports whether the user leaves on k, rather than comes back.
+func (k Kind) Away() bool {
+	return k == Locked || k == Sleeping
+}
+
+func (k Kind) String() string {
+	switch k {
+	case Locked:
+		return "locked"
+	case Unlocked:
+		return "unlocked"
+	case Sleeping:
+		return "sleeping"
+	case Woke:
+		return "woke"
+	}
+	return "unknown"
+}
+
+// Event is a change of the session at a time.
+type Event struct {
+	Kind Kind
+	At   time.Time
+}
+
+// Source delivers the events of the session.
+type Source interface {
+	// Watch calls handle with each event, on a goroutine of its own, until
+	// Close. The system waits for handle to return on Sleeping, for a few
+	// seconds at most, before it suspends.
+	Watch(handle func(Event)) error
+	Close() error
+}
+
+// System returns the source of the events of this session, or nil where
+// there is none.
+func System() Source {
+	return systemSource()
+}
+
+// Fake is a Source for tests and simulations that delivers the events
+// passed to Send.
+type Fake struct {
+	mu     sync.Mutex
+	handle func(Event)
+}
+
+// Watch makes Send call handle.
+func (f *Fake) Watch(handle func(Event)) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.handle = handle
+	return nil
+}
+
+// Close stops the delivery of events.
+func (f *Fake) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.handle = nil
+	return nil
+}
+
+// Send delivers an event of kind at at, and returns once it is handled. It
+// does nothing unless watched.
+func (f *Fake) Send(kind Kind, at time.Time) {
+	f.mu.Lock()
+	handle := f.handle
+	f.mu.Unlock()
+	if handle != nil {
+		handle(Event{Kind: kind, At: at})
+	}
+}
@@ -0,0 +1,223 @@
+//go:build linux
+
+package session
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+)
+
+const (
+	logindDest   = "org.freedesktop.login1"
+	logindPath   = dbus.ObjectPath("/org/freedesktop/login1")
+	logindPrefix = "org.freedesktop.login1."
+)
+
+// dbusTimeout bounds a call, so a stuck bus does not stall the app.
+const dbusTimeout = 2 * time.Second
+
+// systemSource returns the logind and screensaver source.
+func systemSource() Source {
+	return &logind{}
+}
+
+// logind watches suspends and locks through logind on the system bus, and
+// locks through the screensaver of the desktop on the session bus, as not
+// every desktop tells logind when it locks.
+type logind struct {
+	mu        sync.Mutex
+	system    *dbus.Conn
+	session   *dbus.Conn
+	inhibitor *os.File // delays suspends until Sleeping is handled
+	done      chan struct{}
+}
+
+func (l *logind) Watch(handle func(Event)) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.done != nil {
+		return nil
+	}
+
+	system, err := dbus.ConnectSystemBus()
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+	if err := system.AddMatchSignal(
+		dbus.WithMatchObjectPath(logindPath),
+		dbus.WithMatchInterface(logindPrefix+"Manager"),
+		dbus.WithMatchMember("PrepareForSleep"),
+	); err != nil {
+		system.Close()
+		return fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+	// Without a session, e.g. when started by a user service, only
+	// suspends are seen on the system bus.
+	if path, err := sessionPath(system); err == nil {
+		system.AddMatchSignal(dbus.WithMatchObjectPath(path), dbus.WithMatchInterface(logindPrefix+"Session"))
+		system.AddMatchSignal(
+			dbus.WithMatchObjectPath(path),
+			dbus.WithMatchInterface("org.freedesktop.DBus.Properties"),
+			dbus.WithMatchMember("PropertiesChanged"),
+		)
+	}
+	systemSignals := make(chan *dbus.Signal, 8)
+	system.Signal(systemSignals)
+
+	var sessionSignals chan *dbus.Signal
+	if conn, err := dbus.ConnectSessionBus(); err == nil {
+		l.session = conn
+		sessionSignals = make(chan *dbus.Signal, 8)
+		for _, iface := range []string{"org.freedesktop.ScreenSaver", "org.gnome.ScreenSaver"} {
+			conn.AddMatchSignal(dbus.WithMatchInterface(iface), dbus.WithMatchMember("ActiveChanged"))
+		}
+		conn.Signal(sessionSignals)
+	}
+
+	l.system = system
+	l.done = make(chan struct{})
+	l.inhibitLocked()
+	go l.loop(handle, systemSignals, sessionSignals, l.done)
+	return nil
+}
+
+// loop handles the signals of both buses until done is closed.
+func (l *logind) loop(handle func(Event), system, session <-chan *dbus.Signal, done <-chan struct{}) {
+	for {
+		var sig *dbus.Signal
+		var ok bool
+		select {
+		case <-done:
+			return
+		case sig, ok = <-system:
+		case sig, ok = <-session:
+		}
+		if !ok {
+			return
+		}
+		kind, ok := signalKind(sig)
+		if !ok {
+			continue
+		}
+		handle(Event{Kind: kind, At: time.Now()})
+
+		l.mu.Lock()
+		switch kind {
+		case Sleeping:
+			l.releaseLocked()
+		case Woke:
+			l.inhibitLocked()
+		}
+		l.mu.Unlock()
+	}
+}
+
+// signalKind returns the kind of event sig tells, or false if none.
+func signalKind(sig *dbus.Signal) (Kind, bool) {
+	switch sig.Name {
+	case logindPrefix + "Manager.PrepareForSleep":
+		if v, ok := firstBool(sig.Body); ok {
+			return choose(v, Sleeping, Woke), true
+		}
+	case logindPrefix + "Session.Lock":
+		return Locked, true
+	case logindPrefix + "Session.Unlock":
+		return Unlocked, true
+	case "org.freedesktop.DBus.Properties.PropertiesChanged":
+		// LockedHint is kept up to date by GNOME and KDE
+		if len(sig.Body) < 2 || sig.Body[0] != logindPrefix+"Session" {
+			return 0, false
+		}
+		changed, ok := sig.Body[1].(map[string]dbus.Variant)
+		if !ok {
+			return 0, false
+		}
+		if v, ok := changed["LockedHint"].Value().(bool); ok {
+			return choose(v, Locked, Unlocked), true
+		}
+	case "org.freedesktop.ScreenSaver.ActiveChanged", "org.gnome.ScreenSaver.ActiveChanged":
+		if v, ok := firstBool(sig.Body); ok {
+			return choose(v, Locked, Unlocked), true
+		}
+	}
+	return 0, false
+}
+
+func firstBool(body []any) (bool, bool) {
+	if len(body) == 0 {
+		return false, false
+	}
+	v, ok := body[0].(bool)
+	return v, ok
+}
+
+func choose(v bool, yes, no Kind) Kind {
+	if v {
+		return yes
+	}
+	return no
+}
+
+// sessionPath returns the object path of the logind session of the app,
+// or the graphical session of the user when the app is outside any.
+func sessionPath(conn *dbus.Conn) (dbus.ObjectPath, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), dbusTimeout)
+	defer cancel()
+	manager := conn.Object(logindDest, logindPath)
+	var path dbus.ObjectPath
+	err := manager.CallWithContext(ctx, logindPrefix+"Manager.GetSession", 0, "auto").Store(&path)
+	if err != nil {
+		err = manager.CallWithContext(ctx, logindPrefix+"Manager.GetSessionByPID", 0, uint32(os.Getpid())).Store(&path)
+	}
+	return path, err
+}
+
+// inhibitLocked takes a delay lock on suspends, so Sleeping is handled
+// before the system sleeps. Without one the event may only be handled on
+// resume, after the time asleep.
+func (l *logind) inhibitLocked() {
+	if l.inhibitor != nil || l.system == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), dbusTimeout)
+	defer cancel()
+	var fd dbus.UnixFD
+	err := l.system.Object(logindDest, logindPath).CallWithContext(ctx, logindPrefix+"Manager.Inhibit", 0,
+		"sleep", "TaskTracker", "Pause the running task", "delay").Store(&fd)
+	if err != nil {
+		fmt.Printf("warning: failed to delay suspends: %v\n", err)
+		return
+	}
+	l.inhibitor = os.NewFile(uintptr(fd), "logind-inhibitor")
+}
+
+// releaseLocked lets the system suspend.
+func (l *logind) releaseLocked() {
+	if l.inhibitor != nil {
+		l.inhibitor.Close()
+		l.inhibitor = nil
+	}
+}
+
+func (l *logind) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.done == nil {
+		return nil
+	}
+	close(l.done)
+	l.done = nil
+	l.releaseLocked()
+	if l.session != nil {
+		l.session.Close()
+		l.session = nil
+	}
+	err := l.system.Close()
+	l.system = nil
+	return err
+}
@@ -0,0 +1,78 @@
+//go:build linux
+
+package session
+
+import (
+	"testing"
+
+	"github.com/godbus/dbus/v5"
+)
+
+func TestSignalKind(t *testing.T) {
+	tests := []struct {
+		name   string
+		signal *dbus.Signal
+		expect Kind
+		ok     bool
+	}{
+		{
+			name:   "Prepare for sleep",
+			signal: &dbus.Signal{Name: "org.freedesktop.login1.Manager.PrepareForSleep", Body: []any{true}},
+			expect: Sleeping,
+			ok:     true,
+		},
+		{
+			name:   "Resume",
+			signal: &dbus.Signal{Name: "org.freedesktop.login1.Manager.PrepareForSleep", Body: []any{false}},
+			expect: Woke,
+			ok:     true,
+		},
+		{
+			name:   "Session lock",
+			signal: &dbus.Signal{Name: "org.freedesktop.login1.Session.Lock"},
+			expect: Locked,
+			ok:     true,
+		},
+		{
+			name:   "Session unlock",
+			signal: &dbus.Signal{Name: "org.freedesktop.login1.Session.Unlock"},
+			expect: Unlocked,
+			ok:     true,
+		},
+		{
+			name: "Locked hint",
+			signal: &dbus.Signal{
+				Name: "org.freedesktop.DBus.Properties.PropertiesChanged",
+				Body: []any{"org.freedesktop.login1.Session", map[string]dbus.Variant{"LockedHint": dbus.MakeVariant(true)}, []string{}},
+			},
+			expect: Locked,
+			ok:     true,
+		},
+		{
+			name: "Other property",
+			signal: &dbus.Signal{
+				Name: "org.freedesktop.DBus.Properties.PropertiesChanged",
+				Body: []any{"org.freedesktop.login1.Session", map[string]dbus.Variant{"IdleHint": dbus.MakeVariant(true)}, []string{}},
+			},
+		},
+		{
+			name:   "Screensaver off",
+			signal: &dbus.Signal{Name: "org.gnome.ScreenSaver.ActiveChanged", Body: []any{false}},
+			expect: Unlocked,
+			ok:     true,
+		},
+		{
+			name:   "Malformed",
+			signal: &dbus.Signal{Name: "org.freedesktop.ScreenSaver.ActiveChanged", Body: []any{"yes"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kind, ok := signalKind(tt.signal)
+			if ok != tt.ok || (ok && kind != tt.expect) {
+				t.Errorf("expected %v %v, got %v %v", tt.expect, tt.ok, kind, ok)
+			}
+		})
+	}
+}
@@ -0,0 +1,8 @@
+//go:build !linux
+
+package session
+
+// systemSource returns no source: only Linux sessions are watched for now.
+func systemSource() Source {
+	return nil
+}
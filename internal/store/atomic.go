@@ -0,0 +1,121 @@
+package store
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// backupSuffix is appended to a file's path for the copy of its previous
+// version.
+const backupSuffix = ".bak"
+
+// writeFileAtomic replaces path with data so that readers and crashes see
+// either the old or the new content, never a truncated file. The data is
+// written to a temporary file in the same directory, fsynced and renamed over
+// path. The previous version, if any, is kept as path.bak, unless it is
+// damaged: then it is kept aside with a .corrupt-<timestamp> suffix and the
+// backup is left as it is. Callers hold the exclusive lock of the folder,
+// so this is where damaged files are repaired; readers only fall back to the
+// backup.
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmpPath, err := writeTemp(path, data, perm)
+	if err != nil {
+		return err
+	}
+
+	// Rotate the current version into the backup slot. If we crash before
+	// the next rename, readJSONFile falls back to it.
+	if current, err := os.ReadFile(path); err == nil {
+		dest := path + backupSuffix
+		if !json.Valid(current) {
+			dest = fmt.Sprintf("%s.corrupt-%s", path, time.Now().Format("20060102-150405"))
+			log.Printf("Warning: %s could not be parsed; replaced it (damaged copy kept as %s)", path, filepath.Base(dest))
+		}
+		if err := os.Rename(path, dest); err != nil {
+			os.Remove(tmpPath)
+			return err
+		}
+	}
+	return commitTemp(tmpPath, path)
+}
+
+// writeTemp writes data to a synced temporary file next to path and returns
+// its name.
+func writeTemp(path string, data []byte, perm os.FileMode) (string, error) {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return "", err
+	}
+	tmpPath := tmp.Name()
+
+	_, err = tmp.Write(data)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Chmod(tmpPath, perm)
+	}
+	if err != nil {
+		os.Remove(tmpPath)
+		return "", err
+	}
+	return tmpPath, nil
+}
+
+// commitTemp renames tmpPath over path and flushes the directory so the
+// rename itself survives a power loss. Not every platform can sync a
+// directory, so that error is ignored.
+func commitTemp(tmpPath, path string) error {
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	if d, err := os.Open(filepath.Dir(path)); err == nil {
+		d.Sync()
+		d.Close()
+	}
+	return nil
+}
+
+// readJSONFile decodes path into v. When path is missing or cannot be parsed
+// but path.bak can, the backup is used instead; the files are left as they
+// are for the next write to repair, as readers only hold the shared lock.
+// An error satisfying os.IsNotExist is returned when neither file exists.
+func readJSONFile(path string, v any) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			return err
+		}
+		// A crash between the two renames in writeFileAtomic leaves
+		// only the backup behind.
+		if readBackup(path, v) != nil {
+			return err
+		}
+		return nil
+	}
+
+	if perr := json.Unmarshal(data, v); perr != nil {
+		if rerr := readBackup(path, v); rerr != nil {
+			return fmt.Errorf("failed to parse %s: %w", path, perr)
+		}
+		log.Printf("Warning: %s could not be parsed; using %s until the next save", path, filepath.Base(path+backupSuffix))
+	}
+	return nil
+}
+
+// readBackup decodes path.bak into v.
+func readBackup(path string, v any) error {
+	data, err := os.ReadFile(path + backupSuffix)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
@@ -0,0 +1,179 @@
+package store
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteFileAtomicKeepsBackup(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "data.json")
+
+	if err := writeFileAtomic(path, []byte(`["v1"]`), 0644); err != nil {
+		t.Fatalf("first write: %v", err)
+	}
+	if _, err := os.Stat(path + backupSuffix); !os.IsNotExist(err) {
+		t.Errorf("no backup expected after the first write")
+	}
+	if err := writeFileAtomic(path, []byte(`["v2"]`), 0644); err != nil {
+		t.Fatalf("second write: %v", err)
+	}
+
+	if data, _ := os.ReadFile(path); string(data) != `["v2"]` {
+		t.Errorf("current: got %s", data)
+	}
+	if data, _ := os.ReadFile(path + backupSuffix); string(data) != `["v1"]` {
+		t.Errorf("backup: got %s", data)
+	}
+
+	files, _ := os.ReadDir(dir)
+	for _, f := range files {
+		if strings.Contains(f.Name(), ".tmp-") {
+			t.Errorf("temporary file left behind: %s", f.Name())
+		}
+	}
+}
+
+func TestLoadEntriesRecoversFromBackup(t *testing.T) {
+	day := time.Date(2024, 2, 20, 9, 0, 0, 0, time.Local)
+
+	t.Run("truncated file", func(t *testing.T) {
+		dir := t.TempDir()
+		s := NewStorage(dir)
+		s.SaveEntry(entryAt("a", day, ""))
+		s.SaveEntry(entryAt("b", day.Add(time.Hour), ""))
+
+		// Simulate a torn write of the day file.
+		path := s.getEntryFilePath(day)
+		data, _ := os.ReadFile(path)
+		os.WriteFile(path, data[:len(data)/2], 0644)
+
+		entries, err := s.LoadEntries(day)
+		if err != nil {
+			t.Fatalf("LoadEntries: %v", err)
+		}
+		// The backup holds the version before the last save.
+		if !sameIDs(ids(entries), []string{"a"}) {
+			t.Errorf("recovered entries: got %v", ids(entries))
+		}
+		// Readers leave the repair to the next write.
+		if current, _ := os.ReadFile(path); len(current) != len(data)/2 {
+			t.Errorf("day file changed by a read: %s", current)
+		}
+
+		// Saving again works on top of the backup and keeps the damaged
+		// file aside.
+		if err := s.SaveEntry(entryAt("c", day.Add(2*time.Hour), "")); err != nil {
+			t.Fatalf("SaveEntry after recovery: %v", err)
+		}
+		if entries, _ := s.LoadEntries(day); !sameIDs(ids(entries), []string{"a", "c"}) {
+			t.Errorf("after save: got %v", ids(entries))
+		}
+		matches, _ := filepath.Glob(path + ".corrupt-*")
+		if len(matches) != 1 {
+			t.Errorf("expected the damaged file to be kept aside, got %v", matches)
+		}
+		if backup, _ := os.ReadFile(path + backupSuffix); !strings.Contains(string(backup), `"id": "a"`) || strings.Contains(string(backup), `"id": "b"`) {
+			t.Errorf("backup replaced by the damaged file: %s", backup)
+		}
+	})
+
+	t.Run("missing file with backup", func(t *testing.T) {
+		dir := t.TempDir()
+		s := NewStorage(dir)
+		s.SaveEntry(entryAt("a", day, ""))
+		s.SaveEntry(entryAt("b", day.Add(time.Hour), ""))
+
+		// Simulate a crash between rotating the backup and the final rename.
+		os.Remove(s.getEntryFilePath(day))
+
+		entries, err := s.LoadEntriesForRange(day, day)
+		if err != nil || !sameIDs(ids(entries), []string{"a"}) {
+			t.Errorf("LoadEntriesForRange: got %v (%v)", ids(entries), err)
+		}
+	})
+
+	t.Run("concurrent readers", func(t *testing.T) {
+		dir := t.TempDir()
+		s := NewStorage(dir)
+		s.SaveEntry(entryAt("a", day, ""))
+		s.SaveEntry(entryAt("b", day.Add(time.Hour), ""))
+		os.WriteFile(s.getEntryFilePath(day), []byte(`[{"id": "a"`), 0644)
+
+		errs := make(chan error, 8)
+		for range cap(errs) {
+			go func() {
+				// Each reader its own Storage, as separate processes
+				_, err := NewStorage(dir).LoadEntries(day)
+				errs <- err
+			}()
+		}
+		for range cap(errs) {
+			if err := <-errs; err != nil {
+				t.Errorf("LoadEntries: %v", err)
+			}
+		}
+	})
+
+	t.Run("corrupt file without backup", func(t *testing.T) {
+		dir := t.TempDir()
+		s := NewStorage(dir)
+		os.WriteFile(s.getEntryFilePath(day), []byte(`[{"id": "a"`), 0644)
+
+		if _, err := s.LoadEntries(day); err == nil {
+			t.Errorf("expected a parse error")
+		}
+		if err := s.SaveEntry(entryAt("b", day, "")); err == nil {
+			t.Errorf("SaveEntry must not overwrite an unreadable day file")
+		}
+	})
+}
+
+func TestClearAppStateRemovesBackup(t *testing.T) {
+	s := NewStorage(t.TempDir())
+	s.SaveAppState(AppState{ActiveTaskID: "a"})
+	s.SaveAppState(AppState{ActiveTaskID: "b"})
+
+	if err := s.ClearAppState(); err != nil {
+		t.Fatalf("ClearAppState: %v", err)
+	}
+	if state, err := s.LoadAppState(); !os.IsNotExist(err) {
+		t.Errorf("expected no state after clear, got %+v (%v)", state, err)
+	}
+}
+
+func TestConcurrentWritersDoNotLoseEntries(t *testing.T) {
+	dir := t.TempDir()
+	day := time.Date(2024, 3, 1, 9, 0, 0, 0, time.Local)
+
+	// Two Storage values on one folder stand in for two processes: they
+	// share no mutex, only the lock file.
+	a, b := NewStorage(dir), NewStorage(dir)
+	const perWriter = 25
+	done := make(chan struct{})
+	for w, s := range []*Storage{a, b} {
+		go func() {
+			for i := 0; i < perWriter; i++ {
+				id := fmt.Sprintf("w%d-%d", w, i)
+				if err := s.SaveEntry(entryAt(id, day.Add(time.Duration(i)*time.Minute), "")); err != nil {
+					t.Errorf("SaveEntry(%s): %v", id, err)
+				}
+			}
+			done <- struct{}{}
+		}()
+	}
+	<-done
+	<-done
+
+	entries, err := a.LoadEntries(day)
+	if err != nil {
+		t.Fatalf("LoadEntries: %v", err)
+	}
+	if len(entries) != 2*perWriter {
+		t.Errorf("expected %d entries, got %d", 2*perWriter, len(entries))
+	}
+}
@@ -0,0 +1,250 @@
+package store
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/highercomve/tasktracker/internal/models"
+)
+
+// Conflict is an entry edited differently on two machines where the
+// modification timestamps cannot tell which edit is newer, or an entry
+// deleted on this machine and edited on the other one afterwards.
+type Conflict struct {
+	// Path is the conflict copy the remote version came from.
+	Path   string
+	Local  models.TimeEntry
+	Remote models.TimeEntry
+	// DeletedAt is when the entry was deleted on this machine, in which case
+	// Local is its last version. Zero otherwise.
+	DeletedAt time.Time
+}
+
+// ConflictMerger is implemented by stores whose files can be duplicated by
+// sync tools (Syncthing, Nextcloud, Dropbox) when edited on two machines.
+type ConflictMerger interface {
+	// MergeConflicts folds every conflict copy into its day file. Entries
+	// are matched by ID and the newer UpdatedAt wins. Edits that cannot be
+	// ordered are returned and stay in the copy until resolved.
+	MergeConflicts() ([]Conflict, error)
+	// ResolveConflict saves keep as the winning version of c. Keeping Local
+	// of a deleted entry keeps it deleted.
+	ResolveConflict(c Conflict, keep models.TimeEntry) error
+}
+
+// conflictCopyPattern matches the names sync tools give to conflicting
+// copies of YYYY-MM-DD.json, e.g. "2026-10-01 (conflict).json",
+// "2026-10-01 (conflicted copy 2026-10-02 101010).json" or
+// "2026-10-01.sync-conflict-20261002-101010-ABCDEFG.json".
+var conflictCopyPattern = regexp.MustCompile(`^(\d{4}-\d{2}-\d{2})(.*conflict.*)\.json$`)
+
+// conflictCopyDay returns the day a conflict copy belongs to.
+func conflictCopyDay(name string) (time.Time, bool) {
+	m := conflictCopyPattern.FindStringSubmatch(strings.ToLower(name))
+	if m == nil {
+		return time.Time{}, false
+	}
+	day, err := time.ParseInLocation("2006-01-02", m[1], time.Local)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return day, true
+}
+
+// conflictCopies lists the conflict copies in the entries folder, oldest
+// day first.
+func (s *Storage) conflictCopies() ([]string, error) {
+	dir := filepath.Join(s.BaseDir, "entries")
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var paths []string
+	for _, f := range files {
+		if f.IsDir() {
+			continue
+		}
+		if _, ok := conflictCopyDay(f.Name()); ok {
+			paths = append(paths, filepath.Join(dir, f.Name()))
+		}
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+// MergeConflicts folds every conflict copy in the entries folder into its day
+// file. Entries only present on one side are kept and the side with the newer
+// UpdatedAt wins. Entries edited on both sides with equal or missing
+// timestamps are returned as conflicts; the local version stays in the day
+// file and the remote one in the copy until ResolveConflict is called.
+// Entries deleted here are not brought back by an older remote version; a
+// remote edit made after the deletion is returned as a conflict.
+func (s *Storage) MergeConflicts() ([]Conflict, error) {
+	s.mu.Lock()
+	unlock, err := s.lockDir(true)
+	if err != nil {
+		s.mu.Unlock()
+		return nil, err
+	}
+
+	var conflicts []Conflict
+	var changedDays []time.Time
+	var deleted map[string]models.EntryChange
+	copies, err := s.conflictCopies()
+	if err == nil && len(copies) > 0 {
+		deleted, err = s.deletions()
+		if err != nil {
+			copies = nil
+		}
+	}
+	for _, path := range copies {
+		day, _ := conflictCopyDay(filepath.Base(path))
+		found, changed, merr := s.mergeCopy(path, day, deleted)
+		if merr != nil {
+			err = fmt.Errorf("failed to merge %s: %w", filepath.Base(path), merr)
+			break
+		}
+		conflicts = append(conflicts, found...)
+		if changed {
+			changedDays = append(changedDays, day)
+		}
+	}
+	unlock()
+	s.mu.Unlock()
+
+	// The merged data came from another machine, so subscribers are told as
+	// for any other foreign change.
+	for _, day := range changedDays {
+		s.publish(Change{Kind: EntriesChanged, Date: day})
+	}
+	return conflicts, err
+}
+
+// deletions returns the latest deletion recorded in the change log for each
+// deleted entry. Callers hold s.mu and the folder lock.
+func (s *Storage) deletions() (map[string]models.EntryChange, error) {
+	changes, err := readHistory(filepath.Join(s.BaseDir, historyFileName), func(c models.EntryChange) bool {
+		return c.Action == models.EntryDeleted && c.Before != nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	deleted := make(map[string]models.EntryChange, len(changes))
+	for _, c := range changes {
+		if c.At.After(deleted[c.EntryID].At) {
+			deleted[c.EntryID] = c
+		}
+	}
+	return deleted, nil
+}
+
+// mergeCopy merges one conflict copy into the day file, given the entries
+// deleted here. Callers hold s.mu and the folder lock.
+func (s *Storage) mergeCopy(path string, day time.Time, deleted map[string]models.EntryChange) ([]Conflict, bool, error) {
+	var remote []models.TimeEntry
+	if err := readJSONFile(path, &remote); err != nil {
+		return nil, false, err
+	}
+	mainPath := s.getEntryFilePath(day)
+	var local []models.TimeEntry
+	if err := readJSONFile(mainPath, &local); err != nil && !os.IsNotExist(err) {
+		return nil, false, err
+	}
+
+	index := make(map[string]int, len(local))
+	for i, e := range local {
+		index[e.ID] = i
+	}
+
+	changed := false
+	var conflicts []Conflict
+	var unresolved []models.TimeEntry
+	for _, r := range remote {
+		i, ok := index[r.ID]
+		del, wasDeleted := deleted[r.ID]
+		switch {
+		case !ok && wasDeleted && del.At.After(r.UpdatedAt):
+			// Deleted here after the remote edit; keep it deleted.
+		case !ok && wasDeleted:
+			conflicts = append(conflicts, Conflict{Path: path, Local: *del.Before, Remote: r, DeletedAt: del.At})
+			unresolved = append(unresolved, r)
+		case !ok:
+			local = append(local, r)
+			index[r.ID] = len(local) - 1
+			changed = true
+		case reflect.DeepEqual(local[i], r):
+		case r.UpdatedAt.After(local[i].UpdatedAt):
+			local[i] = r
+			changed = true
+		case local[i].UpdatedAt.After(r.UpdatedAt):
+			// Local edit is newer; drop the remote one.
+		default:
+			conflicts = append(conflicts, Conflict{Path: path, Local: local[i], Remote: r})
+			unresolved = append(unresolved, r)
+		}
+	}
+
+	if changed {
+		if err := s.writeJSON(mainPath, local); err != nil {
+			return nil, false, err
+		}
+	}
+	if err := s.rewriteCopy(path, unresolved); err != nil {
+		return nil, changed, err
+	}
+	return conflicts, changed, nil
+}
+
+// rewriteCopy leaves only the unresolved entries in a conflict copy and
+// deletes it when none remain.
+func (s *Storage) rewriteCopy(path string, unresolved []models.TimeEntry) error {
+	if len(unresolved) == 0 {
+		s.noteRemove(path)
+		os.Remove(path + backupSuffix)
+		return os.Remove(path)
+	}
+	return s.writeJSON(path, unresolved)
+}
+
+// ResolveConflict saves keep as the winning version of c and removes the
+// remote version from the conflict copy. Keeping Local of an entry deleted
+// here only drops the remote version.
+func (s *Storage) ResolveConflict(c Conflict, keep models.TimeEntry) error {
+	if c.DeletedAt.IsZero() || !reflect.DeepEqual(keep, c.Local) {
+		if err := s.SaveEntry(keep); err != nil {
+			return err
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	unlock, err := s.lockDir(true)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	var remote []models.TimeEntry
+	if err := readJSONFile(c.Path, &remote); err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	var rest []models.TimeEntry
+	for _, e := range remote {
+		if e.ID != c.Remote.ID {
+			rest = append(rest, e)
+		}
+	}
+	return s.rewriteCopy(c.Path, rest)
+}
@@ -0,0 +1,186 @@
+package store
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/highercomve/tasktracker/internal/models"
+)
+
+func TestConflictCopyDay(t *testing.T) {
+	tests := []struct {
+		name string
+		ok   bool
+	}{
+		{"2026-10-01 (conflict).json", true},
+		{"2026-10-01 (conflicted copy 2026-10-02 101010).json", true},
+		{"2026-10-01 (Ana's conflicted copy 2026-10-02).json", true},
+		{"2026-10-01.sync-conflict-20261002-101010-ABCDEFG.json", true},
+		{"2026-10-01.json", false},
+		{"2026-10-01 (conflict).json.bak", false},
+		{"notes (conflict).json", false},
+	}
+	for _, tt := range tests {
+		day, ok := conflictCopyDay(tt.name)
+		if ok != tt.ok {
+			t.Errorf("%q: expected %v, got %v", tt.name, tt.ok, ok)
+		}
+		if ok && day.Format("2006-01-02") != "2026-10-01" {
+			t.Errorf("%q: got day %v", tt.name, day)
+		}
+	}
+}
+
+func writeCopy(t *testing.T, path string, entries []models.TimeEntry) {
+	t.Helper()
+	data, _ := json.Marshal(entries)
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMergeConflicts(t *testing.T) {
+	dir := t.TempDir()
+	s := NewStorage(dir)
+	day := time.Date(2026, 10, 1, 9, 0, 0, 0, time.Local)
+	t0 := time.Date(2026, 10, 1, 18, 0, 0, 0, time.Local)
+
+	stamp := func(e models.TimeEntry, at time.Time) models.TimeEntry {
+		e.UpdatedAt = at
+		return e
+	}
+	same := stamp(entryAt("same", day, ""), t0)
+	newerRemote := stamp(entryAt("newer-remote", day.Add(time.Hour), ""), t0)
+	newerLocal := stamp(entryAt("newer-local", day.Add(2*time.Hour), ""), t0.Add(time.Minute))
+	clash := entryAt("clash", day.Add(3*time.Hour), "") // legacy: no timestamp
+
+	writeCopy(t, s.getEntryFilePath(day), []models.TimeEntry{same, newerRemote, newerLocal, clash})
+
+	remoteNewer := newerRemote
+	remoteNewer.Description = "edited remotely"
+	remoteNewer.UpdatedAt = t0.Add(time.Hour)
+	remoteOlder := newerLocal
+	remoteOlder.Description = "stale remote edit"
+	remoteOlder.UpdatedAt = t0
+	remoteClash := clash
+	remoteClash.Description = "clashing edit"
+	onlyRemote := stamp(entryAt("only-remote", day.Add(4*time.Hour), ""), t0)
+
+	copyPath := filepath.Join(dir, "entries", "2026-10-01 (conflicted copy 2026-10-02 101010).json")
+	writeCopy(t, copyPath, []models.TimeEntry{same, remoteNewer, remoteOlder, remoteClash, onlyRemote})
+
+	var published []Change
+	s.watch.listeners = append(s.watch.listeners, func(c Change) { published = append(published, c) })
+
+	conflicts, err := s.MergeConflicts()
+	if err != nil {
+		t.Fatalf("MergeConflicts: %v", err)
+	}
+	if len(conflicts) != 1 || conflicts[0].Local.ID != "clash" || conflicts[0].Remote.Description != "clashing edit" {
+		t.Fatalf("conflicts: got %+v", conflicts)
+	}
+	if len(published) != 1 || published[0].Kind != EntriesChanged {
+		t.Errorf("expected one EntriesChanged, got %+v", published)
+	}
+
+	byID := map[string]models.TimeEntry{}
+	entries, _ := s.LoadEntries(day)
+	for _, e := range entries {
+		byID[e.ID] = e
+	}
+	if len(entries) != 5 {
+		t.Errorf("expected 5 merged entries, got %v", ids(entries))
+	}
+	if byID["newer-remote"].Description != "edited remotely" {
+		t.Errorf("newer remote edit lost: %+v", byID["newer-remote"])
+	}
+	if byID["newer-local"].Description != "task newer-local" {
+		t.Errorf("newer local edit overwritten: %+v", byID["newer-local"])
+	}
+	if byID["clash"].Description != "task clash" {
+		t.Errorf("unresolved conflict must keep the local version: %+v", byID["clash"])
+	}
+
+	// The copy keeps only the unresolved remote version.
+	var left []models.TimeEntry
+	readJSONFile(copyPath, &left)
+	if !sameIDs(ids(left), []string{"clash"}) {
+		t.Errorf("copy after merge: got %v", ids(left))
+	}
+
+	if err := s.ResolveConflict(conflicts[0], conflicts[0].Remote); err != nil {
+		t.Fatalf("ResolveConflict: %v", err)
+	}
+	if _, err := os.Stat(copyPath); !os.IsNotExist(err) {
+		t.Errorf("conflict copy should be removed once resolved")
+	}
+	entries, _ = s.LoadEntries(day)
+	for _, e := range entries {
+		if e.ID == "clash" && (e.Description != "clashing edit" || e.UpdatedAt.IsZero()) {
+			t.Errorf("resolved entry: %+v", e)
+		}
+	}
+
+	if conflicts, _ := s.MergeConflicts(); len(conflicts) != 0 {
+		t.Errorf("nothing left to merge, got %+v", conflicts)
+	}
+}
+
+func TestMergeConflictsDeletedEntries(t *testing.T) {
+	dir := t.TempDir()
+	s := NewStorage(dir)
+	day := time.Date(2026, 10, 1, 9, 0, 0, 0, time.Local)
+
+	stale := entryAt("stale", day, "")
+	edited := entryAt("edited", day.Add(time.Hour), "")
+	kept := entryAt("kept", day.Add(2*time.Hour), "")
+	for _, e := range []models.TimeEntry{stale, edited, kept} {
+		if err := s.SaveEntry(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.DeleteEntry(stale); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.DeleteEntry(edited); err != nil {
+		t.Fatal(err)
+	}
+
+	// The other machine still has both entries: one as it was before the
+	// deletion, the other edited after it.
+	stale.UpdatedAt = time.Now().Add(-time.Hour)
+	edited.Description = "edited remotely"
+	edited.UpdatedAt = time.Now().Add(time.Hour)
+	copyPath := filepath.Join(dir, "entries", "2026-10-01 (conflict).json")
+	writeCopy(t, copyPath, []models.TimeEntry{stale, edited})
+
+	conflicts, err := s.MergeConflicts()
+	if err != nil {
+		t.Fatalf("MergeConflicts: %v", err)
+	}
+	if len(conflicts) != 1 || conflicts[0].Remote.ID != "edited" || conflicts[0].Local.Description != "task edited" || conflicts[0].DeletedAt.IsZero() {
+		t.Fatalf("conflicts: got %+v", conflicts)
+	}
+	if entries, _ := s.LoadEntries(day); !sameIDs(ids(entries), []string{"kept"}) {
+		t.Errorf("deleted entries came back: %v", ids(entries))
+	}
+	var left []models.TimeEntry
+	readJSONFile(copyPath, &left)
+	if !sameIDs(ids(left), []string{"edited"}) {
+		t.Errorf("copy after merge: got %v", ids(left))
+	}
+
+	// Keeping the local side keeps the entry deleted.
+	if err := s.ResolveConflict(conflicts[0], conflicts[0].Local); err != nil {
+		t.Fatalf("ResolveConflict: %v", err)
+	}
+	if entries, _ := s.LoadEntries(day); !sameIDs(ids(entries), []string{"kept"}) {
+		t.Errorf("resolving as deleted restored the entry: %v", ids(entries))
+	}
+	if _, err := os.Stat(copyPath); !os.IsNotExist(err) {
+		t.Errorf("conflict copy should be removed once resolved")
+	}
+}
@@ -0,0 +1,127 @@
+package store
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/highercomve/tasktracker/internal/models"
+)
+
+// historyFileName is the entry change log of the JSON backend, one
+// models.EntryChange per line. Lines are only ever appended.
+const historyFileName = "history.jsonl"
+
+// newEntryChange describes the step from before to after, either of which
+// may be nil. It reports false when nothing but UpdatedAt differs, so saving
+// an unchanged entry leaves no record.
+func newEntryChange(before, after *models.TimeEntry, at time.Time) (models.EntryChange, bool) {
+	c := models.EntryChange{At: at, Before: before, After: after}
+	switch {
+	case before == nil && after == nil:
+		return c, false
+	case before == nil:
+		c.EntryID, c.Action = after.ID, models.EntryCreated
+	case after == nil:
+		c.EntryID, c.Action = before.ID, models.EntryDeleted
+	default:
+		if sameContent(*before, *after) {
+			return c, false
+		}
+		c.EntryID, c.Action = after.ID, models.EntryUpdated
+	}
+	return c, true
+}
+
+// sameContent compares two versions of an entry by their JSON form, which
+// ignores monotonic clock readings and time zone pointers. Segments are
+// normalized first, so migrating a legacy entry is not a change.
+func sameContent(a, b models.TimeEntry) bool {
+	a.UpdatedAt, b.UpdatedAt = time.Time{}, time.Time{}
+	a.NormalizeSegments()
+	b.NormalizeSegments()
+	da, err1 := json.Marshal(a)
+	db, err2 := json.Marshal(b)
+	return err1 == nil && err2 == nil && bytes.Equal(da, db)
+}
+
+// recordChange appends the change from before to after to the log. The entry
+// itself is already saved at this point, so a failure is only logged.
+// Callers hold s.mu and the folder lock.
+func (s *Storage) recordChange(before, after *models.TimeEntry) {
+	c, ok := newEntryChange(before, after, time.Now())
+	if !ok {
+		return
+	}
+	if err := appendHistory(filepath.Join(s.BaseDir, historyFileName), c); err != nil {
+		log.Printf("Warning: failed to record change of entry %s: %v", c.EntryID, err)
+	}
+}
+
+func appendHistory(path string, c models.EntryChange) error {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(append(data, '\n'))
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// readHistory returns the records of the change log at path accepted by
+// match, oldest first. A missing log is empty. Lines that cannot be parsed,
+// such as one torn by a crash, are skipped.
+func readHistory(path string, match func(models.EntryChange) bool) ([]models.EntryChange, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []models.EntryChange{}, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	changes := []models.EntryChange{}
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var c models.EntryChange
+		if err := json.Unmarshal(scanner.Bytes(), &c); err != nil {
+			continue
+		}
+		if match == nil || match(c) {
+			changes = append(changes, c)
+		}
+	}
+	return changes, scanner.Err()
+}
+
+// EntryHistory returns the recorded changes of the entry with the given ID,
+// oldest first.
+func (s *Storage) EntryHistory(id string) ([]models.EntryChange, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	unlock, err := s.lockDir(false)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	return readHistory(filepath.Join(s.BaseDir, historyFileName), func(c models.EntryChange) bool {
+		return c.EntryID == id
+	})
+}
@@ -0,0 +1,75 @@
+package store
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/highercomve/tasktracker/internal/models"
+)
+
+// invoicesFileName holds the issued invoices of the JSON backend.
+const invoicesFileName = "invoices.json"
+
+// LoadInvoices loads the issued invoices in the order they were saved.
+// Returns an empty slice if none were issued yet.
+func (s *Storage) LoadInvoices() ([]models.Invoice, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	unlock, err := s.lockDir(false)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	return s.readInvoices()
+}
+
+// readInvoices reads the invoices file. Callers hold s.mu and the folder
+// lock.
+func (s *Storage) readInvoices() ([]models.Invoice, error) {
+	var invoices []models.Invoice
+	if err := readJSONFile(filepath.Join(s.BaseDir, invoicesFileName), &invoices); err != nil {
+		if os.IsNotExist(err) {
+			return []models.Invoice{}, nil
+		}
+		return nil, err
+	}
+	return invoices, nil
+}
+
+// SaveInvoices replaces all invoices with the provided slice.
+func (s *Storage) SaveInvoices(invoices []models.Invoice) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	unlock, err := s.lockDir(true)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	return s.writeJSON(filepath.Join(s.BaseDir, invoicesFileName), invoices)
+}
+
+// UpdateInvoices replaces the invoices with what update returns for the
+// current ones, holding the exclusive folder lock throughout.
+func (s *Storage) UpdateInvoices(update func([]models.Invoice) ([]models.Invoice, error)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	unlock, err := s.lockDir(true)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	invoices, err := s.readInvoices()
+	if err != nil {
+		return err
+	}
+	if invoices, err = update(invoices); err != nil {
+		return err
+	}
+	return s.writeJSON(filepath.Join(s.BaseDir, invoicesFileName), invoices)
+}
@@ -0,0 +1,148 @@
+package store
+
+import (
+	"fmt"
+	"strings"
+)
+
+// dataCopier is implemented by the backends to copy their entries, change
+// log and app state to one another as they are, timestamps included.
+type dataCopier interface {
+	exportData() (trashSnapshot, error)
+	// importData adds snap to a store emptied by DeleteAllEntries.
+	importData(snap trashSnapshot) error
+}
+
+// BackendName returns the canonical name of backend, as accepted by Open.
+func BackendName(backend string) string {
+	if b := strings.ToLower(backend); b != "" {
+		return b
+	}
+	return BackendJSON
+}
+
+// Migrate copies the data in dir from the backend from to the backend to, so
+// that switching backends takes the data along. What the target held
+// before, left behind by an earlier switch, is moved to the trash first,
+// from where RestoreTrash brings it back.
+func Migrate(dir, from, to string) error {
+	from, to = BackendName(from), BackendName(to)
+	if from == to {
+		return nil
+	}
+	src, err := Open(from, dir)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	var dst Store
+	if to == BackendSQLite {
+		// Not importing the JSON files on creation: they are what is
+		// left behind, when from is not JSON.
+		dst, err = newSQLiteStorage(dir, false)
+	} else {
+		dst, err = Open(to, dir)
+	}
+	if err != nil {
+		return err
+	}
+	defer dst.Close()
+
+	data, err := src.(dataCopier).exportData()
+	if err != nil {
+		return fmt.Errorf("failed to read the %s data: %w", from, err)
+	}
+	projects, err := src.LoadProjects()
+	if err != nil {
+		return err
+	}
+	invoices, err := src.LoadInvoices()
+	if err != nil {
+		return err
+	}
+
+	if err := dst.DeleteAllEntries(); err != nil {
+		return err
+	}
+	if err := dst.(dataCopier).importData(data); err != nil {
+		return fmt.Errorf("failed to write the %s data: %w", to, err)
+	}
+	if err := dst.SaveProjects(projects); err != nil {
+		return err
+	}
+	return dst.SaveInvoices(invoices)
+}
+
+// exportData reads the entries, change log and app state of the folder.
+func (s *Storage) exportData() (trashSnapshot, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	unlock, err := s.lockDir(false)
+	if err != nil {
+		return trashSnapshot{}, err
+	}
+	defer unlock()
+
+	// The data folder has the layout of a trash folder
+	return readTrash(s.BaseDir)
+}
+
+func (s *Storage) importData(snap trashSnapshot) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	unlock, err := s.lockDir(true)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	return writeTrash(s.BaseDir, snap)
+}
+
+// exportData reads the entries, change log and app state of the database.
+func (s *SQLiteStorage) exportData() (trashSnapshot, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	db, err := s.conn()
+	if err != nil {
+		return trashSnapshot{}, err
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		return trashSnapshot{}, err
+	}
+	defer tx.Rollback()
+	return snapshotTx(tx)
+}
+
+func (s *SQLiteStorage) importData(snap trashSnapshot) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	db, err := s.conn()
+	if err != nil {
+		return err
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	for _, e := range snap.Entries {
+		if err := saveEntryTx(tx, e); err != nil {
+			return err
+		}
+	}
+	if snap.State != nil {
+		if err := putKV(tx, appStateKey, snap.State); err != nil {
+			return err
+		}
+	}
+	for _, c := range snap.History {
+		if err := insertHistoryTx(tx, c); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,1047 @@
+package store
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/highercomve/tasktracker/internal/models"
+
+	_ "modernc.org/sqlite" // pure-Go driver, registers "sqlite"
+)
+
+// SQLiteFileName is the database file kept inside the data folder.
+const SQLiteFileName = "tasktracker.db"
+
+// sqliteSchemaVersion is stored in PRAGMA user_version. Version 2 added
+// entry_history.
+const sqliteSchemaVersion = 2
+
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS entries (
+	id         TEXT PRIMARY KEY,
+	day        TEXT NOT NULL,
+	start_time INTEGER NOT NULL,
+	project_id TEXT NOT NULL DEFAULT '',
+	data       TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_entries_day ON entries(day, start_time);
+CREATE INDEX IF NOT EXISTS idx_entries_start ON entries(start_time);
+CREATE INDEX IF NOT EXISTS idx_entries_project ON entries(project_id, day);
+
+CREATE TABLE IF NOT EXISTS entry_tags (
+	entry_id TEXT NOT NULL REFERENCES entries(id) ON DELETE CASCADE,
+	tag      TEXT NOT NULL,
+	position INTEGER NOT NULL,
+	PRIMARY KEY (entry_id, position)
+);
+CREATE INDEX IF NOT EXISTS idx_entry_tags_tag ON entry_tags(tag);
+
+CREATE TABLE IF NOT EXISTS projects (
+	id       TEXT PRIMARY KEY,
+	position INTEGER NOT NULL,
+	data     TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS invoices (
+	id       TEXT PRIMARY KEY,
+	position INTEGER NOT NULL,
+	data     TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS entry_history (
+	seq      INTEGER PRIMARY KEY AUTOINCREMENT,
+	entry_id TEXT NOT NULL,
+	data     TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_entry_history_entry ON entry_history(entry_id, seq);
+
+CREATE TABLE IF NOT EXISTS kv (
+	key   TEXT PRIMARY KEY,
+	value TEXT NOT NULL
+);
+`
+
+const appStateKey = "app_state"
+
+// SQLiteStorage is the SQLite backend. Every entry is kept as its JSON
+// document next to the indexed columns used for range, project and tag
+// queries, so new TimeEntry fields need no schema change.
+type SQLiteStorage struct {
+	mu         sync.Mutex
+	baseDir    string
+	db         *sql.DB
+	skipImport bool // leave the JSON data alone on first use
+
+	watchMu   sync.Mutex
+	listeners []func(Change)
+	stopPoll  chan struct{}
+}
+
+// sqlitePollInterval is how often the database is checked for commits made
+// by other processes. A variable so tests can shorten it.
+var sqlitePollInterval = 2 * time.Second
+
+// NewSQLiteStorage opens (creating if needed) the database in baseDir. On
+// first use, existing JSON data in the same folder is imported.
+func NewSQLiteStorage(baseDir string) (*SQLiteStorage, error) {
+	return newSQLiteStorage(baseDir, true)
+}
+
+// newSQLiteStorage is NewSQLiteStorage, importing the JSON data on first
+// use only if importJSON is set.
+func newSQLiteStorage(baseDir string, importJSON bool) (*SQLiteStorage, error) {
+	s := &SQLiteStorage{baseDir: baseDir, skipImport: !importJSON}
+	if err := s.open(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *SQLiteStorage) open() error {
+	if err := os.MkdirAll(s.baseDir, 0755); err != nil {
+		return err
+	}
+	path := filepath.Join(s.baseDir, SQLiteFileName)
+	// Transactions take the write lock as they begin, so that what they
+	// read cannot be changed by another process before they write.
+	dsn := "file:" + path + "?_pragma=busy_timeout(5000)&_pragma=foreign_keys(1)&_pragma=journal_mode(WAL)&_txlock=immediate"
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return err
+	}
+	// A single connection serialises writers and keeps PRAGMAs in effect.
+	db.SetMaxOpenConns(1)
+
+	var version int
+	if err := db.QueryRow("PRAGMA user_version").Scan(&version); err != nil {
+		db.Close()
+		return fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	if version > sqliteSchemaVersion {
+		db.Close()
+		return fmt.Errorf("%s was created by a newer version (schema %d)", path, version)
+	}
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		db.Close()
+		return fmt.Errorf("failed to create schema: %w", err)
+	}
+	s.db = db
+
+	if version == 0 && !s.skipImport {
+		if err := s.importJSON(); err != nil {
+			db.Close()
+			s.db = nil
+			return fmt.Errorf("failed to import JSON data: %w", err)
+		}
+	}
+	if version < sqliteSchemaVersion {
+		if _, err := db.Exec(fmt.Sprintf("PRAGMA user_version = %d", sqliteSchemaVersion)); err != nil {
+			db.Close()
+			s.db = nil
+			return err
+		}
+	}
+	return nil
+}
+
+// importJSON copies the data of a JSON directory store in the same folder
+// into a freshly created database.
+func (s *SQLiteStorage) importJSON() error {
+	legacy := NewStorage(s.baseDir)
+
+	entries, err := legacy.LoadEntriesForRange(time.Time{}, time.Date(9999, 12, 31, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		return err
+	}
+	projects, err := legacy.LoadProjects()
+	if err != nil {
+		return err
+	}
+	invoices, err := legacy.LoadInvoices()
+	if err != nil {
+		return err
+	}
+	state, stateErr := legacy.LoadAppState()
+	history, err := readHistory(filepath.Join(s.baseDir, historyFileName), nil)
+	if err != nil {
+		return err
+	}
+	if len(entries) == 0 && len(projects) == 0 && stateErr != nil {
+		return nil
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	for _, e := range entries {
+		if err := saveEntryTx(tx, e); err != nil {
+			return err
+		}
+	}
+	if err := saveProjectsTx(tx, projects); err != nil {
+		return err
+	}
+	if err := saveInvoicesTx(tx, invoices); err != nil {
+		return err
+	}
+	if stateErr == nil {
+		if err := putKV(tx, appStateKey, state); err != nil {
+			return err
+		}
+	}
+	for _, c := range history {
+		if err := insertHistoryTx(tx, c); err != nil {
+			return err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	log.Printf("Imported %d entries and %d projects from JSON files into %s",
+		len(entries), len(projects), SQLiteFileName)
+	return nil
+}
+
+// Dir returns the data folder holding the database.
+func (s *SQLiteStorage) Dir() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.baseDir
+}
+
+// Close closes the database.
+func (s *SQLiteStorage) Close() error {
+	s.watchMu.Lock()
+	if s.stopPoll != nil {
+		close(s.stopPoll)
+		s.stopPoll = nil
+	}
+	s.watchMu.Unlock()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.db == nil {
+		return nil
+	}
+	err := s.db.Close()
+	s.db = nil
+	return err
+}
+
+// UpdateBaseDir opens (or creates) the database in newDir.
+func (s *SQLiteStorage) UpdateBaseDir(newDir string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.switchDir(newDir, false)
+}
+
+// MoveData moves the database file to newDir and reopens it there.
+func (s *SQLiteStorage) MoveData(newDir string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.switchDir(newDir, true)
+}
+
+// Subscribe registers fn for commits made to the database by other
+// processes. SQLite reports them through PRAGMA data_version, which is polled
+// from the first subscription on. The pool keeps a single connection, so our
+// own commits never change the value it reports.
+func (s *SQLiteStorage) Subscribe(fn func(Change)) {
+	s.watchMu.Lock()
+	defer s.watchMu.Unlock()
+	s.listeners = append(s.listeners, fn)
+	if s.stopPoll != nil {
+		return
+	}
+	s.stopPoll = make(chan struct{})
+	go s.pollLoop(s.stopPoll)
+}
+
+func (s *SQLiteStorage) dataVersion() (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	db, err := s.conn()
+	if err != nil {
+		return 0, err
+	}
+	var v int64
+	err = db.QueryRow("PRAGMA data_version").Scan(&v)
+	return v, err
+}
+
+func (s *SQLiteStorage) pollLoop(stop chan struct{}) {
+	last, _ := s.dataVersion()
+	ticker := time.NewTicker(sqlitePollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			v, err := s.dataVersion()
+			if err != nil || v == last {
+				continue
+			}
+			last = v
+			// The database does not say what changed.
+			s.publish(EntriesChanged, ProjectsChanged, StateChanged)
+		}
+	}
+}
+
+func (s *SQLiteStorage) publish(kinds ...ChangeKind) {
+	s.watchMu.Lock()
+	listeners := append([]func(Change){}, s.listeners...)
+	s.watchMu.Unlock()
+	for _, kind := range kinds {
+		for _, fn := range listeners {
+			fn(Change{Kind: kind})
+		}
+	}
+}
+
+func (s *SQLiteStorage) switchDir(newDir string, move bool) error {
+	oldDir := s.baseDir
+	if s.db != nil {
+		if err := s.db.Close(); err != nil {
+			return err
+		}
+		s.db = nil
+	}
+
+	if move {
+		if err := os.MkdirAll(newDir, 0755); err != nil {
+			s.open()
+			return err
+		}
+		oldPath := filepath.Join(oldDir, SQLiteFileName)
+		if _, err := os.Stat(oldPath); err == nil {
+			if err := os.Rename(oldPath, filepath.Join(newDir, SQLiteFileName)); err != nil {
+				s.open()
+				return err
+			}
+			// The WAL has been checkpointed by Close; drop leftovers.
+			os.Remove(oldPath + "-wal")
+			os.Remove(oldPath + "-shm")
+		}
+		moveAlong(oldDir, newDir, trashDirName)
+	}
+
+	s.baseDir = newDir
+	if err := s.open(); err != nil {
+		s.baseDir = oldDir
+		s.open()
+		return err
+	}
+	return nil
+}
+
+func (s *SQLiteStorage) conn() (*sql.DB, error) {
+	if s.db == nil {
+		return nil, errors.New("database is closed")
+	}
+	return s.db, nil
+}
+
+func dayKey(t time.Time) string {
+	return t.Format("2006-01-02")
+}
+
+func scanEntries(rows *sql.Rows) ([]models.TimeEntry, error) {
+	defer rows.Close()
+	entries := []models.TimeEntry{}
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return nil, err
+		}
+		var e models.TimeEntry
+		if err := json.Unmarshal([]byte(data), &e); err != nil {
+			return nil, err
+		}
+		e.NormalizeSegments()
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+// LoadEntries loads the entries filed under a specific date, the day they
+// started.
+func (s *SQLiteStorage) LoadEntries(date time.Time) ([]models.TimeEntry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	db, err := s.conn()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := db.Query("SELECT data FROM entries WHERE day = ? ORDER BY start_time, rowid", dayKey(date))
+	if err != nil {
+		return nil, err
+	}
+	return scanEntries(rows)
+}
+
+// LoadEntriesForRange loads entries for a date range (inclusive), including
+// those that started shortly before it and overlap it.
+func (s *SQLiteStorage) LoadEntriesForRange(start, end time.Time) ([]models.TimeEntry, error) {
+	if dayKey(end) < dayKey(start) {
+		start, end = end, start
+	}
+	return s.QueryEntries(EntryQuery{Start: start, End: end})
+}
+
+// QueryEntries answers q from the day, project and tag indexes.
+func (s *SQLiteStorage) QueryEntries(q EntryQuery) ([]models.TimeEntry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	db, err := s.conn()
+	if err != nil {
+		return nil, err
+	}
+
+	var where []string
+	var args []any
+	if !q.Start.IsZero() {
+		where = append(where, "e.day >= ?")
+		args = append(args, dayKey(q.lookbackStart()))
+	}
+	if !q.End.IsZero() {
+		where = append(where, "e.day <= ?")
+		args = append(args, dayKey(q.End))
+	}
+	switch q.ProjectID {
+	case "":
+	case "unassigned":
+		where = append(where, "e.project_id = ''")
+	default:
+		where = append(where, "e.project_id = ?")
+		args = append(args, q.ProjectID)
+	}
+	if q.Tag != "" {
+		where = append(where, "e.id IN (SELECT entry_id FROM entry_tags WHERE tag = ?)")
+		args = append(args, q.Tag)
+	}
+
+	query := "SELECT e.data FROM entries e"
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	query += " ORDER BY e.day, e.start_time, e.rowid"
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := scanEntries(rows)
+	if err != nil || q.Start.IsZero() {
+		return entries, err
+	}
+	// Drop the earlier entries that ended before the range.
+	now := time.Now()
+	inRange := entries[:0]
+	for _, e := range entries {
+		if q.InRange(e, now) {
+			inRange = append(inRange, e)
+		}
+	}
+	return inRange, nil
+}
+
+// SaveEntry saves or updates an entry.
+func (s *SQLiteStorage) SaveEntry(entry models.TimeEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	db, err := s.conn()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	entry.UpdatedAt = now
+	entry.NormalizeSegments()
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	before, err := loadEntryTx(tx, entry.ID)
+	if err != nil {
+		return err
+	}
+	if entry.CreatedAt.IsZero() {
+		if before != nil {
+			entry.CreatedAt = before.CreatedAt
+		} else {
+			entry.CreatedAt = now
+		}
+	}
+	if err := saveEntryTx(tx, entry); err != nil {
+		return err
+	}
+	if err := recordChangeTx(tx, before, &entry, now); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// loadEntryTx returns the stored entry with the given ID, or nil.
+func loadEntryTx(tx *sql.Tx, id string) (*models.TimeEntry, error) {
+	var data string
+	err := tx.QueryRow("SELECT data FROM entries WHERE id = ?", id).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var e models.TimeEntry
+	if err := json.Unmarshal([]byte(data), &e); err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+// recordChangeTx logs the change from before to after in the same
+// transaction as the change itself.
+func recordChangeTx(tx *sql.Tx, before, after *models.TimeEntry, at time.Time) error {
+	c, ok := newEntryChange(before, after, at)
+	if !ok {
+		return nil
+	}
+	return insertHistoryTx(tx, c)
+}
+
+func insertHistoryTx(tx *sql.Tx, c models.EntryChange) error {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec("INSERT INTO entry_history (entry_id, data) VALUES (?, ?)", c.EntryID, string(data))
+	return err
+}
+
+func saveEntryTx(tx *sql.Tx, entry models.TimeEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	// Upsert rather than REPLACE so the tag rows are not cascaded away
+	// before they are rewritten below.
+	_, err = tx.Exec(`INSERT INTO entries (id, day, start_time, project_id, data)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(id) DO UPDATE SET
+			day = excluded.day,
+			start_time = excluded.start_time,
+			project_id = excluded.project_id,
+			data = excluded.data`,
+		entry.ID, dayKey(entry.StartTime), entry.StartTime.UnixNano(), entry.ProjectID, string(data))
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM entry_tags WHERE entry_id = ?", entry.ID); err != nil {
+		return err
+	}
+	for i, tag := range entry.Tags {
+		if _, err := tx.Exec("INSERT INTO entry_tags (entry_id, tag, position) VALUES (?, ?, ?)",
+			entry.ID, tag, i); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DeleteEntry removes an entry from the storage.
+func (s *SQLiteStorage) DeleteEntry(entry models.TimeEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	db, err := s.conn()
+	if err != nil {
+		return err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	before, err := loadEntryTx(tx, entry.ID)
+	if err != nil || before == nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM entries WHERE id = ?", entry.ID); err != nil {
+		return err
+	}
+	if err := recordChangeTx(tx, before, nil, time.Now()); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// EntryHistory returns the recorded changes of the entry with the given ID,
+// oldest first.
+func (s *SQLiteStorage) EntryHistory(id string) ([]models.EntryChange, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	db, err := s.conn()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := db.Query("SELECT data FROM entry_history WHERE entry_id = ? ORDER BY seq", id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	changes := []models.EntryChange{}
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return nil, err
+		}
+		var c models.EntryChange
+		if err := json.Unmarshal([]byte(data), &c); err != nil {
+			return nil, err
+		}
+		changes = append(changes, c)
+	}
+	return changes, rows.Err()
+}
+
+// DeleteAllEntries moves all entries, their change log and the app state to
+// the trash folder, in the JSON layout, and removes them from the database.
+// Projects are kept, as with the JSON backend.
+func (s *SQLiteStorage) DeleteAllEntries() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	db, err := s.conn()
+	if err != nil {
+		return err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	snap, err := snapshotTx(tx)
+	if err != nil {
+		return err
+	}
+	var dir string
+	if !snap.empty() {
+		if dir, err = newTrashDir(s.baseDir); err != nil {
+			return err
+		}
+		if err := writeTrash(dir, snap); err != nil {
+			os.RemoveAll(dir)
+			return err
+		}
+	}
+
+	for _, stmt := range []string{
+		"DELETE FROM entry_tags",
+		"DELETE FROM entries",
+		"DELETE FROM entry_history",
+		"DELETE FROM kv WHERE key = '" + appStateKey + "'",
+	} {
+		if _, err := tx.Exec(stmt); err != nil {
+			return err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		if dir != "" {
+			os.RemoveAll(dir)
+		}
+		return err
+	}
+	return nil
+}
+
+// snapshotTx reads everything DeleteAllEntries moves to the trash.
+func snapshotTx(tx *sql.Tx) (trashSnapshot, error) {
+	var snap trashSnapshot
+	rows, err := tx.Query("SELECT data FROM entries ORDER BY day, start_time, rowid")
+	if err != nil {
+		return snap, err
+	}
+	if snap.Entries, err = scanEntries(rows); err != nil {
+		return snap, err
+	}
+
+	var data string
+	err = tx.QueryRow("SELECT value FROM kv WHERE key = ?", appStateKey).Scan(&data)
+	switch {
+	case err == nil:
+		var state AppState
+		if err := json.Unmarshal([]byte(data), &state); err != nil {
+			return snap, err
+		}
+		snap.State = &state
+	case !errors.Is(err, sql.ErrNoRows):
+		return snap, err
+	}
+
+	rows, err = tx.Query("SELECT data FROM entry_history ORDER BY seq")
+	if err != nil {
+		return snap, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var c models.EntryChange
+		if err := rows.Scan(&data); err != nil {
+			return snap, err
+		}
+		if err := json.Unmarshal([]byte(data), &c); err != nil {
+			return snap, err
+		}
+		snap.History = append(snap.History, c)
+	}
+	return snap, rows.Err()
+}
+
+// ListTrash returns the data erased by DeleteAllEntries, newest first.
+func (s *SQLiteStorage) ListTrash() ([]TrashItem, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return listTrash(s.baseDir)
+}
+
+// RestoreTrash brings back the data of a trash item and deletes it from the
+// trash. Entries recreated since the erase are kept as they are, and the app
+// state is only restored when there is none.
+func (s *SQLiteStorage) RestoreTrash(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	db, err := s.conn()
+	if err != nil {
+		return err
+	}
+	dir, err := trashPath(s.baseDir, id)
+	if err != nil {
+		return err
+	}
+	snap, err := readTrash(dir)
+	if err != nil {
+		return err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	for _, e := range snap.Entries {
+		current, err := loadEntryTx(tx, e.ID)
+		if err != nil {
+			return err
+		}
+		if current == nil {
+			if err := saveEntryTx(tx, e); err != nil {
+				return err
+			}
+		}
+	}
+	if snap.State != nil {
+		var exists int
+		if err := tx.QueryRow("SELECT COUNT(*) FROM kv WHERE key = ?", appStateKey).Scan(&exists); err != nil {
+			return err
+		}
+		if exists == 0 {
+			if err := putKV(tx, appStateKey, snap.State); err != nil {
+				return err
+			}
+		}
+	}
+	for _, c := range snap.History {
+		if err := insertHistoryTx(tx, c); err != nil {
+			return err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	return os.RemoveAll(dir)
+}
+
+// App State Management
+
+func putKV(tx *sql.Tx, key string, value any) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec("INSERT OR REPLACE INTO kv (key, value) VALUES (?, ?)", key, string(data))
+	return err
+}
+
+func (s *SQLiteStorage) SaveAppState(state AppState) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	db, err := s.conn()
+	if err != nil {
+		return err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err := putKV(tx, appStateKey, state); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// LoadAppState returns an error wrapping os.ErrNotExist when no state has
+// been saved, mirroring a missing state.json.
+func (s *SQLiteStorage) LoadAppState() (AppState, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	db, err := s.conn()
+	if err != nil {
+		return AppState{}, err
+	}
+
+	var data string
+	err = db.QueryRow("SELECT value FROM kv WHERE key = ?", appStateKey).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return AppState{}, fmt.Errorf("app state: %w", os.ErrNotExist)
+	}
+	if err != nil {
+		return AppState{}, err
+	}
+	var state AppState
+	if err := json.Unmarshal([]byte(data), &state); err != nil {
+		return AppState{}, err
+	}
+	return state, nil
+}
+
+func (s *SQLiteStorage) ClearAppState() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	db, err := s.conn()
+	if err != nil {
+		return err
+	}
+	_, err = db.Exec("DELETE FROM kv WHERE key = ?", appStateKey)
+	return err
+}
+
+// Project Persistence
+
+// LoadProjects loads all projects in the order they were saved.
+func (s *SQLiteStorage) LoadProjects() ([]models.Project, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	db, err := s.conn()
+	if err != nil {
+		return nil, err
+	}
+
+	return loadProjects(db)
+}
+
+// queryer is a *sql.DB or a *sql.Tx.
+type queryer interface {
+	Query(query string, args ...any) (*sql.Rows, error)
+}
+
+func loadProjects(q queryer) ([]models.Project, error) {
+	rows, err := q.Query("SELECT data FROM projects ORDER BY position")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	projects := []models.Project{}
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return nil, err
+		}
+		var p models.Project
+		if err := json.Unmarshal([]byte(data), &p); err != nil {
+			return nil, err
+		}
+		projects = append(projects, p)
+	}
+	return projects, rows.Err()
+}
+
+// SaveProjects replaces all projects with the provided slice.
+func (s *SQLiteStorage) SaveProjects(projects []models.Project) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	db, err := s.conn()
+	if err != nil {
+		return err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err := saveProjectsTx(tx, projects); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// UpdateProjects replaces the projects with what update returns for the
+// current ones in a single transaction.
+func (s *SQLiteStorage) UpdateProjects(update func([]models.Project) ([]models.Project, error)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	db, err := s.conn()
+	if err != nil {
+		return err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	projects, err := loadProjects(tx)
+	if err != nil {
+		return err
+	}
+	if projects, err = update(projects); err != nil {
+		return err
+	}
+	if err := saveProjectsTx(tx, projects); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Invoice Persistence
+
+// LoadInvoices loads the issued invoices in the order they were saved.
+func (s *SQLiteStorage) LoadInvoices() ([]models.Invoice, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	db, err := s.conn()
+	if err != nil {
+		return nil, err
+	}
+
+	return loadInvoices(db)
+}
+
+func loadInvoices(q queryer) ([]models.Invoice, error) {
+	rows, err := q.Query("SELECT data FROM invoices ORDER BY position")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	invoices := []models.Invoice{}
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return nil, err
+		}
+		var inv models.Invoice
+		if err := json.Unmarshal([]byte(data), &inv); err != nil {
+			return nil, err
+		}
+		invoices = append(invoices, inv)
+	}
+	return invoices, rows.Err()
+}
+
+// SaveInvoices replaces all invoices with the provided slice.
+func (s *SQLiteStorage) SaveInvoices(invoices []models.Invoice) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	db, err := s.conn()
+	if err != nil {
+		return err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err := saveInvoicesTx(tx, invoices); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// UpdateInvoices replaces the invoices with what update returns for the
+// current ones in a single transaction.
+func (s *SQLiteStorage) UpdateInvoices(update func([]models.Invoice) ([]models.Invoice, error)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	db, err := s.conn()
+	if err != nil {
+		return err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	invoices, err := loadInvoices(tx)
+	if err != nil {
+		return err
+	}
+	if invoices, err = update(invoices); err != nil {
+		return err
+	}
+	if err := saveInvoicesTx(tx, invoices); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func saveInvoicesTx(tx *sql.Tx, invoices []models.Invoice) error {
+	if _, err := tx.Exec("DELETE FROM invoices"); err != nil {
+		return err
+	}
+	for i, inv := range invoices {
+		data, err := json.Marshal(inv)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec("INSERT INTO invoices (id, position, data) VALUES (?, ?, ?)",
+			inv.ID, i, string(data)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func saveProjectsTx(tx *sql.Tx, projects []models.Project) error {
+	if _, err := tx.Exec("DELETE FROM projects"); err != nil {
+		return err
+	}
+	for i, p := range projects {
+		data, err := json.Marshal(p)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec("INSERT INTO projects (id, position, data) VALUES (?, ?, ?)",
+			p.ID, i, string(data)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -1,8 +1,8 @@
 package store
 
 import (
-	"encoding/json"
 	"fmt"
+	"log"
 	"os"
 	"path/filepath"
 	"sort"
@@ -10,9 +10,157 @@ import (
 	"sync"
 	"time"
 
+	"github.com/highercomve/tasktracker/internal/lockfile"
 	"github.com/highercomve/tasktracker/internal/models"
 )
 
+// Storage backends selectable through the storage_backend config key.
+const (
+	BackendJSON   = "json"
+	BackendSQLite = "sqlite"
+)
+
+// Store is the persistence layer used by the UI, the CLI and the services.
+// Entries are filed by the calendar day of their StartTime.
+type Store interface {
+	// Dir returns the data folder the store reads from and writes to.
+	Dir() string
+	// UpdateBaseDir switches to newDir, leaving the old data in place.
+	UpdateBaseDir(newDir string) error
+	// MoveData moves the existing data to newDir and switches to it.
+	MoveData(newDir string) error
+	// Close releases any resources held by the backend.
+	Close() error
+	// Subscribe registers fn for changes made to the data by other
+	// processes. fn runs on a background goroutine.
+	Subscribe(fn func(Change))
+
+	// LoadEntries returns the entries filed under date, the day they
+	// started.
+	LoadEntries(date time.Time) ([]models.TimeEntry, error)
+	// LoadEntriesForRange returns the entries that started in the days
+	// from start to end, plus those that started in the week before and
+	// overlap the range.
+	LoadEntriesForRange(start, end time.Time) ([]models.TimeEntry, error)
+	// QueryEntries returns the entries matching q. Backends with indexes
+	// use them; the rest filter in memory.
+	QueryEntries(q EntryQuery) ([]models.TimeEntry, error)
+	// SaveEntry creates or replaces the entry with entry.ID, stamping
+	// CreatedAt on the first save and UpdatedAt on every save. Each change
+	// is recorded in the entry change log.
+	SaveEntry(entry models.TimeEntry) error
+	DeleteEntry(entry models.TimeEntry) error
+	// DeleteAllEntries moves all entries, their change log and the app
+	// state to the trash. Projects are kept.
+	DeleteAllEntries() error
+	// ListTrash returns the data erased by DeleteAllEntries, newest first.
+	ListTrash() ([]TrashItem, error)
+	// RestoreTrash brings back a trash item. Entries that exist again are
+	// left untouched.
+	RestoreTrash(id string) error
+	// EntryHistory returns the recorded changes of the entry with the
+	// given ID, oldest first.
+	EntryHistory(id string) ([]models.EntryChange, error)
+
+	LoadAppState() (AppState, error)
+	SaveAppState(state AppState) error
+	ClearAppState() error
+
+	LoadProjects() ([]models.Project, error)
+	SaveProjects(projects []models.Project) error
+	// UpdateProjects replaces the projects with what update returns for
+	// the current ones. Other processes cannot write in between, so no
+	// change of theirs is lost. Nothing is saved if update fails.
+	UpdateProjects(update func([]models.Project) ([]models.Project, error)) error
+
+	// LoadInvoices returns the issued invoices in the order they were
+	// saved.
+	LoadInvoices() ([]models.Invoice, error)
+	// SaveInvoices replaces all invoices with the provided slice.
+	SaveInvoices(invoices []models.Invoice) error
+	// UpdateInvoices is UpdateProjects for the invoices.
+	UpdateInvoices(update func([]models.Invoice) ([]models.Invoice, error)) error
+}
+
+// overlapLookback is how many days before a range start the backends look
+// for entries that started earlier and were still going on that day, such as
+// a night shift from 22:00 to 02:00. Entries are filed by their start day.
+const overlapLookback = 7
+
+// EntryQuery selects entries by day range, project and tag. Zero values
+// leave the corresponding dimension unfiltered.
+type EntryQuery struct {
+	// Start is the first day (inclusive); zero means no lower bound.
+	// Entries that started up to overlapLookback days before it and
+	// overlap it are included.
+	Start time.Time
+	End   time.Time // last day (inclusive); zero means no upper bound
+	// ProjectID matches entries of one project; "unassigned" matches
+	// entries without a project.
+	ProjectID string
+	// Tag matches entries carrying the tag in any position.
+	Tag string
+}
+
+// lookbackStart is the first day the backends load to answer q, or zero.
+func (q EntryQuery) lookbackStart() time.Time {
+	if q.Start.IsZero() {
+		return q.Start
+	}
+	return q.Start.AddDate(0, 0, -overlapLookback)
+}
+
+// InRange reports whether e starts within the day range of q or started
+// before it and was still worked on after the first day began.
+func (q EntryQuery) InRange(e models.TimeEntry, now time.Time) bool {
+	if !q.End.IsZero() && dayKey(e.StartTime) > dayKey(q.End) {
+		return false
+	}
+	if q.Start.IsZero() || dayKey(e.StartTime) >= dayKey(q.Start) {
+		return true
+	}
+	y, m, d := q.Start.Date()
+	return e.WorkedUntil(now).After(time.Date(y, m, d, 0, 0, 0, 0, q.Start.Location()))
+}
+
+// Match reports whether e satisfies the project and tag filters of q. The
+// day range is left to the backend.
+func (q EntryQuery) Match(e models.TimeEntry) bool {
+	switch q.ProjectID {
+	case "":
+	case "unassigned":
+		if e.ProjectID != "" {
+			return false
+		}
+	default:
+		if e.ProjectID != q.ProjectID {
+			return false
+		}
+	}
+	if q.Tag == "" {
+		return true
+	}
+	for _, t := range e.Tags {
+		if t == q.Tag {
+			return true
+		}
+	}
+	return false
+}
+
+// Open returns the store for the named backend rooted at baseDir. An empty
+// backend selects the JSON directory store.
+func Open(backend, baseDir string) (Store, error) {
+	switch BackendName(backend) {
+	case BackendJSON:
+		return NewStorage(baseDir), nil
+	case BackendSQLite:
+		return NewSQLiteStorage(baseDir)
+	default:
+		return nil, fmt.Errorf("unknown storage backend %q", backend)
+	}
+}
+
 type AppState struct {
 	ActiveTaskID   string    `json:"active_task_id"`
 	ActiveTaskDate time.Time `json:"active_task_date"`
@@ -20,9 +168,15 @@ type AppState struct {
 	LastRunVersion string    `json:"last_run_version"`
 }
 
+// lockFileName is the advisory lock file inside the data folder.
+const lockFileName = ".lock"
+
+// Storage is the JSON directory backend: one entries/YYYY-MM-DD.json file per
+// day plus projects.json and state.json.
 type Storage struct {
 	BaseDir string
 	mu      sync.Mutex
+	watch   watchState
 }
 
 func NewStorage(baseDir string) *Storage {
@@ -36,19 +190,68 @@ func (s *Storage) ensureDir() {
 	os.MkdirAll(filepath.Join(s.BaseDir, "entries"), 0755)
 }
 
-// UpdateBaseDir updates the base directory and ensures it exists.
-func (s *Storage) UpdateBaseDir(newDir string) {
+// lockDir takes the advisory lock on the data folder, shared for reads and
+// exclusive for writes, so that load-modify-write cycles of other processes
+// (a second instance, the CLI, sync tools) cannot interleave with ours.
+// Callers must hold s.mu and call the returned function to release it.
+func (s *Storage) lockDir(exclusive bool) (func(), error) {
+	path := filepath.Join(s.BaseDir, lockFileName)
+	var l *lockfile.Lock
+	var err error
+	if exclusive {
+		l, err = lockfile.Exclusive(path)
+	} else {
+		l, err = lockfile.Shared(path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock data folder: %w", err)
+	}
+	return func() { l.Unlock() }, nil
+}
+
+// Dir returns the base directory.
+func (s *Storage) Dir() string {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	return s.BaseDir
+}
+
+// Close stops watching the data folder.
+func (s *Storage) Close() error {
+	s.stopWatching()
+	return nil
+}
+
+// UpdateBaseDir updates the base directory and ensures it exists.
+func (s *Storage) UpdateBaseDir(newDir string) error {
+	s.mu.Lock()
 	s.BaseDir = newDir
 	s.ensureDir()
+	s.mu.Unlock()
+
+	s.rewatch(newDir)
+	return nil
 }
 
 // MoveData attempts to move the data from the current directory to the new one.
 func (s *Storage) MoveData(newDir string) error {
+	if err := s.moveData(newDir); err != nil {
+		return err
+	}
+	s.rewatch(newDir)
+	return nil
+}
+
+func (s *Storage) moveData(newDir string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	unlock, err := s.lockDir(true)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
 	oldEntriesPath := filepath.Join(s.BaseDir, "entries")
 	newEntriesPath := filepath.Join(newDir, "entries")
 
@@ -69,6 +272,7 @@ func (s *Storage) MoveData(newDir string) error {
 	if err := os.Rename(oldEntriesPath, newEntriesPath); err != nil {
 		return err
 	}
+	moveAlong(s.BaseDir, newDir, historyFileName, trashDirName, invoicesFileName)
 
 	// Success
 	s.BaseDir = newDir
@@ -76,6 +280,21 @@ func (s *Storage) MoveData(newDir string) error {
 	return nil
 }
 
+// moveAlong moves the named files or folders from oldDir to newDir when
+// they exist. They are not needed to open the data, so failures are only
+// logged.
+func moveAlong(oldDir, newDir string, names ...string) {
+	for _, name := range names {
+		oldPath := filepath.Join(oldDir, name)
+		if _, err := os.Stat(oldPath); err != nil {
+			continue
+		}
+		if err := os.Rename(oldPath, filepath.Join(newDir, name)); err != nil {
+			log.Printf("Warning: failed to move %s: %v", name, err)
+		}
+	}
+}
+
 // getEntryFilePath returns the path for a specific date's entry file.
 func (s *Storage) getEntryFilePath(date time.Time) string {
 	filename := date.Format("2006-01-02") + ".json"
@@ -87,19 +306,22 @@ func (s *Storage) LoadEntries(date time.Time) ([]models.TimeEntry, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	path := s.getEntryFilePath(date)
-	data, err := os.ReadFile(path)
+	unlock, err := s.lockDir(false)
 	if err != nil {
-		if os.IsNotExist(err) {
-			return []models.TimeEntry{}, nil
-		}
 		return nil, err
 	}
+	defer unlock()
 
 	var entries []models.TimeEntry
-	if err := json.Unmarshal(data, &entries); err != nil {
+	if err := readJSONFile(s.getEntryFilePath(date), &entries); err != nil {
+		if os.IsNotExist(err) {
+			return []models.TimeEntry{}, nil
+		}
 		return nil, err
 	}
+	for i := range entries {
+		entries[i].NormalizeSegments()
+	}
 	return entries, nil
 }
 
@@ -110,37 +332,51 @@ func (s *Storage) SaveEntry(entry models.TimeEntry) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	unlock, err := s.lockDir(true)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	// Stamp the entry so copies edited on other machines can be merged
+	now := time.Now()
+	entry.UpdatedAt = now
+	entry.NormalizeSegments()
+
 	// Determine file based on StartTime
 	path := s.getEntryFilePath(entry.StartTime)
 
 	// Load existing
-	data, err := os.ReadFile(path)
 	var entries []models.TimeEntry
-	if err == nil {
-		if err := json.Unmarshal(data, &entries); err != nil {
-			return fmt.Errorf("failed to parse entries from %s: %w", path, err)
-		}
+	if err := readJSONFile(path, &entries); err != nil && !os.IsNotExist(err) {
+		return err
 	}
 
 	// Update or Append
-	found := false
+	var before *models.TimeEntry
 	for i, e := range entries {
 		if e.ID == entry.ID {
+			before = &e
+			if entry.CreatedAt.IsZero() {
+				entry.CreatedAt = e.CreatedAt
+			}
 			entries[i] = entry
-			found = true
 			break
 		}
 	}
-	if !found {
+	if before == nil {
+		if entry.CreatedAt.IsZero() {
+			entry.CreatedAt = now
+		}
 		entries = append(entries, entry)
 	}
 
 	// Save
-	newData, err := json.MarshalIndent(entries, "", "  ")
-	if err != nil {
+	if err := s.writeJSON(path, entries); err != nil {
 		return err
 	}
-	return os.WriteFile(path, newData, 0644)
+	s.recordChange(before, &entry)
+	return nil
 }
 
 // StopActiveTask stops any active task. This is a legacy helper method.
@@ -186,19 +422,25 @@ func (s *Storage) StopActiveTask(endTime time.Time) error {
 	return nil
 }
 
-// LoadEntriesForRange loads entries for a date range (inclusive).
+// LoadEntriesForRange loads entries for a date range (inclusive), including
+// those that started shortly before it and overlap it.
+func (s *Storage) LoadEntriesForRange(start, end time.Time) ([]models.TimeEntry, error) {
+	if dayKey(end) < dayKey(start) {
+		start, end = end, start
+	}
+	return s.QueryEntries(EntryQuery{Start: start, End: end})
+}
+
+// loadDays loads the entries filed under the days from start to end.
 //
 // Rather than iterating every calendar day in the range (which is O(days) and
 // becomes pathological for wide ranges such as "all time"), it lists the
 // entries directory once and only reads the files that actually fall inside the
 // range. Filenames use the YYYY-MM-DD format, which sorts lexicographically in
 // chronological order, so string comparison is sufficient and timezone-safe.
-func (s *Storage) LoadEntriesForRange(start, end time.Time) ([]models.TimeEntry, error) {
+func (s *Storage) loadDays(start, end time.Time) ([]models.TimeEntry, error) {
 	startStr := start.Format("2006-01-02")
 	endStr := end.Format("2006-01-02")
-	if endStr < startStr {
-		startStr, endStr = endStr, startStr
-	}
 
 	entriesDir := filepath.Join(s.BaseDir, "entries")
 	files, err := os.ReadDir(entriesDir)
@@ -209,13 +451,15 @@ func (s *Storage) LoadEntriesForRange(start, end time.Time) ([]models.TimeEntry,
 		return nil, err
 	}
 
-	// Collect the date strings of files that fall within the range.
+	// Collect the date strings of files that fall within the range. A
+	// backup without its day file still counts: LoadEntries restores it.
 	var dates []string
+	seen := make(map[string]bool)
 	for _, f := range files {
 		if f.IsDir() {
 			continue
 		}
-		name := f.Name()
+		name := strings.TrimSuffix(f.Name(), backupSuffix)
 		if !strings.HasSuffix(name, ".json") {
 			continue
 		}
@@ -224,7 +468,8 @@ func (s *Storage) LoadEntriesForRange(start, end time.Time) ([]models.TimeEntry,
 		if _, perr := time.Parse("2006-01-02", dateStr); perr != nil {
 			continue
 		}
-		if dateStr >= startStr && dateStr <= endStr {
+		if dateStr >= startStr && dateStr <= endStr && !seen[dateStr] {
+			seen[dateStr] = true
 			dates = append(dates, dateStr)
 		}
 	}
@@ -237,8 +482,9 @@ func (s *Storage) LoadEntriesForRange(start, end time.Time) ([]models.TimeEntry,
 		day, _ := time.Parse("2006-01-02", dateStr)
 		entries, err := s.LoadEntries(day)
 		if err != nil {
-			// Log warning but continue - allow partial results
-			fmt.Printf("warning: failed to load entries for %s: %v\n",
+			// Neither the file nor its backup could be read. Log
+			// it but continue - allow partial results
+			log.Printf("Warning: failed to load entries for %s: %v",
 				dateStr, err)
 			continue
 		}
@@ -247,54 +493,58 @@ func (s *Storage) LoadEntriesForRange(start, end time.Time) ([]models.TimeEntry,
 	return allEntries, nil
 }
 
+// QueryEntries loads the day range of q and filters it in memory. A zero
+// Start or End widens the range to the oldest or newest entry file.
+func (s *Storage) QueryEntries(q EntryQuery) ([]models.TimeEntry, error) {
+	start, end := q.lookbackStart(), q.End // a zero Start formats as 0001-01-01
+	if end.IsZero() {
+		end = time.Date(9999, 12, 31, 0, 0, 0, 0, time.UTC)
+	}
+	entries, err := s.loadDays(start, end)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	filtered := []models.TimeEntry{}
+	for _, e := range entries {
+		if q.InRange(e, now) && q.Match(e) {
+			filtered = append(filtered, e)
+		}
+	}
+	return filtered, nil
+}
+
 // DeleteEntry removes an entry from the storage.
 func (s *Storage) DeleteEntry(entry models.TimeEntry) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	path := s.getEntryFilePath(entry.StartTime)
-	data, err := os.ReadFile(path)
+	unlock, err := s.lockDir(true)
 	if err != nil {
 		return err
 	}
+	defer unlock()
 
+	path := s.getEntryFilePath(entry.StartTime)
 	var entries []models.TimeEntry
-	if err := json.Unmarshal(data, &entries); err != nil {
+	if err := readJSONFile(path, &entries); err != nil {
 		return err
 	}
 
+	var before *models.TimeEntry
 	newEntries := []models.TimeEntry{}
 	for _, e := range entries {
 		if e.ID != entry.ID {
 			newEntries = append(newEntries, e)
+		} else {
+			before = &e
 		}
 	}
 
-	newData, err := json.MarshalIndent(newEntries, "", "  ")
-	if err != nil {
-		return err
-	}
-	return os.WriteFile(path, newData, 0644)
-}
-
-// DeleteAllEntries removes all data from the storage (entries and state).
-func (s *Storage) DeleteAllEntries() error {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-
-	// Delete entries directory
-	if err := os.RemoveAll(filepath.Join(s.BaseDir, "entries")); err != nil {
-		return err
-	}
-
-	// Re-create empty entries directory
-	if err := os.MkdirAll(filepath.Join(s.BaseDir, "entries"), 0755); err != nil {
+	if err := s.writeJSON(path, newEntries); err != nil {
 		return err
 	}
-
-	// Delete state file
-	os.Remove(s.getStateFilePath())
-
+	s.recordChange(before, nil)
 	return nil
 }
 
@@ -308,23 +558,27 @@ func (s *Storage) SaveAppState(state AppState) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	data, err := json.MarshalIndent(state, "", "  ")
+	unlock, err := s.lockDir(true)
 	if err != nil {
 		return err
 	}
-	return os.WriteFile(s.getStateFilePath(), data, 0644)
+	defer unlock()
+
+	return s.writeJSON(s.getStateFilePath(), state)
 }
 
 func (s *Storage) LoadAppState() (AppState, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	data, err := os.ReadFile(s.getStateFilePath())
+	unlock, err := s.lockDir(false)
 	if err != nil {
 		return AppState{}, err
 	}
+	defer unlock()
+
 	var state AppState
-	if err := json.Unmarshal(data, &state); err != nil {
+	if err := readJSONFile(s.getStateFilePath(), &state); err != nil {
 		return AppState{}, err
 	}
 	return state, nil
@@ -333,6 +587,15 @@ func (s *Storage) LoadAppState() (AppState, error) {
 func (s *Storage) ClearAppState() error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+
+	unlock, err := s.lockDir(true)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	// Drop the backup too, or the next load would restore it.
+	s.noteRemove(s.getStateFilePath())
+	os.Remove(s.getStateFilePath() + backupSuffix)
 	return os.Remove(s.getStateFilePath())
 }
 
@@ -348,17 +611,23 @@ func (s *Storage) LoadProjects() ([]models.Project, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	path := s.getProjectsFilePath()
-	data, err := os.ReadFile(path)
+	unlock, err := s.lockDir(false)
 	if err != nil {
-		if os.IsNotExist(err) {
-			return []models.Project{}, nil
-		}
 		return nil, err
 	}
+	defer unlock()
 
+	return s.readProjects()
+}
+
+// readProjects reads the projects file. Callers hold s.mu and the folder
+// lock.
+func (s *Storage) readProjects() ([]models.Project, error) {
 	var projects []models.Project
-	if err := json.Unmarshal(data, &projects); err != nil {
+	if err := readJSONFile(s.getProjectsFilePath(), &projects); err != nil {
+		if os.IsNotExist(err) {
+			return []models.Project{}, nil
+		}
 		return nil, err
 	}
 	return projects, nil
@@ -370,9 +639,33 @@ func (s *Storage) SaveProjects(projects []models.Project) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	data, err := json.MarshalIndent(projects, "", "  ")
+	unlock, err := s.lockDir(true)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	return s.writeJSON(s.getProjectsFilePath(), projects)
+}
+
+// UpdateProjects replaces the projects with what update returns for the
+// current ones, holding the exclusive folder lock throughout.
+func (s *Storage) UpdateProjects(update func([]models.Project) ([]models.Project, error)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	unlock, err := s.lockDir(true)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	projects, err := s.readProjects()
 	if err != nil {
 		return err
 	}
-	return os.WriteFile(s.getProjectsFilePath(), data, 0644)
+	if projects, err = update(projects); err != nil {
+		return err
+	}
+	return s.writeJSON(s.getProjectsFilePath(), projects)
 }
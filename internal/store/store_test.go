@@ -0,0 +1,565 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/highercomve/tasktracker/internal/models"
+)
+
+func openBackends(t *testing.T) map[string]Store {
+	t.Helper()
+	backends := map[string]Store{}
+	for _, name := range []string{BackendJSON, BackendSQLite} {
+		s, err := Open(name, t.TempDir())
+		if err != nil {
+			t.Fatalf("Open(%s): %v", name, err)
+		}
+		t.Cleanup(func() { s.Close() })
+		backends[name] = s
+	}
+	return backends
+}
+
+func entryAt(id string, start time.Time, projectID string, tags ...string) models.TimeEntry {
+	return models.TimeEntry{
+		ID:          id,
+		Description: "task " + id,
+		ProjectID:   projectID,
+		StartTime:   start,
+		EndTime:     start.Add(time.Hour),
+		Duration:    3600,
+		Tags:        tags,
+		State:       models.TaskStateStopped,
+	}
+}
+
+func ids(entries []models.TimeEntry) []string {
+	out := make([]string, len(entries))
+	for i, e := range entries {
+		out[i] = e.ID
+	}
+	return out
+}
+
+func sameIDs(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestStoreBackends(t *testing.T) {
+	day1 := time.Date(2024, 1, 15, 9, 0, 0, 0, time.Local)
+	day2 := time.Date(2024, 1, 16, 9, 0, 0, 0, time.Local)
+	day3 := time.Date(2025, 6, 1, 9, 0, 0, 0, time.Local)
+
+	for name, s := range openBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			for _, e := range []models.TimeEntry{
+				entryAt("a", day1, "p1", "dev", "go"),
+				entryAt("b", day1.Add(2*time.Hour), "", "meeting"),
+				entryAt("c", day2, "p1", "review", "dev"),
+				entryAt("d", day3, "p2"),
+			} {
+				if err := s.SaveEntry(e); err != nil {
+					t.Fatalf("SaveEntry(%s): %v", e.ID, err)
+				}
+			}
+
+			got, err := s.LoadEntries(day1)
+			if err != nil || !sameIDs(ids(got), []string{"a", "b"}) {
+				t.Errorf("LoadEntries: got %v (%v)", ids(got), err)
+			}
+			got, _ = s.LoadEntriesForRange(day1, day2)
+			if !sameIDs(ids(got), []string{"a", "b", "c"}) {
+				t.Errorf("LoadEntriesForRange: got %v", ids(got))
+			}
+			got, _ = s.LoadEntriesForRange(day2, day1) // reversed bounds
+			if len(got) != 3 {
+				t.Errorf("LoadEntriesForRange reversed: got %v", ids(got))
+			}
+
+			queries := []struct {
+				name string
+				q    EntryQuery
+				want []string
+			}{
+				{"project", EntryQuery{ProjectID: "p1"}, []string{"a", "c"}},
+				{"unassigned", EntryQuery{ProjectID: "unassigned"}, []string{"b"}},
+				{"tag in any position", EntryQuery{Tag: "dev"}, []string{"a", "c"}},
+				{"range and project", EntryQuery{Start: day2, End: day3, ProjectID: "p1"}, []string{"c"}},
+				{"open start", EntryQuery{End: day1}, []string{"a", "b"}},
+				{"open end", EntryQuery{Start: day2}, []string{"c", "d"}},
+			}
+			for _, tt := range queries {
+				got, err := s.QueryEntries(tt.q)
+				if err != nil || !sameIDs(ids(got), tt.want) {
+					t.Errorf("QueryEntries(%s): expected %v, got %v (%v)", tt.name, tt.want, ids(got), err)
+				}
+			}
+
+			// Updating an entry replaces it, including its tags.
+			updated := entryAt("a", day1, "p2", "ops")
+			updated.Description = "renamed"
+			if err := s.SaveEntry(updated); err != nil {
+				t.Fatalf("SaveEntry update: %v", err)
+			}
+			got, _ = s.LoadEntries(day1)
+			if len(got) != 2 || got[0].Description != "renamed" {
+				t.Errorf("update not applied: %+v", got)
+			}
+			if got, _ := s.QueryEntries(EntryQuery{Tag: "dev"}); !sameIDs(ids(got), []string{"c"}) {
+				t.Errorf("stale tag after update: got %v", ids(got))
+			}
+
+			if err := s.DeleteEntry(updated); err != nil {
+				t.Fatalf("DeleteEntry: %v", err)
+			}
+			if got, _ := s.LoadEntries(day1); !sameIDs(ids(got), []string{"b"}) {
+				t.Errorf("after delete: got %v", ids(got))
+			}
+
+			// App state
+			if _, err := s.LoadAppState(); !errors.Is(err, os.ErrNotExist) {
+				t.Errorf("LoadAppState without state: expected ErrNotExist, got %v", err)
+			}
+			state := AppState{ActiveTaskID: "b", ActiveTaskDate: day1, LastStartTime: day1, LastRunVersion: "v1"}
+			if err := s.SaveAppState(state); err != nil {
+				t.Fatalf("SaveAppState: %v", err)
+			}
+			loaded, err := s.LoadAppState()
+			if err != nil || loaded.ActiveTaskID != "b" || !loaded.LastStartTime.Equal(day1) {
+				t.Errorf("LoadAppState: got %+v (%v)", loaded, err)
+			}
+
+			// Projects keep their order
+			projects := []models.Project{{ID: "p2", Name: "Zeta"}, {ID: "p1", Name: "Alpha"}}
+			if err := s.SaveProjects(projects); err != nil {
+				t.Fatalf("SaveProjects: %v", err)
+			}
+			loadedProjects, _ := s.LoadProjects()
+			if len(loadedProjects) != 2 || loadedProjects[0].ID != "p2" {
+				t.Errorf("LoadProjects: got %+v", loadedProjects)
+			}
+
+			if err := s.DeleteAllEntries(); err != nil {
+				t.Fatalf("DeleteAllEntries: %v", err)
+			}
+			if got, _ := s.QueryEntries(EntryQuery{}); len(got) != 0 {
+				t.Errorf("entries left after DeleteAllEntries: %v", ids(got))
+			}
+			if _, err := s.LoadAppState(); err == nil {
+				t.Errorf("app state left after DeleteAllEntries")
+			}
+			if p, _ := s.LoadProjects(); len(p) != 2 {
+				t.Errorf("DeleteAllEntries must keep projects, got %d", len(p))
+			}
+		})
+	}
+}
+
+func TestEntryHistory(t *testing.T) {
+	start := time.Date(2024, 2, 10, 9, 0, 0, 0, time.Local)
+	for name, s := range openBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			e := entryAt("h", start, "p1")
+			if err := s.SaveEntry(e); err != nil {
+				t.Fatalf("SaveEntry: %v", err)
+			}
+			saved, _ := s.LoadEntries(start)
+			if len(saved) != 1 || saved[0].CreatedAt.IsZero() || saved[0].UpdatedAt.IsZero() {
+				t.Fatalf("timestamps not set: %+v", saved)
+			}
+			created := saved[0].CreatedAt
+
+			// Saving the same content again records nothing.
+			s.SaveEntry(saved[0])
+
+			edited := saved[0]
+			edited.CreatedAt = time.Time{}
+			edited.EndTime = start.Add(2 * time.Hour)
+			edited.Duration = 7200
+			s.SaveEntry(edited)
+			saved, _ = s.LoadEntries(start)
+			if !saved[0].CreatedAt.Equal(created) {
+				t.Errorf("CreatedAt changed on update: %v -> %v", created, saved[0].CreatedAt)
+			}
+
+			s.SaveEntry(entryAt("other", start, ""))
+			s.DeleteEntry(edited)
+
+			h, err := s.EntryHistory("h")
+			if err != nil {
+				t.Fatalf("EntryHistory: %v", err)
+			}
+			var actions []string
+			for _, c := range h {
+				actions = append(actions, c.Action)
+			}
+			want := []string{models.EntryCreated, models.EntryUpdated, models.EntryDeleted}
+			if !sameIDs(actions, want) {
+				t.Fatalf("actions: expected %v, got %v", want, actions)
+			}
+			if h[1].Before.Duration != 3600 || h[1].After.Duration != 7200 {
+				t.Errorf("update record: before %+v, after %+v", h[1].Before, h[1].After)
+			}
+			if h[2].Before == nil || h[2].After != nil || h[2].Before.Duration != 7200 {
+				t.Errorf("delete record: %+v", h[2])
+			}
+
+			s.DeleteAllEntries()
+			if h, _ := s.EntryHistory("h"); len(h) != 0 {
+				t.Errorf("history left after DeleteAllEntries: %+v", h)
+			}
+		})
+	}
+}
+
+func TestLoadEntriesForRangeOverlap(t *testing.T) {
+	night := time.Date(2024, 3, 4, 22, 0, 0, 0, time.Local)
+	next := time.Date(2024, 3, 5, 12, 0, 0, 0, time.Local)
+	for name, s := range openBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			shift := entryAt("night", night, "")
+			shift.EndTime = night.Add(4 * time.Hour) // 02:00 on the next day
+			shift.Duration = 4 * 3600
+			s.SaveEntry(shift)
+			s.SaveEntry(entryAt("evening", night.Add(-4*time.Hour), ""))
+			s.SaveEntry(entryAt("noon", next, ""))
+
+			got, err := s.LoadEntriesForRange(next, next)
+			if err != nil || !sameIDs(ids(got), []string{"night", "noon"}) {
+				t.Errorf("LoadEntriesForRange: got %v (%v)", ids(got), err)
+			}
+			got, _ = s.QueryEntries(EntryQuery{Start: next})
+			if !sameIDs(ids(got), []string{"night", "noon"}) {
+				t.Errorf("QueryEntries: got %v", ids(got))
+			}
+			// LoadEntries still returns the entries filed under a day.
+			if got, _ := s.LoadEntries(next); !sameIDs(ids(got), []string{"noon"}) {
+				t.Errorf("LoadEntries: got %v", ids(got))
+			}
+		})
+	}
+}
+
+func TestEntrySegments(t *testing.T) {
+	start := time.Date(2024, 2, 12, 9, 0, 0, 0, time.Local)
+	for name, s := range openBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			// Entries saved before segments existed get a single one.
+			s.SaveEntry(entryAt("legacy", start, ""))
+
+			paused := entryAt("paused", start.Add(2*time.Hour), "")
+			paused.EndTime = paused.StartTime.Add(3 * time.Hour)
+			paused.Segments = []models.Segment{
+				{Start: paused.StartTime, End: paused.StartTime.Add(time.Hour)},
+				{Start: paused.StartTime.Add(2 * time.Hour), End: paused.EndTime},
+			}
+			paused.Duration = 7200
+			s.SaveEntry(paused)
+
+			// Segments that no longer match the entry are rebuilt.
+			stale := paused
+			stale.ID = "stale"
+			stale.Duration = 600
+			s.SaveEntry(stale)
+
+			entries, err := s.LoadEntries(start)
+			if err != nil {
+				t.Fatalf("LoadEntries: %v", err)
+			}
+			got := map[string][]models.Segment{}
+			for _, e := range entries {
+				got[e.ID] = e.Segments
+			}
+			if segs := got["legacy"]; len(segs) != 1 || !segs[0].Start.Equal(start) || !segs[0].End.Equal(start.Add(time.Hour)) {
+				t.Errorf("legacy segments: %v", segs)
+			}
+			if segs := got["paused"]; len(segs) != 2 || !segs[1].Start.Equal(start.Add(4*time.Hour)) {
+				t.Errorf("paused segments: %v", segs)
+			}
+			if segs := got["stale"]; len(segs) != 1 || segs[0].Duration(time.Time{}) != 10*time.Minute {
+				t.Errorf("stale segments: %v", segs)
+			}
+		})
+	}
+}
+
+func TestInvoices(t *testing.T) {
+	for name, s := range openBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			got, err := s.LoadInvoices()
+			if err != nil || len(got) != 0 {
+				t.Fatalf("LoadInvoices on empty store: %v, %v", got, err)
+			}
+			invoices := []models.Invoice{
+				{ID: "b", Number: "INV-0002", Sequence: 2, Lines: []models.InvoiceLine{{Description: "Website", Hours: 2, Rate: 50, Amount: 100}}},
+				{ID: "a", Number: "INV-0001", Sequence: 1, Paid: true},
+			}
+			if err := s.SaveInvoices(invoices); err != nil {
+				t.Fatalf("SaveInvoices: %v", err)
+			}
+			got, err = s.LoadInvoices()
+			if err != nil {
+				t.Fatalf("LoadInvoices: %v", err)
+			}
+			if len(got) != 2 || got[0].ID != "b" || got[0].Total() != 100 || !got[1].Paid {
+				t.Errorf("invoices: %+v", got)
+			}
+		})
+	}
+}
+
+func TestUpdateProjectsAndInvoices(t *testing.T) {
+	for _, name := range []string{BackendJSON, BackendSQLite} {
+		t.Run(name, func(t *testing.T) {
+			// Two stores on one folder stand in for two processes
+			dir := t.TempDir()
+			var stores []Store
+			for range 2 {
+				s, err := Open(name, dir)
+				if err != nil {
+					t.Fatalf("Open: %v", err)
+				}
+				t.Cleanup(func() { s.Close() })
+				stores = append(stores, s)
+			}
+
+			const perWriter = 10
+			done := make(chan struct{})
+			for w, s := range stores {
+				go func() {
+					defer func() { done <- struct{}{} }()
+					for i := range perWriter {
+						id := fmt.Sprintf("w%d-%d", w, i)
+						err := s.UpdateProjects(func(projects []models.Project) ([]models.Project, error) {
+							return append(projects, models.Project{ID: id}), nil
+						})
+						if err != nil {
+							t.Errorf("UpdateProjects(%s): %v", id, err)
+						}
+						err = s.UpdateInvoices(func(invoices []models.Invoice) ([]models.Invoice, error) {
+							return append(invoices, models.Invoice{ID: id}), nil
+						})
+						if err != nil {
+							t.Errorf("UpdateInvoices(%s): %v", id, err)
+						}
+					}
+				}()
+			}
+			<-done
+			<-done
+
+			projects, _ := stores[0].LoadProjects()
+			invoices, _ := stores[0].LoadInvoices()
+			if len(projects) != 2*perWriter || len(invoices) != 2*perWriter {
+				t.Errorf("expected %d projects and invoices, got %d and %d", 2*perWriter, len(projects), len(invoices))
+			}
+
+			failed := errors.New("failed")
+			err := stores[1].UpdateProjects(func([]models.Project) ([]models.Project, error) {
+				return nil, failed
+			})
+			if !errors.Is(err, failed) {
+				t.Errorf("UpdateProjects: expected the error of update, got %v", err)
+			}
+			if projects, _ := stores[0].LoadProjects(); len(projects) != 2*perWriter {
+				t.Errorf("a failed update must save nothing, got %d projects", len(projects))
+			}
+		})
+	}
+}
+
+func TestTrash(t *testing.T) {
+	start := time.Date(2024, 4, 2, 9, 0, 0, 0, time.Local)
+	for name, s := range openBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			s.SaveEntry(entryAt("a", start, ""))
+			s.SaveEntry(entryAt("b", start.AddDate(0, 0, 1), ""))
+			s.SaveAppState(AppState{LastRunVersion: "v1.0.0"})
+
+			if err := s.DeleteAllEntries(); err != nil {
+				t.Fatalf("DeleteAllEntries: %v", err)
+			}
+			items, err := s.ListTrash()
+			if err != nil || len(items) != 1 || items[0].Entries != 2 {
+				t.Fatalf("ListTrash: %+v, %v", items, err)
+			}
+
+			// Recreated after the erase; the restore must not overwrite it.
+			recreated := entryAt("b", start.AddDate(0, 0, 1), "")
+			recreated.Description = "recreated"
+			s.SaveEntry(recreated)
+
+			if err := s.RestoreTrash(items[0].ID); err != nil {
+				t.Fatalf("RestoreTrash: %v", err)
+			}
+			entries, _ := s.QueryEntries(EntryQuery{})
+			if !sameIDs(ids(entries), []string{"a", "b"}) {
+				t.Fatalf("restored entries: got %v", ids(entries))
+			}
+			if entries[1].Description != "recreated" {
+				t.Errorf("restore overwrote a newer entry: %+v", entries[1])
+			}
+			if st, err := s.LoadAppState(); err != nil || st.LastRunVersion != "v1.0.0" {
+				t.Errorf("restored state: %+v, %v", st, err)
+			}
+			if h, _ := s.EntryHistory("a"); len(h) != 1 {
+				t.Errorf("restored history: %+v", h)
+			}
+			if items, _ := s.ListTrash(); len(items) != 0 {
+				t.Errorf("trash not emptied: %+v", items)
+			}
+			if err := s.RestoreTrash("../entries"); err == nil {
+				t.Errorf("RestoreTrash accepted a path outside the trash")
+			}
+		})
+	}
+}
+
+func TestSQLiteImportsJSONData(t *testing.T) {
+	dir := t.TempDir()
+	start := time.Date(2023, 3, 1, 10, 0, 0, 0, time.Local)
+
+	legacy := NewStorage(dir)
+	legacy.SaveEntry(entryAt("old", start, "p1", "dev"))
+	legacy.SaveProjects([]models.Project{{ID: "p1", Name: "Legacy"}})
+	legacy.SaveAppState(AppState{LastRunVersion: "v0.9.0"})
+
+	s, err := NewSQLiteStorage(dir)
+	if err != nil {
+		t.Fatalf("NewSQLiteStorage: %v", err)
+	}
+	entries, _ := s.QueryEntries(EntryQuery{ProjectID: "p1"})
+	if !sameIDs(ids(entries), []string{"old"}) {
+		t.Errorf("imported entries: got %v", ids(entries))
+	}
+	if p, _ := s.LoadProjects(); len(p) != 1 || p[0].Name != "Legacy" {
+		t.Errorf("imported projects: got %+v", p)
+	}
+	if st, _ := s.LoadAppState(); st.LastRunVersion != "v0.9.0" {
+		t.Errorf("imported state: got %+v", st)
+	}
+	if h, _ := s.EntryHistory("old"); len(h) != 1 || h[0].Action != models.EntryCreated {
+		t.Errorf("imported history: got %+v", h)
+	}
+	s.Close()
+
+	// The import only runs once: data deleted afterwards stays deleted.
+	s, err = NewSQLiteStorage(dir)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	s.DeleteAllEntries()
+	s.Close()
+	s, err = NewSQLiteStorage(dir)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	defer s.Close()
+	if entries, _ := s.QueryEntries(EntryQuery{}); len(entries) != 0 {
+		t.Errorf("JSON data re-imported on reopen: %v", ids(entries))
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	dir := t.TempDir()
+	start := time.Date(2023, 3, 1, 10, 0, 0, 0, time.Local)
+
+	// Switching to SQLite for the first time takes the JSON data along,
+	// timestamps included.
+	legacy := NewStorage(dir)
+	legacy.SaveEntry(entryAt("a", start, "p1"))
+	legacy.SaveProjects([]models.Project{{ID: "p1", Name: "Website"}})
+	legacy.SaveInvoices([]models.Invoice{{ID: "i1", Number: "2023-001"}})
+	legacy.SaveAppState(AppState{ActiveTaskID: "a", LastRunVersion: "v1.0.0"})
+	saved, _ := legacy.LoadEntries(start)
+	if err := Migrate(dir, "", BackendSQLite); err != nil {
+		t.Fatalf("json to sqlite: %v", err)
+	}
+	s, err := Open(BackendSQLite, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	migrated, _ := s.LoadEntries(start)
+	if len(migrated) != 1 || !migrated[0].UpdatedAt.Equal(saved[0].UpdatedAt) {
+		t.Errorf("migrated entries: got %+v", migrated)
+	}
+	if p, _ := s.LoadProjects(); len(p) != 1 || p[0].Name != "Website" {
+		t.Errorf("migrated projects: got %+v", p)
+	}
+	if inv, _ := s.LoadInvoices(); len(inv) != 1 || inv[0].Number != "2023-001" {
+		t.Errorf("migrated invoices: got %+v", inv)
+	}
+	if st, _ := s.LoadAppState(); st.ActiveTaskID != "a" {
+		t.Errorf("migrated state: got %+v", st)
+	}
+	if h, _ := s.EntryHistory("a"); len(h) != 1 {
+		t.Errorf("migrated history: got %+v", h)
+	}
+	if items, _ := s.ListTrash(); len(items) != 0 {
+		t.Errorf("trash after the first switch: %+v", items)
+	}
+
+	// Switching back replaces the JSON data left behind, which goes to
+	// the trash.
+	s.SaveEntry(entryAt("b", start.Add(2*time.Hour), "p1"))
+	s.Close()
+	if err := Migrate(dir, BackendSQLite, BackendJSON); err != nil {
+		t.Fatalf("sqlite to json: %v", err)
+	}
+	if entries, _ := legacy.LoadEntries(start); !sameIDs(ids(entries), []string{"a", "b"}) {
+		t.Errorf("entries after switching back: got %v", ids(entries))
+	}
+	if h, _ := legacy.EntryHistory("b"); len(h) != 1 {
+		t.Errorf("history after switching back: got %+v", h)
+	}
+	if items, _ := legacy.ListTrash(); len(items) != 1 || items[0].Entries != 1 {
+		t.Errorf("stale JSON data not in the trash: %+v", items)
+	}
+
+	// And so does the stale database on the next switch.
+	legacy.DeleteEntry(entryAt("a", start, "p1"))
+	if err := Migrate(dir, BackendJSON, BackendSQLite); err != nil {
+		t.Fatalf("json to sqlite again: %v", err)
+	}
+	s, err = Open(BackendSQLite, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if entries, _ := s.LoadEntries(start); !sameIDs(ids(entries), []string{"b"}) {
+		t.Errorf("entries after switching again: got %v", ids(entries))
+	}
+}
+
+func TestSQLiteMoveData(t *testing.T) {
+	oldDir, newDir := t.TempDir(), t.TempDir()
+	s, err := NewSQLiteStorage(oldDir)
+	if err != nil {
+		t.Fatalf("NewSQLiteStorage: %v", err)
+	}
+	defer s.Close()
+	start := time.Date(2024, 5, 5, 8, 0, 0, 0, time.Local)
+	s.SaveEntry(entryAt("x", start, ""))
+
+	if err := s.MoveData(newDir); err != nil {
+		t.Fatalf("MoveData: %v", err)
+	}
+	if s.Dir() != newDir {
+		t.Errorf("Dir: expected %s, got %s", newDir, s.Dir())
+	}
+	if got, _ := s.LoadEntries(start); !sameIDs(ids(got), []string{"x"}) {
+		t.Errorf("entries after move: got %v", ids(got))
+	}
+}
@@ -0,0 +1,309 @@
+package store
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/highercomve/tasktracker/internal/models"
+)
+
+// trashDirName is the folder inside the data folder that DeleteAllEntries
+// moves erased data to. Each erase gets its own subfolder laid out like a
+// JSON data folder: entries/, state.json and history.jsonl.
+const trashDirName = "trash"
+
+// trashIDLayout names the trash subfolders after the time of the erase.
+const trashIDLayout = "20060102-150405"
+
+// TrashItem is one set of data erased by DeleteAllEntries.
+type TrashItem struct {
+	ID        string
+	DeletedAt time.Time
+	Entries   int
+}
+
+// trashSnapshot is the content of a trash folder.
+type trashSnapshot struct {
+	Entries []models.TimeEntry
+	State   *AppState
+	History []models.EntryChange
+}
+
+func (t trashSnapshot) empty() bool {
+	return len(t.Entries) == 0 && t.State == nil && len(t.History) == 0
+}
+
+// newTrashDir creates the folder for an erase happening now.
+func newTrashDir(baseDir string) (string, error) {
+	root := filepath.Join(baseDir, trashDirName)
+	if err := os.MkdirAll(root, 0755); err != nil {
+		return "", err
+	}
+	name := time.Now().Format(trashIDLayout)
+	for i := 2; ; i++ {
+		dir := filepath.Join(root, name)
+		err := os.Mkdir(dir, 0755)
+		if err == nil {
+			return dir, nil
+		}
+		if !os.IsExist(err) {
+			return "", err
+		}
+		name = fmt.Sprintf("%s-%d", time.Now().Format(trashIDLayout), i)
+	}
+}
+
+// trashPath returns the folder of the trash item id.
+func trashPath(baseDir, id string) (string, error) {
+	if id == "" || id != filepath.Base(id) || strings.HasPrefix(id, ".") {
+		return "", fmt.Errorf("invalid trash item %q", id)
+	}
+	dir := filepath.Join(baseDir, trashDirName, id)
+	if _, err := os.Stat(dir); err != nil {
+		return "", err
+	}
+	return dir, nil
+}
+
+// listTrash returns the trash items in baseDir, newest first.
+func listTrash(baseDir string) ([]TrashItem, error) {
+	dirs, err := os.ReadDir(filepath.Join(baseDir, trashDirName))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []TrashItem{}, nil
+		}
+		return nil, err
+	}
+	items := []TrashItem{}
+	for _, d := range dirs {
+		if !d.IsDir() || len(d.Name()) < len(trashIDLayout) {
+			continue
+		}
+		deletedAt, err := time.ParseInLocation(trashIDLayout, d.Name()[:len(trashIDLayout)], time.Local)
+		if err != nil {
+			continue
+		}
+		snap, err := readTrash(filepath.Join(baseDir, trashDirName, d.Name()))
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, TrashItem{ID: d.Name(), DeletedAt: deletedAt, Entries: len(snap.Entries)})
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].ID > items[j].ID })
+	return items, nil
+}
+
+// readTrash loads the snapshot kept in the trash folder dir.
+func readTrash(dir string) (trashSnapshot, error) {
+	var snap trashSnapshot
+	files, err := os.ReadDir(filepath.Join(dir, "entries"))
+	if err != nil && !os.IsNotExist(err) {
+		return snap, err
+	}
+	for _, f := range files {
+		name := f.Name()
+		if f.IsDir() || !strings.HasSuffix(name, ".json") {
+			continue
+		}
+		if _, ok := conflictCopyDay(name); ok {
+			continue
+		}
+		var entries []models.TimeEntry
+		if err := readJSONFile(filepath.Join(dir, "entries", name), &entries); err != nil {
+			return snap, err
+		}
+		snap.Entries = append(snap.Entries, entries...)
+	}
+
+	var state AppState
+	if err := readJSONFile(filepath.Join(dir, "state.json"), &state); err == nil {
+		snap.State = &state
+	} else if !os.IsNotExist(err) {
+		return snap, err
+	}
+
+	snap.History, err = readHistory(filepath.Join(dir, historyFileName), nil)
+	return snap, err
+}
+
+// writeTrash saves snap to the trash folder dir in the JSON layout.
+func writeTrash(dir string, snap trashSnapshot) error {
+	if err := os.MkdirAll(filepath.Join(dir, "entries"), 0755); err != nil {
+		return err
+	}
+	days := map[string][]models.TimeEntry{}
+	for _, e := range snap.Entries {
+		day := dayKey(e.StartTime)
+		days[day] = append(days[day], e)
+	}
+	for day, entries := range days {
+		if err := writeTrashJSON(filepath.Join(dir, "entries", day+".json"), entries); err != nil {
+			return err
+		}
+	}
+	if snap.State != nil {
+		if err := writeTrashJSON(filepath.Join(dir, "state.json"), snap.State); err != nil {
+			return err
+		}
+	}
+	for _, c := range snap.History {
+		if err := appendHistory(filepath.Join(dir, historyFileName), c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeTrashJSON(path string, v any) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, data, 0644)
+}
+
+// DeleteAllEntries moves all entries, their change log and the app state to
+// a new folder under trash/, from where RestoreTrash can bring them back.
+// Projects are kept.
+func (s *Storage) DeleteAllEntries() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	unlock, err := s.lockDir(true)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	dir, err := newTrashDir(s.BaseDir)
+	if err != nil {
+		return err
+	}
+
+	entriesDir := filepath.Join(s.BaseDir, "entries")
+	if files, err := os.ReadDir(entriesDir); err == nil {
+		for _, f := range files {
+			s.noteRemove(filepath.Join(entriesDir, f.Name()))
+		}
+	}
+	s.noteRemove(s.getStateFilePath())
+
+	moved := false
+	for _, name := range []string{"entries", "state.json", historyFileName} {
+		err := os.Rename(filepath.Join(s.BaseDir, name), filepath.Join(dir, name))
+		if err == nil {
+			moved = true
+		} else if !os.IsNotExist(err) {
+			return err
+		}
+	}
+	if !moved {
+		os.Remove(dir)
+	}
+	// Drop the backup too, or the next load would restore it.
+	os.Remove(s.getStateFilePath() + backupSuffix)
+
+	// Re-create empty entries directory
+	return os.MkdirAll(entriesDir, 0755)
+}
+
+// ListTrash returns the data erased by DeleteAllEntries, newest first.
+func (s *Storage) ListTrash() ([]TrashItem, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	unlock, err := s.lockDir(false)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	return listTrash(s.BaseDir)
+}
+
+// RestoreTrash brings back the data of a trash item and deletes it from the
+// trash. Entries recreated since the erase are kept as they are, and the app
+// state is only restored when there is none.
+func (s *Storage) RestoreTrash(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	unlock, err := s.lockDir(true)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	dir, err := trashPath(s.BaseDir, id)
+	if err != nil {
+		return err
+	}
+	snap, err := readTrash(dir)
+	if err != nil {
+		return err
+	}
+
+	days := map[string][]models.TimeEntry{}
+	for _, e := range snap.Entries {
+		path := s.getEntryFilePath(e.StartTime)
+		days[path] = append(days[path], e)
+	}
+	for path, trashed := range days {
+		var entries []models.TimeEntry
+		if err := readJSONFile(path, &entries); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		present := make(map[string]bool, len(entries))
+		for _, e := range entries {
+			present[e.ID] = true
+		}
+		for _, e := range trashed {
+			if !present[e.ID] {
+				entries = append(entries, e)
+			}
+		}
+		if err := s.writeJSON(path, entries); err != nil {
+			return err
+		}
+	}
+
+	if snap.State != nil {
+		var current AppState
+		if err := readJSONFile(s.getStateFilePath(), &current); os.IsNotExist(err) {
+			if err := s.writeJSON(s.getStateFilePath(), snap.State); err != nil {
+				return err
+			}
+		}
+	}
+
+	if len(snap.History) > 0 {
+		// The trashed records predate everything logged since the erase.
+		historyPath := filepath.Join(s.BaseDir, historyFileName)
+		later, err := os.ReadFile(historyPath)
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		var data []byte
+		for _, c := range snap.History {
+			line, err := json.Marshal(c)
+			if err != nil {
+				return err
+			}
+			data = append(append(data, line...), '\n')
+		}
+		tmpPath, err := writeTemp(historyPath, append(data, later...), 0644)
+		if err != nil {
+			return err
+		}
+		if err := commitTemp(tmpPath, historyPath); err != nil {
+			return err
+		}
+	}
+
+	return os.RemoveAll(dir)
+}
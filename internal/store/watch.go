@@ -0,0 +1,254 @@
+package store
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// ChangeKind tells which part of the data changed outside this process.
+type ChangeKind int
+
+const (
+	EntriesChanged ChangeKind = iota
+	ProjectsChanged
+	StateChanged
+	// ConflictCopyFound reports a sync conflict copy of a day file; see
+	// ConflictMerger.
+	ConflictCopyFound
+)
+
+// Change describes data modified by another process, a sync tool or a manual
+// edit. Writes made through the same Store are not reported.
+type Change struct {
+	Kind ChangeKind
+	// Date is the day of the changed entry file, or zero when unknown.
+	Date time.Time
+}
+
+// changeDebounce coalesces the bursts of events sync tools produce while
+// replacing a file.
+const changeDebounce = 300 * time.Millisecond
+
+// absent is the content hash recorded for a file we deleted.
+var absent = [sha256.Size]byte{}
+
+// watchState is the fsnotify side of the JSON store. It remembers the hash of
+// every file the store itself wrote, so that only foreign changes are
+// published.
+type watchState struct {
+	mu        sync.Mutex
+	listeners []func(Change)
+	watcher   *fsnotify.Watcher
+	dir       string
+	known     map[string][sha256.Size]byte
+	pending   map[string]*time.Timer
+}
+
+// writeJSON marshals v with indentation and writes it atomically, noting the
+// content as our own.
+func (s *Storage) writeJSON(path string, v any) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	s.noteWrite(path, data)
+	return writeFileAtomic(path, data, 0644)
+}
+
+func (s *Storage) noteWrite(path string, data []byte) {
+	s.watch.mu.Lock()
+	defer s.watch.mu.Unlock()
+	if s.watch.known == nil {
+		s.watch.known = make(map[string][sha256.Size]byte)
+	}
+	s.watch.known[path] = sha256.Sum256(data)
+}
+
+func (s *Storage) noteRemove(path string) {
+	s.watch.mu.Lock()
+	defer s.watch.mu.Unlock()
+	if s.watch.known == nil {
+		s.watch.known = make(map[string][sha256.Size]byte)
+	}
+	s.watch.known[path] = absent
+}
+
+// Subscribe registers fn for changes made to the data folder from outside.
+// The folder is watched from the first subscription on; fn runs on the
+// watcher goroutine.
+func (s *Storage) Subscribe(fn func(Change)) {
+	dir := s.Dir()
+	s.watch.mu.Lock()
+	defer s.watch.mu.Unlock()
+	s.watch.listeners = append(s.watch.listeners, fn)
+	if s.watch.watcher != nil {
+		return
+	}
+
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		log.Printf("Warning: cannot watch data folder: %v", err)
+		return
+	}
+	s.watch.watcher = w
+	s.watch.pending = make(map[string]*time.Timer)
+	s.watchDirLocked(dir)
+	go s.watchLoop(w)
+}
+
+// watchDirLocked points the watcher at dir and its entries folder.
+func (s *Storage) watchDirLocked(dir string) {
+	w := s.watch.watcher
+	if s.watch.dir != "" {
+		w.Remove(filepath.Join(s.watch.dir, "entries"))
+		w.Remove(s.watch.dir)
+	}
+	s.watch.dir = dir
+	if err := w.Add(dir); err != nil {
+		log.Printf("Warning: cannot watch %s: %v", dir, err)
+	}
+	// Missing until the first entry is saved; picked up on Create.
+	w.Add(filepath.Join(dir, "entries"))
+}
+
+// rewatch follows the store to a new data folder. Everything may differ
+// there, so all views are told to reload.
+func (s *Storage) rewatch(dir string) {
+	s.watch.mu.Lock()
+	if s.watch.watcher == nil {
+		s.watch.mu.Unlock()
+		return
+	}
+	s.watchDirLocked(dir)
+	s.watch.mu.Unlock()
+
+	for _, kind := range []ChangeKind{EntriesChanged, ProjectsChanged, StateChanged} {
+		s.publish(Change{Kind: kind})
+	}
+}
+
+func (s *Storage) stopWatching() {
+	s.watch.mu.Lock()
+	defer s.watch.mu.Unlock()
+	if s.watch.watcher == nil {
+		return
+	}
+	s.watch.watcher.Close()
+	s.watch.watcher = nil
+	for _, t := range s.watch.pending {
+		t.Stop()
+	}
+}
+
+func (s *Storage) watchLoop(w *fsnotify.Watcher) {
+	for {
+		select {
+		case ev, ok := <-w.Events:
+			if !ok {
+				return
+			}
+			s.handleEvent(w, ev)
+		case err, ok := <-w.Errors:
+			if !ok {
+				return
+			}
+			log.Printf("Warning: data folder watcher: %v", err)
+		}
+	}
+}
+
+func (s *Storage) handleEvent(w *fsnotify.Watcher, ev fsnotify.Event) {
+	s.watch.mu.Lock()
+	defer s.watch.mu.Unlock()
+
+	dir, name := filepath.Split(filepath.Clean(ev.Name))
+	dir = filepath.Clean(dir)
+	if dir == s.watch.dir && name == "entries" && ev.Has(fsnotify.Create) {
+		w.Add(ev.Name)
+		return
+	}
+	if _, ok := changeFor(s.watch.dir, ev.Name); !ok {
+		return
+	}
+
+	if t, ok := s.watch.pending[ev.Name]; ok {
+		t.Reset(changeDebounce)
+		return
+	}
+	path := ev.Name
+	s.watch.pending[path] = time.AfterFunc(changeDebounce, func() { s.settle(path) })
+}
+
+// settle runs once a file has been quiet for changeDebounce and publishes
+// the change unless the content is what we wrote last.
+func (s *Storage) settle(path string) {
+	s.watch.mu.Lock()
+	delete(s.watch.pending, path)
+	change, ok := changeFor(s.watch.dir, path)
+	if !ok {
+		s.watch.mu.Unlock()
+		return
+	}
+	sum := absent
+	if data, err := os.ReadFile(path); err == nil {
+		sum = sha256.Sum256(data)
+	} else if change.Kind == ConflictCopyFound {
+		// A copy that is gone needs no merging.
+		s.watch.mu.Unlock()
+		return
+	}
+	if s.watch.known == nil {
+		s.watch.known = make(map[string][sha256.Size]byte)
+	}
+	if prev, seen := s.watch.known[path]; seen && prev == sum {
+		s.watch.mu.Unlock()
+		return
+	}
+	s.watch.known[path] = sum
+	s.watch.mu.Unlock()
+
+	s.publish(change)
+}
+
+func (s *Storage) publish(c Change) {
+	s.watch.mu.Lock()
+	listeners := append([]func(Change){}, s.watch.listeners...)
+	s.watch.mu.Unlock()
+	for _, fn := range listeners {
+		fn(c)
+	}
+}
+
+// changeFor maps a path inside the data folder dir to the change it
+// represents. Temporary files, backups and the lock file are ignored.
+func changeFor(dir, path string) (Change, bool) {
+	parent, name := filepath.Split(filepath.Clean(path))
+	parent = filepath.Clean(parent)
+	switch {
+	case parent == dir && name == "projects.json":
+		return Change{Kind: ProjectsChanged}, true
+	case parent == dir && name == "state.json":
+		return Change{Kind: StateChanged}, true
+	case parent != filepath.Join(dir, "entries"):
+		return Change{}, false
+	}
+	if day, ok := conflictCopyDay(name); ok {
+		return Change{Kind: ConflictCopyFound, Date: day}, true
+	}
+	if !strings.HasSuffix(name, ".json") {
+		return Change{}, false
+	}
+	day, err := time.ParseInLocation("2006-01-02", strings.TrimSuffix(name, ".json"), time.Local)
+	if err != nil {
+		return Change{}, false
+	}
+	return Change{Kind: EntriesChanged, Date: day}, true
+}
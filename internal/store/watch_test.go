@@ -0,0 +1,96 @@
+package store
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func collectChanges(s Store) <-chan Change {
+	ch := make(chan Change, 16)
+	s.Subscribe(func(c Change) { ch <- c })
+	return ch
+}
+
+func expectChange(t *testing.T, ch <-chan Change, kind ChangeKind) Change {
+	t.Helper()
+	timeout := time.After(3 * time.Second)
+	for {
+		select {
+		case c := <-ch:
+			if c.Kind == kind {
+				return c
+			}
+		case <-timeout:
+			t.Fatalf("no change of kind %d published", kind)
+			return Change{}
+		}
+	}
+}
+
+func expectQuiet(t *testing.T, ch <-chan Change, d time.Duration) {
+	t.Helper()
+	select {
+	case c := <-ch:
+		t.Errorf("unexpected change published: %+v", c)
+	case <-time.After(d):
+	}
+}
+
+func TestStorageWatchPublishesForeignChanges(t *testing.T) {
+	dir := t.TempDir()
+	s := NewStorage(dir)
+	defer s.Close()
+	day := time.Date(2024, 4, 2, 9, 0, 0, 0, time.Local)
+	changes := collectChanges(s)
+
+	// Our own writes are not reported.
+	s.SaveEntry(entryAt("own", day, ""))
+	s.SaveAppState(AppState{ActiveTaskID: "own"})
+	expectQuiet(t, changes, 2*changeDebounce)
+
+	// Another process writing the same day file is.
+	other := NewStorage(dir)
+	other.SaveEntry(entryAt("foreign", day.Add(time.Hour), ""))
+	c := expectChange(t, changes, EntriesChanged)
+	if c.Date.Format("2006-01-02") != "2024-04-02" {
+		t.Errorf("Date: got %v", c.Date)
+	}
+
+	other.SaveProjects(nil)
+	expectChange(t, changes, ProjectsChanged)
+
+	os.Remove(filepath.Join(dir, "state.json"))
+	expectChange(t, changes, StateChanged)
+
+	// Lock, temporary and backup files are ignored.
+	os.WriteFile(filepath.Join(dir, "entries", "2024-04-02.json.bak"), []byte("[]"), 0644)
+	os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("x"), 0644)
+	expectQuiet(t, changes, 2*changeDebounce)
+}
+
+func TestSQLiteWatchPublishesForeignCommits(t *testing.T) {
+	saved := sqlitePollInterval
+	sqlitePollInterval = 50 * time.Millisecond
+	defer func() { sqlitePollInterval = saved }()
+
+	dir := t.TempDir()
+	s, err := NewSQLiteStorage(dir)
+	if err != nil {
+		t.Fatalf("NewSQLiteStorage: %v", err)
+	}
+	defer s.Close()
+	changes := collectChanges(s)
+
+	s.SaveEntry(entryAt("own", time.Now(), ""))
+	expectQuiet(t, changes, 300*time.Millisecond)
+
+	other, err := NewSQLiteStorage(dir)
+	if err != nil {
+		t.Fatalf("second connection: %v", err)
+	}
+	defer other.Close()
+	other.SaveEntry(entryAt("foreign", time.Now(), ""))
+	expectChange(t, changes, EntriesChanged)
+}
@@ -0,0 +1,152 @@
+package ui
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/highercomve/tasktracker/internal/importer"
+	"github.com/highercomve/tasktracker/internal/models"
+	"github.com/highercomve/tasktracker/internal/service"
+	"github.com/highercomve/tasktracker/internal/store"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	fyneDialog "fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/lang"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"github.com/spf13/viper"
+	"github.com/sqweek/dialog"
+)
+
+// exportICS saves the entries between start and end as an iCalendar file.
+func exportICS(w fyne.Window, s store.Store, start, end time.Time) {
+	filename := fmt.Sprintf("report_%s_%s.ics", start.Format("20060102"), end.Format("20060102"))
+	path, err := dialog.File().Title(lang.L("export_ics")).SetStartFile(filename).Filter("iCalendar files", "ics").Save()
+	if err != nil {
+		if err != dialog.ErrCancelled {
+			fyneDialog.ShowError(err, w)
+		}
+		return
+	}
+	if path == "" {
+		return
+	}
+
+	entries, err := s.LoadEntriesForRange(start, end)
+	if err != nil {
+		fyneDialog.ShowError(err, w)
+		return
+	}
+	projects, err := s.LoadProjects()
+	if err != nil {
+		fyneDialog.ShowError(err, w)
+		return
+	}
+	var buf bytes.Buffer
+	if err := service.ExportICS(&buf, entries, projects, time.Now()); err != nil {
+		fyneDialog.ShowError(err, w)
+		return
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		fyneDialog.ShowError(err, w)
+		return
+	}
+	fyneDialog.ShowInformation(lang.L("success"), lang.L("ics_saved"), w)
+}
+
+// chooseCalendar asks for the .ics file whose events are suggested as
+// entries and remembers it in the config file.
+func chooseCalendar(w fyne.Window, onDone func()) {
+	path, err := dialog.File().Title(lang.L("choose_calendar")).Filter("iCalendar files", "ics").Load()
+	if err != nil {
+		if err != dialog.ErrCancelled {
+			fyneDialog.ShowError(err, w)
+		}
+		return
+	}
+	setCalendarFile(path)
+	onDone()
+}
+
+func setCalendarFile(path string) {
+	viper.Set("calendar_file", path)
+	if err := viper.WriteConfigAs(viper.ConfigFileUsed()); err != nil {
+		fmt.Printf("warning: failed to save calendar file: %v\n", err)
+	}
+}
+
+// makeSuggestions lists the events of the calendar file on day that are not
+// tracked yet, each with a button that adds it as an entry. It returns an
+// empty container when no calendar file is set.
+func (r *Reports) makeSuggestions(day time.Time, onChange func()) fyne.CanvasObject {
+	path := viper.GetString("calendar_file")
+	if path == "" {
+		return container.NewVBox()
+	}
+	forget := widget.NewButtonWithIcon("", theme.CancelIcon(), func() {
+		setCalendarFile("")
+		onChange()
+	})
+	header := container.NewHBox(widget.NewLabelWithStyle(lang.L("suggested_entries"), fyne.TextAlignLeading, fyne.TextStyle{Bold: true}), layout.NewSpacer(), forget)
+
+	events, err := readCalendar(path)
+	if err != nil {
+		fmt.Printf("warning: failed to read calendar %s: %v\n", path, err)
+		return container.NewVBox(widget.NewSeparator(), header, widget.NewLabel(lang.L("calendar_read_error")))
+	}
+	start := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, time.Local)
+	entries, err := r.storage.LoadEntriesForRange(start, start)
+	if err != nil {
+		fmt.Printf("warning: failed to load entries: %v\n", err)
+	}
+	suggestions := importer.SuggestEntries(events, entries, r.projects, start, start.AddDate(0, 0, 1))
+	if len(suggestions) == 0 {
+		return container.NewVBox(widget.NewSeparator(), header, widget.NewLabel(lang.L("no_suggestions")))
+	}
+
+	rows := container.NewVBox(widget.NewSeparator(), header)
+	for _, e := range suggestions {
+		text := fmt.Sprintf("%s–%s  %s", e.StartTime.Format("15:04"), e.EndTime.Format("15:04"), e.Description)
+		var extra []string
+		if p := service.FindProjectByID(r.projects, e.ProjectID); p != nil {
+			extra = append(extra, p.Name)
+		}
+		extra = append(extra, e.Tags...)
+		if len(extra) > 0 {
+			text += " (" + strings.Join(extra, ", ") + ")"
+		}
+		addBtn := widget.NewButtonWithIcon(lang.L("track_event"), theme.ContentAddIcon(), func() {
+			r.addSuggestion(e, onChange)
+		})
+		rows.Add(container.NewBorder(nil, nil, nil, addBtn, widget.NewLabel(text)))
+	}
+	return rows
+}
+
+// addSuggestion saves a suggested entry, recording it on undo.
+func (r *Reports) addSuggestion(e models.TimeEntry, onChange func()) {
+	if err := r.storage.SaveEntry(e); err != nil {
+		fyneDialog.ShowError(fmt.Errorf("%s: %w", lang.L("save_error"), err), safeGetMainWindow())
+		return
+	}
+	r.undo.Push(service.UndoAction{
+		Label: lang.L("event_tracked"),
+		Undo:  func() error { return r.storage.DeleteEntry(e) },
+		Redo:  func() error { return r.storage.SaveEntry(e) },
+	})
+	onChange()
+}
+
+func readCalendar(path string) ([]importer.CalendarEvent, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return importer.ReadCalendar(f)
+}
@@ -1,9 +1,15 @@
 package ui
 
 import (
+	"errors"
 	"fmt"
 	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/highercomve/tasktracker/internal/service"
 	"github.com/highercomve/tasktracker/internal/store"
 
 	"fyne.io/fyne/v2"
@@ -18,12 +24,13 @@ import (
 
 type Config struct {
 	window             fyne.Window
-	storage            *store.Storage
+	storage            store.Store
+	undo               *service.UndoStack
 	userConfigFilePath string
 }
 
-func NewConfig(w fyne.Window, s *store.Storage, userConfigFilePath string) *Config {
-	return &Config{window: w, storage: s, userConfigFilePath: userConfigFilePath}
+func NewConfig(w fyne.Window, s store.Store, userConfigFilePath string, undo *service.UndoStack) *Config {
+	return &Config{window: w, storage: s, undo: undo, userConfigFilePath: userConfigFilePath}
 }
 
 func (c *Config) MakeUI() fyne.CanvasObject {
@@ -42,6 +49,8 @@ func (c *Config) MakeUI() fyne.CanvasObject {
 	thresholdEntry := widget.NewEntry()
 	thresholdEntry.SetText(fmt.Sprintf("%d", idleThreshold))
 
+	awayPolicy := newOptionSelect(awayPolicies, viper.GetString("away_policy"))
+
 	hourlyRate := viper.GetFloat64("hourly_rate")
 	hourlyRateEntry := widget.NewEntry()
 	hourlyRateEntry.SetText(fmt.Sprintf("%.2f", hourlyRate))
@@ -54,6 +63,85 @@ func (c *Config) MakeUI() fyne.CanvasObject {
 	extraRateEntry := widget.NewEntry()
 	extraRateEntry.SetText(fmt.Sprintf("%.2f", extraRate))
 
+	currencyEntry := widget.NewEntry()
+	currencyEntry.SetPlaceHolder("EUR")
+	currencyEntry.SetText(viper.GetString("currency"))
+
+	roundingMode := newOptionSelect(roundingModes, viper.GetString("rounding_mode"))
+	roundingScope := newOptionSelect(roundingScopes, viper.GetString("rounding_scope"))
+	roundingIncrementEntry := widget.NewEntry()
+	roundingIncrementEntry.SetText(fmt.Sprintf("%d", viper.GetInt("rounding_increment")))
+	roundingMinimumEntry := widget.NewEntry()
+	roundingMinimumEntry.SetText(fmt.Sprintf("%d", viper.GetInt("rounding_minimum")))
+
+	thresholdMode := newOptionSelect(thresholdModes, viper.GetString("threshold_mode"))
+	workDayLabels := make([]string, len(weekdays))
+	for i, d := range weekdays {
+		workDayLabels[i] = lang.L(d.key)
+	}
+	workDaysCheck := widget.NewCheckGroup(workDayLabels, nil)
+	workDaysCheck.Horizontal = true
+	for _, d := range workCalendar().Weekdays {
+		workDaysCheck.Selected = append(workDaysCheck.Selected, lang.L(weekdays[(int(d)+6)%7].key))
+	}
+	holidaysEntry := widget.NewEntry()
+	holidaysEntry.SetPlaceHolder("2025-12-25, 2026-01-01")
+	holidaysEntry.SetText(viper.GetString("holidays"))
+
+	// Working-hours targets, one entry per day from Monday
+	targetEntries := make([]*widget.Entry, len(weekdays))
+	targetCells := make([]fyne.CanvasObject, len(weekdays))
+	for i, d := range weekdays {
+		targetEntries[i] = widget.NewEntry()
+		if h := viper.GetFloat64("target_hours." + d.value); h > 0 {
+			targetEntries[i].SetText(strconv.FormatFloat(h, 'f', -1, 64))
+		}
+		dayLabel := widget.NewLabel(lang.L(d.key))
+		dayLabel.Truncation = fyne.TextTruncateEllipsis
+		targetCells[i] = container.NewVBox(dayLabel, targetEntries[i])
+	}
+	weeklyTargetEntry := widget.NewEntry()
+	if h := viper.GetFloat64("weekly_target"); h > 0 {
+		weeklyTargetEntry.SetText(strconv.FormatFloat(h, 'f', -1, 64))
+	}
+	pomodoroEntries := make(map[string]*widget.Entry)
+	pomodoro := pomodoroConfig()
+	for key, v := range map[string]int{
+		"pomodoro_work":             int(pomodoro.Work / time.Minute),
+		"pomodoro_short_break":      int(pomodoro.ShortBreak / time.Minute),
+		"pomodoro_long_break":       int(pomodoro.LongBreak / time.Minute),
+		"pomodoro_long_break_every": pomodoro.LongBreakEvery,
+	} {
+		pomodoroEntries[key] = widget.NewEntry()
+		if v > 0 {
+			pomodoroEntries[key].SetText(strconv.Itoa(v))
+		}
+	}
+
+	// Reminder rules, each turned on by its check
+	noTimerCheck := widget.NewCheck(lang.L("reminder_no_timer"), nil)
+	noTimerCheck.SetChecked(viper.GetBool("reminders.no_timer"))
+	noTimerEntry := widget.NewEntry()
+	noTimerEntry.SetText(strconv.Itoa(viper.GetInt("reminders.no_timer_minutes")))
+	workStartEntry := widget.NewEntry()
+	workStartEntry.SetText(viper.GetString("reminders.work_start"))
+	workEndEntry := widget.NewEntry()
+	workEndEntry.SetText(viper.GetString("reminders.work_end"))
+	longRunningCheck := widget.NewCheck(lang.L("reminder_long_running"), nil)
+	longRunningCheck.SetChecked(viper.GetBool("reminders.long_running"))
+	longRunningEntry := widget.NewEntry()
+	longRunningEntry.SetText(strconv.FormatFloat(viper.GetFloat64("reminders.long_running_hours"), 'f', -1, 64))
+	daySummaryCheck := widget.NewCheck(lang.L("reminder_day_summary"), nil)
+	daySummaryCheck.SetChecked(viper.GetBool("reminders.day_summary"))
+	daySummaryEntry := widget.NewEntry()
+	daySummaryEntry.SetText(viper.GetString("reminders.day_summary_at"))
+	forgottenCheck := widget.NewCheck(lang.L("reminder_forgotten_task"), nil)
+	forgottenCheck.SetChecked(viper.GetBool("reminders.forgotten_task"))
+
+	flexStartEntry := widget.NewEntry()
+	flexStartEntry.SetPlaceHolder(lang.L("flex_start_hint"))
+	flexStartEntry.SetText(viper.GetString("flex_start"))
+
 	browseBtn := widget.NewButtonWithIcon("", theme.FolderOpenIcon(), func() {
 		path, err := dialog.Directory().Title(lang.L("data_folder")).Browse()
 		if err != nil {
@@ -69,6 +157,18 @@ func (c *Config) MakeUI() fyne.CanvasObject {
 
 	folderContainer := container.NewBorder(nil, nil, nil, browseBtn, entry)
 
+	backendOptions := map[string]string{
+		lang.L("storage_backend_json"):   store.BackendJSON,
+		lang.L("storage_backend_sqlite"): store.BackendSQLite,
+	}
+	backend := viper.GetString("storage_backend")
+	backendSelect := widget.NewSelect([]string{lang.L("storage_backend_json"), lang.L("storage_backend_sqlite")}, nil)
+	if backend == store.BackendSQLite {
+		backendSelect.SetSelected(lang.L("storage_backend_sqlite"))
+	} else {
+		backendSelect.SetSelected(lang.L("storage_backend_json"))
+	}
+
 	saveBtn := widget.NewButton(lang.L("save_configuration"), func() {
 		newDataFolder := entry.Text
 		if newDataFolder == "" {
@@ -85,20 +185,127 @@ func (c *Config) MakeUI() fyne.CanvasObject {
 		fmt.Sscanf(maxHoursEntry.Text, "%f", &newMaxHours)
 		fmt.Sscanf(extraRateEntry.Text, "%f", &newExtraRate)
 
-		oldDataFolder := c.storage.BaseDir
+		targetHours := make(map[string]float64)
+		for i, d := range weekdays {
+			var h float64
+			fmt.Sscanf(targetEntries[i].Text, "%f", &h)
+			if h > 0 {
+				targetHours[d.value] = h
+			}
+		}
+		var newWeeklyTarget float64
+		fmt.Sscanf(weeklyTargetEntry.Text, "%f", &newWeeklyTarget)
+		newFlexStart := strings.TrimSpace(flexStartEntry.Text)
+		if _, err := time.Parse("2006-01-02", newFlexStart); newFlexStart != "" && err != nil {
+			fyneDialog.ShowError(fmt.Errorf("%s: %w", lang.L("flex_start"), err), c.window)
+			return
+		}
+		var workDays []string
+		for _, d := range weekdays {
+			if slices.Contains(workDaysCheck.Selected, lang.L(d.key)) {
+				workDays = append(workDays, d.value)
+			}
+		}
+		if len(workDays) == 0 {
+			fyneDialog.ShowError(errors.New(lang.L("work_days_required")), c.window)
+			return
+		}
+
+		var newNoTimerMinutes int
+		var newLongRunningHours float64
+		fmt.Sscanf(noTimerEntry.Text, "%d", &newNoTimerMinutes)
+		fmt.Sscanf(longRunningEntry.Text, "%f", &newLongRunningHours)
+		times := map[string]string{
+			"reminders.work_start":     strings.TrimSpace(workStartEntry.Text),
+			"reminders.work_end":       strings.TrimSpace(workEndEntry.Text),
+			"reminders.day_summary_at": strings.TrimSpace(daySummaryEntry.Text),
+		}
+		for _, v := range times {
+			if _, err := time.Parse("15:04", v); err != nil {
+				fyneDialog.ShowError(fmt.Errorf("%s: %w", lang.L("invalid_time_of_day"), err), c.window)
+				return
+			}
+		}
+
+		pomodoroSettings := make(map[string]int)
+		for key, e := range pomodoroEntries {
+			var v int
+			fmt.Sscanf(e.Text, "%d", &v)
+			pomodoroSettings[key] = max(v, 0)
+		}
+
+		var newRoundingIncrement, newRoundingMinimum int
+		fmt.Sscanf(roundingIncrementEntry.Text, "%d", &newRoundingIncrement)
+		fmt.Sscanf(roundingMinimumEntry.Text, "%d", &newRoundingMinimum)
+
+		newBackend := backendOptions[backendSelect.Selected]
+
+		oldDataFolder := c.storage.Dir()
 
-		saveConfig := func() {
+		migrated := false
+		var saveConfig func()
+		saveConfig = func() {
+			oldBackend := viper.GetString("storage_backend")
+			backendChanged := store.BackendName(newBackend) != store.BackendName(oldBackend)
+			if backendChanged && !migrated {
+				// The data goes along, or the backends would drift apart
+				msg := fmt.Sprintf(lang.L("storage_backend_switch_msg"), backendSelect.Selected)
+				fyneDialog.ShowConfirm(lang.L("storage_backend"), msg, func(ok bool) {
+					if !ok {
+						return
+					}
+					if err := store.Migrate(c.storage.Dir(), oldBackend, newBackend); err != nil {
+						fyneDialog.ShowError(err, c.window)
+						return
+					}
+					migrated = true
+					saveConfig()
+				}, c.window)
+				return
+			}
 			viper.Set("data_folder", newDataFolder)
+			viper.Set("storage_backend", newBackend)
 			viper.Set("idle_detection", newIdleEnabled)
 			viper.Set("idle_threshold", newIdleThreshold)
+			viper.Set("away_policy", awayPolicy.value())
 			viper.Set("hourly_rate", newHourlyRate)
 			viper.Set("max_hours", newMaxHours)
 			viper.Set("extra_rate", newExtraRate)
+			viper.Set("currency", strings.TrimSpace(currencyEntry.Text))
+			viper.Set("rounding_mode", roundingMode.value())
+			viper.Set("rounding_increment", max(newRoundingIncrement, 0))
+			viper.Set("rounding_scope", roundingScope.value())
+			viper.Set("rounding_minimum", max(newRoundingMinimum, 0))
+			viper.Set("threshold_mode", thresholdMode.value())
+			viper.Set("work_days", workDays)
+			viper.Set("holidays", strings.TrimSpace(holidaysEntry.Text))
+			viper.Set("target_hours", targetHours)
+			viper.Set("weekly_target", max(newWeeklyTarget, 0))
+			viper.Set("flex_start", newFlexStart)
+			for key, v := range pomodoroSettings {
+				viper.Set(key, v)
+			}
+			viper.Set("reminders.no_timer", noTimerCheck.Checked)
+			viper.Set("reminders.no_timer_minutes", max(newNoTimerMinutes, 1))
+			viper.Set("reminders.long_running", longRunningCheck.Checked)
+			viper.Set("reminders.long_running_hours", max(newLongRunningHours, 0.25))
+			viper.Set("reminders.day_summary", daySummaryCheck.Checked)
+			viper.Set("reminders.forgotten_task", forgottenCheck.Checked)
+			for key, v := range times {
+				viper.Set(key, v)
+			}
 			err := viper.WriteConfigAs(c.userConfigFilePath)
 			if err != nil {
 				fyneDialog.ShowError(err, c.window)
 				return
 			}
+			if backendChanged {
+				// Quitting, so that nothing is saved to the old backend
+				d := fyneDialog.NewInformation(lang.L("success"), lang.L("storage_backend_restart"), c.window)
+				d.SetOnClosed(fyne.CurrentApp().Quit)
+				d.Show()
+				return
+			}
 			fyneDialog.ShowInformation(lang.L("success"), lang.L("config_saved"), c.window)
 		}
 
@@ -117,7 +324,10 @@ func (c *Config) MakeUI() fyne.CanvasObject {
 
 			freshBtn := widget.NewButton(lang.L("start_fresh"), func() {
 				d.Hide()
-				c.storage.UpdateBaseDir(newDataFolder)
+				if err := c.storage.UpdateBaseDir(newDataFolder); err != nil {
+					fyneDialog.ShowError(err, c.window)
+					return
+				}
 				saveConfig()
 			})
 
@@ -137,38 +347,308 @@ func (c *Config) MakeUI() fyne.CanvasObject {
 
 	eraseBtn := widget.NewButtonWithIcon(lang.L("erase_all_history"), theme.DeleteIcon(), func() {
 		fyneDialog.ShowConfirm(lang.L("erase_all_history"), lang.L("erase_history_confirm"), func(confirmed bool) {
-			if confirmed {
-				if err := c.storage.DeleteAllEntries(); err != nil {
-					fyneDialog.ShowError(err, c.window)
-				} else {
-					fyneDialog.ShowInformation(lang.L("success"), lang.L("history_erased"), c.window)
-				}
+			if !confirmed {
+				return
 			}
+			trashID, err := c.eraseAll()
+			if err != nil {
+				fyneDialog.ShowError(err, c.window)
+				return
+			}
+			c.pushTrashAction(lang.L("history_erased"), trashID, false)
 		}, c.window)
 	})
 	eraseBtn.Importance = widget.DangerImportance
 
+	trashBtn := widget.NewButtonWithIcon(lang.L("restore_erased_history"), theme.HistoryIcon(), c.showTrash)
+
+	importBtn := widget.NewButtonWithIcon(lang.L("import_entries"), theme.UploadIcon(), func() {
+		showImportWizard(c.window, c.storage, c.undo, nil)
+	})
+
 	quitBtn := widget.NewButtonWithIcon(lang.L("quit_application"), theme.LogoutIcon(), func() {
 		_ = viper.WriteConfigAs(c.userConfigFilePath)
 		fyne.CurrentApp().Quit()
 	})
 
-	return container.NewVBox(
+	return container.NewVScroll(container.NewVBox(
 		widget.NewLabel(lang.L("config_tab")),
 		widget.NewForm(
 			widget.NewFormItem(lang.L("data_folder"), folderContainer),
+			widget.NewFormItem(lang.L("storage_backend"), backendSelect),
 			widget.NewFormItem(lang.L("idle_detection"), idleCheck),
 			widget.NewFormItem(lang.L("idle_threshold"), thresholdEntry),
+			widget.NewFormItem(lang.L("away_policy"), awayPolicy.Select),
 			widget.NewFormItem("", widget.NewSeparator()),
 			widget.NewFormItem(lang.L("billing_settings"), widget.NewLabel("")),
 			widget.NewFormItem(lang.L("hourly_rate"), hourlyRateEntry),
+			widget.NewFormItem(lang.L("threshold_mode"), thresholdMode.Select),
 			widget.NewFormItem(lang.L("max_hours"), maxHoursEntry),
 			widget.NewFormItem(lang.L("extra_rate"), extraRateEntry),
+			widget.NewFormItem(lang.L("work_days"), workDaysCheck),
+			widget.NewFormItem(lang.L("holidays"), holidaysEntry),
+			widget.NewFormItem(lang.L("currency"), currencyEntry),
+			widget.NewFormItem(lang.L("rounding_mode"), roundingMode.Select),
+			widget.NewFormItem(lang.L("rounding_increment"), roundingIncrementEntry),
+			widget.NewFormItem(lang.L("rounding_scope"), roundingScope.Select),
+			widget.NewFormItem(lang.L("rounding_minimum"), roundingMinimumEntry),
+			widget.NewFormItem("", widget.NewSeparator()),
+			widget.NewFormItem(lang.L("goals_settings"), widget.NewLabel("")),
+			widget.NewFormItem(lang.L("target_hours"), container.NewGridWithColumns(len(targetCells), targetCells...)),
+			widget.NewFormItem(lang.L("weekly_target"), weeklyTargetEntry),
+			widget.NewFormItem(lang.L("flex_start"), flexStartEntry),
+			widget.NewFormItem("", widget.NewSeparator()),
+			widget.NewFormItem(lang.L("focus_settings"), widget.NewLabel("")),
+			widget.NewFormItem(lang.L("pomodoro_work"), pomodoroEntries["pomodoro_work"]),
+			widget.NewFormItem(lang.L("pomodoro_short_break"), pomodoroEntries["pomodoro_short_break"]),
+			widget.NewFormItem(lang.L("pomodoro_long_break"), pomodoroEntries["pomodoro_long_break"]),
+			widget.NewFormItem(lang.L("pomodoro_long_break_every"), pomodoroEntries["pomodoro_long_break_every"]),
+			widget.NewFormItem("", widget.NewSeparator()),
+			widget.NewFormItem(lang.L("reminders_settings"), widget.NewLabel("")),
+			widget.NewFormItem(lang.L("work_hours"), container.NewGridWithColumns(2, workStartEntry, workEndEntry)),
+			widget.NewFormItem("", container.NewBorder(nil, nil, noTimerCheck, nil, noTimerEntry)),
+			widget.NewFormItem("", container.NewBorder(nil, nil, longRunningCheck, nil, longRunningEntry)),
+			widget.NewFormItem("", container.NewBorder(nil, nil, daySummaryCheck, nil, daySummaryEntry)),
+			widget.NewFormItem("", forgottenCheck),
 		),
 		saveBtn,
 		widget.NewSeparator(),
+		importBtn,
+		widget.NewSeparator(),
 		eraseBtn,
+		trashBtn,
 		widget.NewSeparator(),
 		quitBtn,
-	)
+	))
+}
+
+// eraseAll moves all entries to the trash and returns the ID of the new
+// trash item.
+func (c *Config) eraseAll() (string, error) {
+	if err := c.storage.DeleteAllEntries(); err != nil {
+		return "", err
+	}
+	items, err := c.storage.ListTrash()
+	if err != nil {
+		return "", err
+	}
+	if len(items) == 0 {
+		// Nothing was there to erase.
+		return "", nil
+	}
+	return items[0].ID, nil
+}
+
+// showTrash lets the user bring back history erased earlier, also after the
+// undo stack is gone.
+func (c *Config) showTrash() {
+	items, err := c.storage.ListTrash()
+	if err != nil {
+		fyneDialog.ShowError(err, c.window)
+		return
+	}
+	if len(items) == 0 {
+		fyneDialog.ShowInformation(lang.L("restore_erased_history"), lang.L("trash_empty"), c.window)
+		return
+	}
+
+	options := make([]string, len(items))
+	for i, item := range items {
+		options[i] = fmt.Sprintf(lang.L("trash_item"), item.DeletedAt.Format("2006-01-02 15:04"), item.Entries)
+	}
+	trashSelect := widget.NewSelect(options, nil)
+	trashSelect.SetSelectedIndex(0)
+
+	fyneDialog.ShowForm(lang.L("restore_erased_history"), lang.L("restore"), lang.L("cancel"),
+		[]*widget.FormItem{widget.NewFormItem(lang.L("erased_on"), trashSelect)},
+		func(ok bool) {
+			i := trashSelect.SelectedIndex()
+			if !ok || i < 0 {
+				return
+			}
+			if err := c.storage.RestoreTrash(items[i].ID); err != nil {
+				fyneDialog.ShowError(err, c.window)
+				return
+			}
+			c.pushTrashAction(lang.L("history_restored"), "", true)
+		}, c.window)
+}
+
+// pushTrashAction records an erase (or, with restored set, a restore from the
+// trash) on the undo stack. Each side moves the data to or from the trash
+// item created by the other.
+func (c *Config) pushTrashAction(label, trashID string, restored bool) {
+	erase := func() error {
+		var err error
+		trashID, err = c.eraseAll()
+		return err
+	}
+	restore := func() error {
+		if trashID == "" {
+			return nil
+		}
+		return c.storage.RestoreTrash(trashID)
+	}
+	a := service.UndoAction{Label: label, Undo: restore, Redo: erase}
+	if restored {
+		a.Undo, a.Redo = erase, restore
+	}
+	c.undo.Push(a)
+}
+
+// billingConfig returns the global billing settings, which projects may
+// override.
+func billingConfig() service.BillingConfig {
+	return service.BillingConfig{
+		HourlyRate: viper.GetFloat64("hourly_rate"),
+		MaxHours:   viper.GetFloat64("max_hours"),
+		ExtraRate:  viper.GetFloat64("extra_rate"),
+		Currency:   viper.GetString("currency"),
+		Rounding: service.RoundingRule{
+			Mode:      viper.GetString("rounding_mode"),
+			Increment: time.Duration(viper.GetInt("rounding_increment")) * time.Minute,
+			Scope:     viper.GetString("rounding_scope"),
+			Minimum:   time.Duration(viper.GetInt("rounding_minimum")) * time.Minute,
+		},
+		ThresholdMode: viper.GetString("threshold_mode"),
+		WorkCalendar:  workCalendar(),
+	}
+}
+
+// workCalendar returns the days worked: the weekdays in work_days, Monday to
+// Friday if unset, except the dates in holidays.
+func workCalendar() service.WorkCalendar {
+	var c service.WorkCalendar
+	days := viper.GetStringSlice("work_days")
+	if !viper.IsSet("work_days") {
+		days = []string{"mon", "tue", "wed", "thu", "fri"}
+	}
+	for i, d := range weekdays {
+		if slices.Contains(days, d.value) {
+			c.Weekdays = append(c.Weekdays, time.Weekday((i+1)%7))
+		}
+	}
+	for _, h := range strings.Split(viper.GetString("holidays"), ",") {
+		if h = strings.TrimSpace(h); h == "" {
+			continue
+		}
+		day, err := time.ParseInLocation("2006-01-02", h, time.Local)
+		if err != nil {
+			fmt.Printf("warning: ignoring holiday %q: %v\n", h, err)
+			continue
+		}
+		c.Holidays = append(c.Holidays, day)
+	}
+	return c
+}
+
+// goals returns the working-hours targets: the hours of each weekday in
+// target_hours, keyed as in work_days, and the weekly_target.
+func goals() service.Goals {
+	g := service.Goals{
+		Weekly:   time.Duration(viper.GetFloat64("weekly_target") * float64(time.Hour)),
+		Calendar: workCalendar(),
+	}
+	for i, d := range weekdays {
+		g.Daily[(i+1)%7] = time.Duration(viper.GetFloat64("target_hours."+d.value) * float64(time.Hour))
+	}
+	return g
+}
+
+// flexStart returns the day the flex-time balance starts from: flex_start,
+// or the first day tracked when unset.
+func flexStart(s store.Store) time.Time {
+	if v := viper.GetString("flex_start"); v != "" {
+		if day, err := time.ParseInLocation("2006-01-02", v, time.Local); err == nil {
+			return day
+		}
+		fmt.Printf("warning: ignoring flex_start %q\n", v)
+	}
+	entries, err := s.QueryEntries(store.EntryQuery{})
+	if err != nil {
+		fmt.Printf("warning: failed to load entries for the flex-time balance: %v\n", err)
+		return time.Time{}
+	}
+	var first time.Time
+	for _, e := range entries {
+		if first.IsZero() || e.StartTime.Before(first) {
+			first = e.StartTime
+		}
+	}
+	if first.IsZero() {
+		return first
+	}
+	y, m, d := first.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.Local)
+}
+
+// An option is a setting value with the translation key of its label.
+type option struct {
+	key, value string
+}
+
+var (
+	roundingModes = []option{
+		{"rounding_none", service.RoundNone},
+		{"rounding_up", service.RoundUp},
+		{"rounding_down", service.RoundDown},
+		{"rounding_nearest", service.RoundNearest},
+	}
+	thresholdModes = []option{
+		{"threshold_pro_rated", service.ThresholdProRated},
+		{"threshold_month", service.ThresholdMonth},
+		{"threshold_week", service.ThresholdWeek},
+		{"threshold_day", service.ThresholdDay},
+		{"threshold_working_day", service.ThresholdWorkingDay},
+	}
+	// weekdays are the days of the week from Monday, as kept in work_days.
+	weekdays = []option{
+		{"monday", "mon"},
+		{"tuesday", "tue"},
+		{"wednesday", "wed"},
+		{"thursday", "thu"},
+		{"friday", "fri"},
+		{"saturday", "sat"},
+		{"sunday", "sun"},
+	}
+	awayPolicies = []option{
+		{"away_ask", service.AwayAsk},
+		{"away_pause", service.AwayPause},
+		{"away_keep", service.AwayKeep},
+	}
+	roundingScopes = []option{
+		{"rounding_per_entry", service.RoundPerEntry},
+		{"rounding_per_day", service.RoundPerDay},
+		{"rounding_per_total", service.RoundPerTotal},
+	}
+)
+
+// optionSelect picks one of a list of options by their translated labels.
+type optionSelect struct {
+	*widget.Select
+	options []option
+}
+
+// newOptionSelect returns a select showing options with value selected, or
+// the first option when no option has it.
+func newOptionSelect(options []option, value string) optionSelect {
+	labels := make([]string, len(options))
+	selected := 0
+	for i, o := range options {
+		labels[i] = lang.L(o.key)
+		if o.value == value {
+			selected = i
+		}
+	}
+	s := optionSelect{Select: widget.NewSelect(labels, nil), options: options}
+	s.SetSelectedIndex(selected)
+	return s
+}
+
+// value returns the value of the selected option.
+func (s optionSelect) value() string {
+	if i := s.SelectedIndex(); i >= 0 {
+		return s.options[i].value
+	}
+	return s.options[0].value
 }
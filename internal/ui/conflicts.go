@@ -0,0 +1,142 @@
+package ui
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/highercomve/tasktracker/internal/models"
+	"github.com/highercomve/tasktracker/internal/store"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/lang"
+	"fyne.io/fyne/v2/widget"
+)
+
+// conflictResolver merges sync conflict copies and walks the user through
+// the edits that cannot be merged automatically.
+type conflictResolver struct {
+	window  fyne.Window
+	storage store.Store
+	merger  store.ConflictMerger
+
+	mu      sync.Mutex
+	busy    bool
+	pending bool
+}
+
+// WatchSyncConflicts merges sync conflict copies in the data folder now and
+// whenever a new one shows up. Stores without conflict copies are ignored.
+func WatchSyncConflicts(w fyne.Window, s store.Store) {
+	merger, ok := s.(store.ConflictMerger)
+	if !ok {
+		return
+	}
+	r := &conflictResolver{window: w, storage: s, merger: merger}
+	s.Subscribe(func(c store.Change) {
+		if c.Kind == store.ConflictCopyFound {
+			r.run()
+		}
+	})
+	go r.run()
+}
+
+// run merges the conflict copies. A request arriving while the user is still
+// resolving a previous batch is queued, so dialogs are never stacked.
+func (r *conflictResolver) run() {
+	r.mu.Lock()
+	if r.busy {
+		r.pending = true
+		r.mu.Unlock()
+		return
+	}
+	r.busy = true
+	r.mu.Unlock()
+
+	conflicts, err := r.merger.MergeConflicts()
+	if err != nil {
+		fmt.Printf("warning: failed to merge sync conflicts: %v\n", err)
+	}
+	if len(conflicts) == 0 {
+		r.done()
+		return
+	}
+	fyne.Do(func() { r.ask(conflicts, 0) })
+}
+
+func (r *conflictResolver) done() {
+	r.mu.Lock()
+	again := r.pending
+	r.busy, r.pending = false, false
+	r.mu.Unlock()
+	if again {
+		go r.run()
+	}
+}
+
+// ask shows conflicts[i] and moves on to the next one once answered.
+func (r *conflictResolver) ask(conflicts []store.Conflict, i int) {
+	if i >= len(conflicts) {
+		r.done()
+		return
+	}
+	c := conflicts[i]
+	projects, _ := r.storage.LoadProjects()
+
+	var d dialog.Dialog
+	choose := func(keep *models.TimeEntry) func() {
+		return func() {
+			d.Hide()
+			if keep != nil {
+				if err := r.merger.ResolveConflict(c, *keep); err != nil {
+					dialog.ShowError(fmt.Errorf("%s: %w", lang.L("save_error"), err), r.window)
+				}
+			}
+			r.ask(conflicts, i+1)
+		}
+	}
+
+	local, remote := c.Local, c.Remote
+	keepLocal := widget.NewButton(lang.L("keep_this_version"), choose(&local))
+	keepLocal.Importance = widget.HighImportance
+	keepRemote := widget.NewButton(lang.L("keep_other_version"), choose(&remote))
+	later := widget.NewButton(lang.L("decide_later"), choose(nil))
+
+	message := widget.NewLabel(lang.L("sync_conflict_msg"))
+	message.Wrapping = fyne.TextWrapWord
+	localCard := conflictCard(lang.L("this_device"), "", local, local.UpdatedAt, projects)
+	if !c.DeletedAt.IsZero() {
+		keepLocal.SetText(lang.L("keep_deleted"))
+		message.SetText(lang.L("sync_conflict_deleted_msg"))
+		localCard = conflictCard(lang.L("this_device"), lang.L("entry_delete"), local, c.DeletedAt, projects)
+	}
+
+	content := container.NewVBox(
+		message,
+		container.NewGridWithColumns(2,
+			localCard,
+			conflictCard(lang.L("other_device"), "", remote, remote.UpdatedAt, projects),
+		),
+		container.NewHBox(later, keepRemote, keepLocal),
+	)
+
+	title := lang.L("sync_conflict_title")
+	if len(conflicts) > 1 {
+		title = fmt.Sprintf("%s (%d/%d)", title, i+1, len(conflicts))
+	}
+	d = dialog.NewCustomWithoutButtons(title, content, r.window)
+	d.Resize(fyne.NewSize(560, 0))
+	d.Show()
+}
+
+// conflictCard shows one side of a conflict, last modified at modified.
+func conflictCard(title, subtitle string, e models.TimeEntry, modified time.Time, projects []models.Project) fyne.CanvasObject {
+	form := widget.NewForm()
+	for _, f := range entryFields(e, projects) {
+		form.Append(f[0], widget.NewLabel(f[1]))
+	}
+	form.Append(lang.L("last_modified"), widget.NewLabel(formatStamp(modified)))
+	return widget.NewCard(title, subtitle, form)
+}
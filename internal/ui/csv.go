@@ -0,0 +1,256 @@
+package ui
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/highercomve/tasktracker/internal/models"
+	"github.com/highercomve/tasktracker/internal/service"
+	"github.com/highercomve/tasktracker/internal/store"
+
+	"fyne.io/fyne/v2"
+	fyneDialog "fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/lang"
+	"fyne.io/fyne/v2/widget"
+	"github.com/spf13/viper"
+	"github.com/sqweek/dialog"
+)
+
+// csvColumnLabels are the translation keys of the CSV columns.
+var csvColumnLabels = map[string]string{
+	service.CSVColumnDate:        "date",
+	service.CSVColumnStart:       "start_time",
+	service.CSVColumnEnd:         "end_time",
+	service.CSVColumnDuration:    "duration",
+	service.CSVColumnDescription: "task_description",
+	service.CSVColumnProject:     "project",
+	service.CSVColumnClient:      "client",
+	service.CSVColumnTags:        "tags",
+}
+
+// csvDelimiters are the delimiters offered, by translation key.
+var csvDelimiters = []struct {
+	key   string
+	comma rune
+}{
+	{"delimiter_comma", ','},
+	{"delimiter_semicolon", ';'},
+	{"delimiter_tab", '\t'},
+}
+
+var csvDurationFormats = []struct {
+	key    string
+	format string
+}{
+	{"duration_clock", service.DurationClock},
+	{"duration_decimal", service.DurationDecimal},
+	{"duration_minutes", service.DurationMinutes},
+}
+
+// loadCSVOptions returns the CSV options last used, kept in the config file.
+func loadCSVOptions() service.CSVOptions {
+	opts := service.CSVOptions{
+		Columns:        viper.GetStringSlice("csv_columns"),
+		DurationFormat: viper.GetString("csv_duration_format"),
+	}
+	if d := []rune(viper.GetString("csv_delimiter")); len(d) == 1 {
+		opts.Delimiter = d[0]
+	}
+	return opts
+}
+
+func saveCSVOptions(opts service.CSVOptions) {
+	if len(opts.Columns) > 0 {
+		viper.Set("csv_columns", opts.Columns)
+	}
+	viper.Set("csv_delimiter", string(opts.Delimiter))
+	viper.Set("csv_duration_format", opts.DurationFormat)
+	if err := viper.WriteConfigAs(viper.ConfigFileUsed()); err != nil {
+		fmt.Printf("warning: failed to save CSV options: %v\n", err)
+	}
+}
+
+// newDelimiterSelect offers the CSV delimiters with selected chosen.
+func newDelimiterSelect(selected rune, onChanged func(rune)) *widget.Select {
+	options := make([]string, len(csvDelimiters))
+	for i, d := range csvDelimiters {
+		options[i] = lang.L(d.key)
+	}
+	sel := widget.NewSelect(options, nil)
+	sel.SetSelectedIndex(0)
+	for i, d := range csvDelimiters {
+		if d.comma == selected {
+			sel.SetSelectedIndex(i)
+		}
+	}
+	sel.OnChanged = func(string) {
+		if onChanged != nil {
+			onChanged(csvDelimiters[sel.SelectedIndex()].comma)
+		}
+	}
+	return sel
+}
+
+func newDurationFormatSelect(selected string) *widget.Select {
+	options := make([]string, len(csvDurationFormats))
+	for i, f := range csvDurationFormats {
+		options[i] = lang.L(f.key)
+	}
+	sel := widget.NewSelect(options, nil)
+	sel.SetSelectedIndex(0)
+	for i, f := range csvDurationFormats {
+		if f.format == selected {
+			sel.SetSelectedIndex(i)
+		}
+	}
+	return sel
+}
+
+// showCSVExportDialog asks for the columns, delimiter and duration format
+// and saves entries, as shown in a report, to a CSV file.
+func showCSVExportDialog(w fyne.Window, entries []models.TimeEntry, projects []models.Project, start, end time.Time, groupBy string) {
+	opts := loadCSVOptions()
+
+	labels := make([]string, len(service.CSVColumns))
+	selected := []string{}
+	for i, col := range service.CSVColumns {
+		labels[i] = lang.L(csvColumnLabels[col])
+		if len(opts.Columns) == 0 || slices.Contains(opts.Columns, col) {
+			selected = append(selected, labels[i])
+		}
+	}
+	columnsCheck := widget.NewCheckGroup(labels, nil)
+	columnsCheck.Horizontal = true
+	columnsCheck.SetSelected(selected)
+	delimiterSelect := newDelimiterSelect(opts.Delimiter, nil)
+	durationSelect := newDurationFormatSelect(opts.DurationFormat)
+
+	items := []*widget.FormItem{
+		widget.NewFormItem(lang.L("csv_columns"), columnsCheck),
+		widget.NewFormItem(lang.L("delimiter"), delimiterSelect),
+		widget.NewFormItem(lang.L("duration_format"), durationSelect),
+	}
+	fyneDialog.ShowForm(lang.L("export_csv"), lang.L("export_csv"), lang.L("cancel"), items, func(ok bool) {
+		if !ok {
+			return
+		}
+		opts = service.CSVOptions{
+			Delimiter:      csvDelimiters[delimiterSelect.SelectedIndex()].comma,
+			DurationFormat: csvDurationFormats[durationSelect.SelectedIndex()].format,
+		}
+		// Keep the columns in their canonical order.
+		for i, col := range service.CSVColumns {
+			if slices.Contains(columnsCheck.Selected, labels[i]) {
+				opts.Columns = append(opts.Columns, col)
+			}
+		}
+		if len(opts.Columns) == 0 {
+			fyneDialog.ShowError(fmt.Errorf("%s", lang.L("csv_no_columns")), w)
+			return
+		}
+		saveCSVOptions(opts)
+
+		filename := fmt.Sprintf("report_%s_%s.csv", start.Format("20060102"), end.Format("20060102"))
+		path, err := dialog.File().Title(lang.L("export_csv")).SetStartFile(filename).Filter("CSV files", "csv").Save()
+		if err != nil {
+			if err != dialog.ErrCancelled {
+				fyneDialog.ShowError(err, w)
+			}
+			return
+		}
+		if path == "" {
+			return
+		}
+
+		// Attribute time to the days it was worked on, as the report does.
+		entries = service.SplitByDay(entries, start, end, time.Now())
+		var buf bytes.Buffer
+		if err := service.ExportCSV(&buf, entries, projects, groupBy, opts); err != nil {
+			fyneDialog.ShowError(err, w)
+			return
+		}
+		if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+			fyneDialog.ShowError(err, w)
+			return
+		}
+		fyneDialog.ShowConfirm(lang.L("success"), lang.L("csv_saved")+"\n"+lang.L("open_file_question"), func(open bool) {
+			if open {
+				if err := openFile(path); err != nil {
+					fyneDialog.ShowError(err, w)
+				}
+			}
+		}, w)
+	}, w)
+}
+
+// showCSVMapping lets the user map the columns of a CSV file to entry
+// fields and previews the import.
+func showCSVMapping(w fyne.Window, s store.Store, undo *service.UndoStack, data []byte, onDone func()) {
+	projects, err := s.LoadProjects()
+	if err != nil {
+		fyneDialog.ShowError(err, w)
+		return
+	}
+
+	opts := loadCSVOptions()
+	opts.Delimiter = service.SniffCSVDelimiter(data)
+
+	// One selector per field, offering the columns of the file.
+	mappingSelects := make(map[string]*widget.Select, len(service.CSVColumns))
+	readHeader := func(delimiter rune) {
+		opts.Delimiter = delimiter
+		header, _, _ := service.ReadCSV(bytes.NewReader(data), opts)
+		detected := service.DetectCSVMapping(header)
+		options := append([]string{lang.L("none")}, header...)
+		for _, col := range service.CSVColumns {
+			sel := mappingSelects[col]
+			sel.Options = options
+			if i, ok := detected[col]; ok {
+				sel.SetSelectedIndex(i + 1)
+			} else {
+				sel.SetSelectedIndex(0)
+			}
+		}
+	}
+
+	delimiterSelect := newDelimiterSelect(opts.Delimiter, readHeader)
+	durationSelect := newDurationFormatSelect(opts.DurationFormat)
+	items := []*widget.FormItem{
+		widget.NewFormItem(lang.L("delimiter"), delimiterSelect),
+		widget.NewFormItem(lang.L("duration_format"), durationSelect),
+		widget.NewFormItem("", widget.NewLabel(lang.L("csv_mapping"))),
+	}
+	for _, col := range service.CSVColumns {
+		mappingSelects[col] = widget.NewSelect(nil, nil)
+		items = append(items, widget.NewFormItem(lang.L(csvColumnLabels[col]), mappingSelects[col]))
+	}
+	readHeader(opts.Delimiter)
+
+	fyneDialog.ShowForm(lang.L("import_csv"), lang.L("import_preview"), lang.L("cancel"), items, func(ok bool) {
+		if !ok {
+			return
+		}
+		opts.DurationFormat = csvDurationFormats[durationSelect.SelectedIndex()].format
+		_, records, err := service.ReadCSV(bytes.NewReader(data), opts)
+		if err != nil {
+			fyneDialog.ShowError(err, w)
+			return
+		}
+		mapping := service.CSVMapping{}
+		for col, sel := range mappingSelects {
+			if i := sel.SelectedIndex(); i > 0 {
+				mapping[col] = i - 1
+			}
+		}
+
+		entries, created, rowErrors := service.ParseCSVEntries(records, mapping, projects, opts)
+		problems := make([]string, len(rowErrors))
+		for i, e := range rowErrors {
+			problems[i] = e.Error()
+		}
+		showImportPreview(w, s, undo, entries, created, problems, onDone)
+	}, w)
+}
@@ -3,12 +3,15 @@ package ui
 import (
 	"fmt"
 	"image/color"
+	"slices"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/highercomve/tasktracker/internal/idle"
 	"github.com/highercomve/tasktracker/internal/models"
 	"github.com/highercomve/tasktracker/internal/service"
+	"github.com/highercomve/tasktracker/internal/session"
 	"github.com/highercomve/tasktracker/internal/store"
 	"github.com/highercomve/tasktracker/internal/utils"
 
@@ -22,25 +25,34 @@ import (
 	"fyne.io/fyne/v2/layout"
 	"fyne.io/fyne/v2/theme"
 	"fyne.io/fyne/v2/widget"
-	"github.com/google/uuid"
 	"github.com/spf13/viper"
 )
 
 type Dashboard struct {
-	storage   *store.Storage
+	storage   store.Store
+	timer     *service.Timer
+	undo      *service.UndoStack
 	timerData binding.String
 	taskList  []models.TimeEntry
+	// activity is the time of the last input to the app, the idle source
+	// when the desktop session has none
+	activity *idle.Activity
 
 	// State - protected by mu
 	mu                  sync.RWMutex
-	activeID            string
-	activeOriginalStart time.Time
-	activeLastStart     time.Time
-	accumulated         int64
-	activeState         int
-	lastActivity        time.Time
+	idleSource          idle.Source
+	sessionSource       session.Source
 	isIdleDialogShowing bool
 	stopTicker          chan struct{}
+	// budgetStale tells that entries changed since budgetEntries was filled
+	budgetStale bool
+
+	// Budget alerts - only used by the ticker goroutine
+	budgetAlerts    map[string]int // alert level notified per budget period
+	lastBudgetCheck time.Time
+	// budgetEntries are the stored entries of projects with a lifetime
+	// budget, which would otherwise be read in full every check
+	budgetEntries map[string][]models.TimeEntry
 
 	// UI
 	startBtn      *widget.Button
@@ -51,75 +63,113 @@ type Dashboard struct {
 	categoryEntry *widget.Entry
 	refreshList   func()
 	projects      []models.Project
+
+	// Today's goal, from the entries worked on today
+	goalRing     *progressRing
+	goalLabel    *widget.Label
+	goalBox      *fyne.Container
+	todayEntries []models.TimeEntry
+
+	// Focus mode
+	pomodoro   *service.Pomodoro
+	focusBtn   *widget.Button
+	focusLabel *widget.Label
+	// trayFocus shows the focus countdown in the system tray, if any
+	trayFocus func(status string)
 }
 
-func NewDashboard(s *store.Storage) *Dashboard {
+func NewDashboard(s store.Store, timer *service.Timer, undo *service.UndoStack) *Dashboard {
+	activity := idle.NewActivity()
 	return &Dashboard{
-		storage:      s,
-		timerData:    binding.NewString(),
-		lastActivity: time.Now(),
+		storage:       s,
+		timer:         timer,
+		undo:          undo,
+		timerData:     binding.NewString(),
+		activity:      activity,
+		idleSource:    idle.Detect(activity),
+		sessionSource: session.System(),
+		pomodoro:      service.NewPomodoro(pomodoroConfig(), nil),
 	}
 }
 
-// Safe accessor methods for protected state
+// GetActiveState returns the state of the active task.
 func (d *Dashboard) GetActiveState() int {
-	d.mu.RLock()
-	defer d.mu.RUnlock()
-	return d.activeState
+	return d.timer.State()
 }
 
-func (d *Dashboard) SetActiveState(state int) {
-	d.mu.Lock()
-	defer d.mu.Unlock()
-	d.activeState = state
+// GetActiveID returns the ID of the active task, or "" if none.
+func (d *Dashboard) GetActiveID() string {
+	return d.timer.ActiveID()
 }
 
-func (d *Dashboard) GetActiveID() string {
-	d.mu.RLock()
-	defer d.mu.RUnlock()
-	return d.activeID
+// Safe accessor methods for protected state
+func (d *Dashboard) GetLastActivity() time.Time {
+	return d.activity.Last()
 }
 
-func (d *Dashboard) SetActiveID(id string) {
-	d.mu.Lock()
-	defer d.mu.Unlock()
-	d.activeID = id
+func (d *Dashboard) SetLastActivity(t time.Time) {
+	d.activity.Set(t)
 }
 
-func (d *Dashboard) GetLastActivity() time.Time {
-	d.mu.RLock()
-	defer d.mu.RUnlock()
-	return d.lastActivity
+// SetIdleSource replaces the source of the idle time, detected from the
+// desktop session by NewDashboard.
+func (d *Dashboard) SetIdleSource(s idle.Source) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.idleSource = s
 }
 
-func (d *Dashboard) SetLastActivity(t time.Time) {
+// SetSessionSource replaces the source of the locks and suspends of the
+// session, the system one by default, e.g. with a session.Fake. It takes
+// effect on MakeUI.
+func (d *Dashboard) SetSessionSource(s session.Source) {
 	d.mu.Lock()
 	defer d.mu.Unlock()
-	d.lastActivity = t
+	d.sessionSource = s
 }
 
-func (d *Dashboard) IsIdleDialogShowing() bool {
+// watchSession applies the away_policy setting to the running task as the
+// session locks or the system suspends.
+func (d *Dashboard) watchSession() {
 	d.mu.RLock()
-	defer d.mu.RUnlock()
-	return d.isIdleDialogShowing
+	src := d.sessionSource
+	d.mu.RUnlock()
+	if src == nil {
+		return
+	}
+	guard := service.NewAwayGuard(d.timer, func() string { return viper.GetString("away_policy") })
+	err := guard.Watch(src, func(since time.Time) {
+		msg := fmt.Sprintf(lang.L("away_detected_msg"), int(time.Since(since).Minutes()))
+		fyne.Do(func() { d.showIdleDialog(since, msg) })
+	})
+	if err != nil {
+		fmt.Printf("warning: failed to watch session locks and suspends: %v\n", err)
+	}
 }
 
-func (d *Dashboard) SetIsIdleDialogShowing(showing bool) {
-	d.mu.Lock()
-	defer d.mu.Unlock()
-	d.isIdleDialogShowing = showing
+// idleTime returns the time since the user's last input, as told by the
+// idle source.
+func (d *Dashboard) idleTime() time.Duration {
+	d.mu.RLock()
+	s := d.idleSource
+	d.mu.RUnlock()
+	idleTime, err := s.IdleTime()
+	if err != nil {
+		return 0
+	}
+	return idleTime
 }
 
-func (d *Dashboard) GetAccumulated() int64 {
+func (d *Dashboard) IsIdleDialogShowing() bool {
 	d.mu.RLock()
 	defer d.mu.RUnlock()
-	return d.accumulated
+	return d.isIdleDialogShowing
 }
 
-func (d *Dashboard) SetAccumulated(acc int64) {
+func (d *Dashboard) SetIsIdleDialogShowing(showing bool) {
 	d.mu.Lock()
 	defer d.mu.Unlock()
-	d.accumulated = acc
+	d.isIdleDialogShowing = showing
 }
 
 // safeGetMainWindow returns the main window or nil if no windows are available
@@ -132,10 +182,106 @@ func safeGetMainWindow() fyne.Window {
 }
 
 func (d *Dashboard) RegisterActivity() {
-	d.SetLastActivity(time.Now())
+	d.activity.Touch()
+}
+
+// budgetEntriesOf returns the stored entries the budget of project counts:
+// those of the month for a monthly budget, all of them otherwise. The latter
+// are cached until entries change. The slice is the caller's to modify.
+func (d *Dashboard) budgetEntriesOf(project models.Project) ([]models.TimeEntry, error) {
+	q := store.EntryQuery{ProjectID: project.ID}
+	q.Start, q.End = service.BudgetPeriod(project, d.timer.Now())
+	if !q.Start.IsZero() {
+		return d.storage.QueryEntries(q)
+	}
+
+	d.mu.Lock()
+	if d.budgetStale {
+		d.budgetEntries = nil
+		d.budgetStale = false
+	}
+	d.mu.Unlock()
+	entries, ok := d.budgetEntries[project.ID]
+	if !ok {
+		var err error
+		if entries, err = d.storage.QueryEntries(q); err != nil {
+			return nil, err
+		}
+		if d.budgetEntries == nil {
+			d.budgetEntries = make(map[string][]models.TimeEntry)
+		}
+		d.budgetEntries[project.ID] = entries
+	}
+	return slices.Clone(entries), nil
+}
+
+// staleBudgets drops the entries cached for budget checks on the next one.
+func (d *Dashboard) staleBudgets() {
+	d.mu.Lock()
+	d.budgetStale = true
+	d.mu.Unlock()
+}
+
+// budgetCheckInterval is how often the running task is checked against the
+// budget of its project.
+const budgetCheckInterval = time.Minute
+
+// checkBudget notifies when the running task takes its project past the
+// warning share or the whole of its budget. Levels reached before the first
+// check are recorded without notifying, so restarting the app does not
+// repeat alerts.
+func (d *Dashboard) checkBudget() {
+	if time.Since(d.lastBudgetCheck) < budgetCheckInterval {
+		return
+	}
+	d.lastBudgetCheck = time.Now()
+	active, ok := d.timer.Active()
+	if !ok || active.ProjectID == "" || d.timer.State() != models.TaskStateRunning {
+		return
+	}
+	projects, err := d.storage.LoadProjects()
+	if err != nil {
+		return
+	}
+	project := service.FindProjectByID(projects, active.ProjectID)
+	if project == nil || project.Budget <= 0 {
+		return
+	}
+	entries, err := d.budgetEntriesOf(*project)
+	if err != nil {
+		fmt.Printf("warning: failed to load entries for budget check: %v\n", err)
+		return
+	}
+	// The stored copy of the running entry is behind the timer
+	entries = slices.DeleteFunc(entries, func(e models.TimeEntry) bool { return e.ID == active.ID })
+	entries = append(entries, active)
+
+	status, ok := service.ProjectBudget(*project, entries, billingConfig(), d.timer.Now())
+	if !ok {
+		return
+	}
+	key := project.ID + "|" + status.Start.Format("2006-01")
+	level := status.AlertLevel()
+	previous, seen := d.budgetAlerts[key]
+	if d.budgetAlerts == nil {
+		d.budgetAlerts = make(map[string]int)
+	}
+	d.budgetAlerts[key] = level
+	if !seen || level <= previous {
+		return
+	}
+	msg := fmt.Sprintf(lang.L("budget_warning_msg"), project.Name, int(status.Fraction()*100))
+	if level == service.BudgetAlertExceeded {
+		msg = fmt.Sprintf(lang.L("budget_exceeded_msg"), project.Name)
+	}
+	if app := fyne.CurrentApp(); app != nil {
+		app.SendNotification(fyne.NewNotification(lang.L("budget_alert_title"), msg))
+	}
 }
 
-func (d *Dashboard) checkIdle() {
+// checkIdle asks what to do with the idle time of the running task once
+// idleTime passes the threshold.
+func (d *Dashboard) checkIdle(idleTime time.Duration) {
 	if d.GetActiveState() != models.TaskStateRunning || d.IsIdleDialogShowing() {
 		return
 	}
@@ -149,42 +295,99 @@ func (d *Dashboard) checkIdle() {
 		threshold = 5 // Default 5 minutes
 	}
 
-	idleTime := time.Since(d.GetLastActivity())
-	if idleTime > time.Duration(threshold)*time.Minute {
-		d.SetIsIdleDialogShowing(true)
+	if idleTime <= time.Duration(threshold)*time.Minute {
+		return
+	}
+	// Round idle time to minutes for the message
+	d.showIdleDialog(time.Now().Add(-idleTime), fmt.Sprintf(lang.L("idle_detected_msg"), int(idleTime.Minutes())))
+}
 
-		parentWindow := safeGetMainWindow()
-		if parentWindow == nil {
-			return
+// showIdleDialog asks what to do with the time of the running task since
+// idleStart: keep it, discard it, stop the task at idleStart or move the
+// time to an entry of its own.
+func (d *Dashboard) showIdleDialog(idleStart time.Time, msg string) {
+	if d.GetActiveState() != models.TaskStateRunning || d.IsIdleDialogShowing() {
+		return
+	}
+	parentWindow := safeGetMainWindow()
+	if parentWindow == nil {
+		return
+	}
+	d.SetIsIdleDialogShowing(true)
+
+	active, _ := d.timer.Active()
+
+	options := []string{
+		lang.L("keep_idle_time"),
+		lang.L("discard_idle_time"),
+		fmt.Sprintf(lang.L("stop_at_idle_start"), idleStart.Format("15:04")),
+		lang.L("split_idle_time"),
+	}
+	choice := widget.NewRadioGroup(options, nil)
+	choice.Required = true
+	choice.SetSelected(options[0])
+
+	// The entry the idle time moves to
+	descEntry := widget.NewEntry()
+	descEntry.SetPlaceHolder(lang.L("split_idle_hint"))
+	// The projects may be reloaded while the dialog is open
+	projects := slices.Clone(d.projects)
+	projectOptions := []string{lang.L("none")}
+	for _, p := range projects {
+		projectOptions = append(projectOptions, p.Name)
+	}
+	projectSelect := widget.NewSelect(projectOptions, nil)
+	projectSelect.SetSelectedIndex(0)
+	splitForm := widget.NewForm(
+		widget.NewFormItem(lang.L("task_description"), descEntry),
+		widget.NewFormItem(lang.L("project"), projectSelect),
+	)
+	splitForm.Hide()
+	choice.OnChanged = func(selected string) {
+		if selected == options[3] {
+			splitForm.Show()
+		} else {
+			splitForm.Hide()
 		}
+	}
 
-		// Round idle time to minutes for the message
-		idleMinutes := int(idleTime.Minutes())
-
-		msg := fmt.Sprintf(lang.L("idle_detected_msg"), idleMinutes)
-
-		dialog.ShowCustomConfirm(
-			lang.L("idle_detected_title"),
-			lang.L("keep_idle_time"),
-			lang.L("discard_idle_time"),
-			widget.NewLabel(msg),
-			func(keep bool) {
-				d.SetIsIdleDialogShowing(false)
-				d.RegisterActivity() // Reset activity after dialog
-
-				if !keep {
-					// Discard idle time
-					// Subtract idleTime from the current run
-					// A simpler way: set activeLastStart to now
-					d.mu.Lock()
-					d.activeLastStart = time.Now()
-					d.mu.Unlock()
-					d.refreshList()
+	content := container.NewVBox(widget.NewLabel(msg), choice, splitForm)
+	dialog.ShowCustomConfirm(
+		lang.L("idle_detected_title"),
+		lang.L("save"),
+		lang.L("cancel"),
+		content,
+		func(ok bool) {
+			d.SetIsIdleDialogShowing(false)
+			d.RegisterActivity() // Reset activity after dialog
+
+			// The task may have changed while the dialog was open
+			if !ok || d.timer.ActiveID() != active.ID {
+				return
+			}
+			var err error
+			switch choice.Selected {
+			case options[1]:
+				err = d.timer.DiscardIdle(idleStart)
+			case options[2]:
+				err = d.stopAt(idleStart)
+			case options[3]:
+				desc := strings.TrimSpace(descEntry.Text)
+				if desc == "" {
+					desc = lang.L("split_idle_hint")
 				}
-			},
-			parentWindow,
-		)
-	}
+				projectID := ""
+				if i := projectSelect.SelectedIndex(); i > 0 {
+					projectID = projects[i-1].ID
+				}
+				_, err = d.timer.SplitIdle(idleStart, desc, projectID, nil)
+			}
+			if err != nil {
+				d.showSaveError(err)
+			}
+		},
+		parentWindow,
+	)
 }
 
 func (d *Dashboard) MakeUI() fyne.CanvasObject {
@@ -201,6 +404,11 @@ func (d *Dashboard) MakeUI() fyne.CanvasObject {
 		d.projects = projects
 	}
 
+	// Today's progress towards the working-hours target
+	d.goalRing = newProgressRing()
+	d.goalLabel = widget.NewLabel("")
+	d.goalBox = container.NewHBox(d.goalRing, d.goalLabel)
+
 	// Input
 	d.taskEntry = widget.NewEntry()
 	d.taskEntry.PlaceHolder = lang.L("what_working_on")
@@ -210,11 +418,7 @@ func (d *Dashboard) MakeUI() fyne.CanvasObject {
 	d.categoryEntry.PlaceHolder = lang.L("category_hint")
 
 	// Project Selection with color indicator
-	projectOptions := []string{lang.L("none")}
-	for _, p := range d.projects {
-		projectOptions = append(projectOptions, p.Name)
-	}
-	d.projectSelect = widget.NewSelect(projectOptions, nil)
+	d.projectSelect = widget.NewSelect(d.projectOptions(), nil)
 	d.projectSelect.SetSelected(lang.L("none"))
 	d.projectSelect.PlaceHolder = lang.L("select_project")
 
@@ -242,6 +446,10 @@ func (d *Dashboard) MakeUI() fyne.CanvasObject {
 	d.startBtn = widget.NewButtonWithIcon(lang.L("start"), theme.MediaPlayIcon(), nil)
 	d.pauseBtn = widget.NewButtonWithIcon(lang.L("pause"), theme.MediaPauseIcon(), nil)
 	d.pauseBtn.Disable() // Initially disabled
+	d.focusBtn = widget.NewButtonWithIcon(lang.L("focus"), theme.VisibilityIcon(), d.ToggleFocus)
+	d.focusLabel = widget.NewLabel("")
+	d.focusLabel.Alignment = fyne.TextAlignCenter
+	d.focusLabel.Hide()
 
 	d.startBtn.OnTapped = func() {
 		d.RegisterActivity()
@@ -264,18 +472,11 @@ func (d *Dashboard) MakeUI() fyne.CanvasObject {
 			d.categoryEntry.SetText("")
 			d.projectSelect.SetSelected(lang.L("none"))
 		}
-		d.refreshList()
 	}
 
 	d.pauseBtn.OnTapped = func() {
 		d.RegisterActivity()
-		state := d.GetActiveState()
-		if state == models.TaskStateRunning {
-			d.PauseTask()
-		} else if state == models.TaskStatePaused {
-			d.ResumeTask()
-		}
-		d.refreshList()
+		d.TogglePause()
 	}
 
 	d.taskEntry.OnSubmitted = func(text string) {
@@ -289,7 +490,6 @@ func (d *Dashboard) MakeUI() fyne.CanvasObject {
 		d.taskEntry.SetText("")
 		d.categoryEntry.SetText("")
 		d.projectSelect.SetSelected(lang.L("none"))
-		d.refreshList()
 	}
 
 	d.taskEntry.OnChanged = func(s string) {
@@ -347,18 +547,9 @@ func (d *Dashboard) MakeUI() fyne.CanvasObject {
 
 			// Calculate duration for display
 			activeID := d.GetActiveID()
-			activeState := d.GetActiveState()
 			if entry.ID == activeID {
 				// Active task - use in-memory state for live update
-				accumulated := d.GetAccumulated()
-				currentDur := time.Duration(accumulated) * time.Second
-				if activeState == models.TaskStateRunning {
-					d.mu.RLock()
-					lastStart := d.activeLastStart
-					d.mu.RUnlock()
-					currentDur += time.Since(lastStart)
-				}
-				dur.SetText(utils.FormatDuration(currentDur))
+				dur.SetText(utils.FormatDuration(d.timer.Elapsed()))
 				dur.TextStyle = fyne.TextStyle{Italic: true}
 				editBtn.Disable()
 			} else {
@@ -381,7 +572,7 @@ func (d *Dashboard) MakeUI() fyne.CanvasObject {
 			}
 
 			editBtn.OnTapped = func() {
-				d.showEditDialog(entry)
+				showEditEntryDialog(d.storage, d.undo, d.projects, entry, d.refreshList)
 			}
 			delBtn.OnTapped = func() {
 				parentWindow := safeGetMainWindow()
@@ -395,15 +586,15 @@ func (d *Dashboard) MakeUI() fyne.CanvasObject {
 
 					// If deleting the active task, clear the active state
 					if entry.ID == d.GetActiveID() {
-						d.storage.ClearAppState()
-						d.SetActiveID("")
-						d.SetActiveState(models.TaskStateStopped)
-						d.SetAccumulated(0)
-						d.timerData.Set("00:00:00")
-						d.updateButtons()
+						d.deleteActiveTask()
+						return
 					}
 
-					d.storage.DeleteEntry(entry)
+					if err := d.storage.DeleteEntry(entry); err != nil {
+						d.showSaveError(err)
+						return
+					}
+					d.undo.Push(deleteEntryAction(d.storage, entry))
 					d.refreshList()
 				}, parentWindow)
 			}
@@ -422,6 +613,10 @@ func (d *Dashboard) MakeUI() fyne.CanvasObject {
 		d.taskList = entries
 		simpleList.Refresh()
 		d.updateButtons()
+
+		today := time.Now()
+		d.todayEntries, _ = d.storage.LoadEntriesForRange(today, today)
+		d.updateGoal()
 	}
 
 	// Ticker with lifecycle management
@@ -434,29 +629,81 @@ func (d *Dashboard) MakeUI() fyne.CanvasObject {
 			case <-d.stopTicker:
 				return
 			case <-ticker.C:
+				d.checkBudget()
+				// The idle source may call out to the desktop session, so
+				// it is asked here rather than on the UI thread.
+				var idleTime time.Duration
+				if d.GetActiveState() == models.TaskStateRunning && viper.GetBool("idle_detection") {
+					idleTime = d.idleTime()
+				}
 				fyne.Do(func() {
 					activeState := d.GetActiveState()
 					if activeState == models.TaskStateRunning {
-						accumulated := d.GetAccumulated()
-						d.mu.RLock()
-						lastStart := d.activeLastStart
-						d.mu.RUnlock()
-						dur := time.Duration(accumulated)*time.Second + time.Since(lastStart)
-						d.timerData.Set(utils.FormatDuration(dur))
-						d.checkIdle()
+						d.timerData.Set(utils.FormatDuration(d.timer.Elapsed()))
+						d.checkIdle(idleTime)
 					} else if activeState == models.TaskStatePaused {
-						accumulated := d.GetAccumulated()
-						d.timerData.Set(utils.FormatDuration(time.Duration(accumulated) * time.Second))
+						d.timerData.Set(utils.FormatDuration(d.timer.Elapsed()))
 					} else {
 						// Stopped
 						d.timerData.Set("00:00:00")
 					}
 					simpleList.Refresh()
+					d.updateGoal()
+					d.tickFocus()
 				})
 			}
 		}
 	}()
 
+	// Pause or ask about the running task across locks and suspends
+	d.watchSession()
+
+	// Keep the view in sync with timer transitions, whoever triggers them
+	// (buttons, tray, shortcuts or forwarded commands).
+	d.timer.Subscribe(func(ev service.TimerEvent) {
+		d.staleBudgets()
+		if ev.Type == service.TimerStopped || ev.Type == service.TimerCleared {
+			// Focus sessions follow the task
+			d.pomodoro.Stop()
+		}
+		fyne.Do(func() {
+			if d.GetActiveID() == "" {
+				d.timerData.Set("00:00:00")
+			}
+			d.refreshList()
+		})
+	})
+
+	// Pick up changes made by another instance, a sync tool or an editor.
+	d.storage.Subscribe(func(c store.Change) {
+		switch c.Kind {
+		case store.StateChanged, store.EntriesChanged:
+			d.staleBudgets()
+			// A task may have been started, paused or stopped elsewhere.
+			if c.Kind == store.StateChanged || d.activeOn(c.Date) {
+				if err := d.timer.Reload(); err != nil {
+					fmt.Printf("warning: failed to reload active task: %v\n", err)
+				}
+			}
+			fyne.Do(d.refreshList)
+		case store.ProjectsChanged:
+			fyne.Do(d.reloadProjects)
+		}
+	})
+
+	// Undoable actions, and undoing them, may touch the active task,
+	// projects or any entry, also from other tabs.
+	d.undo.Subscribe(func(service.UndoEvent) {
+		d.staleBudgets()
+		if err := d.timer.Reload(); err != nil {
+			fmt.Printf("warning: failed to reload active task: %v\n", err)
+		}
+		fyne.Do(func() {
+			d.reloadProjects()
+			d.refreshList()
+		})
+	})
+
 	// Check for active task on load
 	d.checkForActiveTask()
 	d.refreshList() // Initial load
@@ -478,11 +725,12 @@ func (d *Dashboard) MakeUI() fyne.CanvasObject {
 	)
 
 	// Main input area with task entry and buttons
-	taskInputRow := container.NewBorder(nil, nil, nil, container.NewHBox(d.startBtn, d.pauseBtn), d.taskEntry)
+	taskInputRow := container.NewBorder(nil, nil, nil, container.NewHBox(d.startBtn, d.pauseBtn, d.focusBtn), d.taskEntry)
 
 	return container.NewBorder(
 		container.NewVBox(
-			timerLabel,
+			container.NewBorder(nil, nil, nil, d.goalBox, timerLabel),
+			d.focusLabel,
 			taskInputRow,
 			inputDetailsRow,
 			layout.NewSpacer(),
@@ -493,6 +741,46 @@ func (d *Dashboard) MakeUI() fyne.CanvasObject {
 	)
 }
 
+// updateGoal shows the time worked today against today's target, or hides
+// the goal when there is none.
+func (d *Dashboard) updateGoal() {
+	now := time.Now()
+	g := goals()
+	if g.DayTarget(now) <= 0 {
+		d.goalBox.Hide()
+		return
+	}
+	today := service.DayGoals(d.todayEntries, g, now, now, now)[0]
+	d.goalRing.set(today.Progress(), fmt.Sprintf("%d%%", int(today.Progress()*100)))
+	d.goalLabel.SetText(goalText(today))
+	d.goalBox.Show()
+}
+
+// projectOptions returns the project selector options, "None" first.
+func (d *Dashboard) projectOptions() []string {
+	options := []string{lang.L("none")}
+	for _, p := range d.projects {
+		options = append(options, p.Name)
+	}
+	return options
+}
+
+// reloadProjects refreshes the project selector from storage, keeping the
+// current selection when the project still exists.
+func (d *Dashboard) reloadProjects() {
+	projects, err := d.storage.LoadProjects()
+	if err != nil {
+		return
+	}
+	d.projects = projects
+	selected := d.projectSelect.Selected
+	d.projectSelect.SetOptions(d.projectOptions())
+	if service.FindProjectByName(d.projects, selected) == nil {
+		selected = lang.L("none")
+	}
+	d.projectSelect.SetSelected(selected)
+}
+
 // StopTicker stops the background ticker goroutine to prevent memory leaks.
 // Call this when the Dashboard is being destroyed or rebuilt.
 func (d *Dashboard) StopTicker() {
@@ -563,183 +851,48 @@ func (d *Dashboard) updateButtons() {
 		d.pauseBtn.SetIcon(theme.MediaPauseIcon())
 		d.pauseBtn.Disable()
 	}
-}
 
-func (d *Dashboard) checkForActiveTask() {
-	// Try LoadAppState first
-	state, err := d.storage.LoadAppState()
-	if err == nil && state.ActiveTaskID != "" {
-		entries, _ := d.storage.LoadEntries(state.ActiveTaskDate)
-		for _, e := range entries {
-			if e.ID == state.ActiveTaskID {
-				d.SetActiveID(e.ID)
-				d.mu.Lock()
-				d.activeOriginalStart = e.StartTime
-				d.activeLastStart = state.LastStartTime
-				d.mu.Unlock()
-				d.SetAccumulated(e.Accumulated)
-				state := e.State
-				// If state is blank (legacy), assume running
-				if state == 0 {
-					state = models.TaskStateRunning
-				}
-				d.SetActiveState(state)
-
-				d.updateButtons()
-				return
-			}
-		}
-		// Entry not found - log and clear state
-		fmt.Printf("warning: active task %s not found; clearing state\n", state.ActiveTaskID)
-		d.storage.ClearAppState()
-	}
-
-	// Fallback: Check today's entries for any running task (legacy support)
-	entries, _ := d.storage.LoadEntries(time.Now())
-	for _, e := range entries {
-		if e.EndTime.IsZero() {
-			d.SetActiveID(e.ID)
-			d.mu.Lock()
-			d.activeOriginalStart = e.StartTime
-			d.activeLastStart = e.StartTime // Assume started just now if legacy? Or original start.
-			d.mu.Unlock()
-			d.SetAccumulated(0)
-			d.SetActiveState(models.TaskStateRunning)
-
-			// Save migrated state
-			d.saveState()
-			d.updateButtons()
-			return
-		}
+	// Focus sessions need a task, but can always be ended
+	if activeState == models.TaskStateStopped && d.pomodoro.Status().Phase == service.PomodoroOff {
+		d.focusBtn.Disable()
+	} else {
+		d.focusBtn.Enable()
 	}
-
-	d.SetActiveState(models.TaskStateStopped)
-	d.updateButtons()
 }
 
-func (d *Dashboard) saveState() {
-	d.mu.RLock()
-	activeID := d.activeID
-	activeOriginalStart := d.activeOriginalStart
-	activeLastStart := d.activeLastStart
-	d.mu.RUnlock()
-	
-	d.storage.SaveAppState(store.AppState{
-		ActiveTaskID:   activeID,
-		ActiveTaskDate: activeOriginalStart,
-		LastStartTime:  activeLastStart,
-	})
+// activeOn reports whether the entries of day may hold the active task,
+// which is filed under the day it started. A zero day may hold any.
+func (d *Dashboard) activeOn(day time.Time) bool {
+	if day.IsZero() {
+		return true
+	}
+	active, ok := d.timer.Active()
+	return ok && active.StartTime.Format("2006-01-02") == day.Format("2006-01-02")
 }
 
-func (d *Dashboard) updateActiveEntry() error {
-	// Helper to update the persistent entry with current in-memory values (accumulated, state)
-	d.mu.RLock()
-	activeOriginalStart := d.activeOriginalStart
-	activeID := d.activeID
-	activeState := d.activeState
-	accumulated := d.accumulated
-	d.mu.RUnlock()
-	
-	entries, err := d.storage.LoadEntries(activeOriginalStart)
-	if err != nil {
-		return err
-	}
-	for _, e := range entries {
-		if e.ID == activeID {
-			e.State = activeState
-			e.Accumulated = accumulated
-			// StartTime remains original
-			return d.storage.SaveEntry(e)
-		}
+func (d *Dashboard) checkForActiveTask() {
+	if err := d.timer.Restore(); err != nil {
+		fmt.Printf("warning: failed to restore active task: %v\n", err)
 	}
-	return nil
+	d.updateButtons()
 }
 
 func (d *Dashboard) StartTask(desc, projectID string, tags []string) {
-	// If another task is running, stop it
-	if d.GetActiveID() != "" {
-		d.StopTask()
-	}
-
-	now := time.Now()
-	entry := models.TimeEntry{
-		ID:          uuid.New().String(),
-		Description: desc,
-		ProjectID:   projectID,
-		Tags:        tags,
-		StartTime:   now,
-		State:       models.TaskStateRunning,
-		Accumulated: 0,
-	}
-
-	if err := d.storage.SaveEntry(entry); err != nil {
+	if _, err := d.timer.Start(desc, projectID, tags); err != nil {
 		d.showSaveError(err)
-		return
 	}
-
-	d.SetActiveID(entry.ID)
-	d.mu.Lock()
-	d.activeOriginalStart = now
-	d.activeLastStart = now
-	d.mu.Unlock()
-	d.SetAccumulated(0)
-	d.SetActiveState(models.TaskStateRunning)
-
-	d.saveState()
-	d.updateButtons()
 }
 
 func (d *Dashboard) PauseTask() {
-	if d.GetActiveState() != models.TaskStateRunning {
-		return
-	}
-	now := time.Now()
-	
-	d.mu.Lock()
-	prevAccumulated := d.accumulated
-	prevState := d.activeState
-	prevLastStart := d.activeLastStart
-	d.accumulated += int64(now.Sub(d.activeLastStart).Seconds())
-	d.activeState = models.TaskStatePaused
-	d.mu.Unlock()
-
-	if err := d.updateActiveEntry(); err != nil {
-		// Revert state on error
-		d.mu.Lock()
-		d.accumulated = prevAccumulated
-		d.activeState = prevState
-		d.activeLastStart = prevLastStart
-		d.mu.Unlock()
+	if err := d.timer.Pause(); err != nil {
 		d.showSaveError(err)
-		return
 	}
-	d.saveState()
-	d.updateButtons()
 }
 
 func (d *Dashboard) ResumeTask() {
-	if d.GetActiveState() != models.TaskStatePaused {
-		return
-	}
-	d.mu.Lock()
-	prevLastStart := d.activeLastStart
-	prevState := d.activeState
-
-	d.activeLastStart = time.Now()
-	d.activeState = models.TaskStateRunning
-	d.mu.Unlock()
-
-	if err := d.updateActiveEntry(); err != nil {
-		// Revert state on error
-		d.mu.Lock()
-		d.activeLastStart = prevLastStart
-		d.activeState = prevState
-		d.mu.Unlock()
+	if err := d.timer.Resume(); err != nil {
 		d.showSaveError(err)
-		return
 	}
-	d.saveState()
-	d.updateButtons()
 }
 
 func (d *Dashboard) TogglePause() {
@@ -749,171 +902,84 @@ func (d *Dashboard) TogglePause() {
 	} else if activeState == models.TaskStatePaused {
 		d.ResumeTask()
 	}
-	d.refreshList()
 }
 
 func (d *Dashboard) StopTask() {
-	if d.GetActiveID() == "" {
+	active, ok := d.timer.Active()
+	if !ok {
 		return
 	}
-
-	now := time.Now()
-
-	// Load entry to finalize
-	d.mu.RLock()
-	activeOriginalStart := d.activeOriginalStart
-	activeID := d.activeID
-	accumulated := d.accumulated
-	activeState := d.activeState
-	activeLastStart := d.activeLastStart
-	d.mu.RUnlock()
-
-	entries, err := d.storage.LoadEntries(activeOriginalStart)
-	if err != nil {
+	lastStart := d.timer.LastStart()
+	if _, err := d.timer.Stop(); err != nil {
 		d.showSaveError(err)
 		return
 	}
-
-	for _, e := range entries {
-		if e.ID == activeID {
-			// Calculate final duration
-			finalDuration := accumulated
-			if activeState == models.TaskStateRunning {
-				finalDuration += int64(now.Sub(activeLastStart).Seconds())
-			}
-
-			e.EndTime = now
-			e.Duration = finalDuration
-			e.State = models.TaskStateStopped
-			e.Accumulated = accumulated // Optional: keep this for record
-
-			if err := d.storage.SaveEntry(e); err != nil {
-				d.showSaveError(err)
-				return
-			}
-			break
-		}
-	}
-
-	// Clear state
-	d.storage.ClearAppState()
-	d.SetActiveID("")
-	d.SetActiveState(models.TaskStateStopped)
-	d.SetAccumulated(0)
-	d.timerData.Set("00:00:00")
-
-	d.updateButtons()
-	d.refreshList()
+	d.undo.Push(service.UndoAction{
+		Label: lang.L("task_stopped"),
+		Undo:  func() error { return d.timer.Reopen(active, lastStart) },
+		Redo:  func() error { return d.stopIfActive(active.ID) },
+	})
 }
 
-func (d *Dashboard) showEditDialog(entry models.TimeEntry) {
-	descEntry := widget.NewEntry()
-	descEntry.SetText(entry.Description)
-
-	tagsEntry := widget.NewEntry()
-	tagsEntry.SetPlaceHolder(lang.L("category_hint"))
-	if len(entry.Tags) > 0 {
-		tagsEntry.SetText(fmt.Sprintf("%s", entry.Tags[0]))
-		if len(entry.Tags) > 1 {
-			for i := 1; i < len(entry.Tags); i++ {
-				tagsEntry.SetText(fmt.Sprintf("%s, %s", tagsEntry.Text, entry.Tags[i]))
-			}
-		}
-	}
-
-	// Project selection dropdown
-	projectOptions := []string{lang.L("none")}
-	selectedProjectIndex := 0
-	for i, p := range d.projects {
-		projectOptions = append(projectOptions, p.Name)
-		if p.ID == entry.ProjectID {
-			selectedProjectIndex = i + 1 // +1 because "None" is at index 0
-		}
-	}
-	projectSelect := widget.NewSelect(projectOptions, nil)
-	if selectedProjectIndex < len(projectOptions) {
-		projectSelect.SetSelectedIndex(selectedProjectIndex)
+// stopAt stops the active task at, undoably, the same way StopTask does.
+func (d *Dashboard) stopAt(at time.Time) error {
+	active, ok := d.timer.Active()
+	if !ok {
+		return nil
 	}
-
-	startEntry := widget.NewEntry()
-	startEntry.SetText(entry.StartTime.Format("2006-01-02 15:04:05"))
-
-	endEntry := widget.NewEntry()
-	if !entry.EndTime.IsZero() {
-		endEntry.SetText(entry.EndTime.Format("2006-01-02 15:04:05"))
+	lastStart := d.timer.LastStart()
+	if _, err := d.timer.StopAt(at); err != nil {
+		return err
 	}
+	d.undo.Push(service.UndoAction{
+		Label: lang.L("task_stopped"),
+		Undo:  func() error { return d.timer.Reopen(active, lastStart) },
+		Redo: func() error {
+			if d.timer.ActiveID() != active.ID {
+				return d.stopIfActive(active.ID)
+			}
+			_, err := d.timer.StopAt(at)
+			return err
+		},
+	})
+	return nil
+}
 
-	items := []*widget.FormItem{
-		widget.NewFormItem(lang.L("task_description"), descEntry),
-		widget.NewFormItem(lang.L("project"), projectSelect),
-		widget.NewFormItem(lang.L("add_category"), tagsEntry),
-		widget.NewFormItem(lang.L("start_time"), startEntry),
-		widget.NewFormItem(lang.L("end_time"), endEntry),
+// deleteActiveTask drops the active task and deletes its entry.
+func (d *Dashboard) deleteActiveTask() {
+	active, ok := d.timer.Active()
+	if !ok {
+		return
 	}
-
-	parentWindow := safeGetMainWindow()
-	if parentWindow == nil {
+	lastStart := d.timer.LastStart()
+	d.timer.Clear()
+	if err := d.storage.DeleteEntry(active); err != nil {
+		d.showSaveError(err)
 		return
 	}
-	dlg := dialog.NewForm(lang.L("edit_task"), lang.L("save"), lang.L("cancel"), items, func(b bool) {
-		if !b {
-			return
-		}
-
-		newDesc := descEntry.Text
-		newStart, err1 := time.Parse("2006-01-02 15:04:05", startEntry.Text)
-		newEnd, err2 := time.Parse("2006-01-02 15:04:05", endEntry.Text)
-
-		if err1 != nil || (endEntry.Text != "" && err2 != nil) {
-			// Show error? For now just return
-			fmt.Println(lang.L("error_parsing_time"))
-			return
-		}
-
-		// Parse tags from comma-separated input
-		var newTags []string
-		if tagsEntry.Text != "" {
-			for _, tag := range strings.Split(tagsEntry.Text, ",") {
-				trimmed := strings.TrimSpace(tag)
-				if trimmed != "" {
-					newTags = append(newTags, trimmed)
-				}
+	d.undo.Push(service.UndoAction{
+		Label: lang.L("task_deleted"),
+		Undo:  func() error { return d.timer.Reopen(active, lastStart) },
+		Redo: func() error {
+			if d.timer.ActiveID() == active.ID {
+				d.timer.Clear()
 			}
-		}
-
-		// Get selected project ID
-		newProjectID := ""
-		if projectSelect.Selected != "" && projectSelect.Selected != lang.L("none") {
-			for _, p := range d.projects {
-				if p.Name == projectSelect.Selected {
-					newProjectID = p.ID
-					break
-				}
-			}
-		}
-
-		// Update entry
-		oldEntry := entry
-		entry.Description = newDesc
-		entry.Tags = newTags
-		entry.ProjectID = newProjectID
-		entry.StartTime = newStart
-		if endEntry.Text != "" {
-			entry.EndTime = newEnd
-			entry.Duration = int64(newEnd.Sub(newStart).Seconds())
-			entry.State = models.TaskStateStopped
-		}
-
-		// If start date changed, we need to delete old and save new
-		if oldEntry.StartTime.Format("2006-01-02") != entry.StartTime.Format("2006-01-02") {
-			d.storage.DeleteEntry(oldEntry)
-		}
+			return d.storage.DeleteEntry(active)
+		},
+	})
+}
 
-		d.storage.SaveEntry(entry)
-		d.refreshList()
-	}, parentWindow)
-	dlg.Resize(fyne.NewSize(parentWindow.Canvas().Size().Width, dlg.MinSize().Height))
-	dlg.Show()
+// stopIfActive stops the active task if it is the one with the given ID.
+func (d *Dashboard) stopIfActive(id string) error {
+	switch d.timer.ActiveID() {
+	case id:
+		_, err := d.timer.Stop()
+		return err
+	case "":
+		return service.ErrNoActiveTask
+	default:
+		return service.ErrTaskActive
+	}
 }
 
 // getSelectedProjectID returns the project ID for the currently selected project.
@@ -0,0 +1,315 @@
+package ui
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/highercomve/tasktracker/internal/models"
+	"github.com/highercomve/tasktracker/internal/service"
+	"github.com/highercomve/tasktracker/internal/store"
+	"github.com/highercomve/tasktracker/internal/utils"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/lang"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+// entryTimeLayout is the format of the start and end fields of the edit
+// dialog.
+const entryTimeLayout = "2006-01-02 15:04:05"
+
+// showEditEntryDialog lets the user edit entry, or restore one of its earlier
+// versions, and calls onSaved afterwards. Both are recorded on undo. It is
+// shared by the Dashboard and Reports lists.
+func showEditEntryDialog(s store.Store, undo *service.UndoStack, projects []models.Project, entry models.TimeEntry, onSaved func()) {
+	descEntry := widget.NewEntry()
+	descEntry.SetText(entry.Description)
+
+	tagsEntry := widget.NewEntry()
+	tagsEntry.SetPlaceHolder(lang.L("category_hint"))
+	tagsEntry.SetText(strings.Join(entry.Tags, ", "))
+
+	// Project selection dropdown
+	projectOptions := []string{lang.L("none")}
+	selectedProjectIndex := 0
+	for i, p := range projects {
+		projectOptions = append(projectOptions, p.Name)
+		if p.ID == entry.ProjectID {
+			selectedProjectIndex = i + 1 // +1 because "None" is at index 0
+		}
+	}
+	projectSelect := widget.NewSelect(projectOptions, nil)
+	if selectedProjectIndex < len(projectOptions) {
+		projectSelect.SetSelectedIndex(selectedProjectIndex)
+	}
+
+	parentWindow := safeGetMainWindow()
+	if parentWindow == nil {
+		return
+	}
+
+	var dlg dialog.Dialog
+
+	// One start/end row per worked segment
+	type segmentRow struct{ start, end *widget.Entry }
+	var rows []*segmentRow
+	segmentsBox := container.NewVBox()
+	addRow := func(seg models.Segment) {
+		row := &segmentRow{start: widget.NewEntry(), end: widget.NewEntry()}
+		row.start.SetPlaceHolder(lang.L("start_time"))
+		row.end.SetPlaceHolder(lang.L("end_time"))
+		if !seg.Start.IsZero() {
+			row.start.SetText(seg.Start.Format(entryTimeLayout))
+		}
+		if !seg.End.IsZero() {
+			row.end.SetText(seg.End.Format(entryTimeLayout))
+		}
+		var line *fyne.Container
+		removeButton := widget.NewButtonWithIcon("", theme.DeleteIcon(), func() {
+			if len(rows) == 1 {
+				return
+			}
+			rows = slices.DeleteFunc(rows, func(r *segmentRow) bool { return r == row })
+			segmentsBox.Remove(line)
+		})
+		line = container.NewBorder(nil, nil, nil, removeButton, container.NewGridWithColumns(2, row.start, row.end))
+		rows = append(rows, row)
+		segmentsBox.Add(line)
+	}
+	if len(entry.Segments) > 0 {
+		for _, seg := range entry.Segments {
+			addRow(seg)
+		}
+	} else {
+		addRow(models.Segment{Start: entry.StartTime, End: entry.EndTime})
+	}
+	addSegmentButton := widget.NewButtonWithIcon(lang.L("add_segment"), theme.ContentAddIcon(), func() {
+		addRow(models.Segment{})
+		if dlg != nil {
+			dlg.Resize(fyne.NewSize(parentWindow.Canvas().Size().Width, dlg.MinSize().Height))
+		}
+	})
+	billableCheck := widget.NewCheck(lang.L("billable"), nil)
+	billableCheck.SetChecked(!entry.NonBillable)
+
+	historyButton := widget.NewButtonWithIcon(lang.L("entry_history"), theme.HistoryIcon(), func() {
+		showEntryHistory(s, undo, projects, entry, func() {
+			dlg.Hide()
+			onSaved()
+		})
+	})
+
+	items := []*widget.FormItem{
+		widget.NewFormItem(lang.L("task_description"), descEntry),
+		widget.NewFormItem(lang.L("project"), projectSelect),
+		widget.NewFormItem(lang.L("add_category"), tagsEntry),
+		widget.NewFormItem("", billableCheck),
+		widget.NewFormItem(lang.L("segments"), container.NewVBox(segmentsBox, container.NewHBox(addSegmentButton))),
+		widget.NewFormItem(lang.L("created"), widget.NewLabel(formatStamp(entry.CreatedAt))),
+		widget.NewFormItem(lang.L("last_modified"), widget.NewLabel(formatStamp(entry.UpdatedAt))),
+		widget.NewFormItem("", container.NewHBox(historyButton)),
+	}
+
+	dlg = dialog.NewForm(lang.L("edit_task"), lang.L("save"), lang.L("cancel"), items, func(b bool) {
+		if !b {
+			return
+		}
+
+		var segments []models.Segment
+		for _, row := range rows {
+			start, err1 := time.ParseInLocation(entryTimeLayout, row.start.Text, time.Local)
+			end, err2 := time.ParseInLocation(entryTimeLayout, row.end.Text, time.Local)
+			if err1 != nil || err2 != nil {
+				dialog.ShowError(fmt.Errorf("%s", lang.L("error_parsing_time")), parentWindow)
+				return
+			}
+			segments = append(segments, models.Segment{Start: start, End: end})
+		}
+
+		// Parse tags from comma-separated input
+		var newTags []string
+		for _, tag := range strings.Split(tagsEntry.Text, ",") {
+			if trimmed := strings.TrimSpace(tag); trimmed != "" {
+				newTags = append(newTags, trimmed)
+			}
+		}
+
+		// Get selected project ID
+		newProjectID := ""
+		if projectSelect.Selected != "" && projectSelect.Selected != lang.L("none") {
+			for _, p := range projects {
+				if p.Name == projectSelect.Selected {
+					newProjectID = p.ID
+					break
+				}
+			}
+		}
+
+		updated := entry
+		updated.Description = descEntry.Text
+		updated.Tags = newTags
+		updated.ProjectID = newProjectID
+		updated.NonBillable = !billableCheck.Checked
+		if err := service.SetSegments(&updated, segments); err != nil {
+			dialog.ShowError(fmt.Errorf("%s", lang.L("segment_invalid")), parentWindow)
+			return
+		}
+
+		if err := replaceEntry(s, entry, updated); err != nil {
+			dialog.ShowError(fmt.Errorf("%s: %w", lang.L("save_error"), err), parentWindow)
+		} else {
+			undo.Push(editEntryAction(s, entry, updated))
+		}
+		onSaved()
+	}, parentWindow)
+	dlg.Resize(fyne.NewSize(parentWindow.Canvas().Size().Width, dlg.MinSize().Height))
+	dlg.Show()
+}
+
+// replaceEntry saves updated in place of old. Entries are filed by the day
+// they start, so one moved to another day is deleted from the old one first.
+func replaceEntry(s store.Store, old, updated models.TimeEntry) error {
+	if old.StartTime.Format("2006-01-02") != updated.StartTime.Format("2006-01-02") {
+		if err := s.DeleteEntry(old); err != nil {
+			return err
+		}
+	}
+	return s.SaveEntry(updated)
+}
+
+// showEntryHistory lists the recorded changes of current, newest first, and
+// offers to restore the versions it had before. onRestored runs after a
+// version has been saved.
+func showEntryHistory(s store.Store, undo *service.UndoStack, projects []models.Project, current models.TimeEntry, onRestored func()) {
+	parentWindow := safeGetMainWindow()
+	if parentWindow == nil {
+		return
+	}
+	changes, err := s.EntryHistory(current.ID)
+	if err != nil {
+		dialog.ShowError(err, parentWindow)
+		return
+	}
+	if len(changes) == 0 {
+		dialog.ShowInformation(lang.L("entry_history"), lang.L("entry_history_empty"), parentWindow)
+		return
+	}
+
+	var d dialog.Dialog
+	restore := func(version models.TimeEntry) {
+		dialog.ShowConfirm(lang.L("restore_version"), lang.L("restore_version_confirm"), func(ok bool) {
+			if !ok {
+				return
+			}
+			version.UpdatedAt = time.Time{}
+			if err := replaceEntry(s, current, version); err != nil {
+				dialog.ShowError(fmt.Errorf("%s: %w", lang.L("save_error"), err), parentWindow)
+				return
+			}
+			undo.Push(editEntryAction(s, current, version))
+			d.Hide()
+			onRestored()
+		}, parentWindow)
+	}
+
+	list := container.NewVBox()
+	for i := len(changes) - 1; i >= 0; i-- {
+		c := changes[i]
+		version := c.After
+		if version == nil {
+			version = c.Before
+		}
+
+		var lines []string
+		if c.Action == models.EntryUpdated && c.Before != nil {
+			lines = entryDiff(*c.Before, *version, projects)
+		} else {
+			for _, f := range entryFields(*version, projects) {
+				lines = append(lines, fmt.Sprintf("%s: %s", f[0], f[1]))
+			}
+		}
+		details := widget.NewLabel(strings.Join(lines, "\n"))
+		details.Wrapping = fyne.TextWrapWord
+		content := container.NewVBox(details)
+
+		// The newest saved version is what the entry holds now. Running
+		// entries belong to the timer and are not restored over.
+		latest := i == len(changes)-1 && c.Action != models.EntryDeleted
+		if !latest && version.State == models.TaskStateStopped && current.State == models.TaskStateStopped {
+			v := *version
+			content.Add(container.NewHBox(widget.NewButtonWithIcon(lang.L("restore_version"), theme.HistoryIcon(), func() {
+				restore(v)
+			})))
+		}
+		list.Add(widget.NewCard(lang.L("entry_"+c.Action), formatStamp(c.At), content))
+	}
+
+	scroll := container.NewVScroll(list)
+	scroll.SetMinSize(fyne.NewSize(480, 360))
+	d = dialog.NewCustom(lang.L("entry_history"), lang.L("close"), scroll, parentWindow)
+	d.Show()
+}
+
+// entryFields lists the fields of e shown to the user as label/value pairs.
+func entryFields(e models.TimeEntry, projects []models.Project) [][2]string {
+	projectName := lang.L("none")
+	if p := service.FindProjectByID(projects, e.ProjectID); p != nil {
+		projectName = p.Name
+	}
+	end := "-"
+	if !e.EndTime.IsZero() {
+		end = e.EndTime.Format(entryTimeLayout)
+	}
+	return [][2]string{
+		{lang.L("task_description"), e.Description},
+		{lang.L("project"), projectName},
+		{lang.L("tags"), strings.Join(e.Tags, ", ")},
+		{lang.L("start_time"), e.StartTime.Format(entryTimeLayout)},
+		{lang.L("end_time"), end},
+		{lang.L("segments"), formatSegments(e)},
+		{lang.L("duration"), utils.FormatDuration(time.Duration(e.Duration) * time.Second)},
+	}
+}
+
+// entryDiff describes the fields that differ between two versions of an
+// entry as "label: old → new" lines.
+func entryDiff(before, after models.TimeEntry, projects []models.Project) []string {
+	old, cur := entryFields(before, projects), entryFields(after, projects)
+	var lines []string
+	for i := range cur {
+		if old[i][1] != cur[i][1] {
+			lines = append(lines, fmt.Sprintf("%s: %s → %s", cur[i][0], old[i][1], cur[i][1]))
+		}
+	}
+	if len(lines) == 0 {
+		lines = append(lines, lang.L("entry_no_visible_change"))
+	}
+	return lines
+}
+
+// formatSegments lists the worked stretches of e as clock ranges, e.g.
+// "09:00–10:30, 13:00–14:00". A running segment has no end yet.
+func formatSegments(e models.TimeEntry) string {
+	var parts []string
+	for _, seg := range e.Segments {
+		end := ""
+		if !seg.End.IsZero() {
+			end = seg.End.Format("15:04")
+		}
+		parts = append(parts, seg.Start.Format("15:04")+"–"+end)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// formatStamp formats an audit timestamp, which legacy entries lack.
+func formatStamp(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.Format(entryTimeLayout)
+}
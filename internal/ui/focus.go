@@ -0,0 +1,118 @@
+package ui
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/highercomve/tasktracker/internal/models"
+	"github.com/highercomve/tasktracker/internal/service"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/lang"
+	"fyne.io/fyne/v2/theme"
+	"github.com/spf13/viper"
+)
+
+// ToggleFocus starts focus sessions on the active task, resuming it if
+// paused, or ends them, resuming the task if it was paused for a break.
+func (d *Dashboard) ToggleFocus() {
+	status := d.pomodoro.Status()
+	if status.Phase != service.PomodoroOff {
+		d.pomodoro.Stop()
+		if status.Phase.IsBreak() && d.GetActiveState() == models.TaskStatePaused {
+			d.ResumeTask()
+		}
+		d.tickFocus()
+		return
+	}
+	if d.GetActiveID() == "" {
+		return
+	}
+	if d.GetActiveState() == models.TaskStatePaused {
+		d.ResumeTask()
+	}
+	d.pomodoro.SetConfig(pomodoroConfig())
+	d.pomodoro.Start()
+	d.tickFocus()
+}
+
+// tickFocus moves the focus session to its next phase when due and shows
+// the countdown. Breaks pause the active task and work sessions resume it,
+// the same way the pause button does.
+func (d *Dashboard) tickFocus() {
+	if t, ok := d.pomodoro.Advance(); ok {
+		var title, msg string
+		switch {
+		case t.From == service.PomodoroWork:
+			if err := d.timer.AddFocusSession(); err != nil && err != service.ErrNoActiveTask {
+				fmt.Printf("warning: failed to record focus session: %v\n", err)
+			}
+			if d.GetActiveState() == models.TaskStateRunning {
+				d.PauseTask()
+			}
+			title, msg = lang.L("focus_break_title"), fmt.Sprintf(lang.L("focus_break_msg"), t.Cycles)
+			if t.To == service.PomodoroLongBreak {
+				title = lang.L("focus_long_break_title")
+			}
+		case t.To == service.PomodoroWork:
+			if d.GetActiveState() == models.TaskStatePaused {
+				d.ResumeTask()
+			}
+			title, msg = lang.L("focus_work_title"), lang.L("focus_work_msg")
+		}
+		if app := fyne.CurrentApp(); app != nil && title != "" {
+			app.SendNotification(fyne.NewNotification(title, msg))
+		}
+	}
+
+	status := d.pomodoro.Status()
+	text := ""
+	if status.Phase != service.PomodoroOff {
+		text = fmt.Sprintf(lang.L("focus_status"), focusPhaseLabel(status.Phase), formatCountdown(status.Remaining), status.Cycles)
+	}
+	if d.trayFocus != nil {
+		d.trayFocus(text)
+	}
+	if d.focusLabel == nil {
+		return
+	}
+	if text == "" {
+		d.focusLabel.Hide()
+		d.focusBtn.SetText(lang.L("focus"))
+		d.focusBtn.SetIcon(theme.VisibilityIcon())
+		return
+	}
+	d.focusLabel.SetText(text)
+	d.focusLabel.Show()
+	d.focusBtn.SetText(lang.L("end_focus"))
+	d.focusBtn.SetIcon(theme.VisibilityOffIcon())
+}
+
+// focusPhaseLabel names a phase of a focus session.
+func focusPhaseLabel(phase service.PomodoroPhase) string {
+	switch phase {
+	case service.PomodoroShortBreak:
+		return lang.L("short_break")
+	case service.PomodoroLongBreak:
+		return lang.L("long_break")
+	}
+	return lang.L("focus")
+}
+
+// formatCountdown formats the time left in a phase as MM:SS.
+func formatCountdown(d time.Duration) string {
+	d = d.Round(time.Second)
+	return fmt.Sprintf("%02d:%02d", int(d/time.Minute), int(d%time.Minute/time.Second))
+}
+
+// pomodoroConfig returns the focus session lengths: the minutes in
+// pomodoro_work, pomodoro_short_break and pomodoro_long_break, and a long
+// break every pomodoro_long_break_every sessions.
+func pomodoroConfig() service.PomodoroConfig {
+	return service.PomodoroConfig{
+		Work:           time.Duration(viper.GetInt("pomodoro_work")) * time.Minute,
+		ShortBreak:     time.Duration(viper.GetInt("pomodoro_short_break")) * time.Minute,
+		LongBreak:      time.Duration(viper.GetInt("pomodoro_long_break")) * time.Minute,
+		LongBreakEvery: viper.GetInt("pomodoro_long_break_every"),
+	}
+}
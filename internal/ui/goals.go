@@ -0,0 +1,164 @@
+package ui
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/highercomve/tasktracker/internal/service"
+	"github.com/highercomve/tasktracker/internal/store"
+	"github.com/highercomve/tasktracker/internal/utils"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/lang"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+// progressRing draws the share of a goal reached as a ring filling
+// clockwise, with a text in its middle.
+type progressRing struct {
+	*fyne.Container
+	arc   *canvas.Arc
+	label *widget.Label
+}
+
+func newProgressRing() *progressRing {
+	track := canvas.NewArc(0, 360, 0.8, theme.Color(theme.ColorNameInputBackground))
+	arc := canvas.NewArc(0, 0, 0.8, theme.Color(theme.ColorNamePrimary))
+	label := widget.NewLabel("")
+	label.Alignment = fyne.TextAlignCenter
+	size := canvas.NewRectangle(nil)
+	size.SetMinSize(fyne.NewSize(72, 72))
+	return &progressRing{
+		Container: container.NewStack(size, track, arc, container.NewCenter(label)),
+		arc:       arc,
+		label:     label,
+	}
+}
+
+// set fills the share progress of the ring, turning it green once full.
+func (r *progressRing) set(progress float64, text string) {
+	r.arc.EndAngle = float32(min(progress, 1) * 360)
+	r.arc.FillColor = theme.Color(theme.ColorNamePrimary)
+	if progress >= 1 {
+		r.arc.FillColor = theme.Color(theme.ColorNameSuccess)
+	}
+	r.arc.Refresh()
+	r.label.SetText(text)
+}
+
+// goalText describes a day goal: the time left to reach it, or the time
+// worked over it.
+func goalText(g service.DayGoal) string {
+	if g.Diff() >= 0 {
+		return fmt.Sprintf(lang.L("goal_reached"), utils.FormatDuration(g.Diff()))
+	}
+	return fmt.Sprintf(lang.L("goal_remaining"), utils.FormatDuration(-g.Diff()))
+}
+
+// weeklyGoals shows the time worked each day of a week against the targets,
+// the week's total and the flex-time balance carried to its end.
+type weeklyGoals struct {
+	*fyne.Container
+	storage store.Store
+	days    *fyne.Container
+	summary *widget.Label
+}
+
+func newWeeklyGoals(s store.Store) *weeklyGoals {
+	w := &weeklyGoals{
+		storage: s,
+		days:    container.NewGridWithColumns(7),
+		summary: widget.NewLabel(""),
+	}
+	w.Container = container.NewVBox(w.days, w.summary, widget.NewSeparator())
+	return w
+}
+
+// update shows the week of seven days from start, or hides the panel when
+// no target is set. All entries count, whatever the report filters.
+func (w *weeklyGoals) update(start time.Time) {
+	g := goals()
+	if g.IsZero() {
+		w.Hide()
+		return
+	}
+	w.Show()
+	now := time.Now()
+	end := start.AddDate(0, 0, 6)
+	entries, err := w.storage.LoadEntriesForRange(start, end)
+	if err != nil {
+		fmt.Printf("warning: failed to load entries for the goals: %v\n", err)
+	}
+
+	days := service.DayGoals(entries, g, start, end, now)
+	cells := make([]fyne.CanvasObject, len(days))
+	for i, d := range days {
+		text := fmt.Sprintf("%s\n%s / %s", lang.L(weekdays[i].key), utils.FormatDuration(d.Actual), utils.FormatDuration(d.Target))
+		label := widget.NewLabel(text)
+		label.Truncation = fyne.TextTruncateEllipsis
+		if !d.Day.After(now) {
+			// Only days begun are over or under
+			label.SetText(text + "\n" + utils.FormatSignedDuration(d.Diff()))
+			label.Importance = widget.SuccessImportance
+			if d.Diff() < 0 && d.Day.AddDate(0, 0, 1).Before(now) {
+				label.Importance = widget.DangerImportance
+			}
+		}
+		cells[i] = label
+	}
+	w.days.Objects = cells
+	w.days.Refresh()
+
+	total := service.GoalTotals(days)
+	summary := fmt.Sprintf(lang.L("week_goal"), utils.FormatDuration(total.Actual), utils.FormatDuration(g.WeekTarget(start)))
+	if balance, ok := w.flexBalance(g, end, now); ok {
+		summary += " · " + fmt.Sprintf(lang.L("flex_balance"), utils.FormatSignedDuration(balance))
+	}
+	w.summary.SetText(summary)
+}
+
+// flexBalance returns the balance from flexStart to the end of the week, or
+// to yesterday while the week is going on; today's shortfall is not due
+// yet, but its extra time counts.
+func (w *weeklyGoals) flexBalance(g service.Goals, end, now time.Time) (time.Duration, bool) {
+	from := flexStart(w.storage)
+	if from.IsZero() || from.After(end) {
+		return 0, false
+	}
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	until := end
+	if !until.Before(today) {
+		until = today.AddDate(0, 0, -1)
+	}
+	entries, err := w.storage.LoadEntriesForRange(from, end)
+	if err != nil {
+		fmt.Printf("warning: failed to load entries for the flex-time balance: %v\n", err)
+		return 0, false
+	}
+	balance := service.FlexBalance(entries, g, from, until, now)
+	if !end.Before(today) {
+		balance += max(service.FlexBalance(entries, g, today, today, now), 0)
+	}
+	return balance, true
+}
+
+// goalRows lists the target, the time worked and the difference of each
+// day with a target or time worked, for the PDF.
+func goalRows(days []service.DayGoal) [][]string {
+	var rows [][]string
+	for _, d := range days {
+		if d.Target == 0 && d.Actual == 0 {
+			continue
+		}
+		rows = append(rows, []string{
+			d.Day.Format("2006-01-02 Mon"),
+			utils.FormatDuration(d.Target),
+			utils.FormatDuration(d.Actual),
+			utils.FormatSignedDuration(d.Diff()),
+		})
+	}
+	return rows
+}
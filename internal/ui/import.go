@@ -0,0 +1,205 @@
+package ui
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/highercomve/tasktracker/internal/importer"
+	"github.com/highercomve/tasktracker/internal/models"
+	"github.com/highercomve/tasktracker/internal/service"
+	"github.com/highercomve/tasktracker/internal/store"
+	"github.com/highercomve/tasktracker/internal/utils"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	fyneDialog "fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/lang"
+	"fyne.io/fyne/v2/widget"
+	"github.com/sqweek/dialog"
+)
+
+// importPreviewRows is how many entries the import preview lists.
+const importPreviewRows = 50
+
+// importFormats are the formats offered by the import wizard, with the
+// translation keys of their names and the file extension they use.
+var importFormats = []struct {
+	name, key, ext string
+}{
+	{importer.FormatCSV, "import_format_csv", "csv"},
+	{importer.FormatTogglCSV, "import_format_toggl_csv", "csv"},
+	{importer.FormatTogglJSON, "import_format_toggl_json", "json"},
+	{importer.FormatClockifyCSV, "import_format_clockify_csv", "csv"},
+	{importer.FormatWatson, "import_format_watson", ""}, // Watson's frames file has no extension
+}
+
+// showImportWizard asks for the format of the export and the file to read,
+// then previews the import. Generic CSV files go through the column mapping
+// first.
+func showImportWizard(w fyne.Window, s store.Store, undo *service.UndoStack, onDone func()) {
+	options := make([]string, len(importFormats))
+	for i, f := range importFormats {
+		options[i] = lang.L(f.key)
+	}
+	formatRadio := widget.NewRadioGroup(options, nil)
+	formatRadio.Required = true
+	formatRadio.SetSelected(options[0])
+
+	content := container.NewVBox(widget.NewLabel(lang.L("import_choose_format")), formatRadio)
+	fyneDialog.ShowCustomConfirm(lang.L("import_entries"), lang.L("choose_file"), lang.L("cancel"), content, func(ok bool) {
+		if !ok {
+			return
+		}
+		f := importFormats[0]
+		for i, option := range options {
+			if option == formatRadio.Selected {
+				f = importFormats[i]
+			}
+		}
+
+		picker := dialog.File().Title(lang.L("import_entries"))
+		if f.ext != "" {
+			picker = picker.Filter(strings.ToUpper(f.ext)+" files", f.ext)
+		}
+		path, err := picker.Load()
+		if err != nil {
+			if err != dialog.ErrCancelled {
+				fyneDialog.ShowError(err, w)
+			}
+			return
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			fyneDialog.ShowError(err, w)
+			return
+		}
+		if f.name == importer.FormatCSV {
+			showCSVMapping(w, s, undo, data, onDone)
+			return
+		}
+
+		format, _ := importer.Lookup(f.name)
+		parseImport(w, s, undo, format, data, importer.Options{}, onDone)
+	}, w)
+}
+
+// parseImport reads data in format and previews the import. When the dates
+// of the file read both as day/month and month/day, the user picks the
+// order and the file is read again.
+func parseImport(w fyne.Window, s store.Store, undo *service.UndoStack, format importer.Format, data []byte, opts importer.Options, onDone func()) {
+	projects, err := s.LoadProjects()
+	if err != nil {
+		fyneDialog.ShowError(err, w)
+		return
+	}
+	res, err := format.Parse(bytes.NewReader(data), projects, opts)
+	if errors.Is(err, importer.ErrAmbiguousDates) && opts.DateOrder == "" {
+		orders := []option{
+			{"import_date_dmy", importer.DateOrderDMY},
+			{"import_date_mdy", importer.DateOrderMDY},
+		}
+		labels := make([]string, len(orders))
+		for i, o := range orders {
+			labels[i] = lang.L(o.key)
+		}
+		orderRadio := widget.NewRadioGroup(labels, nil)
+		orderRadio.Required = true
+		orderRadio.SetSelected(labels[0])
+		message := widget.NewLabel(lang.L("import_date_order_msg"))
+		message.Wrapping = fyne.TextWrapWord
+		content := container.NewVBox(message, orderRadio)
+		fyneDialog.ShowCustomConfirm(lang.L("import_date_order"), lang.L("import_read_dates"), lang.L("cancel"), content, func(ok bool) {
+			if !ok {
+				return
+			}
+			for i, label := range labels {
+				if label == orderRadio.Selected {
+					opts.DateOrder = orders[i].value
+				}
+			}
+			parseImport(w, s, undo, format, data, opts, onDone)
+		}, w)
+		return
+	}
+	if err != nil {
+		fyneDialog.ShowError(fmt.Errorf("%s: %w", lang.L("import_read_error"), err), w)
+		return
+	}
+	showImportPreview(w, s, undo, res.Entries, res.Projects, res.Problems, onDone)
+}
+
+// showImportPreview shows what importing entries would add, after dropping
+// those already stored, and writes them once the user confirms. created are
+// the projects the entries need; problems describe the source rows that
+// could not be read. The import is recorded on undo.
+func showImportPreview(w fyne.Window, s store.Store, undo *service.UndoStack, entries []models.TimeEntry, created []models.Project, problems []string, onDone func()) {
+	plan, err := service.PlanImport(s, entries, created)
+	if err != nil {
+		fyneDialog.ShowError(err, w)
+		return
+	}
+	projects, err := s.LoadProjects()
+	if err != nil {
+		fyneDialog.ShowError(err, w)
+		return
+	}
+	projects = append(projects, plan.Projects...)
+
+	var lines []string
+	for i, e := range plan.Entries {
+		if i == importPreviewRows {
+			lines = append(lines, fmt.Sprintf(lang.L("import_more"), len(plan.Entries)-i))
+			break
+		}
+		line := fmt.Sprintf("%s  %s  %s", e.StartTime.Format("2006-01-02 15:04"),
+			utils.FormatDuration(time.Duration(e.Duration)*time.Second), e.Description)
+		if p := service.FindProjectByID(projects, e.ProjectID); p != nil {
+			line += " (" + p.Name + ")"
+		}
+		lines = append(lines, line)
+	}
+	if len(problems) > 0 {
+		lines = append(lines, "", fmt.Sprintf(lang.L("import_problems"), len(problems)))
+		lines = append(lines, problems...)
+	}
+
+	summary := fmt.Sprintf(lang.L("import_summary"), len(plan.Entries), plan.Duplicates, len(plan.Projects))
+	if len(plan.Projects) > 0 {
+		names := make([]string, len(plan.Projects))
+		for i, p := range plan.Projects {
+			names[i] = p.Name
+		}
+		summary += "\n" + lang.L("new_projects") + ": " + strings.Join(names, ", ")
+	}
+	if len(plan.Entries) == 0 {
+		fyneDialog.ShowInformation(lang.L("import_preview"), summary+"\n"+lang.L("nothing_to_import"), w)
+		return
+	}
+
+	details := widget.NewLabel(strings.Join(lines, "\n"))
+	scroll := container.NewVScroll(details)
+	scroll.SetMinSize(fyne.NewSize(520, 320))
+	content := container.NewBorder(widget.NewLabel(summary), nil, nil, nil, scroll)
+
+	fyneDialog.ShowCustomConfirm(lang.L("import_preview"), lang.L("import"), lang.L("cancel"), content, func(ok bool) {
+		if !ok {
+			return
+		}
+		if err := service.Import(s, plan); err != nil {
+			fyneDialog.ShowError(fmt.Errorf("%s: %w", lang.L("save_error"), err), w)
+			return
+		}
+		undo.Push(service.UndoAction{
+			Label: fmt.Sprintf(lang.L("entries_imported"), len(plan.Entries)),
+			Undo:  func() error { return service.RevertImport(s, plan) },
+			Redo:  func() error { return service.Import(s, plan) },
+		})
+		if onDone != nil {
+			onDone()
+		}
+	}, w)
+}
@@ -0,0 +1,147 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"fyne.io/fyne/v2/lang"
+	"github.com/highercomve/tasktracker/internal/models"
+	"github.com/highercomve/tasktracker/internal/service"
+	"github.com/johnfercher/maroto/pkg/color"
+	"github.com/johnfercher/maroto/pkg/consts"
+	"github.com/johnfercher/maroto/pkg/pdf"
+	"github.com/johnfercher/maroto/pkg/props"
+)
+
+// partyLines are the lines printed for the issuer or the recipient.
+func partyLines(p models.InvoiceParty) []string {
+	lines := []string{p.Name}
+	for _, l := range strings.Split(p.Address, "\n") {
+		if l = strings.TrimSpace(l); l != "" {
+			lines = append(lines, l)
+		}
+	}
+	if p.Email != "" {
+		lines = append(lines, p.Email)
+	}
+	if p.TaxID != "" {
+		lines = append(lines, fmt.Sprintf("%s: %s", lang.L("tax_id"), p.TaxID))
+	}
+	return lines
+}
+
+// GenerateInvoicePDF saves inv as a PDF invoice.
+func GenerateInvoicePDF(path string, inv models.Invoice) error {
+	m := pdf.NewMaroto(consts.Portrait, consts.A4)
+	m.SetPageMargins(20, 15, 20)
+
+	const lineHeight = 5.0
+	business, client := partyLines(inv.Business), partyLines(inv.Client)
+	details := []string{
+		fmt.Sprintf("%s: %s", lang.L("invoice_number"), inv.Number),
+		fmt.Sprintf("%s: %s", lang.L("issue_date"), inv.IssueDate.Format("2006-01-02")),
+		fmt.Sprintf("%s: %s", lang.L("due_date"), inv.DueDate.Format("2006-01-02")),
+	}
+
+	m.Row(12, func() {
+		m.Col(12, func() {
+			m.Text(lang.L("invoice_title"), props.Text{
+				Size:  20,
+				Style: consts.Bold,
+				Align: consts.Right,
+				Color: blueColor,
+			})
+		})
+	})
+	m.Row(float64(max(len(business), len(details)))*lineHeight+4, func() {
+		m.Col(7, func() {
+			for i, l := range business {
+				style := consts.Normal
+				if i == 0 {
+					style = consts.Bold
+				}
+				m.Text(l, props.Text{Top: float64(i) * lineHeight, Size: 10, Style: style})
+			}
+		})
+		m.Col(5, func() {
+			for i, l := range details {
+				m.Text(l, props.Text{Top: float64(i) * lineHeight, Size: 10, Align: consts.Right})
+			}
+		})
+	})
+	m.Line(1.0, props.Line{Color: blueColor})
+
+	m.Row(float64(len(client)+1)*lineHeight+6, func() {
+		m.Col(7, func() {
+			m.Text(lang.L("bill_to"), props.Text{Top: 3, Size: 10, Style: consts.Bold, Color: blueColor})
+			for i, l := range client {
+				m.Text(l, props.Text{Top: 3 + float64(i+1)*lineHeight, Size: 10})
+			}
+		})
+		m.Col(5, func() {
+			m.Text(fmt.Sprintf("%s: %s - %s", lang.L("invoice_period"), inv.PeriodStart.Format("2006-01-02"), inv.PeriodEnd.Format("2006-01-02")),
+				props.Text{Top: 3, Size: 10, Align: consts.Right})
+		})
+	})
+
+	headers := []string{lang.L("task_description"), lang.L("hours"), lang.L("rate"), lang.L("amount")}
+	rows := make([][]string, len(inv.Lines))
+	for i, l := range inv.Lines {
+		rows[i] = []string{
+			l.Description,
+			fmt.Sprintf("%.2f", l.Hours),
+			fmt.Sprintf("%.2f", l.Rate),
+			service.FormatMoney(l.Amount, inv.Currency),
+		}
+	}
+	m.TableList(headers, rows, props.TableList{
+		HeaderProp: props.TableListContent{
+			Size:      10,
+			GridSizes: []uint{6, 2, 2, 2},
+		},
+		ContentProp: props.TableListContent{
+			Size:      9,
+			GridSizes: []uint{6, 2, 2, 2},
+		},
+		Align:                consts.Center,
+		AlternatedBackground: &color.Color{Red: 245, Green: 245, Blue: 245},
+		HeaderContentSpace:   1,
+		Line:                 false,
+	})
+
+	m.Row(5, func() {})
+	m.Line(1.0, props.Line{Color: blueColor})
+
+	total := func(label, value string, size float64, style consts.Style) {
+		m.Row(size*0.8, func() {
+			m.ColSpace(6)
+			m.Col(3, func() {
+				m.Text(label, props.Text{Style: style, Size: size, Align: consts.Left})
+			})
+			m.Col(3, func() {
+				m.Text(value, props.Text{Style: style, Size: size, Align: consts.Right})
+			})
+		})
+	}
+	total(lang.L("subtotal"), service.FormatMoney(inv.Subtotal(), inv.Currency), 10, consts.Normal)
+	if inv.TaxPercent != 0 {
+		total(fmt.Sprintf("%s (%g%%)", lang.L("tax"), inv.TaxPercent), service.FormatMoney(inv.Tax(), inv.Currency), 10, consts.Normal)
+	}
+	total(lang.L("total_due"), service.FormatMoney(inv.Total(), inv.Currency), 14, consts.Bold)
+
+	if inv.PaymentTerms != "" {
+		m.Row(10, func() {})
+		m.Row(6, func() {
+			m.Col(12, func() {
+				m.Text(lang.L("payment_terms"), props.Text{Size: 10, Style: consts.Bold, Color: blueColor})
+			})
+		})
+		m.Row(12, func() {
+			m.Col(12, func() {
+				m.Text(inv.PaymentTerms, props.Text{Size: 9})
+			})
+		})
+	}
+
+	return m.OutputFileAndClose(path)
+}
@@ -0,0 +1,374 @@
+package ui
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/highercomve/tasktracker/internal/models"
+	"github.com/highercomve/tasktracker/internal/service"
+	"github.com/highercomve/tasktracker/internal/store"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	fyneDialog "fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/lang"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"github.com/spf13/viper"
+	"github.com/sqweek/dialog"
+)
+
+// Invoices lists the issued invoices and issues new ones.
+type Invoices struct {
+	storage  store.Store
+	invoices []models.Invoice
+	list     *widget.List
+}
+
+func NewInvoices(s store.Store) *Invoices {
+	return &Invoices{storage: s}
+}
+
+func (iv *Invoices) MakeUI() fyne.CanvasObject {
+	newBtn := widget.NewButtonWithIcon(lang.L("new_invoice"), theme.ContentAddIcon(), iv.showNewInvoiceDialog)
+	businessBtn := widget.NewButtonWithIcon(lang.L("business_details"), theme.AccountIcon(), iv.showBusinessDialog)
+
+	iv.list = widget.NewList(
+		func() int { return len(iv.invoices) },
+		func() fyne.CanvasObject {
+			return container.NewBorder(nil, nil, nil,
+				container.NewHBox(
+					widget.NewCheck(lang.L("paid"), nil),
+					widget.NewButtonWithIcon("", theme.DocumentSaveIcon(), nil),
+				),
+				container.NewVBox(
+					widget.NewLabelWithStyle("INV-0000", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+					widget.NewLabel("Client"),
+				),
+			)
+		},
+		func(i int, o fyne.CanvasObject) {
+			if i >= len(iv.invoices) {
+				return
+			}
+			iv.updateItem(o, iv.invoices[i])
+		},
+	)
+	iv.reload()
+
+	iv.storage.Subscribe(func(c store.Change) {
+		if c.Kind == store.StateChanged {
+			return
+		}
+		fyne.Do(iv.reload)
+	})
+
+	return container.NewBorder(
+		container.NewHBox(newBtn, businessBtn),
+		nil, nil, nil,
+		iv.list,
+	)
+}
+
+// reload reads the invoices, newest first.
+func (iv *Invoices) reload() {
+	invoices, err := iv.storage.LoadInvoices()
+	if err != nil {
+		fmt.Printf("warning: failed to load invoices: %v\n", err)
+		return
+	}
+	for i, j := 0, len(invoices)-1; i < j; i, j = i+1, j-1 {
+		invoices[i], invoices[j] = invoices[j], invoices[i]
+	}
+	iv.invoices = invoices
+	iv.list.Refresh()
+}
+
+func (iv *Invoices) updateItem(o fyne.CanvasObject, inv models.Invoice) {
+	box := o.(*fyne.Container)
+	labels := box.Objects[0].(*fyne.Container)
+	buttons := box.Objects[1].(*fyne.Container)
+	title := labels.Objects[0].(*widget.Label)
+	detail := labels.Objects[1].(*widget.Label)
+	paidCheck := buttons.Objects[0].(*widget.Check)
+	pdfBtn := buttons.Objects[1].(*widget.Button)
+
+	status := lang.L("unpaid")
+	switch {
+	case inv.Paid:
+		status = fmt.Sprintf(lang.L("paid_on"), inv.PaidAt.Format("2006-01-02"))
+	case inv.Overdue(time.Now()):
+		status = lang.L("overdue")
+	}
+	title.SetText(fmt.Sprintf("%s  %s  %s", inv.Number, inv.Client.Name, service.FormatMoney(inv.Total(), inv.Currency)))
+	detail.SetText(fmt.Sprintf("%s %s · %s %s · %s", lang.L("issue_date"), inv.IssueDate.Format("2006-01-02"),
+		lang.L("due_date"), inv.DueDate.Format("2006-01-02"), status))
+
+	paidCheck.OnChanged = nil
+	paidCheck.SetChecked(inv.Paid)
+	paidCheck.OnChanged = func(paid bool) {
+		iv.setPaid(inv.ID, paid)
+	}
+	pdfBtn.OnTapped = func() {
+		saveInvoicePDF(safeGetMainWindow(), inv)
+	}
+}
+
+// setPaid records whether the invoice with id was paid.
+func (iv *Invoices) setPaid(id string, paid bool) {
+	err := iv.storage.UpdateInvoices(func(invoices []models.Invoice) ([]models.Invoice, error) {
+		for i := range invoices {
+			if invoices[i].ID == id {
+				invoices[i].Paid = paid
+				invoices[i].PaidAt = time.Time{}
+				if paid {
+					invoices[i].PaidAt = time.Now()
+				}
+			}
+		}
+		return invoices, nil
+	})
+	if err != nil {
+		fyneDialog.ShowError(fmt.Errorf("%s: %w", lang.L("save_error"), err), safeGetMainWindow())
+	}
+	iv.reload()
+}
+
+// invoiceLinesOptions are the line item choices, by translation key.
+var invoiceLinesOptions = []struct {
+	key, linesBy string
+}{
+	{"invoice_lines_project", service.InvoiceLinesByProject},
+	{"invoice_lines_task", service.InvoiceLinesByTask},
+}
+
+// showNewInvoiceDialog asks for the client, period and terms, then issues
+// the invoice and saves its PDF.
+func (iv *Invoices) showNewInvoiceDialog() {
+	w := safeGetMainWindow()
+	projects, err := iv.storage.LoadProjects()
+	if err != nil {
+		fyneDialog.ShowError(err, w)
+		return
+	}
+	clients := service.InvoiceClients(projects)
+	if len(clients) == 0 {
+		fyneDialog.ShowInformation(lang.L("new_invoice"), lang.L("no_clients"), w)
+		return
+	}
+
+	// Default to the previous calendar month.
+	now := time.Now()
+	firstOfMonth := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.Local)
+	start, end := firstOfMonth.AddDate(0, -1, 0), firstOfMonth.AddDate(0, 0, -1)
+
+	clientSelect := widget.NewSelect(clients, nil)
+	clientSelect.SetSelectedIndex(0)
+	startEntry := widget.NewEntry()
+	startEntry.SetText(start.Format("2006-01-02"))
+	endEntry := widget.NewEntry()
+	endEntry.SetText(end.Format("2006-01-02"))
+
+	lineLabels := make([]string, len(invoiceLinesOptions))
+	for i, o := range invoiceLinesOptions {
+		lineLabels[i] = lang.L(o.key)
+	}
+	linesRadio := widget.NewRadioGroup(lineLabels, nil)
+	linesRadio.Required = true
+	linesRadio.SetSelected(lineLabels[0])
+	for i, o := range invoiceLinesOptions {
+		if o.linesBy == viper.GetString("invoice_lines") {
+			linesRadio.SetSelected(lineLabels[i])
+		}
+	}
+
+	taxEntry := widget.NewEntry()
+	taxEntry.SetText(strconv.FormatFloat(viper.GetFloat64("invoice_tax_percent"), 'f', -1, 64))
+	dueEntry := widget.NewEntry()
+	dueDays := 30
+	if viper.IsSet("invoice_due_days") {
+		dueDays = viper.GetInt("invoice_due_days")
+	}
+	dueEntry.SetText(strconv.Itoa(dueDays))
+	termsEntry := widget.NewMultiLineEntry()
+	termsEntry.SetText(viper.GetString("invoice_payment_terms"))
+	termsEntry.SetMinRowsVisible(2)
+
+	items := []*widget.FormItem{
+		widget.NewFormItem(lang.L("client"), clientSelect),
+		widget.NewFormItem(lang.L("start_date"), startEntry),
+		widget.NewFormItem(lang.L("end_date"), endEntry),
+		widget.NewFormItem(lang.L("invoice_lines"), linesRadio),
+		widget.NewFormItem(lang.L("tax_percent"), taxEntry),
+		widget.NewFormItem(lang.L("due_days"), dueEntry),
+		widget.NewFormItem(lang.L("payment_terms"), termsEntry),
+	}
+	dlg := fyneDialog.NewForm(lang.L("new_invoice"), lang.L("issue_invoice"), lang.L("cancel"), items, func(ok bool) {
+		if !ok {
+			return
+		}
+		start, err1 := time.ParseInLocation("2006-01-02", strings.TrimSpace(startEntry.Text), time.Local)
+		end, err2 := time.ParseInLocation("2006-01-02", strings.TrimSpace(endEntry.Text), time.Local)
+		if err := errors.Join(err1, err2); err != nil || end.Before(start) {
+			fyneDialog.ShowError(errors.New(lang.L("invalid_date_range")), w)
+			return
+		}
+		tax, err1 := strconv.ParseFloat(strings.TrimSpace(taxEntry.Text), 64)
+		due, err2 := strconv.Atoi(strings.TrimSpace(dueEntry.Text))
+		if err := errors.Join(err1, err2); err != nil || tax < 0 || due < 0 {
+			fyneDialog.ShowError(errors.New(lang.L("invalid_number")), w)
+			return
+		}
+		opts := service.InvoiceOptions{
+			Client:        clientSelect.Selected,
+			Start:         start,
+			End:           end,
+			LinesBy:       invoiceLinesOptions[0].linesBy,
+			Billing:       billingConfig(),
+			Business:      businessDetails(),
+			Prefix:        viper.GetString("invoice_prefix"),
+			TaxPercent:    tax,
+			DueDays:       due,
+			PaymentTerms:  strings.TrimSpace(termsEntry.Text),
+			OvertimeLabel: lang.L("overtime"),
+			RoundingLabel: lang.L("rounding"),
+		}
+		for i, label := range lineLabels {
+			if label == linesRadio.Selected {
+				opts.LinesBy = invoiceLinesOptions[i].linesBy
+			}
+		}
+
+		// Remember the terms for the next invoice.
+		viper.Set("invoice_lines", opts.LinesBy)
+		viper.Set("invoice_tax_percent", opts.TaxPercent)
+		viper.Set("invoice_due_days", opts.DueDays)
+		viper.Set("invoice_payment_terms", opts.PaymentTerms)
+		if err := viper.WriteConfigAs(viper.ConfigFileUsed()); err != nil {
+			fmt.Printf("warning: failed to save invoice settings: %v\n", err)
+		}
+
+		inv, err := iv.issue(opts)
+		if err != nil {
+			fyneDialog.ShowError(err, w)
+			return
+		}
+		iv.reload()
+		saveInvoicePDF(w, inv)
+	}, w)
+	dlg.Resize(fyne.NewSize(w.Canvas().Size().Width*3/4, dlg.MinSize().Height))
+	dlg.Show()
+}
+
+// issue builds the invoice for opts and saves it, taking the next number.
+func (iv *Invoices) issue(opts service.InvoiceOptions) (models.Invoice, error) {
+	entries, err := iv.storage.LoadEntriesForRange(opts.Start, opts.End)
+	if err != nil {
+		return models.Invoice{}, err
+	}
+	projects, err := iv.storage.LoadProjects()
+	if err != nil {
+		return models.Invoice{}, err
+	}
+	// Built while the invoices are locked, so that no other process takes
+	// the same number
+	var inv models.Invoice
+	err = iv.storage.UpdateInvoices(func(invoices []models.Invoice) ([]models.Invoice, error) {
+		var err error
+		if inv, err = service.BuildInvoice(entries, projects, invoices, opts, time.Now()); err != nil {
+			return nil, err
+		}
+		return append(invoices, inv), nil
+	})
+	switch {
+	case errors.Is(err, service.ErrNoHourlyRate):
+		return inv, errors.New(lang.L("invoice_no_rate"))
+	case errors.Is(err, service.ErrNothingToInvoice):
+		return inv, errors.New(lang.L("invoice_nothing"))
+	case errors.Is(err, service.ErrMixedCurrencies):
+		return inv, errors.New(lang.L("invoice_mixed_currencies"))
+	case err != nil:
+		return inv, fmt.Errorf("%s: %w", lang.L("save_error"), err)
+	}
+	return inv, nil
+}
+
+// saveInvoicePDF asks where to save the PDF of inv and offers to open it.
+func saveInvoicePDF(w fyne.Window, inv models.Invoice) {
+	filename := fmt.Sprintf("invoice_%s.pdf", strings.NewReplacer("/", "-", " ", "_").Replace(inv.Number))
+	path, err := dialog.File().Title(lang.L("save_invoice")).SetStartFile(filename).Filter("PDF files", "pdf").Save()
+	if err != nil {
+		if err != dialog.ErrCancelled {
+			fyneDialog.ShowError(err, w)
+		}
+		return
+	}
+	if path == "" {
+		return
+	}
+	if err := GenerateInvoicePDF(path, inv); err != nil {
+		fyneDialog.ShowError(err, w)
+		return
+	}
+	fyneDialog.ShowConfirm(lang.L("success"), lang.L("pdf_saved")+"\n"+lang.L("open_file_question"), func(open bool) {
+		if open {
+			if err := openFile(path); err != nil {
+				fyneDialog.ShowError(err, w)
+			}
+		}
+	}, w)
+}
+
+// businessDetails are the issuer details kept in the config file.
+func businessDetails() models.InvoiceParty {
+	return models.InvoiceParty{
+		Name:    viper.GetString("business_name"),
+		Address: viper.GetString("business_address"),
+		Email:   viper.GetString("business_email"),
+		TaxID:   viper.GetString("business_tax_id"),
+	}
+}
+
+// showBusinessDialog edits the issuer details and the invoice numbering.
+func (iv *Invoices) showBusinessDialog() {
+	w := safeGetMainWindow()
+	b := businessDetails()
+	nameEntry := widget.NewEntry()
+	nameEntry.SetText(b.Name)
+	addressEntry := widget.NewMultiLineEntry()
+	addressEntry.SetText(b.Address)
+	addressEntry.SetMinRowsVisible(3)
+	emailEntry := widget.NewEntry()
+	emailEntry.SetText(b.Email)
+	taxIDEntry := widget.NewEntry()
+	taxIDEntry.SetText(b.TaxID)
+	prefixEntry := widget.NewEntry()
+	prefixEntry.SetPlaceHolder(service.DefaultInvoicePrefix)
+	prefixEntry.SetText(viper.GetString("invoice_prefix"))
+
+	items := []*widget.FormItem{
+		widget.NewFormItem(lang.L("business_name"), nameEntry),
+		widget.NewFormItem(lang.L("business_address"), addressEntry),
+		widget.NewFormItem(lang.L("business_email"), emailEntry),
+		widget.NewFormItem(lang.L("tax_id"), taxIDEntry),
+		widget.NewFormItem(lang.L("invoice_prefix"), prefixEntry),
+	}
+	dlg := fyneDialog.NewForm(lang.L("business_details"), lang.L("save"), lang.L("cancel"), items, func(ok bool) {
+		if !ok {
+			return
+		}
+		viper.Set("business_name", strings.TrimSpace(nameEntry.Text))
+		viper.Set("business_address", strings.TrimSpace(addressEntry.Text))
+		viper.Set("business_email", strings.TrimSpace(emailEntry.Text))
+		viper.Set("business_tax_id", strings.TrimSpace(taxIDEntry.Text))
+		viper.Set("invoice_prefix", strings.TrimSpace(prefixEntry.Text))
+		if err := viper.WriteConfigAs(viper.ConfigFileUsed()); err != nil {
+			fyneDialog.ShowError(err, w)
+		}
+	}, w)
+	dlg.Resize(fyne.NewSize(w.Canvas().Size().Width*3/4, dlg.MinSize().Height))
+	dlg.Show()
+}
@@ -14,7 +14,6 @@ import (
 	"github.com/johnfercher/maroto/pkg/consts"
 	"github.com/johnfercher/maroto/pkg/pdf"
 	"github.com/johnfercher/maroto/pkg/props"
-	"github.com/spf13/viper"
 )
 
 var (
@@ -23,7 +22,19 @@ var (
 	whiteColor = color.Color{Red: 255, Green: 255, Blue: 255}
 )
 
-func GeneratePDF(path string, entries []models.TimeEntry, start, end time.Time, groupBy string) error {
+// pdfDescription is the description column of an entry row, followed by the
+// times it was worked on when it was paused in between.
+func pdfDescription(e models.TimeEntry) string {
+	if len(e.Segments) < 2 {
+		return e.Description
+	}
+	return fmt.Sprintf("%s (%s)", e.Description, formatSegments(e))
+}
+
+func GeneratePDF(path string, entries []models.TimeEntry, projects []models.Project, start, end time.Time, groupBy string) error {
+	// Attribute time to the days it was worked on
+	entries = service.SplitByDay(entries, start, end, time.Now())
+
 	m := pdf.NewMaroto(consts.Portrait, consts.A4)
 	m.SetPageMargins(20, 15, 20)
 
@@ -93,10 +104,7 @@ func GeneratePDF(path string, entries []models.TimeEntry, start, end time.Time,
 	// Calculate total duration
 	var totalDuration time.Duration
 	for _, e := range entries {
-		dur := time.Duration(e.Duration) * time.Second
-		if e.EndTime.IsZero() {
-			dur = time.Since(e.StartTime)
-		}
+		dur := e.TrackedDuration(time.Now())
 		totalDuration += dur
 	}
 
@@ -114,14 +122,11 @@ func GeneratePDF(path string, entries []models.TimeEntry, start, end time.Time,
 	if groupBy == service.GroupByNone {
 		rows := [][]string{}
 		for _, e := range entries {
-			dur := time.Duration(e.Duration) * time.Second
-			if e.EndTime.IsZero() {
-				dur = time.Since(e.StartTime)
-			}
+			dur := e.TrackedDuration(time.Now())
 
 			rows = append(rows, []string{
 				e.StartTime.Format("2006-01-02"),
-				e.Description,
+				pdfDescription(e),
 				utils.FormatDuration(dur),
 			})
 		}
@@ -162,15 +167,12 @@ func GeneratePDF(path string, entries []models.TimeEntry, start, end time.Time,
 			rows := [][]string{}
 
 			for _, e := range groupEntries {
-				dur := time.Duration(e.Duration) * time.Second
-				if e.EndTime.IsZero() {
-					dur = time.Since(e.StartTime)
-				}
+				dur := e.TrackedDuration(time.Now())
 				groupTotal += dur
 
 				rows = append(rows, []string{
 					e.StartTime.Format("2006-01-02"),
-					e.Description,
+					pdfDescription(e),
 					utils.FormatDuration(dur),
 				})
 			}
@@ -243,75 +245,112 @@ func GeneratePDF(path string, entries []models.TimeEntry, start, end time.Time,
 		})
 	})
 
-	// Billing summary in PDF
-	hourlyRate := viper.GetFloat64("hourly_rate")
-	if hourlyRate > 0 {
-		billingConfig := service.BillingConfig{
-			HourlyRate: hourlyRate,
-			MaxHours:   viper.GetFloat64("max_hours"),
-			ExtraRate:  viper.GetFloat64("extra_rate"),
-		}
-
-		periodDays := int(end.Sub(start).Hours()/24) + 1
-		billing := service.CalculateBilling(totalDuration, billingConfig, periodDays)
+	// Time that is billed, without internal work and once rounded
+	config := billingConfig()
+	m.Row(8, func() {
+		m.ColSpace(6)
+		m.Col(3, func() {
+			m.Text(lang.L("billable_time"), props.Text{
+				Style: consts.Normal,
+				Size:  10,
+				Align: consts.Left,
+			})
+		})
+		m.Col(3, func() {
+			m.Text(utils.FormatDuration(service.BillableTime(entries, projects, config.Rounding, time.Now())), props.Text{
+				Style: consts.Normal,
+				Size:  10,
+				Align: consts.Right,
+			})
+		})
+	})
 
-		m.Row(12, func() {
+	// Billing summary in PDF: the cost of each project, then the totals of
+	// each currency.
+	costs := service.CalculateProjectBilling(entries, projects, config, start, end, time.Now())
+	summaryRow := func(label, value string, size float64, style consts.Style, c color.Color) {
+		m.Row(size*0.8, func() {
 			m.ColSpace(6)
 			m.Col(3, func() {
-				m.Text(lang.L("total_cost"), props.Text{
-					Style: consts.Bold,
-					Align: consts.Left,
-					Size:  14,
-					Color: blueColor,
-				})
+				m.Text(label, props.Text{Style: style, Align: consts.Left, Size: size, Color: c})
 			})
 			m.Col(3, func() {
-				m.Text(fmt.Sprintf("%.2f", billing.TotalCost), props.Text{
-					Style: consts.Bold,
-					Align: consts.Right,
-					Size:  14,
-					Color: blueColor,
-				})
+				m.Text(value, props.Text{Style: style, Align: consts.Right, Size: size, Color: c})
 			})
 		})
+	}
+	black := color.NewBlack()
+	if len(costs) > 1 {
+		for _, c := range costs {
+			name := lang.L("unassigned")
+			if p := service.FindProjectByID(projects, c.ProjectID); p != nil {
+				name = p.Name
+			}
+			summaryRow(name, service.FormatMoney(c.TotalCost, c.Currency), 10, consts.Normal, black)
+		}
+	}
+	for _, t := range service.CurrencyTotals(costs) {
+		summaryRow(lang.L("total_cost"), service.FormatMoney(t.TotalCost, t.Currency), 14, consts.Bold, blueColor)
+		if t.ExtraCost > 0 {
+			summaryRow(lang.L("standard_cost"), service.FormatMoney(t.StandardCost, t.Currency), 10, consts.Normal, black)
+			summaryRow(lang.L("extra_cost"), service.FormatMoney(t.ExtraCost, t.Currency), 10, consts.Normal, black)
+		}
+	}
 
-		if billing.ExtraCost > 0 {
-			m.Row(8, func() {
-				m.ColSpace(6)
-				m.Col(3, func() {
-					m.Text(lang.L("standard_cost"), props.Text{
-						Style: consts.Normal,
-						Align: consts.Left,
-						Size:  10,
-					})
-				})
-				m.Col(3, func() {
-					m.Text(fmt.Sprintf("%.2f", billing.StandardCost), props.Text{
-						Style: consts.Normal,
-						Align: consts.Right,
-						Size:  10,
-					})
-				})
+	// Overtime of each period of the threshold mode
+	if lines := overtimeBreakdown(costs, projects); len(lines) > 0 {
+		m.Row(6, func() {})
+		m.Row(6, func() {
+			m.ColSpace(6)
+			m.Col(6, func() {
+				m.Text(lang.L("overtime"), props.Text{Style: consts.Bold, Size: 9, Color: blueColor})
 			})
-			m.Row(8, func() {
+		})
+		for _, l := range lines {
+			m.Row(5, func() {
 				m.ColSpace(6)
-				m.Col(3, func() {
-					m.Text(lang.L("extra_cost"), props.Text{
-						Style: consts.Normal,
-						Align: consts.Left,
-						Size:  10,
-					})
-				})
-				m.Col(3, func() {
-					m.Text(fmt.Sprintf("%.2f", billing.ExtraCost), props.Text{
-						Style: consts.Normal,
-						Align: consts.Right,
-						Size:  10,
-					})
+				m.Col(6, func() {
+					m.Text(l, props.Text{Size: 8})
 				})
 			})
 		}
 	}
 
+	// Working hours against the targets of each day
+	if g := goals(); !g.IsZero() {
+		days := service.DayGoals(entries, g, start, end, time.Now())
+		total := service.GoalTotals(days)
+		m.Row(15, func() {
+			m.Col(12, func() {
+				m.Text(lang.L("goals"), props.Text{
+					Top:   10,
+					Style: consts.Bold,
+					Size:  14,
+					Color: blueColor,
+				})
+			})
+		})
+		rows := append(goalRows(days), []string{
+			lang.L("total"),
+			utils.FormatDuration(total.Target),
+			utils.FormatDuration(total.Actual),
+			utils.FormatSignedDuration(total.Diff()),
+		})
+		m.TableList([]string{lang.L("date"), lang.L("target"), lang.L("worked"), lang.L("difference")}, rows, props.TableList{
+			HeaderProp: props.TableListContent{
+				Size:      10,
+				GridSizes: []uint{3, 3, 3, 3},
+			},
+			ContentProp: props.TableListContent{
+				Size:      9,
+				GridSizes: []uint{3, 3, 3, 3},
+			},
+			Align:                consts.Center,
+			AlternatedBackground: &color.Color{Red: 245, Green: 245, Blue: 245},
+			HeaderContentSpace:   1,
+			Line:                 false,
+		})
+	}
+
 	return m.OutputFileAndClose(path)
 }
@@ -1,7 +1,12 @@
 package ui
 
 import (
+	"errors"
 	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/highercomve/tasktracker/internal/models"
@@ -20,7 +25,8 @@ import (
 )
 
 type Projects struct {
-	storage  *store.Storage
+	storage  store.Store
+	undo     *service.UndoStack
 	projects []models.Project
 	entries  []models.TimeEntry
 
@@ -29,9 +35,10 @@ type Projects struct {
 	refreshList func()
 }
 
-func NewProjects(s *store.Storage) *Projects {
+func NewProjects(s store.Store, undo *service.UndoStack) *Projects {
 	return &Projects{
 		storage: s,
+		undo:    undo,
 	}
 }
 
@@ -104,6 +111,17 @@ func (p *Projects) MakeUI() fyne.CanvasObject {
 		p.projectList.Refresh()
 	}
 
+	// Projects and their stats may change outside this process.
+	p.storage.Subscribe(func(c store.Change) {
+		if c.Kind == store.StateChanged {
+			return
+		}
+		fyne.Do(p.refreshList)
+	})
+	p.undo.Subscribe(func(service.UndoEvent) {
+		fyne.Do(p.refreshList)
+	})
+
 	return container.NewBorder(
 		toolbar,
 		nil, nil, nil,
@@ -123,6 +141,9 @@ func createProjectItemContainer() fyne.CanvasObject {
 			widget.NewLabel("Project Name"),
 			widget.NewLabel("Description"),
 			widget.NewLabel("Entries: 0 | Time: 00:00"),
+			// Budget and estimates, hidden for projects without them
+			container.NewVBox(widget.NewProgressBar(), widget.NewLabel("Budget")),
+			widget.NewLabel("Estimates"),
 		),
 	)
 }
@@ -136,6 +157,8 @@ func (p *Projects) updateProjectItem(o fyne.CanvasObject, project models.Project
 	nameLabel := vbox.Objects[0].(*widget.Label)
 	descLabel := vbox.Objects[1].(*widget.Label)
 	statsLabel := vbox.Objects[2].(*widget.Label)
+	budgetBox := vbox.Objects[3].(*fyne.Container)
+	estimatesLabel := vbox.Objects[4].(*widget.Label)
 
 	editBtn := buttons.Objects[0].(*widget.Button)
 	delBtn := buttons.Objects[1].(*widget.Button)
@@ -156,7 +179,18 @@ func (p *Projects) updateProjectItem(o fyne.CanvasObject, project models.Project
 
 	// Calculate and set stats
 	stats := p.calculateProjectStats(project.ID)
-	statsLabel.SetText(fmt.Sprintf("Entries: %d | Time: %s", stats.EntryCount, utils.FormatDuration(stats.TotalTime)))
+	statsText := fmt.Sprintf("Entries: %d | Time: %s", stats.EntryCount, utils.FormatDuration(stats.TotalTime))
+	if stats.Amount > 0 {
+		statsText += " | " + service.FormatMoney(stats.Amount, stats.Currency)
+	}
+	statsLabel.SetText(statsText)
+	updateBudget(budgetBox, stats)
+
+	estimatesLabel.Hide()
+	if len(project.TaskEstimates) > 0 {
+		estimatesLabel.SetText(formatEstimates(service.TaskEstimates(project, p.entriesOf(project.ID), time.Now())))
+		estimatesLabel.Show()
+	}
 
 	// Edit button
 	editBtn.OnTapped = func() {
@@ -191,37 +225,97 @@ func (p *Projects) updateProjectItem(o fyne.CanvasObject, project models.Project
 					return
 				}
 
-				// Unassign tasks from this project
-				if taskCount > 0 {
-					entries, _ := p.storage.LoadEntriesForRange(time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC), time.Now())
-					for _, e := range entries {
-						if e.ProjectID == project.ID {
-							e.ProjectID = ""
-							p.storage.SaveEntry(e)
-						}
-					}
-				}
-
-				// Delete the project
-				updatedProjects, deleted := service.DeleteProject(p.projects, project.ID)
-				if deleted {
-					p.projects = updatedProjects
-					p.storage.SaveProjects(p.projects)
-					p.refreshList()
+				unassigned, position, err := p.deleteProject(project)
+				if err != nil {
+					dialog.ShowError(fmt.Errorf("%s: %w", lang.L("save_error"), err), parentWindow)
+				} else {
+					p.undo.Push(service.UndoAction{
+						Label: lang.L("project_deleted"),
+						Undo:  func() error { return p.restoreProject(project, position, unassigned) },
+						Redo: func() error {
+							var err error
+							unassigned, position, err = p.deleteProject(project)
+							return err
+						},
+					})
 				}
+				p.refreshList()
 			},
 			parentWindow,
 		)
 	}
 }
 
-// calculateProjectStats calculates stats for a project
-func (p *Projects) calculateProjectStats(projectID string) service.ProjectStats {
-	// Load all entries to calculate stats
-	entries, err := p.storage.LoadEntriesForRange(time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC), time.Now())
+// deleteProject unassigns the project's tasks and deletes it. It returns the
+// unassigned tasks and the position the project had in the list.
+func (p *Projects) deleteProject(project models.Project) ([]models.TimeEntry, int, error) {
+	entries, err := p.storage.QueryEntries(store.EntryQuery{ProjectID: project.ID})
+	if err != nil {
+		return nil, 0, err
+	}
+	for _, e := range entries {
+		e.ProjectID = ""
+		if err := p.storage.SaveEntry(e); err != nil {
+			return nil, 0, err
+		}
+	}
+
+	// The list may be filtered by the search, so delete from the full one.
+	var position int
+	err = p.storage.UpdateProjects(func(projects []models.Project) ([]models.Project, error) {
+		position = slices.IndexFunc(projects, func(pr models.Project) bool { return pr.ID == project.ID })
+		if position < 0 {
+			position = len(projects)
+		}
+		projects, _ = service.DeleteProject(projects, project.ID)
+		return projects, nil
+	})
+	return entries, position, err
+}
+
+// restoreProject puts a deleted project back at its position and reassigns
+// the tasks deleteProject unassigned, unless they have been assigned
+// elsewhere since.
+func (p *Projects) restoreProject(project models.Project, position int, unassigned []models.TimeEntry) error {
+	err := p.storage.UpdateProjects(func(projects []models.Project) ([]models.Project, error) {
+		if service.FindProjectByID(projects, project.ID) == nil {
+			projects = slices.Insert(projects, min(position, len(projects)), project)
+		}
+		return projects, nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, e := range unassigned {
+		entries, err := p.storage.LoadEntries(e.StartTime)
+		if err != nil {
+			return err
+		}
+		for _, cur := range entries {
+			if cur.ID == e.ID && cur.ProjectID == "" {
+				cur.ProjectID = project.ID
+				if err := p.storage.SaveEntry(cur); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// entriesOf loads the entries of a project.
+func (p *Projects) entriesOf(projectID string) []models.TimeEntry {
+	entries, err := p.storage.QueryEntries(store.EntryQuery{ProjectID: projectID})
 	if err != nil {
-		entries = []models.TimeEntry{}
+		return []models.TimeEntry{}
 	}
+	return entries
+}
+
+// calculateProjectStats calculates stats for a project
+func (p *Projects) calculateProjectStats(projectID string) service.ProjectStats {
+	// Load the project's entries to calculate stats
+	entries := p.entriesOf(projectID)
 
 	// Find the project
 	var project *models.Project
@@ -237,15 +331,72 @@ func (p *Projects) calculateProjectStats(projectID string) service.ProjectStats
 	}
 
 	// Get stats
-	stats := service.GetProjectsWithStats([]models.Project{*project}, entries)
-	if len(stats) > 0 {
-		return stats[0]
-	}
-
-	return service.ProjectStats{
+	stats := service.ProjectStats{
 		ProjectID: projectID,
 		Name:      project.Name,
 	}
+	if all := service.GetProjectsWithBudgets([]models.Project{*project}, entries, billingConfig(), time.Now()); len(all) > 0 {
+		stats = all[0]
+	}
+	config := billingConfig().ForProject(project)
+	stats.Amount = config.Amount(entries, time.Now())
+	stats.Currency = config.Currency
+	return stats
+}
+
+// updateBudget shows the progress of the project's budget in box, or hides
+// it when the project has none.
+func updateBudget(box *fyne.Container, stats service.ProjectStats) {
+	bar := box.Objects[0].(*widget.ProgressBar)
+	label := box.Objects[1].(*widget.Label)
+	b := stats.Budget
+	if b == nil {
+		box.Hide()
+		return
+	}
+	box.Show()
+	bar.SetValue(min(b.Fraction(), 1))
+
+	format := func(v float64) string {
+		if b.Type == models.BudgetMoney {
+			return service.FormatMoney(v, stats.Currency)
+		}
+		return utils.FormatDuration(time.Duration(v * float64(time.Hour)).Round(time.Second))
+	}
+	key := "budget_status"
+	if b.Start.IsZero() {
+		key = "budget_status_total"
+	}
+	text := fmt.Sprintf(lang.L(key), format(b.Used), format(b.Budget))
+	if b.Remaining >= 0 {
+		text += " · " + fmt.Sprintf(lang.L("budget_remaining"), format(b.Remaining))
+	} else {
+		text += " · " + fmt.Sprintf(lang.L("budget_exceeded"), format(-b.Remaining))
+	}
+	if !b.BurnOut.IsZero() {
+		text += " · " + fmt.Sprintf(lang.L("budget_burn_out"), b.BurnOut.Format("2006-01-02"))
+	}
+	label.SetText(text)
+	label.Importance = widget.MediumImportance
+	switch b.AlertLevel() {
+	case service.BudgetAlertExceeded:
+		label.Importance = widget.DangerImportance
+	case service.BudgetAlertWarning:
+		label.Importance = widget.WarningImportance
+	}
+	label.Refresh()
+}
+
+// formatEstimates lists the tracked time and the estimate of each task.
+func formatEstimates(tasks []service.TaskEstimate) string {
+	parts := make([]string, len(tasks))
+	for i, t := range tasks {
+		parts[i] = fmt.Sprintf("%s %s / %s", t.Description, utils.FormatDuration(t.Tracked.Round(time.Second)), utils.FormatDuration(t.Estimate))
+		if t.Over() {
+			parts[i] += " ⚠"
+		}
+	}
+	return lang.L("estimates") + ": " + strings.Join(parts, ", ")
 }
 
 // showCreateProjectDialog shows dialog to create a new project
@@ -259,11 +410,18 @@ func (p *Projects) showCreateProjectDialog(onSave func()) {
 	colorEntry := widget.NewEntry()
 	colorEntry.PlaceHolder = "Color hex code (optional, e.g., #FF5733)"
 
+	client := newClientFields(models.Project{})
+	billing := newBillingFields(models.Project{})
+	budget := newBudgetFields(models.Project{})
+
 	items := []*widget.FormItem{
 		widget.NewFormItem("Name", nameEntry),
 		widget.NewFormItem("Description", descEntry),
 		widget.NewFormItem("Color", colorEntry),
 	}
+	items = append(items, client.items()...)
+	items = append(items, billing.items()...)
+	items = append(items, budget.items()...)
 
 	parentWindow := safeGetMainWindow()
 	if parentWindow == nil {
@@ -290,10 +448,17 @@ func (p *Projects) showCreateProjectDialog(onSave func()) {
 
 		// Create new project
 		newProject := service.CreateProject(name, descEntry.Text, colorEntry.Text)
+		client.apply(&newProject)
+		if err := errors.Join(billing.apply(&newProject), budget.apply(&newProject)); err != nil {
+			dialog.ShowError(err, parentWindow)
+			return
+		}
 
-		// Add to list and save
-		p.projects = append(p.projects, newProject)
-		if err := p.storage.SaveProjects(p.projects); err != nil {
+		// Add to the full list, which may differ from the one shown
+		err := p.storage.UpdateProjects(func(projects []models.Project) ([]models.Project, error) {
+			return append(projects, newProject), nil
+		})
+		if err != nil {
 			dialog.ShowError(err, parentWindow)
 			return
 		}
@@ -316,11 +481,17 @@ func (p *Projects) showEditProjectDialog(project models.Project, onSave func())
 	colorEntry := widget.NewEntry()
 	colorEntry.SetText(project.ColorHex)
 
+	client := newClientFields(project)
+	billing := newBillingFields(project)
+	budget := newBudgetFields(project)
+
 	items := []*widget.FormItem{
 		widget.NewFormItem("Name", nameEntry),
 		widget.NewFormItem("Description", descEntry),
 		widget.NewFormItem("Color", colorEntry),
 	}
+	items = append(items, client.items()...)
+	items = append(items, billing.items()...)
 
 	parentWindow := safeGetMainWindow()
 	if parentWindow == nil {
@@ -358,10 +529,26 @@ func (p *Projects) showEditProjectDialog(project models.Project, onSave func())
 			return
 		}
 
-		service.UpdateProject(updatedProject, newName, descEntry.Text, colorEntry.Text)
-
-		// Save
-		if err := p.storage.SaveProjects(p.projects); err != nil {
+		// Validate before changing anything
+		edited := *updatedProject
+		if err := errors.Join(billing.apply(&edited), budget.apply(&edited)); err != nil {
+			dialog.ShowError(err, parentWindow)
+			return
+		}
+		service.UpdateProject(&edited, newName, descEntry.Text, colorEntry.Text)
+		client.apply(&edited)
+		*updatedProject = edited
+
+		// Save, replacing only this project in the full list
+		err := p.storage.UpdateProjects(func(projects []models.Project) ([]models.Project, error) {
+			for i := range projects {
+				if projects[i].ID == edited.ID {
+					projects[i] = edited
+				}
+			}
+			return projects, nil
+		})
+		if err != nil {
 			dialog.ShowError(err, parentWindow)
 			return
 		}
@@ -373,6 +560,242 @@ func (p *Projects) showEditProjectDialog(project models.Project, onSave func())
 	dlg.Show()
 }
 
+// clientFields edits the client a project is billed to.
+type clientFields struct {
+	name, address, email, taxID *widget.Entry
+}
+
+func newClientFields(project models.Project) clientFields {
+	f := clientFields{
+		name:    widget.NewEntry(),
+		address: widget.NewMultiLineEntry(),
+		email:   widget.NewEntry(),
+		taxID:   widget.NewEntry(),
+	}
+	f.name.SetText(project.Client)
+	f.address.SetText(project.ClientAddress)
+	f.address.SetMinRowsVisible(2)
+	f.email.SetText(project.ClientEmail)
+	f.taxID.SetText(project.ClientTaxID)
+	return f
+}
+
+func (f clientFields) items() []*widget.FormItem {
+	return []*widget.FormItem{
+		widget.NewFormItem(lang.L("client"), f.name),
+		widget.NewFormItem(lang.L("client_address"), f.address),
+		widget.NewFormItem(lang.L("client_email"), f.email),
+		widget.NewFormItem(lang.L("client_tax_id"), f.taxID),
+	}
+}
+
+func (f clientFields) apply(project *models.Project) {
+	project.Client = strings.TrimSpace(f.name.Text)
+	project.ClientAddress = strings.TrimSpace(f.address.Text)
+	project.ClientEmail = strings.TrimSpace(f.email.Text)
+	project.ClientTaxID = strings.TrimSpace(f.taxID.Text)
+}
+
+// billingFields edits the rates and currency of a project. Empty fields
+// use the global settings.
+type billingFields struct {
+	rate, maxHours, extraRate, currency, tagRates *widget.Entry
+	billable                                      *widget.Check
+}
+
+func newBillingFields(project models.Project) billingFields {
+	f := billingFields{
+		rate:      widget.NewEntry(),
+		maxHours:  widget.NewEntry(),
+		extraRate: widget.NewEntry(),
+		currency:  widget.NewEntry(),
+		tagRates:  widget.NewEntry(),
+		billable:  widget.NewCheck(lang.L("billable"), nil),
+	}
+	global := billingConfig()
+	for _, field := range []struct {
+		entry          *widget.Entry
+		value, initial float64
+	}{
+		{f.rate, project.HourlyRate, global.HourlyRate},
+		{f.maxHours, project.MaxHours, global.MaxHours},
+		{f.extraRate, project.ExtraRate, global.ExtraRate},
+	} {
+		field.entry.SetPlaceHolder(strconv.FormatFloat(field.initial, 'f', -1, 64))
+		if field.value > 0 {
+			field.entry.SetText(strconv.FormatFloat(field.value, 'f', -1, 64))
+		}
+	}
+	f.currency.SetPlaceHolder(global.Currency)
+	f.currency.SetText(project.Currency)
+	f.tagRates.SetPlaceHolder("design=80, support=40")
+	f.tagRates.SetText(formatTagRates(project.TagRates))
+	f.billable.SetChecked(!project.NonBillable)
+	return f
+}
+
+func (f billingFields) items() []*widget.FormItem {
+	return []*widget.FormItem{
+		widget.NewFormItem(lang.L("hourly_rate"), f.rate),
+		widget.NewFormItem(lang.L("max_hours"), f.maxHours),
+		widget.NewFormItem(lang.L("extra_rate"), f.extraRate),
+		widget.NewFormItem(lang.L("currency"), f.currency),
+		widget.NewFormItem(lang.L("tag_rates"), f.tagRates),
+		widget.NewFormItem("", f.billable),
+	}
+}
+
+// apply sets the billing of project, or returns an error if a value is not
+// a valid number.
+func (f billingFields) apply(project *models.Project) error {
+	var values [3]float64
+	for i, entry := range []*widget.Entry{f.rate, f.maxHours, f.extraRate} {
+		text := strings.TrimSpace(entry.Text)
+		if text == "" {
+			continue
+		}
+		v, err := strconv.ParseFloat(text, 64)
+		if err != nil || v < 0 {
+			return errors.New(lang.L("invalid_number"))
+		}
+		values[i] = v
+	}
+	tagRates, err := parseTagRates(f.tagRates.Text)
+	if err != nil {
+		return err
+	}
+	project.HourlyRate, project.MaxHours, project.ExtraRate = values[0], values[1], values[2]
+	project.Currency = strings.TrimSpace(f.currency.Text)
+	project.TagRates = tagRates
+	project.NonBillable = !f.billable.Checked
+	return nil
+}
+
+// formatTagRates writes tag rates as "tag=rate" pairs sorted by tag.
+func formatTagRates(rates map[string]float64) string {
+	pairs := make([]string, 0, len(rates))
+	for _, tag := range slices.Sorted(maps.Keys(rates)) {
+		pairs = append(pairs, tag+"="+strconv.FormatFloat(rates[tag], 'f', -1, 64))
+	}
+	return strings.Join(pairs, ", ")
+}
+
+// parseTagRates reads the "tag=rate" pairs written by formatTagRates.
+func parseTagRates(text string) (map[string]float64, error) {
+	var rates map[string]float64
+	for _, pair := range strings.Split(text, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		tag, value, ok := strings.Cut(pair, "=")
+		tag = strings.TrimSpace(tag)
+		rate, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if !ok || tag == "" || err != nil || rate <= 0 {
+			return nil, fmt.Errorf(lang.L("invalid_tag_rate"), strings.TrimSpace(pair))
+		}
+		if rates == nil {
+			rates = make(map[string]float64)
+		}
+		rates[tag] = rate
+	}
+	return rates, nil
+}
+
+// budgetFields edits the budget of a project and the estimates of its
+// tasks.
+type budgetFields struct {
+	budget    *widget.Entry
+	unit      optionSelect
+	monthly   *widget.Check
+	estimates *widget.Entry
+}
+
+var budgetTypes = []option{
+	{"budget_hours", models.BudgetHours},
+	{"budget_money", models.BudgetMoney},
+}
+
+func newBudgetFields(project models.Project) budgetFields {
+	f := budgetFields{
+		budget:    widget.NewEntry(),
+		unit:      newOptionSelect(budgetTypes, project.BudgetType),
+		monthly:   widget.NewCheck(lang.L("budget_monthly"), nil),
+		estimates: widget.NewMultiLineEntry(),
+	}
+	if project.Budget > 0 {
+		f.budget.SetText(strconv.FormatFloat(project.Budget, 'f', -1, 64))
+	}
+	f.monthly.SetChecked(project.BudgetMonthly)
+	f.estimates.SetPlaceHolder(lang.L("task_estimates_hint"))
+	f.estimates.SetMinRowsVisible(3)
+	f.estimates.SetText(formatTaskEstimates(project.TaskEstimates))
+	return f
+}
+
+func (f budgetFields) items() []*widget.FormItem {
+	return []*widget.FormItem{
+		widget.NewFormItem(lang.L("budget"), container.NewBorder(nil, nil, nil, container.NewHBox(f.unit.Select, f.monthly), f.budget)),
+		widget.NewFormItem(lang.L("task_estimates"), f.estimates),
+	}
+}
+
+// apply sets the budget and estimates of project, or returns an error if a
+// value is not a valid number.
+func (f budgetFields) apply(project *models.Project) error {
+	var budget float64
+	if text := strings.TrimSpace(f.budget.Text); text != "" {
+		v, err := strconv.ParseFloat(text, 64)
+		if err != nil || v < 0 {
+			return errors.New(lang.L("invalid_number"))
+		}
+		budget = v
+	}
+	estimates, err := parseTaskEstimates(f.estimates.Text)
+	if err != nil {
+		return err
+	}
+	project.Budget = budget
+	project.BudgetType = f.unit.value()
+	project.BudgetMonthly = f.monthly.Checked
+	project.TaskEstimates = estimates
+	return nil
+}
+
+// formatTaskEstimates writes estimates as "task = hours" lines sorted by
+// task.
+func formatTaskEstimates(estimates map[string]float64) string {
+	lines := make([]string, 0, len(estimates))
+	for _, task := range slices.Sorted(maps.Keys(estimates)) {
+		lines = append(lines, task+" = "+strconv.FormatFloat(estimates[task], 'f', -1, 64))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// parseTaskEstimates reads the lines written by formatTaskEstimates. Task
+// descriptions may contain "=", so the hours follow the last one.
+func parseTaskEstimates(text string) (map[string]float64, error) {
+	var estimates map[string]float64
+	for _, line := range strings.Split(text, "\n") {
+		if line = strings.TrimSpace(line); line == "" {
+			continue
+		}
+		i := strings.LastIndex(line, "=")
+		var hours float64
+		err := errors.New("missing hours")
+		if i > 0 {
+			hours, err = strconv.ParseFloat(strings.TrimSpace(line[i+1:]), 64)
+		}
+		if err != nil || hours <= 0 || strings.TrimSpace(line[:max(i, 0)]) == "" {
+			return nil, fmt.Errorf(lang.L("invalid_task_estimate"), line)
+		}
+		if estimates == nil {
+			estimates = make(map[string]float64)
+		}
+		estimates[strings.TrimSpace(line[:i])] = hours
+	}
+	return estimates, nil
+}
+
 // filterProjects filters projects by name or description
 func (p *Projects) filterProjects(projects []models.Project, query string) []models.Project {
 	if query == "" {
@@ -433,15 +856,12 @@ func (p *Projects) ShowProjectDetails(projectID string) fyne.CanvasObject {
 		)
 	}
 
-	// Load entries
-	entries, err := p.storage.LoadEntriesForRange(time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC), time.Now())
+	// Load entries for this project
+	projectEntries, err := p.storage.QueryEntries(store.EntryQuery{ProjectID: projectID})
 	if err != nil {
-		entries = []models.TimeEntry{}
+		projectEntries = []models.TimeEntry{}
 	}
 
-	// Filter entries for this project
-	projectEntries := service.FilterByProject(entries, projectID)
-
 	// Calculate stats
 	stats := p.calculateProjectStats(projectID)
 
@@ -463,6 +883,7 @@ func (p *Projects) ShowProjectDetails(projectID string) fyne.CanvasObject {
 	statsBox := container.NewVBox(
 		widget.NewLabel(fmt.Sprintf("Total Entries: %d", stats.EntryCount)),
 		widget.NewLabel(fmt.Sprintf("Total Time: %s", utils.FormatDuration(stats.TotalTime))),
+		widget.NewLabel(fmt.Sprintf("%s: %s", lang.L("amount"), service.FormatMoney(stats.Amount, stats.Currency))),
 	)
 
 	// Task list for this project
@@ -0,0 +1,85 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/highercomve/tasktracker/internal/service"
+	"github.com/highercomve/tasktracker/internal/store"
+	"github.com/highercomve/tasktracker/internal/utils"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/lang"
+	"github.com/spf13/viper"
+)
+
+// reminderInterval is how often the reminder rules are checked.
+const reminderInterval = 30 * time.Second
+
+// StartReminders checks the reminder rules set in the Config tab in the
+// background and shows the reminders due as desktop notifications. Stop the
+// returned scheduler on exit.
+func StartReminders(s store.Store, timer *service.Timer) *service.ReminderScheduler {
+	scheduler := service.NewReminderScheduler(s, timer, nil, reminderRules, showReminder)
+	scheduler.Start(reminderInterval)
+	return scheduler
+}
+
+// reminderRules returns the rules turned on under reminders in the config.
+func reminderRules() service.ReminderRules {
+	rules := service.ReminderRules{
+		WorkStart:     timeOfDay("reminders.work_start", 9*time.Hour),
+		WorkEnd:       timeOfDay("reminders.work_end", 17*time.Hour),
+		Calendar:      workCalendar(),
+		DaySummary:    viper.GetBool("reminders.day_summary"),
+		DaySummaryAt:  timeOfDay("reminders.day_summary_at", 18*time.Hour),
+		ForgottenTask: viper.GetBool("reminders.forgotten_task"),
+	}
+	if viper.GetBool("reminders.no_timer") {
+		rules.NoTimer = time.Duration(viper.GetInt("reminders.no_timer_minutes")) * time.Minute
+	}
+	if viper.GetBool("reminders.long_running") {
+		rules.LongRunning = time.Duration(viper.GetFloat64("reminders.long_running_hours") * float64(time.Hour))
+	}
+	return rules
+}
+
+// timeOfDay reads an HH:MM setting as an offset from midnight, or returns
+// def when it is unset or invalid.
+func timeOfDay(key string, def time.Duration) time.Duration {
+	v := strings.TrimSpace(viper.GetString(key))
+	if v == "" {
+		return def
+	}
+	t, err := time.Parse("15:04", v)
+	if err != nil {
+		fmt.Printf("warning: ignoring %s %q: %v\n", key, v, err)
+		return def
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute
+}
+
+// showReminder sends the desktop notification of a reminder.
+func showReminder(r service.Reminder) {
+	var title, msg string
+	switch r.Kind {
+	case service.ReminderNoTimer:
+		title = lang.L("reminder_no_timer_title")
+		msg = fmt.Sprintf(lang.L("reminder_no_timer_msg"), r.Since.Format("15:04"))
+	case service.ReminderLongRunning:
+		title = lang.L("reminder_long_running_title")
+		msg = fmt.Sprintf(lang.L("reminder_long_running_msg"), r.Entry.Description, utils.FormatDuration(r.Entry.TrackedDuration(time.Now())))
+	case service.ReminderDaySummary:
+		title = lang.L("reminder_day_summary_title")
+		msg = fmt.Sprintf(lang.L("reminder_day_summary_msg"), utils.FormatDuration(r.Total), r.Entries)
+	case service.ReminderForgottenTask:
+		title = lang.L("reminder_forgotten_task_title")
+		msg = fmt.Sprintf(lang.L("reminder_forgotten_task_msg"), r.Entry.Description, r.Entry.StartTime.Format("2006-01-02 15:04"))
+	default:
+		return
+	}
+	if app := fyne.CurrentApp(); app != nil {
+		app.SendNotification(fyne.NewNotification(title, msg))
+	}
+}
@@ -6,14 +6,12 @@ import (
 	"os/exec"
 	"runtime"
 	"sort"
-	"strings"
 	"time"
 
 	"github.com/highercomve/tasktracker/internal/models"
 	"github.com/highercomve/tasktracker/internal/service"
 	"github.com/highercomve/tasktracker/internal/store"
 	"github.com/highercomve/tasktracker/internal/utils"
-	"github.com/spf13/viper"
 	"github.com/sqweek/dialog"
 
 	"fyne.io/fyne/v2"
@@ -40,14 +38,16 @@ func openFile(path string) error {
 }
 
 type Reports struct {
-	storage      *store.Storage
+	storage      store.Store
+	undo         *service.UndoStack
 	filterStates map[string]*FilterStateManager
 	projects     []models.Project
 }
 
-func NewReports(s *store.Storage) *Reports {
+func NewReports(s store.Store, undo *service.UndoStack) *Reports {
 	return &Reports{
 		storage:      s,
+		undo:         undo,
 		filterStates: make(map[string]*FilterStateManager),
 	}
 }
@@ -282,31 +282,41 @@ func (r *Reports) MakeUI() fyne.CanvasObject {
 	monthlyContent := container.NewStack()
 	customContent := container.NewStack()
 
+	// Entries shown in each report, after filtering, for the CSV export
+	shown := make(map[*fyne.Container][]models.TimeEntry)
+
 	// Helper to refresh content
 	refreshReport := func(content *fyne.Container, start, end time.Time, groupBy string, selectedCategory string, selectedProject string, searchQuery string, refreshFunc func()) {
-		entries, _ := r.storage.LoadEntriesForRange(start, end)
-		// Filter by search query
-		if searchQuery != "" {
-			entries = service.FilterTasks(entries, searchQuery)
-		}
-		// Filter by category if selected
-		if selectedCategory != "" && selectedCategory != lang.L("all_categories") {
-			entries = service.FilterByCategory(entries, selectedCategory)
-		}
+		query := store.EntryQuery{Start: start, End: end}
 		// Filter by project if selected
 		if selectedProject != "" && selectedProject != lang.L("all_projects") {
 			if selectedProject == lang.L("no_project") {
-				entries = service.FilterByProject(entries, "unassigned")
+				query.ProjectID = "unassigned"
 			} else {
 				// Find project ID by name
 				for _, p := range r.projects {
 					if p.Name == selectedProject {
-						entries = service.FilterByProject(entries, p.ID)
+						query.ProjectID = p.ID
 						break
 					}
 				}
 			}
 		}
+		// Filter by category if selected. The store narrows by tag; the
+		// category is the primary tag, which FilterByCategory checks.
+		filterCategory := selectedCategory != "" && selectedCategory != lang.L("all_categories")
+		if filterCategory && selectedCategory != "Untagged" {
+			query.Tag = selectedCategory
+		}
+		entries, _ := r.storage.QueryEntries(query)
+		// Filter by search query
+		if searchQuery != "" {
+			entries = service.FilterTasks(entries, searchQuery)
+		}
+		if filterCategory {
+			entries = service.FilterByCategory(entries, selectedCategory)
+		}
+		shown[content] = entries
 		reportUI := r.renderHistory(entries, groupBy, start, end, refreshFunc)
 		content.Objects = []fyne.CanvasObject{reportUI}
 		content.Refresh()
@@ -321,8 +331,12 @@ func (r *Reports) MakeUI() fyne.CanvasObject {
 		return options
 	}
 
-	createExportButton := func(getRange func() (time.Time, time.Time), getGroupBy func() string) *widget.Button {
-		return widget.NewButtonWithIcon(lang.L("export_pdf"), theme.DocumentSaveIcon(), func() {
+	createExportButtons := func(content *fyne.Container, getRange func() (time.Time, time.Time), getGroupBy func() string) fyne.CanvasObject {
+		csvBtn := widget.NewButtonWithIcon(lang.L("export_csv"), theme.DocumentSaveIcon(), func() {
+			start, end := getRange()
+			showCSVExportDialog(safeGetMainWindow(), shown[content], r.projects, start, end, getGroupBy())
+		})
+		pdfBtn := widget.NewButtonWithIcon(lang.L("export_pdf"), theme.DocumentSaveIcon(), func() {
 			start, end := getRange()
 			groupBy := getGroupBy()
 
@@ -347,7 +361,13 @@ func (r *Reports) MakeUI() fyne.CanvasObject {
 				return
 			}
 
-			if err := GeneratePDF(path, entries, start, end, groupBy); err != nil {
+			projects, err := r.storage.LoadProjects()
+			if err != nil {
+				fyneDialog.ShowError(err, safeGetMainWindow())
+				return
+			}
+
+			if err := GeneratePDF(path, entries, projects, start, end, groupBy); err != nil {
 				fyneDialog.ShowError(err, safeGetMainWindow())
 			} else {
 				fyneDialog.ShowConfirm(lang.L("success"), lang.L("pdf_saved")+"\n"+lang.L("open_file_question"), func(open bool) {
@@ -359,6 +379,11 @@ func (r *Reports) MakeUI() fyne.CanvasObject {
 				}, safeGetMainWindow())
 			}
 		})
+		icsBtn := widget.NewButtonWithIcon(lang.L("export_ics"), theme.DocumentSaveIcon(), func() {
+			start, end := getRange()
+			exportICS(safeGetMainWindow(), r.storage, start, end)
+		})
+		return container.NewHBox(csvBtn, icsBtn, pdfBtn)
 	}
 
 	// Helper to create GroupBy selector
@@ -433,6 +458,9 @@ func (r *Reports) MakeUI() fyne.CanvasObject {
 	// Filter badges container for daily tab
 	dailyBadgeContainer := container.NewHBox()
 
+	// Calendar events of the day not tracked yet
+	dailySuggestions := container.NewStack()
+
 	var updateDaily func()
 	updateDaily = func() {
 		dailyLabel.SetText(lang.L("report_for") + selectedDay.Format("Mon, 02 Jan 2006"))
@@ -474,6 +502,8 @@ func (r *Reports) MakeUI() fyne.CanvasObject {
 			dailyProjectSelector.SetSelected(dailySelectedProject)
 		}
 		refreshReport(dailyContent, selectedDay, selectedDay, service.GroupByNone, dailySelectedCategory, dailySelectedProject, dailySearchEntry.Text, updateDaily)
+		dailySuggestions.Objects = []fyne.CanvasObject{r.makeSuggestions(selectedDay, updateDaily)}
+		dailySuggestions.Refresh()
 		r.updateFilterBadgesWithProject(dailyBadgeContainer, dailySearchEntry.Text, dailySelectedCategory, dailySelectedProject, lang.L("all_categories"), lang.L("all_projects"),
 			func() { dailySearchEntry.SetText(""); dailyFilterState.SetSearchQuery(""); updateDaily() },
 			func() {
@@ -525,7 +555,10 @@ func (r *Reports) MakeUI() fyne.CanvasObject {
 		}),
 		dailyLabel,
 		layout.NewSpacer(),
-		createExportButton(func() (time.Time, time.Time) {
+		widget.NewButtonWithIcon(lang.L("calendar"), theme.CalendarIcon(), func() {
+			chooseCalendar(safeGetMainWindow(), updateDaily)
+		}),
+		createExportButtons(dailyContent, func() (time.Time, time.Time) {
 			return selectedDay, selectedDay
 		}, func() string {
 			return service.GroupByNone
@@ -561,7 +594,7 @@ func (r *Reports) MakeUI() fyne.CanvasObject {
 
 	dailyTab := container.NewBorder(
 		dailyToolbarContainer,
-		nil, nil, nil,
+		dailySuggestions, nil, nil,
 		dailyContent,
 	)
 
@@ -588,6 +621,7 @@ func (r *Reports) MakeUI() fyne.CanvasObject {
 	}
 	var selectedWeekStart = getWeekStart(time.Now())
 	weeklyLabel := widget.NewLabel("")
+	weeklyGoals := newWeeklyGoals(r.storage)
 	weeklyGroupBy := service.GroupByNone
 	var weeklySelectedCategory = lang.L("all_categories")
 	var weeklySelectedProject = lang.L("all_projects")
@@ -661,6 +695,7 @@ func (r *Reports) MakeUI() fyne.CanvasObject {
 			weeklyProjectSelector.SetSelected(weeklySelectedProject)
 		}
 		refreshReport(weeklyContent, selectedWeekStart, end, weeklyGroupBy, weeklySelectedCategory, weeklySelectedProject, weeklySearchEntry.Text, updateWeekly)
+		weeklyGoals.update(selectedWeekStart)
 		r.updateFilterBadgesWithProject(weeklyBadgeContainer, weeklySearchEntry.Text, weeklySelectedCategory, weeklySelectedProject, lang.L("all_categories"), lang.L("all_projects"),
 			func() { weeklySearchEntry.SetText(""); weeklyFilterState.SetSearchQuery(""); updateWeekly() },
 			func() {
@@ -736,7 +771,7 @@ func (r *Reports) MakeUI() fyne.CanvasObject {
 		}),
 		weeklyLabel,
 		layout.NewSpacer(),
-		createExportButton(func() (time.Time, time.Time) {
+		createExportButtons(weeklyContent, func() (time.Time, time.Time) {
 			return selectedWeekStart, selectedWeekStart.AddDate(0, 0, 6)
 		}, func() string {
 			return weeklyGroupBy
@@ -775,7 +810,7 @@ func (r *Reports) MakeUI() fyne.CanvasObject {
 	}
 
 	weeklyTab := container.NewBorder(
-		weeklyToolbarContainer,
+		container.NewVBox(weeklyToolbarContainer, weeklyGoals),
 		nil, nil, nil,
 		weeklyContent,
 	)
@@ -946,7 +981,7 @@ func (r *Reports) MakeUI() fyne.CanvasObject {
 		}),
 		monthlyLabel,
 		layout.NewSpacer(),
-		createExportButton(func() (time.Time, time.Time) {
+		createExportButtons(monthlyContent, func() (time.Time, time.Time) {
 			return selectedMonth, selectedMonth.AddDate(0, 1, -1)
 		}, func() string {
 			return monthlyGroupBy
@@ -1201,7 +1236,7 @@ func (r *Reports) MakeUI() fyne.CanvasObject {
 		widget.NewLabel(lang.L("to")), endBtn,
 		lastWeekBtn, lastMonthBtn, last3MonthsBtn, allTimeBtn,
 		layout.NewSpacer(),
-		createExportButton(func() (time.Time, time.Time) {
+		createExportButtons(customContent, func() (time.Time, time.Time) {
 			return startDate, endDate
 		}, func() string {
 			return customGroupBy
@@ -1282,9 +1317,53 @@ func (r *Reports) MakeUI() fyne.CanvasObject {
 	// Select initial tab to trigger data load
 	tabs.SelectIndex(0)
 
+	reload := func(projectsChanged bool) {
+		if projectsChanged {
+			if projects, err := r.storage.LoadProjects(); err == nil {
+				r.projects = projects
+			}
+			for _, sel := range []*widget.Select{dailyProjectSelector, weeklyProjectSelector, monthlyProjectSelector, customProjectSelector} {
+				sel.SetOptions(buildProjectOptions())
+			}
+		}
+		if item := tabs.Selected(); item != nil {
+			tabs.OnSelected(item)
+		}
+	}
+
+	// Reload the visible tab when the data changes outside this process.
+	r.storage.Subscribe(func(c store.Change) {
+		fyne.Do(func() { reload(c.Kind == store.ProjectsChanged) })
+	})
+	// Or after any undoable action, which may come from another tab.
+	r.undo.Subscribe(func(service.UndoEvent) {
+		fyne.Do(func() { reload(true) })
+	})
+
 	return tabs
 }
 
+// overtimeBreakdown describes the overtime of each period and project, such
+// as "2024-W19 Website: 2:00:00 = 100.00 EUR". Overtime pro-rated over the
+// whole range has no periods to break it down by.
+func overtimeBreakdown(costs []service.ProjectBilling, projects []models.Project) []string {
+	var lines []string
+	for _, c := range costs {
+		name := lang.L("unassigned")
+		if p := service.FindProjectByID(projects, c.ProjectID); p != nil {
+			name = p.Name
+		}
+		for _, b := range c.Buckets {
+			if b.Label == "" || b.ExtraHours <= 0 {
+				continue
+			}
+			extra := time.Duration(b.ExtraHours * float64(time.Hour)).Round(time.Second)
+			lines = append(lines, fmt.Sprintf("%s %s: %s = %s", b.Label, name, utils.FormatDuration(extra), service.FormatMoney(b.ExtraCost, c.Currency)))
+		}
+	}
+	return lines
+}
+
 type ListItem struct {
 	IsHeader bool
 	IsFooter bool
@@ -1293,6 +1372,13 @@ type ListItem struct {
 }
 
 func (r *Reports) renderHistory(entries []models.TimeEntry, groupBy string, start, end time.Time, onRefresh func()) fyne.CanvasObject {
+	// Attribute time to the days it was worked on. The list shows the
+	// pieces, but edits and deletes apply to the whole entry.
+	originals := make(map[string]models.TimeEntry, len(entries))
+	for _, e := range entries {
+		originals[e.ID] = e
+	}
+	entries = service.SplitByDay(entries, start, end, time.Now())
 	if len(entries) == 0 {
 		return widget.NewLabel(lang.L("no_entries"))
 	}
@@ -1302,42 +1388,41 @@ func (r *Reports) renderHistory(entries []models.TimeEntry, groupBy string, star
 	categoryTotals := service.GetCategoryTotals(entries)
 	var total time.Duration
 	for _, e := range entries {
-		dur := time.Duration(e.Duration) * time.Second
-		if e.EndTime.IsZero() {
-			dur = time.Since(e.StartTime)
-		}
+		dur := e.TrackedDuration(time.Now())
 		sums[e.Description] += dur
 		total += dur
 	}
 
 	summaryText := fmt.Sprintf(lang.L("total_time")+"%s\n", utils.FormatDuration(total))
 
-	// Billing calculation
-	hourlyRate := viper.GetFloat64("hourly_rate")
-	if hourlyRate > 0 {
-		billingConfig := service.BillingConfig{
-			HourlyRate: hourlyRate,
-			MaxHours:   viper.GetFloat64("max_hours"),
-			ExtraRate:  viper.GetFloat64("extra_rate"),
-		}
-
-		// Calculate period days
-		periodDays := 0
-		if !start.IsZero() && !end.IsZero() {
-			periodDays = int(end.Sub(start).Hours()/24) + 1
+	// Time that is billed, without internal work and once rounded
+	projects, _ := r.storage.LoadProjects()
+	config := billingConfig()
+	summaryText += fmt.Sprintf(lang.L("billable_time")+"%s\n", utils.FormatDuration(service.BillableTime(entries, projects, config.Rounding, time.Now())))
+
+	// Billing calculation: each project is billed with its own rates and
+	// currency, so costs are summed per currency.
+	costs := service.CalculateProjectBilling(entries, projects, config, start, end, time.Now())
+	for _, t := range service.CurrencyTotals(costs) {
+		summaryText += fmt.Sprintf("%s%s\n", lang.L("total_cost"), service.FormatMoney(t.TotalCost, t.Currency))
+		if t.ExtraCost > 0 {
+			summaryText += fmt.Sprintf("  - %s%s\n", lang.L("standard_cost"), service.FormatMoney(t.StandardCost, t.Currency))
+			summaryText += fmt.Sprintf("  - %s%s\n", lang.L("extra_cost"), service.FormatMoney(t.ExtraCost, t.Currency))
 		}
-
-		billing := service.CalculateBilling(total, billingConfig, periodDays)
-		summaryText += fmt.Sprintf("%s%.2f\n", lang.L("total_cost"), billing.TotalCost)
-		if billing.ExtraCost > 0 {
-			summaryText += fmt.Sprintf("  - %s%.2f\n", lang.L("standard_cost"), billing.StandardCost)
-			summaryText += fmt.Sprintf("  - %s%.2f\n", lang.L("extra_cost"), billing.ExtraCost)
+	}
+	for _, l := range overtimeBreakdown(costs, projects) {
+		summaryText += fmt.Sprintf("    · %s\n", l)
+	}
+	projectCosts := make(map[string]service.ProjectBilling, len(costs))
+	for _, c := range costs {
+		if c.ProjectID == "" {
+			c.ProjectID = "unassigned"
 		}
+		projectCosts[c.ProjectID] = c
 	}
 
 	// Add project totals if grouping by project
 	if groupBy == service.GroupByProject {
-		projects, _ := r.storage.LoadProjects()
 		projectTotals := service.GetProjectTotals(entries)
 		if len(projectTotals) > 0 {
 			summaryText += fmt.Sprintf("\n%s:\n", lang.L("by_project"))
@@ -1384,7 +1469,11 @@ func (r *Reports) renderHistory(entries []models.TimeEntry, groupBy string, star
 				} else {
 					projName = lang.L("unassigned")
 				}
-				summaryText += fmt.Sprintf("  - %s: %s\n", projName, utils.FormatDuration(projectTotals[projID]))
+				summaryText += fmt.Sprintf("  - %s: %s", projName, utils.FormatDuration(projectTotals[projID]))
+				if c, ok := projectCosts[projID]; ok {
+					summaryText += fmt.Sprintf(" (%s)", service.FormatMoney(c.TotalCost, c.Currency))
+				}
+				summaryText += "\n"
 			}
 		}
 	} else if len(categoryTotals) > 1 {
@@ -1400,9 +1489,23 @@ func (r *Reports) renderHistory(entries []models.TimeEntry, groupBy string, star
 		}
 	}
 
+	// Focus sessions are counted once per entry, not per piece
+	focus := make(map[string]int)
+	counted := make(map[string]bool)
+	for _, e := range entries {
+		if !counted[e.ID] {
+			counted[e.ID] = true
+			focus[e.Description] += e.FocusSessions
+		}
+	}
+
 	summaryText += "\n"
 	for desc, dur := range sums {
-		summaryText += fmt.Sprintf("- %s: %s\n", desc, utils.FormatDuration(dur))
+		summaryText += fmt.Sprintf("- %s: %s", desc, utils.FormatDuration(dur))
+		if focus[desc] > 0 {
+			summaryText += " · " + fmt.Sprintf(lang.L("focus_sessions_count"), focus[desc])
+		}
+		summaryText += "\n"
 	}
 	summaryLabel := widget.NewLabel(summaryText)
 
@@ -1464,10 +1567,7 @@ func (r *Reports) renderHistory(entries []models.TimeEntry, groupBy string, star
 			// Calculate group total
 			var groupTotal time.Duration
 			for _, e := range groupEntries {
-				dur := time.Duration(e.Duration) * time.Second
-				if e.EndTime.IsZero() {
-					dur = time.Since(e.StartTime)
-				}
+				dur := e.TrackedDuration(time.Now())
 				groupTotal += dur
 			}
 
@@ -1517,10 +1617,7 @@ func (r *Reports) renderHistory(entries []models.TimeEntry, groupBy string, star
 			// Calculate group total
 			var groupTotal time.Duration
 			for _, e := range groupEntries {
-				dur := time.Duration(e.Duration) * time.Second
-				if e.EndTime.IsZero() {
-					dur = time.Since(e.StartTime)
-				}
+				dur := e.TrackedDuration(time.Now())
 				groupTotal += dur
 			}
 
@@ -1587,6 +1684,7 @@ func (r *Reports) renderHistory(entries []models.TimeEntry, groupBy string, star
 				taskBox.Show()
 
 				entry := item.Entry
+				original := originals[entry.ID]
 
 				// Extract sub-widgets from taskBox
 				rightBox := taskBox.Objects[1].(*fyne.Container)
@@ -1600,7 +1698,11 @@ func (r *Reports) renderHistory(entries []models.TimeEntry, groupBy string, star
 				projectLabel := infoBox.Objects[2].(*widget.Label)
 
 				titleLabel.SetText(entry.Description)
-				dateLabel.SetText(entry.StartTime.Format("Mon, 02 Jan 15:04"))
+				if len(entry.Segments) > 0 {
+					dateLabel.SetText(entry.StartTime.Format("Mon, 02 Jan") + " " + formatSegments(entry))
+				} else {
+					dateLabel.SetText(entry.StartTime.Format("Mon, 02 Jan 15:04"))
+				}
 
 				// Display project name if assigned
 				if entry.ProjectID != "" {
@@ -1623,9 +1725,8 @@ func (r *Reports) renderHistory(entries []models.TimeEntry, groupBy string, star
 					projectLabel.Hide()
 				}
 
-				dur := time.Duration(entry.Duration) * time.Second
-				if entry.EndTime.IsZero() {
-					dur = time.Since(entry.StartTime)
+				dur := entry.TrackedDuration(time.Now())
+				if original.EndTime.IsZero() {
 					durLabel.TextStyle = fyne.TextStyle{Italic: true}
 					editBtn.Disable()
 				} else {
@@ -1635,7 +1736,7 @@ func (r *Reports) renderHistory(entries []models.TimeEntry, groupBy string, star
 				durLabel.SetText(utils.FormatDuration(dur))
 
 				editBtn.OnTapped = func() {
-					r.showEditDialog(entry, onRefresh)
+					showEditEntryDialog(r.storage, r.undo, r.projects, original, onRefresh)
 				}
 				delBtn.OnTapped = func() {
 					parentWindow := safeGetMainWindow()
@@ -1646,7 +1747,11 @@ func (r *Reports) renderHistory(entries []models.TimeEntry, groupBy string, star
 						if !confirmed {
 							return
 						}
-						r.storage.DeleteEntry(entry)
+						if err := r.storage.DeleteEntry(original); err != nil {
+							fyneDialog.ShowError(fmt.Errorf("%s: %w", lang.L("save_error"), err), parentWindow)
+							return
+						}
+						r.undo.Push(deleteEntryAction(r.storage, original))
 						onRefresh()
 					}, parentWindow)
 				}
@@ -1660,113 +1765,3 @@ func (r *Reports) renderHistory(entries []models.TimeEntry, groupBy string, star
 		listView,
 	)
 }
-
-func (r *Reports) showEditDialog(entry models.TimeEntry, onSuccess func()) {
-	descEntry := widget.NewEntry()
-	descEntry.SetText(entry.Description)
-
-	tagsEntry := widget.NewEntry()
-	tagsEntry.SetPlaceHolder(lang.L("category_hint"))
-	if len(entry.Tags) > 0 {
-		tagsEntry.SetText(fmt.Sprintf("%s", entry.Tags[0]))
-		if len(entry.Tags) > 1 {
-			for i := 1; i < len(entry.Tags); i++ {
-				tagsEntry.SetText(fmt.Sprintf("%s, %s", tagsEntry.Text, entry.Tags[i]))
-			}
-		}
-	}
-
-	// Project selection dropdown
-	projectOptions := []string{lang.L("none")}
-	selectedProjectIndex := 0
-	for i, p := range r.projects {
-		projectOptions = append(projectOptions, p.Name)
-		if p.ID == entry.ProjectID {
-			selectedProjectIndex = i + 1 // +1 because "None" is at index 0
-		}
-	}
-	projectSelect := widget.NewSelect(projectOptions, nil)
-	if selectedProjectIndex < len(projectOptions) {
-		projectSelect.SetSelectedIndex(selectedProjectIndex)
-	}
-
-	startEntry := widget.NewEntry()
-	startEntry.SetText(entry.StartTime.Format("2006-01-02 15:04:05"))
-
-	endEntry := widget.NewEntry()
-	if !entry.EndTime.IsZero() {
-		endEntry.SetText(entry.EndTime.Format("2006-01-02 15:04:05"))
-	}
-
-	items := []*widget.FormItem{
-		widget.NewFormItem(lang.L("task_description"), descEntry),
-		widget.NewFormItem(lang.L("project"), projectSelect),
-		widget.NewFormItem(lang.L("add_category"), tagsEntry),
-		widget.NewFormItem(lang.L("start_time"), startEntry),
-		widget.NewFormItem(lang.L("end_time"), endEntry),
-	}
-
-	parentWindow := safeGetMainWindow()
-	if parentWindow == nil {
-		return
-	}
-	dlg := fyneDialog.NewForm(lang.L("edit_task"), lang.L("save"), lang.L("cancel"), items, func(b bool) {
-		if !b {
-			return
-		}
-
-		newDesc := descEntry.Text
-		newStart, err1 := time.Parse("2006-01-02 15:04:05", startEntry.Text)
-		newEnd, err2 := time.Parse("2006-01-02 15:04:05", endEntry.Text)
-
-		if err1 != nil || (endEntry.Text != "" && err2 != nil) {
-			// Show error? For now just return
-			fmt.Println(lang.L("error_parsing_time"))
-			return
-		}
-
-		// Parse tags from comma-separated input
-		var newTags []string
-		if tagsEntry.Text != "" {
-			for _, tag := range strings.Split(tagsEntry.Text, ",") {
-				trimmed := strings.TrimSpace(tag)
-				if trimmed != "" {
-					newTags = append(newTags, trimmed)
-				}
-			}
-		}
-
-		// Get selected project ID
-		newProjectID := ""
-		if projectSelect.Selected != "" && projectSelect.Selected != lang.L("none") {
-			for _, p := range r.projects {
-				if p.Name == projectSelect.Selected {
-					newProjectID = p.ID
-					break
-				}
-			}
-		}
-
-		// Update entry
-		oldEntry := entry
-		entry.Description = newDesc
-		entry.Tags = newTags
-		entry.ProjectID = newProjectID
-		entry.StartTime = newStart
-		if endEntry.Text != "" {
-			entry.EndTime = newEnd
-			entry.Duration = int64(newEnd.Sub(newStart).Seconds())
-			entry.State = models.TaskStateStopped
-		}
-
-		// If start date changed, we need to delete old and save new
-		if oldEntry.StartTime.Format("2006-01-02") != entry.StartTime.Format("2006-01-02") {
-			r.storage.DeleteEntry(oldEntry)
-		}
-
-		r.storage.SaveEntry(entry)
-		onSuccess()
-	}, parentWindow)
-	dlg.Resize(fyne.NewSize(parentWindow.Canvas().Size().Width, dlg.MinSize().Height))
-	dlg.Show()
-}
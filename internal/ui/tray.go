@@ -9,6 +9,9 @@ import (
 
 func SetupTray(a fyne.App, w fyne.Window, icon fyne.Resource, d *Dashboard) {
 	if desk, ok := a.(desktop.App); ok {
+		// Focus countdown, shown while a focus session runs
+		focusItem := fyne.NewMenuItem("", nil)
+		focusItem.Disabled = true
 		m := fyne.NewMenu(lang.L("app_title"),
 			fyne.NewMenuItem(lang.L("show"), func() {
 				w.Show()
@@ -16,6 +19,9 @@ func SetupTray(a fyne.App, w fyne.Window, icon fyne.Resource, d *Dashboard) {
 			fyne.NewMenuItem(lang.L("pause_resume"), func() {
 				d.TogglePause()
 			}),
+			fyne.NewMenuItem(lang.L("focus_start_end"), func() {
+				d.ToggleFocus()
+			}),
 			fyne.NewMenuItem(lang.L("stop"), func() {
 				d.StopTask()
 			}),
@@ -27,6 +33,18 @@ func SetupTray(a fyne.App, w fyne.Window, icon fyne.Resource, d *Dashboard) {
 		)
 		desk.SetSystemTrayMenu(m)
 		desk.SetSystemTrayIcon(icon)
+		d.trayFocus = func(status string) {
+			if status == focusItem.Label {
+				return
+			}
+			if focusItem.Label == "" {
+				m.Items = append([]*fyne.MenuItem{focusItem}, m.Items...)
+			} else if status == "" {
+				m.Items = m.Items[1:]
+			}
+			focusItem.Label = status
+			m.Refresh()
+		}
 	}
 
 	w.SetCloseIntercept(func() {
@@ -0,0 +1,129 @@
+package ui
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/highercomve/tasktracker/internal/models"
+	"github.com/highercomve/tasktracker/internal/service"
+	"github.com/highercomve/tasktracker/internal/store"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/driver/desktop"
+	"fyne.io/fyne/v2/lang"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+// snackbarTimeout is how long the undo snackbar stays on screen.
+const snackbarTimeout = 6 * time.Second
+
+// snackbar is the bar shown at the bottom of the window after an undoable
+// action, offering to undo (or redo) it. It is laid over the window content
+// rather than shown as a pop-up, so it never swallows clicks elsewhere.
+type snackbar struct {
+	window fyne.Window
+	stack  *service.UndoStack
+	bar    *fyne.Container
+	label  *widget.Label
+	button *widget.Button
+	action func()
+
+	mu     sync.Mutex
+	expire *time.Timer
+}
+
+// SetupUndo binds Ctrl+Z and Ctrl+Shift+Z to the undo stack and shows a
+// snackbar whenever an action is recorded, undone or redone. Call it after
+// the window content has been set.
+func SetupUndo(w fyne.Window, stack *service.UndoStack) {
+	b := &snackbar{window: w, stack: stack, label: widget.NewLabel("")}
+	b.button = widget.NewButton("", func() {
+		b.bar.Hide()
+		if b.action != nil {
+			b.action()
+		}
+	})
+	b.button.Importance = widget.HighImportance
+	b.bar = container.NewStack(
+		canvas.NewRectangle(theme.Color(theme.ColorNameOverlayBackground)),
+		container.NewPadded(container.NewHBox(b.label, b.button)),
+	)
+	b.bar.Hide()
+	w.SetContent(container.NewStack(
+		w.Content(),
+		container.NewVBox(layout.NewSpacer(), container.NewCenter(b.bar), widget.NewLabel("")),
+	))
+
+	w.Canvas().AddShortcut(&desktop.CustomShortcut{KeyName: fyne.KeyZ, Modifier: fyne.KeyModifierControl}, func(fyne.Shortcut) {
+		b.undo()
+	})
+	w.Canvas().AddShortcut(&desktop.CustomShortcut{KeyName: fyne.KeyZ, Modifier: fyne.KeyModifierControl | fyne.KeyModifierShift}, func(fyne.Shortcut) {
+		b.redo()
+	})
+
+	stack.Subscribe(func(ev service.UndoEvent) {
+		fyne.Do(func() {
+			switch ev.Type {
+			case service.ActionUndone:
+				b.show(fmt.Sprintf("%s: %s", lang.L("undone"), ev.Action.Label), lang.L("redo"), b.redo)
+			default:
+				b.show(ev.Action.Label, lang.L("undo"), b.undo)
+			}
+		})
+	})
+}
+
+func (b *snackbar) undo() {
+	if _, err := b.stack.Undo(); err != nil && err != service.ErrNothingToUndo {
+		dialog.ShowError(fmt.Errorf("%s: %w", lang.L("undo_failed"), err), b.window)
+	}
+}
+
+func (b *snackbar) redo() {
+	if _, err := b.stack.Redo(); err != nil && err != service.ErrNothingToRedo {
+		dialog.ShowError(fmt.Errorf("%s: %w", lang.L("redo_failed"), err), b.window)
+	}
+}
+
+// show sets the snackbar to read text with a button running action. It hides
+// itself after snackbarTimeout. Runs on the UI goroutine.
+func (b *snackbar) show(text, actionLabel string, action func()) {
+	b.label.SetText(text)
+	b.button.SetText(actionLabel)
+	b.action = action
+	b.bar.Show()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.expire != nil {
+		b.expire.Stop()
+	}
+	b.expire = time.AfterFunc(snackbarTimeout, func() {
+		fyne.Do(b.bar.Hide)
+	})
+}
+
+// deleteEntryAction records the deletion of entry, which has already been
+// removed from s.
+func deleteEntryAction(s store.Store, entry models.TimeEntry) service.UndoAction {
+	return service.UndoAction{
+		Label: lang.L("task_deleted"),
+		Undo:  func() error { return s.SaveEntry(entry) },
+		Redo:  func() error { return s.DeleteEntry(entry) },
+	}
+}
+
+// editEntryAction records that old was replaced with updated in s.
+func editEntryAction(s store.Store, old, updated models.TimeEntry) service.UndoAction {
+	return service.UndoAction{
+		Label: lang.L("task_edited"),
+		Undo:  func() error { return replaceEntry(s, updated, old) },
+		Redo:  func() error { return replaceEntry(s, old, updated) },
+	}
+}
@@ -15,7 +15,7 @@ import (
 //go:embed CHANGELOG.md
 var changelogData string
 
-func CheckVersion(w fyne.Window, s *store.Storage) {
+func CheckVersion(w fyne.Window, s store.Store) {
 	appState, _ := s.LoadAppState()
 	// appState is zero-valued if error (LastRunVersion == ""), which triggers the update below.
 
@@ -29,7 +29,7 @@ func CheckVersion(w fyne.Window, s *store.Storage) {
 	}
 }
 
-func updateVersion(s *store.Storage, v string) {
+func updateVersion(s store.Store, v string) {
 	state, _ := s.LoadAppState()
 	state.LastRunVersion = v
 	s.SaveAppState(state)
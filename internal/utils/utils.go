@@ -17,6 +17,15 @@ func FormatDuration(d time.Duration) string {
 	return fmt.Sprintf("%02d:%02d:%02d", h, m, s)
 }
 
+// FormatSignedDuration formats a time.Duration as +HH:MM:SS or -HH:MM:SS,
+// for differences such as the time over or under a target.
+func FormatSignedDuration(d time.Duration) string {
+	if d < 0 {
+		return "-" + FormatDuration(-d)
+	}
+	return "+" + FormatDuration(d)
+}
+
 // ParseHexColor parses a hex color string and returns a color.Color.
 // Accepts formats: #RRGGBB, RRGGBB, #RGB, RGB.
 // Returns color.Transparent if the input is invalid.